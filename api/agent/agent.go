@@ -39,10 +39,10 @@ const (
 // Agent exposes an api to create calls from various parameters and then submit
 // those calls, it also exposes a 'safe' shutdown mechanism via its Close method.
 // Agent has a few roles:
-//	* manage the memory pool for a given server
-//	* manage the container lifecycle for calls
-//	* execute calls against containers
-//	* invoke Start and End for each call appropriately
+//   - manage the memory pool for a given server
+//   - manage the container lifecycle for calls
+//   - execute calls against containers
+//   - invoke Start and End for each call appropriately
 //
 // Overview:
 // Upon submission of a call, Agent will start the call's timeout timer
@@ -70,6 +70,13 @@ type Agent interface {
 	// or the call times out).
 	Submit(Call) error
 
+	// PreWarm starts up to n idle hot containers for the function of a call
+	// ahead of its calls, each of which stays idle for keepWarm waiting for its
+	// first call, or as long as any other container if keepWarm is 0. Only as
+	// many containers as there are free resources for are started, PreWarm
+	// returns how many were.
+	PreWarm(ctx context.Context, call Call, n int, keepWarm time.Duration) (int, error)
+
 	// Close will wait for any outstanding calls to complete and then exit.
 	// Closing the agent will invoke Close on the underlying DataAccess.
 	// Close is not safe to be called from multiple threads.
@@ -84,8 +91,10 @@ type agent struct {
 
 	driver drivers.Driver
 
-	slotMgr *slotQueueMgr
-	evictor Evictor
+	slotMgr   *slotQueueMgr
+	evictor   Evictor
+	keepAlive KeepAlivePolicy
+	limiter   *concurrencyLimiter
 	// track usage
 	resources ResourceTracker
 
@@ -101,6 +110,11 @@ type agent struct {
 
 	// deferred actions to call at end of initialisation
 	onStartup []func()
+
+	// ships user logs to the syslog urls of apps
+	syslog *syslogPool
+	// hands user logs to the followers of fns
+	logHub *fnLogHub
 }
 
 // Option configures an agent at startup
@@ -124,7 +138,9 @@ func New(options ...Option) Agent {
 
 	a.shutWg = common.NewWaitGroup()
 	a.slotMgr = NewSlotQueueMgr()
-	a.evictor = NewEvictor()
+	a.limiter = newConcurrencyLimiter()
+	a.syslog = newSyslogPool()
+	a.logHub = newFnLogHub()
 
 	// Allow overriding config
 	for _, option := range options {
@@ -137,13 +153,27 @@ func New(options ...Option) Agent {
 	logrus.Infof("agent starting cfg=%+v", a.cfg)
 
 	if a.driver == nil {
-		d, err := NewDockerDriver(&a.cfg)
+		d, err := NewDriver(&a.cfg)
 		if err != nil {
-			logrus.WithError(err).Fatal("failed to create docker driver ")
+			logrus.WithError(err).Fatalf("failed to create %s driver", a.cfg.Driver)
 		}
 		a.driver = d
 	}
 
+	if a.keepAlive == nil {
+		p, err := NewKeepAlivePolicy(a.cfg.KeepAlivePolicy)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to create keep-alive policy")
+		}
+		a.keepAlive = p
+	}
+
+	policy, err := NewEvictionPolicy(a.cfg.EvictionPolicy)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to create eviction policy")
+	}
+	a.evictor = NewEvictorWithPolicy(policy)
+
 	a.resources = NewResourceTracker(&a.cfg)
 
 	for _, sup := range a.onStartup {
@@ -177,6 +207,17 @@ func WithDockerDriver(drv drivers.Driver) Option {
 	}
 }
 
+// WithKeepAlivePolicy provides a custom keep-alive policy to agent, in place of the one named in its config
+func WithKeepAlivePolicy(p KeepAlivePolicy) Option {
+	return func(a *agent) error {
+		if a.keepAlive != nil {
+			return errors.New("cannot add keep-alive policy to agent, keep-alive policy already exists")
+		}
+		a.keepAlive = p
+		return nil
+	}
+}
+
 // WithCallOverrider registers register a CallOverrider to modify a Call and extensions on call construction
 func WithCallOverrider(fn CallOverrider) Option {
 	return func(a *agent) error {
@@ -199,7 +240,16 @@ func WithCallOptions(opts ...CallOpt) Option {
 
 // NewDockerDriver creates a default docker driver from agent config
 func NewDockerDriver(cfg *Config) (drivers.Driver, error) {
-	return drivers.New("docker", drivers.Config{
+	return drivers.New("docker", driverConfig(cfg))
+}
+
+// NewDriver creates the driver named in agent config
+func NewDriver(cfg *Config) (drivers.Driver, error) {
+	return drivers.New(cfg.Driver, driverConfig(cfg))
+}
+
+func driverConfig(cfg *Config) drivers.Config {
+	return drivers.Config{
 		DockerNetworks:                cfg.DockerNetworks,
 		DockerLoadFile:                cfg.DockerLoadFile,
 		ServerVersion:                 cfg.MinDockerVersion,
@@ -215,7 +265,14 @@ func NewDockerDriver(cfg *Config) (drivers.Driver, error) {
 		ImageCleanExemptTags:          cfg.ImageCleanExemptTags,
 		ImageEnableVolume:             cfg.ImageEnableVolume,
 		DisableUnprivilegedContainers: cfg.DisableUnprivilegedContainers,
-	})
+		ProcessEntrypointDir:          cfg.ProcessEntrypointDir,
+		ProcessCgroupRoot:             cfg.ProcessCgroupRoot,
+		ContainerdAddress:             cfg.ContainerdAddress,
+		ContainerdNamespace:           cfg.ContainerdNamespace,
+		ContainerdCNIConfDir:          cfg.ContainerdCNIConfDir,
+		ContainerdCNIBinDir:           cfg.ContainerdCNIBinDir,
+		ContainerdHostNetwork:         cfg.ContainerdHostNetwork,
+	}
 }
 
 func (a *agent) Close() error {
@@ -229,6 +286,7 @@ func (a *agent) Close() error {
 		if a.driver != nil {
 			err = a.driver.Close()
 		}
+		a.syslog.close()
 	})
 
 	return err
@@ -288,6 +346,12 @@ func (a *agent) submit(ctx context.Context, call *call) error {
 	a.startStateTrackers(ctx, call)
 	defer a.endStateTrackers(ctx, call)
 
+	release, err := a.limiter.acquireCall(ctx, call, int(a.cfg.MaxConcurrencyQueue))
+	if err != nil {
+		return a.handleCallEnd(ctx, call, nil, err, false)
+	}
+	defer release()
+
 	slot, err := a.getSlot(ctx, call)
 	if err != nil {
 		return a.handleCallEnd(ctx, call, slot, err, false)
@@ -318,6 +382,7 @@ func (a *agent) handleCallEnd(ctx context.Context, call *call, slot Slot, err er
 
 	// This means call was routed (executed)
 	if isStarted {
+		ctx = withStartType(ctx, call.StartType)
 		call.End(ctx, err)
 		statsStopRun(ctx)
 		if err == nil {
@@ -350,14 +415,12 @@ func (a *agent) getSlot(ctx context.Context, call *call) (Slot, error) {
 	defer span.End()
 
 	// For hot requests, we use a long lived slot queue, which we use to manage hot containers
-	var isNew bool
-
-	if call.slotHashId == "" {
-		slotExtns := a.driver.GetSlotKeyExtensions(call.Extensions())
-		call.slotHashId = getSlotQueueKey(call, slotExtns)
-	}
-
-	call.slots, isNew = a.slotMgr.getSlotQueue(call.slotHashId)
+	isNew := a.getSlotQueue(call)
+	now := time.Now()
+	call.slots.arrived(now)
+	a.keepAlive.Arrived(call.slotHashId, now)
+	priority, _ := models.EvictionPriority(call.Annotations)
+	a.evictor.RecordCall(call.slotHashId, priority)
 	call.requestState.UpdateState(ctx, RequestStateWait, call.slots)
 
 	// setup slot caller with a ctx that gets cancelled once waitHot() is completed.
@@ -371,6 +434,7 @@ func (a *agent) getSlot(ctx context.Context, call *call) (Slot, error) {
 		caller.id = call.ID
 		caller.done = ctx.Done()
 		caller.notify = make(chan error, 1)
+		caller.priority = call.Priority
 	}
 
 	// update registry token of the slot queue
@@ -388,21 +452,65 @@ func (a *agent) getSlot(ctx context.Context, call *call) (Slot, error) {
 	return s, err
 }
 
+// getSlotQueue sets the slot queue of call, and returns true if it is new
+func (a *agent) getSlotQueue(call *call) bool {
+	var isNew bool
+
+	if call.slotHashId == "" {
+		slotExtns := a.driver.GetSlotKeyExtensions(call.Extensions())
+		call.slotHashId = getSlotQueueKey(call, slotExtns)
+	}
+
+	call.slots, isNew = a.slotMgr.getSlotQueue(call.slotHashId)
+	return isNew
+}
+
+// implements Agent
+func (a *agent) PreWarm(ctx context.Context, callI Call, n int, keepWarm time.Duration) (int, error) {
+	call := callI.(*call)
+
+	if !a.shutWg.AddSession(1) {
+		return 0, models.ErrCallTimeoutServerBusy
+	}
+	defer a.shutWg.DoneSession()
+
+	if a.getSlotQueue(call) {
+		// no call waits yet, but the calls to come need a launcher
+		go a.hotLauncher(ctx, call, &slotCaller{})
+	}
+
+	// pre-warmed containers count towards the maximum of the fn too
+	_, maxHot, _ := models.HotContainers(call.Annotations)
+
+	started := 0
+	for started < n {
+		// the fn may be at its maximum before any is started
+		if curStats := call.slots.getStats(); maxHot > 0 && curStats.containers() >= uint64(maxHot) {
+			break
+		}
+		if !a.preWarm(ctx, call, keepWarm) {
+			break
+		}
+		started++
+	}
+	common.Logger(ctx).WithFields(logrus.Fields{"fn_id": call.FnID, "requested": n, "started": started}).Info("Pre-warmed hot function")
+	return started, nil
+}
+
 // hotLauncher is spawned in a go routine for each slot queue to monitor stats and launch hot
-// containers if needed. Upon shutdown or activity timeout, hotLauncher exits and during exit,
-// it destroys the slot queue.
+// containers if needed, or ahead of calls as the keep-alive policy says. Upon shutdown or
+// activity timeout, hotLauncher exits and during exit, it destroys the slot queue.
 func (a *agent) hotLauncher(ctx context.Context, call *call, caller *slotCaller) {
-	// Let use 60 minutes or 2 * IdleTimeout as hot queue idle timeout, pick
-	// whichever is longer. If in this time, there's no activity, then
-	// we destroy the hot queue.
-	timeout := a.cfg.HotLauncherTimeout
-	idleTimeout := time.Duration(call.IdleTimeout) * time.Second * 2
-	if timeout < idleTimeout {
-		timeout = idleTimeout
-	}
+	idleTimeout := time.Duration(call.IdleTimeout) * time.Second
 
 	logger := common.Logger(ctx)
-	logger.WithField("launcher_timeout", timeout).Debug("Hot function launcher starting")
+	logger.Debug("Hot function launcher starting")
+
+	// the annotations of a fn are validated as it is stored, invalid ones set no limits
+	minHot, maxHot, err := models.HotContainers(call.Annotations)
+	if err != nil {
+		logger.WithError(err).Warn("Ignoring hot container limits")
+	}
 
 	// IMPORTANT: get a context that has a child span / logger but NO timeout
 	// TODO this is a 'FollowsFrom'
@@ -422,23 +530,98 @@ func (a *agent) hotLauncher(ctx context.Context, call *call, caller *slotCaller)
 		}
 	}()
 
+	var preWarmed int64 // the last arrival a container was pre-warmed after
 	for {
+		window := a.keepAlive.Window(call.slots.key, idleTimeout)
+
+		// Let use 60 minutes or 2 * IdleTimeout as hot queue idle timeout, pick
+		// whichever is longer, but outlive any pre-warmed container. If in this
+		// time, there's no activity, then we destroy the hot queue.
+		timeout := a.cfg.HotLauncherTimeout
+		if timeout < 2*idleTimeout {
+			timeout = 2 * idleTimeout
+		}
+		if window.PreWarm > 0 && timeout < window.PreWarm+window.KeepAlive {
+			timeout = window.PreWarm + window.KeepAlive
+		}
+
 		ctx, cancel := context.WithTimeout(ctx, timeout)
-		a.checkLaunch(ctx, call, *caller)
+		a.checkLaunch(ctx, call, *caller, maxHot)
+
+		// below the minimum, start containers with no waiters and check back every poll
+		var poll *time.Timer
+		var pollC <-chan time.Time
+		if minHot > 0 {
+			a.keepMinHot(ctx, call, minHot)
+			poll = time.NewTimer(a.cfg.HotPoll)
+			pollC = poll.C
+		}
+
+		// pre-warm once after each last arrival, if the policy says to
+		var preWarm *time.Timer
+		var preWarmC <-chan time.Time
+		last := call.slots.getLastArrival()
+		if window.PreWarm > 0 && last != preWarmed {
+			preWarm = time.NewTimer(time.Until(time.Unix(0, last).Add(window.PreWarm)))
+			preWarmC = preWarm.C
+		}
 
 		select {
 		case <-ctx.Done(): // timed out
 			cancel()
 			if a.slotMgr.deleteSlotQueue(call.slots) {
+				a.evictor.DeleteSlot(call.slots.key)
+				a.keepAlive.Forget(call.slots.key)
 				logger.Debug("Hot function launcher timed out")
 				return
 			}
 		case caller = <-call.slots.signaller:
 			cancel()
+		case <-preWarmC:
+			cancel()
+			preWarmed = last
+			if call.slots.getLastArrival() == last && !call.slots.hasContainers() {
+				a.preWarm(ctx, call, 0)
+			}
+		case <-pollC:
+			cancel()
+		}
+		if preWarm != nil {
+			preWarm.Stop()
+		}
+		if poll != nil {
+			poll.Stop()
 		}
 	}
 }
 
+// preWarm starts a container for the slot queue of call ahead of its calls, which
+// stays idle for keepWarm waiting for the first, or as the keep-alive policy says if
+// 0. Only free resources are used, a pre-warmed container is not worth evicting
+// others or waiting for. It returns false if the container could not be started.
+func (a *agent) preWarm(ctx context.Context, call *call, keepWarm time.Duration) bool {
+	tok := a.resources.GetResourceTokenNB(ctx, call.Memory+uint64(call.TmpFsSize), call.CPUs)
+	if tok.Error() != nil {
+		tok.Close()
+		return false
+	}
+
+	state := NewContainerState()
+	state.UpdateState(ctx, ContainerStateWait, call)
+
+	if a.shutWg.AddSession(1) {
+		common.Logger(ctx).Debug("Pre-warming hot function")
+		go func() {
+			a.runHot(ctx, slotCaller{preWarm: true, keepWarm: keepWarm}, call, tok, state)
+			a.shutWg.DoneSession()
+		}()
+		return true
+	}
+	tok.Close()
+	state.UpdateState(ctx, ContainerStateDone, call)
+	return false
+}
+
 func tryNotify(notifyChan chan error, err error) {
 	if notifyChan != nil && err != nil {
 		select {
@@ -448,13 +631,30 @@ func tryNotify(notifyChan chan error, err error) {
 	}
 }
 
-func (a *agent) checkLaunch(ctx context.Context, call *call, caller slotCaller) {
+// keepMinHot starts containers for the slot queue of call while it has fewer than minHot,
+// as far as free resources allow.
+func (a *agent) keepMinHot(ctx context.Context, call *call, minHot int) {
+	for {
+		curStats := call.slots.getStats()
+		if curStats.containers() >= uint64(minHot) || !a.preWarm(ctx, call, 0) {
+			return
+		}
+	}
+}
+
+func (a *agent) checkLaunch(ctx context.Context, call *call, caller slotCaller, maxHot int) {
 	curStats := call.slots.getStats()
 	isBlocking := !a.cfg.EnableNBResourceTracker
 	if !isNewContainerNeeded(&curStats) {
 		return
 	}
 
+	// at the maximum of the fn, calls queue for the containers it has rather
+	// than taking capacity from other functions
+	if maxHot > 0 && curStats.containers() >= uint64(maxHot) {
+		return
+	}
+
 	// IMPORTANT: we are here because: isNewContainerNeeded is true,
 	// in other words, we need to launch a new container at this time due to high load.
 
@@ -486,7 +686,8 @@ func (a *agent) checkLaunch(ctx context.Context, call *call, caller slotCaller)
 				tryNotify(caller.notify, tok.Error())
 			} else {
 				needMem, needCpu := tok.NeededCapacity()
-				notifyChans = a.evictor.PerformEviction(call.slotHashId, needMem, uint64(needCpu))
+				// a call may evict the idle containers of calls of no higher priority than its own
+				notifyChans = a.evictor.PerformPriorityEviction(call.slotHashId, caller.priority, needMem, uint64(needCpu))
 				// For Non-blocking mode, if there's nothing to evict, we emit 503.
 				if len(notifyChans) == 0 && !isBlocking {
 					tryNotify(caller.notify, models.ErrCallTimeoutServerBusy)
@@ -527,7 +728,7 @@ func (a *agent) waitHot(ctx context.Context, call *call, caller *slotCaller) (Sl
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel() // shut down dequeuer if we grab a slot
 
-	ch := call.slots.startDequeuer(ctx)
+	ch := call.slots.startDequeuer(ctx, call.Priority)
 
 	// 1) if we can get a slot immediately, grab it.
 	// 2) if we don't, send a signaller every x msecs until we do.
@@ -572,6 +773,7 @@ type hotSlot struct {
 	container     *container // TODO mask this
 	cfg           *Config
 	containerSpan trace.SpanContext
+	startType     string // how the container came to be available for this slot
 }
 
 func (s *hotSlot) SetError(err error) {
@@ -590,6 +792,8 @@ func (s *hotSlot) exec(ctx context.Context, call *call) error {
 	defer span.End()
 
 	call.requestState.UpdateState(ctx, RequestStateExec, call.slots)
+	call.StartType = s.startType
+	atomic.StoreInt32(&s.container.priority, int32(call.Priority))
 
 	// link the container id and id in the logs [for us!]
 	// common.Logger(ctx).WithField("container_id", s.container.id).Info("starting call")
@@ -686,9 +890,21 @@ func (s *hotSlot) dispatch(ctx context.Context, call *call) error {
 
 	common.Logger(ctx).WithField("resp", resp).Debug("Got resp from UDS socket")
 
+	// tell the caller how the container came to be available, now that the
+	// request has gone to it
+	if rw, ok := call.respWriter.(http.ResponseWriter); ok {
+		rw.Header().Set(models.StartTypeHeader, call.StartType)
+	}
+
+	// a streamed response is not held anywhere, so its size is not bounded
+	maxSize := s.cfg.MaxResponseSize
+	if call.Stream {
+		maxSize = 0
+	}
+
 	ioErrChan := make(chan error, 1)
 	go func() {
-		ioErrChan <- s.writeResp(ctx, s.cfg.MaxResponseSize, resp, call.respWriter)
+		ioErrChan <- s.writeResp(ctx, maxSize, resp, call.respWriter)
 	}()
 
 	select {
@@ -925,6 +1141,10 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 			initTime := time.Now() // Declaring this prior to keep the stats in sync
 			statsContainerUDSInitLatency(ctx, initStart, initTime, "initialized")
 			atomic.StoreInt64(&call.initStartTime, int64(initTime.Sub(initStart)))
+			// a pre-warmed container takes no call's cold start, eviction or not
+			if a.evictor.RecordColdStart(call.slotHashId, initTime.Sub(ctrCreatePrepStart)) && !caller.preWarm {
+				statsEvictionColdStart(ctx, a.cfg.EvictionPolicy)
+			}
 		case <-a.shutWg.Closer(): // agent shutdown
 			closerTime := time.Now()
 			statsContainerUDSInitLatency(ctx, initStart, closerTime, "canceled")
@@ -945,17 +1165,33 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 
 		timer.Stop() // no longer needed
 
+		// the first call a container takes waited for it to start, unless
+		// it was pre-warmed ahead of calls
+		startType := models.StartTypeCold
+		if caller.preWarm {
+			startType = models.StartTypeWarm
+		} else if pc, ok := cookie.(drivers.PreforkPoolCookie); ok && pc.FromPreforkPool() {
+			startType = models.StartTypePreforkPool
+		}
+		preWarmed := caller.preWarm
 		for ctx.Err() == nil {
 			slot := &hotSlot{
 				done:          make(chan error, 1),
 				container:     container,
 				cfg:           &a.cfg,
 				containerSpan: trace.FromContext(ctx).SpanContext(),
+				startType:     startType,
 			}
 
-			if !a.runHotReq(ctx, call, state, logger, cookie, slot, container) {
+			idleTimeout := a.idleTimeout(call, preWarmed)
+			if preWarmed && caller.keepWarm > 0 {
+				idleTimeout = caller.keepWarm
+			}
+			if !a.runHotReq(ctx, call, state, logger, cookie, slot, container, tok, idleTimeout) {
 				return
 			}
+			startType = models.StartTypeWarm
+			preWarmed = false
 
 			// wait for this call to finish
 			// NOTE do NOT select with shutdown / other channels. slot handles this.
@@ -972,7 +1208,7 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 	}
 }
 
-//checkSocketDestination verifies that the socket file created by the FDK is valid and permitted - notably verifying that any symlinks are relative to the socket dir
+// checkSocketDestination verifies that the socket file created by the FDK is valid and permitted - notably verifying that any symlinks are relative to the socket dir
 func checkSocketDestination(filename string) error {
 	finfo, err := os.Lstat(filename)
 	if err != nil {
@@ -1069,15 +1305,41 @@ func inotifyAwait(ctx context.Context, iofsDir string, udsWait chan error) {
 	}()
 }
 
+// idleTimeout returns how long a container of call stays idle before it shuts down, as the
+// keep-alive policy says. Containers the policy will pre-warm again are only kept long enough
+// to pick up calls that are waiting already, unless they are pre-warmed and waiting for a call.
+func (a *agent) idleTimeout(call *call, preWarmed bool) time.Duration {
+	window := a.keepAlive.Window(call.slots.key, time.Duration(call.IdleTimeout)*time.Second)
+	if window.PreWarm > 0 && !preWarmed && window.KeepAlive > a.cfg.HotPoll {
+		return a.cfg.HotPoll
+	}
+	return window.KeepAlive
+}
+
+// isMinHot returns true if the slot queue of call has no more containers than the
+// minimum of its fn
+func (a *agent) isMinHot(call *call) bool {
+	minHot, _, _ := models.HotContainers(call.Annotations)
+	curStats := call.slots.getStats()
+	return minHot > 0 && curStats.containers() <= uint64(minHot)
+}
+
 // runHotReq enqueues a free slot to slot queue manager and watches various timers and the consumer until
-// the slot is consumed. A return value of false means, the container should shutdown and no subsequent
-// calls should be made to this function.
-func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState, logger logrus.FieldLogger, cookie drivers.Cookie, slot *hotSlot, c *container) bool {
+// the slot is consumed or it is idle for idleTimeout. A container idle for FreezeIdle is frozen until then,
+// and its slot queued behind those of running containers. A return value of false means, the container
+// should shutdown and no subsequent calls should be made to this function.
+func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState, logger logrus.FieldLogger, cookie drivers.Cookie, slot *hotSlot, c *container, tok ResourceToken, idleTimeout time.Duration) bool {
 
 	var err error
+	var isFrozen bool
 
 	freezeTimer := common.NewTimer(a.cfg.FreezeIdle)
-	idleTimer := common.NewTimer(time.Duration(call.IdleTimeout) * time.Second)
+	freeze := freezeTimer.C
+	if a.cfg.FreezeIdle == 0 {
+		// a zero FreezeIdle never freezes
+		freeze = nil
+	}
+	idleTimer := common.NewTimer(idleTimeout)
 
 	defer func() {
 		freezeTimer.Stop()
@@ -1102,6 +1364,25 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 		case <-ctx.Done(): // container shutdown
 		case <-a.shutWg.Closer(): // agent shutdown
 		case <-idleTimer.C:
+			// containers up to the minimum of the fn stay, however idle
+			if a.isMinHot(call) {
+				idleTimer.Reset(idleTimeout)
+				continue
+			}
+		case <-freeze:
+			// take the slot off the queue while freezing, so that no call is sent
+			// to a container half frozen, unless a call already has it
+			if !call.slots.acquireSlot(s) {
+				continue
+			}
+			if err = a.freeze(ctx, cookie, c, tok); err != nil {
+				return false
+			}
+			isFrozen = true
+			state.UpdateState(ctx, ContainerStatePaused, call)
+			slot.startType = models.StartTypePausedResume
+			s = call.slots.queuePausedSlot(slot)
+			continue
 		case <-evicted:
 		}
 		break
@@ -1111,14 +1392,29 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 	// abort/shutdown/timeout/evict, attempt to acquire and terminate,
 	// otherwise continue processing the request
 	if call.slots.acquireSlot(s) {
+		// a paused container is only resumed to be torn down, its token is
+		// closed as paused rather than taking its memory back from others
+		if isFrozen {
+			if err := cookie.Unfreeze(ctx); err != nil {
+				logger.WithError(err).Info("cannot unfreeze container to shut it down")
+			}
+		}
 		select {
 		case <-evicted:
-			statsContainerEvicted(ctx, state.GetState())
+			statsContainerEvicted(ctx, state.GetState(), a.cfg.EvictionPolicy)
 		default:
 		}
 		return false
 	}
 
+	// a paused container takes its memory back to run the call, and fails
+	// the call with it if it cannot be resumed
+	if isFrozen {
+		if err = a.unfreeze(ctx, call, cookie, c, tok); err != nil {
+			return false
+		}
+	}
+
 	// We disable eviction after acquisition attempt above, since
 	// this can reinstall an eviction token if eviction has taken place.
 	c.DisableEviction(call)
@@ -1127,6 +1423,42 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 	return true
 }
 
+// freeze pauses the container of cookie, and counts it as paused until it is
+// unfrozen
+func (a *agent) freeze(ctx context.Context, cookie drivers.Cookie, c *container, tok ResourceToken) error {
+	start := time.Now()
+	if err := cookie.Freeze(ctx); err != nil {
+		return err
+	}
+	statsContainerPauseLatency(ctx, time.Since(start))
+
+	c.SetEvictionPaused(true)
+	tok.SetPaused(true)
+	statsUtilization(ctx, a.resources.GetUtilization())
+	return nil
+}
+
+// unfreeze resumes the container of cookie that freeze paused. If part of its
+// memory was reserved for other containers meanwhile, other containers are
+// evicted to get it back.
+func (a *agent) unfreeze(ctx context.Context, call *call, cookie drivers.Cookie, c *container, tok ResourceToken) error {
+	start := time.Now()
+	if err := cookie.Unfreeze(ctx); err != nil {
+		return err
+	}
+	statsContainerUnpauseLatency(ctx, time.Since(start))
+
+	c.SetEvictionPaused(false)
+	tok.SetPaused(false)
+	util := a.resources.GetUtilization()
+	if util.MemOvercommitted > 0 {
+		needMem := (util.MemOvercommitted + Mem1MB - 1) / Mem1MB
+		a.evictor.PerformEviction(call.slotHashId, needMem, 0)
+	}
+	statsUtilization(ctx, util)
+	return nil
+}
+
 // container implements drivers.ContainerTask container is the execution of a
 // single container, which may run multiple functions [consecutively]. the id
 // and stderr can be swapped out by new calls in the container.  input and
@@ -1147,7 +1479,7 @@ type container struct {
 	tmpFsSize      uint64
 	disableNet     bool
 	iofs           iofs
-	logCfg         drivers.LoggerConfig
+	logCfg         drivers.LoggerConfig // unset, as the agent ships user logs to syslog itself
 	close          func()
 	beforeCall     drivers.BeforeCall
 	afterCall      drivers.AfterCall
@@ -1164,6 +1496,7 @@ type container struct {
 
 	evictor    Evictor
 	evictToken *EvictToken
+	priority   int32 // priority of the last call, accessed atomically
 }
 
 var _ drivers.ContainerTask = &container{}
@@ -1253,14 +1586,7 @@ func newHotContainer(ctx context.Context, evictor Evictor, caller *slotCaller, c
 		iofs:           iofs,
 		dockerAuth:     call.dockerAuth,
 		authToken:      authToken,
-		logCfg: drivers.LoggerConfig{
-			URL: strings.TrimSpace(call.SyslogURL),
-			Tags: []drivers.LoggerTag{
-				{Name: "app_id", Value: call.AppID},
-				{Name: "fn_id", Value: call.FnID},
-			},
-		},
-		stderr: stderr,
+		stderr:         stderr,
 		udsClient: http.Client{
 			// use this transport so we can trace the requests to container, handy for debugging...
 			Transport: &ochttp.Transport{
@@ -1271,6 +1597,7 @@ func newHotContainer(ctx context.Context, evictor Evictor, caller *slotCaller, c
 			},
 		},
 		evictor:    evictor,
+		priority:   int32(call.Priority),
 		beforeCall: func(context.Context, *models.Call, drivers.CallExtensions) error { return nil },
 		afterCall:  func(context.Context, *models.Call, drivers.CallExtensions) error { return nil },
 		close: func() {
@@ -1364,6 +1691,7 @@ func (c *container) EnableEviction(call *call) {
 	if c.evictToken == nil {
 		c.evictToken = c.evictor.CreateEvictToken(call.slotHashId, call.Memory+uint64(call.TmpFsSize), uint64(call.CPUs))
 	}
+	c.evictToken.SetPriority(int(atomic.LoadInt32(&c.priority)))
 	c.evictToken.SetEvictable(true)
 }
 
@@ -1386,6 +1714,14 @@ func (c *container) DisableEviction(call *call) {
 	c.evictToken = c.evictor.CreateEvictToken(call.slotHashId, call.Memory+uint64(call.TmpFsSize), uint64(call.CPUs))
 }
 
+// SetEvictionPaused marks the container as frozen, or no longer, to the
+// evictor, which evicts paused containers first.
+func (c *container) SetEvictionPaused(paused bool) {
+	if c.evictToken != nil {
+		c.evictToken.SetPaused(paused)
+	}
+}
+
 // Close closes container and releases resources associated with it
 func (c *container) Close() {
 	if c.close != nil {
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -15,6 +16,7 @@ import (
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
 	"github.com/sirupsen/logrus"
 	"go.opencensus.io/trace"
 )
@@ -56,6 +58,16 @@ func FromHTTPFnRequest(app *models.App, fn *models.Fn, req *http.Request) CallOp
 			syslogURL = *app.SyslogURL
 		}
 
+		priority, err := models.RequestPriority(req.Header.Get(models.PriorityHeader), app)
+		if err != nil {
+			return err
+		}
+
+		// each from its own annotations, so that a fn can't lift the cap of
+		// its app; invalid annotations are refused when fns and apps are saved
+		fnMax, _ := models.FnMaxConcurrency(fn.Annotations)
+		appMax, _ := models.AppMaxConcurrency(app.Annotations)
+
 		c.Call = &models.Call{
 			ID:    id,
 			Image: fn.Image,
@@ -78,6 +90,10 @@ func FromHTTPFnRequest(app *models.App, fn *models.Fn, req *http.Request) CallOp
 			AppName:     app.Name,
 			FnID:        fn.ID,
 			SyslogURL:   syslogURL,
+			Priority:    priority,
+
+			FnMaxConcurrency:  fnMax,
+			AppMaxConcurrency: appMax,
 		}
 
 		c.req = req
@@ -156,8 +172,11 @@ func FromModelAndInput(mCall *models.Call, in io.ReadCloser) CallOpt {
 // TODO consider removal, this is from a shuffle
 func WithTrigger(t *models.Trigger) CallOpt {
 	return func(c *call) error {
-		// right now just set the trigger id
 		c.TriggerID = t.ID
+		// the trigger decides the priority, if it sets one
+		if p, ok, _ := models.TriggerPriority(t.Annotations); ok {
+			c.Priority = p
+		}
 		return nil
 	}
 }
@@ -187,6 +206,24 @@ func InvokeDetached() CallOpt {
 	}
 }
 
+// InvokeStreaming streams the response of the call to its writer as the
+// function writes it, without bounding its size
+func InvokeStreaming() CallOpt {
+	return func(c *call) error {
+		c.Model().Stream = true
+		return nil
+	}
+}
+
+// WithCallID overrides the ID of the call, for a call that runs a request
+// which was given its ID before, eg. when it was queued
+func WithCallID(id string) CallOpt {
+	return func(c *call) error {
+		c.ID = id
+		return nil
+	}
+}
+
 // WithContext overrides the context on the call
 func WithContext(ctx context.Context) CallOpt {
 	return func(c *call) error {
@@ -261,7 +298,13 @@ func (a *agent) GetCall(opts ...CallOpt) (Call, error) {
 	if c.stderr == nil {
 		// TODO(reed): is line writer is vulnerable to attack?
 		// XXX(reed): forcing this as default is not great / configuring it isn't great either. reconsider.
-		c.stderr = setupLogger(c.req.Context(), a.cfg.MaxLogSize, !a.cfg.DisableDebugUserLogs, c.Call)
+		c.stderr = setupLogger(c.req.Context(), a.cfg.MaxLogSize, !a.cfg.DisableDebugUserLogs, c.Call, a.logSinks(c.Call)...)
+	} else if _, ok := c.stderr.(common.NoopReadWriteCloser); ok {
+		// logs that are otherwise discarded still go to the syslog of the app,
+		// and to the followers of the fn
+		if sinks := a.logSinks(c.Call); len(sinks) > 0 {
+			c.stderr = &rwc{multiWriteCloser(sinks), new(bytes.Buffer)}
+		}
 	}
 	if c.respWriter == nil {
 		// send function output to logs if no writer given (TODO no longer need w/o async?)
@@ -308,6 +351,10 @@ type call struct {
 
 	// LB & Pure Runner Extra Config
 	extensions map[string]string
+
+	// streamBody is set on an LB when the request body is streamed to the
+	// runner, rather than buffered
+	streamBody bool
 }
 
 // SlotHashId returns a string identity for this call that can be used to uniquely place the call in a given container
@@ -320,6 +367,11 @@ func (c *call) Extensions() map[string]string {
 	return c.extensions
 }
 
+// StreamsBody returns whether the request body is streamed to the runner
+func (c *call) StreamsBody() bool {
+	return c.streamBody
+}
+
 func (c *call) RequestBody() io.ReadCloser {
 	if c.req.Body != nil && c.req.GetBody != nil {
 		rdr, err := c.req.GetBody()
@@ -389,10 +441,14 @@ func (c *call) End(ctx context.Context, errIn error) error {
 	// ensure stats histogram is reasonably bounded
 	c.Call.Stats = stats.Decimate(240, c.Call.Stats)
 
-	// NOTE call this after InsertLog or the buffer will get reset
+	// hand the captured stderr to the listeners, before closing it resets the buffer
+	if _, noop := c.stderr.(common.NoopReadWriteCloser); !noop {
+		ctx = context.WithValue(ctx, fnext.CallLogKey, io.Reader(c.stderr))
+	}
+	err := c.ct.fireAfterCall(ctx, c.Model())
 	c.stderr.Close()
 
-	if err := c.ct.fireAfterCall(ctx, c.Model()); err != nil {
+	if err != nil {
 		return err
 	}
 	return errIn // original error, important for use in sync call returns
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// concurrencyLimiter caps how many calls of each fn and of each app run at
+// once in an agent. Calls beyond a cap wait in a queue, first in first out,
+// and are turned away with models.ErrCallConcurrencyLimit once it is full.
+type concurrencyLimiter struct {
+	lock   sync.Mutex
+	limits map[string]*concurrencyLimit
+}
+
+// concurrencyLimit is the state of the calls under one cap
+type concurrencyLimit struct {
+	max     int
+	running int
+	waiters []chan struct{}
+}
+
+func newConcurrencyLimiter() *concurrencyLimiter {
+	return &concurrencyLimiter{
+		limits: make(map[string]*concurrencyLimit),
+	}
+}
+
+// acquireCall waits until call may run under the max_concurrency annotations
+// of its fn and app, queueing behind at most maxQueue other calls for each,
+// and returns the func that releases the call once it is done.
+func (l *concurrencyLimiter) acquireCall(ctx context.Context, call *call, maxQueue int) (func(), error) {
+	fnMax, appMax := call.FnMaxConcurrency, call.AppMaxConcurrency
+
+	fnKey := "fn:" + call.FnID
+	appKey := "app:" + call.AppID
+
+	// always fn before app, so that no two calls wait on each other
+	if err := l.acquire(ctx, fnKey, fnMax, maxQueue); err != nil {
+		return nil, err
+	}
+	if err := l.acquire(ctx, appKey, appMax, maxQueue); err != nil {
+		l.release(fnKey, fnMax)
+		return nil, err
+	}
+
+	return func() {
+		l.release(appKey, appMax)
+		l.release(fnKey, fnMax)
+	}, nil
+}
+
+// acquire waits until fewer than max calls with key are running, behind at
+// most maxQueue others. There is no limit if max is 0.
+func (l *concurrencyLimiter) acquire(ctx context.Context, key string, max, maxQueue int) error {
+	if max <= 0 {
+		return nil
+	}
+
+	l.lock.Lock()
+
+	limit, ok := l.limits[key]
+	if !ok {
+		limit = &concurrencyLimit{}
+		l.limits[key] = limit
+	}
+	// the annotation may have changed since the last call
+	limit.max = max
+
+	if limit.running < max && len(limit.waiters) == 0 {
+		limit.running++
+		l.lock.Unlock()
+		return nil
+	}
+	if len(limit.waiters) >= maxQueue {
+		l.lock.Unlock()
+		return models.ErrCallConcurrencyLimit
+	}
+
+	wait := make(chan struct{})
+	limit.waiters = append(limit.waiters, wait)
+	l.lock.Unlock()
+
+	select {
+	case <-wait:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.lock.Lock()
+	for i, w := range limit.waiters {
+		if w == wait {
+			limit.waiters = append(limit.waiters[:i], limit.waiters[i+1:]...)
+			l.lock.Unlock()
+			return ctx.Err()
+		}
+	}
+	l.lock.Unlock()
+
+	// too late, a release handed us its place, so pass it on
+	l.release(key, max)
+	return ctx.Err()
+}
+
+// release gives the place of a call with key that is done to the next call
+// waiting for it, if any
+func (l *concurrencyLimiter) release(key string, max int) {
+	if max <= 0 {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	limit, ok := l.limits[key]
+	if !ok {
+		return
+	}
+
+	limit.running--
+	for len(limit.waiters) > 0 && limit.running < limit.max {
+		limit.running++
+		close(limit.waiters[0])
+		limit.waiters = limit.waiters[1:]
+	}
+
+	if limit.running <= 0 && len(limit.waiters) == 0 {
+		delete(l.limits, key)
+	}
+}
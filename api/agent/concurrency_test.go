@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	l := newConcurrencyLimiter()
+	ctx := context.Background()
+
+	// no limit
+	for i := 0; i < 10; i++ {
+		if err := l.acquire(ctx, "fn", 0, 0); err != nil {
+			t.Fatalf("should not be limited: %v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := l.acquire(ctx, "fn", 2, 1); err != nil {
+			t.Fatalf("should run %d under the limit: %v", i, err)
+		}
+	}
+
+	queued := make(chan error, 1)
+	go func() {
+		queued <- l.acquire(ctx, "fn", 2, 1)
+	}()
+
+	// wait for it to queue
+	for {
+		l.lock.Lock()
+		waiting := len(l.limits["fn"].waiters)
+		l.lock.Unlock()
+		if waiting == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := l.acquire(ctx, "fn", 2, 1); err != models.ErrCallConcurrencyLimit {
+		t.Fatalf("the queue should be full, got: %v", err)
+	}
+
+	select {
+	case err := <-queued:
+		t.Fatalf("should wait for a call to be released, got: %v", err)
+	default:
+	}
+
+	l.release("fn", 2)
+	if err := <-queued; err != nil {
+		t.Fatalf("should run once a call is released: %v", err)
+	}
+
+	// a call that gives up waiting leaves the queue
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(cctx, "fn", 2, 1); err != context.DeadlineExceeded {
+		t.Fatalf("should time out waiting, got: %v", err)
+	}
+
+	l.release("fn", 2)
+	l.release("fn", 2)
+	if len(l.limits) != 0 {
+		t.Fatalf("limits should be dropped once no calls run, got: %+v", l.limits)
+	}
+}
+
+func TestConcurrencyLimiterCall(t *testing.T) {
+	l := newConcurrencyLimiter()
+	ctx := context.Background()
+
+	call1 := &call{Call: &models.Call{FnID: "fn1", AppID: "app", AppMaxConcurrency: 1}}
+	call2 := &call{Call: &models.Call{FnID: "fn2", AppID: "app", AppMaxConcurrency: 1}}
+
+	release, err := l.acquireCall(ctx, call1, 0)
+	if err != nil {
+		t.Fatalf("should run under the app limit: %v", err)
+	}
+
+	// another fn of the app is held to the app limit, and may not queue
+	if _, err := l.acquireCall(ctx, call2, 0); err != models.ErrCallConcurrencyLimit {
+		t.Fatalf("should be turned away at the app limit, got: %v", err)
+	}
+	if len(l.limits) != 1 {
+		t.Fatalf("the fn with no limit should hold none, got: %+v", l.limits)
+	}
+
+	release()
+	release, err = l.acquireCall(ctx, call2, 0)
+	if err != nil {
+		t.Fatalf("should run once the app has room: %v", err)
+	}
+	release()
+}
+
+func TestConcurrencyFromAnnotations(t *testing.T) {
+	appAnnotations, _ := models.EmptyAnnotations().With(models.AppMaxConcurrencyAnnotation, 1)
+	appAnnotations, _ = appAnnotations.With(models.FnMaxConcurrencyAnnotation, 5)
+	fnAnnotations, _ := models.EmptyAnnotations().With(models.AppMaxConcurrencyAnnotation, 10)
+	fnAnnotations, _ = fnAnnotations.With(models.FnMaxConcurrencyAnnotation, 2)
+
+	app := &models.App{ID: "app_id", Annotations: appAnnotations}
+	fn := &models.Fn{ID: "fn_id", AppID: app.ID, Annotations: fnAnnotations}
+	req, err := http.NewRequest("GET", "http://127.0.0.1:8080/invoke/"+fn.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := new(call)
+	if err := FromHTTPFnRequest(app, fn, req)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// the app cap is the app's alone, the fn can't lift it, nor can the app set the fn cap
+	if c.AppMaxConcurrency != 1 || c.FnMaxConcurrency != 2 {
+		t.Fatalf("expected an app cap of 1 and a fn cap of 2, got %d and %d", c.AppMaxConcurrency, c.FnMaxConcurrency)
+	}
+}
@@ -11,18 +11,25 @@ import (
 
 // Config specifies various settings for an agent
 type Config struct {
+	Driver                        string        `json:"driver"`
 	MinDockerVersion              string        `json:"min_docker_version"`
 	ContainerLabelTag             string        `json:"container_label_tag"`
 	DockerNetworks                string        `json:"docker_networks"`
 	DockerLoadFile                string        `json:"docker_load_file"`
 	DisableUnprivilegedContainers bool          `json:"disable_unprivileged_containers"`
 	FreezeIdle                    time.Duration `json:"freeze_idle_msecs"`
+	PausedMemoryOvercommit        uint64        `json:"paused_memory_overcommit_percent"`
 	HotPoll                       time.Duration `json:"hot_poll_msecs"`
 	HotLauncherTimeout            time.Duration `json:"hot_launcher_timeout_msecs"`
 	HotPullTimeout                time.Duration `json:"hot_pull_timeout_msecs"`
 	HotStartTimeout               time.Duration `json:"hot_start_timeout_msecs"`
+	KeepAlivePolicy               string        `json:"keep_alive_policy"`
+	EvictionPolicy                string        `json:"eviction_policy"`
+	MaxConcurrencyQueue           uint64        `json:"max_concurrency_queue"`
 	DetachedHeadRoom              time.Duration `json:"detached_head_room_msecs"`
 	MaxResponseSize               uint64        `json:"max_response_size_bytes"`
+	MaxBufferedRequestSize        uint64        `json:"max_buffered_request_size_bytes"`
+	MaxStreamedRequestSize        uint64        `json:"max_streamed_request_size_bytes"`
 	MaxHdrResponseSize            uint64        `json:"max_hdr_response_size_bytes"`
 	MaxLogSize                    uint64        `json:"max_log_size_bytes"`
 	MaxTotalCPU                   uint64        `json:"max_total_cpu_mcpus"`
@@ -50,9 +57,18 @@ type Config struct {
 	ImageCleanMaxSize             uint64        `json:"image_clean_max_size"`
 	ImageCleanExemptTags          string        `json:"image_clean_exempt_tags"`
 	ImageEnableVolume             bool          `json:"image_enable_volume"`
+	ProcessEntrypointDir          string        `json:"process_entrypoint_dir"`
+	ProcessCgroupRoot             string        `json:"process_cgroup_root"`
+	ContainerdAddress             string        `json:"containerd_address"`
+	ContainerdNamespace           string        `json:"containerd_namespace"`
+	ContainerdCNIConfDir          string        `json:"containerd_cni_conf_dir"`
+	ContainerdCNIBinDir           string        `json:"containerd_cni_bin_dir"`
+	ContainerdHostNetwork         bool          `json:"containerd_host_network"`
 }
 
 const (
+	// EnvDriver is the name of the driver that runs functions, one of "docker", "containerd" and "process"
+	EnvDriver = "FN_DRIVER"
 	// EnvContainerLabelTag is a classifier label tag that is used to distinguish fn managed containers
 	EnvContainerLabelTag = "FN_CONTAINER_LABEL_TAG"
 	// EnvImageCleanMaxSize enables image cleaner and sets the high water mark for image cache in bytes
@@ -67,8 +83,13 @@ const (
 	EnvDockerLoadFile = "FN_DOCKER_LOAD_FILE"
 	// EnvDisableUnprivilegedContainers disables docker security features like user name, cap drop etc.
 	EnvDisableUnprivilegedContainers = "FN_DISABLE_UNPRIVILEGED_CONTAINERS"
-	// EnvFreezeIdle is the delay between a container being last used and being frozen
+	// EnvFreezeIdle is the delay between a container being last used and being frozen, zero to never freeze.
+	// Freezing is off by default.
 	EnvFreezeIdle = "FN_FREEZE_IDLE_MSECS"
+	// EnvPausedMemoryOvercommit is the percentage of the memory of paused containers that is
+	// reserved for other containers as well, paused containers being evicted if it is needed back. None of
+	// it is by default.
+	EnvPausedMemoryOvercommit = "FN_PAUSED_MEMORY_OVERCOMMIT_PERCENT"
 	// EnvHotPoll is the interval to ping for a slot manager thread to check if a container should be
 	// launched for a given function
 	EnvHotPoll = "FN_HOT_POLL_MSECS"
@@ -78,8 +99,23 @@ const (
 	EnvHotPullTimeout = "FN_HOT_PULL_TIMEOUT_MSECS"
 	// EnvHotStartTimeout is the timeout for a hot container to become available for use for requests after EnvHotStartTimeout
 	EnvHotStartTimeout = "FN_HOT_START_TIMEOUT_MSECS"
+	// EnvKeepAlivePolicy is the policy that decides how long idle hot containers stay alive, one of
+	// "fixed" (the idle timeout of each function) and "hybrid-histogram"
+	EnvKeepAlivePolicy = "FN_KEEP_ALIVE_POLICY"
+	// EnvEvictionPolicy is the policy that decides which idle hot containers are evicted first, one of
+	// "lru", "lfu", "cost" and "priority"
+	EnvEvictionPolicy = "FN_EVICTION_POLICY"
+	// EnvMaxConcurrencyQueue is how many calls may wait for a fn or app at its max_concurrency annotation before
+	// further calls are turned away
+	EnvMaxConcurrencyQueue = "FN_MAX_CONCURRENCY_QUEUE"
 	// EnvMaxResponseSize is the maximum number of bytes that a function may return from an invocation
 	EnvMaxResponseSize = "FN_MAX_RESPONSE_SIZE"
+	// EnvMaxBufferedRequestSize is the largest request body that an LB agent buffers, so that its call may be
+	// retried on another runner. Larger bodies, and those of unknown length, are streamed to the one runner that
+	// acknowledges the call. Every body is buffered if it is 0.
+	EnvMaxBufferedRequestSize = "FN_MAX_BUFFERED_REQUEST_SIZE"
+	// EnvMaxStreamedRequestSize is the largest request body that an LB agent streams to a runner, with no limit if 0
+	EnvMaxStreamedRequestSize = "FN_MAX_STREAMED_REQUEST_SIZE"
 	// EnvHdrMaxResponseSize is the maximum number of bytes that a function may return in an invocation header
 	EnvMaxHdrResponseSize = "FN_MAX_HDR_RESPONSE_SIZE"
 	// EnvMaxLogSize is the maximum size that a function's log may reach
@@ -132,6 +168,25 @@ const (
 	// EnvIOFSOpts are the options to set when mounting the iofs directory for unix socket files
 	EnvIOFSOpts = "FN_IOFS_OPTS"
 
+	// EnvProcessEntrypointDir is the directory of the executables that the process driver runs, named by
+	// the images of functions
+	EnvProcessEntrypointDir = "FN_PROCESS_ENTRYPOINT_DIR"
+	// EnvProcessCgroupRoot is the cgroup v2 directory under which the process driver creates a cgroup for
+	// each function process. It must be delegated to the agent, with the memory, cpu and pids controllers.
+	EnvProcessCgroupRoot = "FN_PROCESS_CGROUP_ROOT"
+
+	// EnvContainerdAddress is the unix socket of the containerd API that the containerd driver connects to
+	EnvContainerdAddress = "FN_CONTAINERD_ADDRESS"
+	// EnvContainerdNamespace is the containerd namespace of the images and containers of the containerd driver
+	EnvContainerdNamespace = "FN_CONTAINERD_NAMESPACE"
+	// EnvContainerdCNIConfDir is the directory of the CNI network configs that a containerd container is joined to
+	EnvContainerdCNIConfDir = "FN_CONTAINERD_CNI_CONF_DIR"
+	// EnvContainerdCNIBinDir is the directory of the CNI plugins of the containerd driver
+	EnvContainerdCNIBinDir = "FN_CONTAINERD_CNI_BIN_DIR"
+	// EnvContainerdHostNetwork shares the network of the host with containerd containers, rather than setting
+	// up a network of their own with CNI. Every function can then reach any port of the host, so it is off by default.
+	EnvContainerdHostNetwork = "FN_CONTAINERD_HOST_NETWORK"
+
 	// EnvDetachedHeadroom is the extra room we want to give to a detached function to run.
 	EnvDetachedHeadroom = "FN_EXECUTION_HEADROOM"
 
@@ -155,10 +210,18 @@ const (
 // NewConfig returns a config set from env vars, plus defaults
 func NewConfig() (*Config, error) {
 	cfg := &Config{
-		MinDockerVersion: "17.10.0-ce",
-		MaxLogSize:       1 * 1024 * 1024,
-		PreForkImage:     "busybox",
-		PreForkCmd:       "tail -f /dev/null",
+		Driver:               "docker",
+		MinDockerVersion:     "17.10.0-ce",
+		MaxLogSize:           1 * 1024 * 1024,
+		KeepAlivePolicy:      KeepAliveFixed,
+		EvictionPolicy:       EvictionLRU,
+		PreForkImage:         "busybox",
+		PreForkCmd:           "tail -f /dev/null",
+		ProcessCgroupRoot:    "/sys/fs/cgroup/fn",
+		ContainerdAddress:    "/run/containerd/containerd.sock",
+		ContainerdNamespace:  "fn",
+		ContainerdCNIConfDir: "/etc/cni/net.d",
+		ContainerdCNIBinDir:  "/opt/cni/bin",
 	}
 
 	defaultMaxPIDs := uint64(50)
@@ -166,15 +229,23 @@ func NewConfig() (*Config, error) {
 	defaultMaxLockedMemory := uint64(64 * 1024)
 	defaultMaxPendingSignals := uint64(5000)
 	defaultMaxMessageQueue := uint64(819200)
+	defaultMaxConcurrencyQueue := uint64(100)
 
 	var err error
-	err = setEnvMsecs(err, EnvFreezeIdle, &cfg.FreezeIdle, 50*time.Millisecond)
+	err = setEnvStr(err, EnvDriver, &cfg.Driver)
+	err = setEnvMsecs(err, EnvFreezeIdle, &cfg.FreezeIdle, 0)
+	err = setEnvUint(err, EnvPausedMemoryOvercommit, &cfg.PausedMemoryOvercommit, nil)
 	err = setEnvMsecs(err, EnvHotPoll, &cfg.HotPoll, DefaultHotPoll)
 	err = setEnvMsecs(err, EnvHotLauncherTimeout, &cfg.HotLauncherTimeout, time.Duration(60)*time.Minute)
 	err = setEnvMsecs(err, EnvHotPullTimeout, &cfg.HotPullTimeout, time.Duration(10)*time.Minute)
 	err = setEnvMsecs(err, EnvHotStartTimeout, &cfg.HotStartTimeout, time.Duration(5)*time.Second)
+	err = setEnvStr(err, EnvKeepAlivePolicy, &cfg.KeepAlivePolicy)
+	err = setEnvStr(err, EnvEvictionPolicy, &cfg.EvictionPolicy)
+	err = setEnvUint(err, EnvMaxConcurrencyQueue, &cfg.MaxConcurrencyQueue, &defaultMaxConcurrencyQueue)
 	err = setEnvMsecs(err, EnvDetachedHeadroom, &cfg.DetachedHeadRoom, time.Duration(360)*time.Second)
 	err = setEnvUint(err, EnvMaxResponseSize, &cfg.MaxResponseSize, nil)
+	err = setEnvUint(err, EnvMaxBufferedRequestSize, &cfg.MaxBufferedRequestSize, nil)
+	err = setEnvUint(err, EnvMaxStreamedRequestSize, &cfg.MaxStreamedRequestSize, nil)
 	err = setEnvUint(err, EnvMaxHdrResponseSize, &cfg.MaxHdrResponseSize, nil)
 	err = setEnvUint(err, EnvMaxLogSize, &cfg.MaxLogSize, nil)
 	err = setEnvUint(err, EnvMaxTotalCPU, &cfg.MaxTotalCPU, nil)
@@ -205,6 +276,13 @@ func NewConfig() (*Config, error) {
 	err = setEnvUint(err, EnvImageCleanMaxSize, &cfg.ImageCleanMaxSize, nil)
 	err = setEnvStr(err, EnvImageCleanExemptTags, &cfg.ImageCleanExemptTags)
 	err = setEnvBool(err, EnvImageEnableVolume, &cfg.ImageEnableVolume)
+	err = setEnvStr(err, EnvProcessEntrypointDir, &cfg.ProcessEntrypointDir)
+	err = setEnvStr(err, EnvProcessCgroupRoot, &cfg.ProcessCgroupRoot)
+	err = setEnvStr(err, EnvContainerdAddress, &cfg.ContainerdAddress)
+	err = setEnvStr(err, EnvContainerdNamespace, &cfg.ContainerdNamespace)
+	err = setEnvStr(err, EnvContainerdCNIConfDir, &cfg.ContainerdCNIConfDir)
+	err = setEnvStr(err, EnvContainerdCNIBinDir, &cfg.ContainerdCNIBinDir)
+	err = setEnvBool(err, EnvContainerdHostNetwork, &cfg.ContainerdHostNetwork)
 
 	if err != nil {
 		return cfg, err
@@ -215,6 +293,18 @@ func NewConfig() (*Config, error) {
 		return cfg, fmt.Errorf("error invalid %s %v > %v", EnvMaxLogSize, cfg.MaxLogSize, math.MaxInt64)
 	}
 
+	if cfg.PausedMemoryOvercommit > 100 {
+		return cfg, fmt.Errorf("error invalid %s %v > 100", EnvPausedMemoryOvercommit, cfg.PausedMemoryOvercommit)
+	}
+
+	if _, err := NewKeepAlivePolicy(cfg.KeepAlivePolicy); err != nil {
+		return cfg, fmt.Errorf("error invalid %s: %v", EnvKeepAlivePolicy, err)
+	}
+
+	if _, err := NewEvictionPolicy(cfg.EvictionPolicy); err != nil {
+		return cfg, fmt.Errorf("error invalid %s: %v", EnvEvictionPolicy, err)
+	}
+
 	return cfg, nil
 }
 
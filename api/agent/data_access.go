@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/fnproject/fn/api/models"
@@ -19,6 +20,8 @@ type ReadDataAccess interface {
 	GetAppByID(ctx context.Context, appID string) (*models.App, error)
 	GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error)
 	GetFnByID(ctx context.Context, fnID string) (*models.Fn, error)
+	// GetStateMachine gets a version of a state machine by its app and name, version 0 being the latest.
+	GetStateMachine(ctx context.Context, appID, name string, version int64) (*models.StoredStateMachine, error)
 }
 
 // XXX(reed): replace all uses of ReadDataAccess with DataAccess or vice versa, whatever is easier
@@ -59,6 +62,12 @@ func (m *metricda) GetFnByID(ctx context.Context, fnID string) (*models.Fn, erro
 	return m.rda.GetFnByID(ctx, fnID)
 }
 
+func (m *metricda) GetStateMachine(ctx context.Context, appID, name string, version int64) (*models.StoredStateMachine, error) {
+	ctx, span := trace.StartSpan(ctx, "rda_get_state_machine")
+	defer span.End()
+	return m.rda.GetStateMachine(ctx, appID, name, version)
+}
+
 // CachedDataAccess wraps a DataAccess and caches the results of GetApp.
 type cachedDataAccess struct {
 	ReadDataAccess
@@ -82,6 +91,9 @@ func fnCacheKey(fnID string) string         { return "f:" + fnID }
 func trigSourceCacheKey(app, typ, source string) string {
 	return "t:" + app + string('\x00') + typ + string('\x00') + source
 }
+func stateMachineCacheKey(app, name string, version int64) string {
+	return "s:" + app + string('\x00') + name + string('\x00') + strconv.FormatInt(version, 10)
+}
 
 func (da *cachedDataAccess) GetAppID(ctx context.Context, appName string) (string, error) {
 	key := appNameCacheKey(appName)
@@ -162,3 +174,23 @@ func (da *cachedDataAccess) GetFnByID(ctx context.Context, fnID string) (*models
 	da.cache.Set(key, fn, cache.DefaultExpiration)
 	return fn.(*models.Fn), nil
 }
+
+func (da *cachedDataAccess) GetStateMachine(ctx context.Context, appID, name string, version int64) (*models.StoredStateMachine, error) {
+	key := stateMachineCacheKey(appID, name, version)
+	sm, ok := da.cache.Get(key)
+	if ok {
+		return sm.(*models.StoredStateMachine), nil
+	}
+
+	resp, err := da.singleflight.Do(key,
+		func() (interface{}, error) {
+			return da.ReadDataAccess.GetStateMachine(ctx, appID, name, version)
+		})
+
+	if err != nil {
+		return nil, err
+	}
+	sm = resp.(*models.StoredStateMachine)
+	da.cache.Set(key, sm, cache.DefaultExpiration)
+	return sm.(*models.StoredStateMachine), nil
+}
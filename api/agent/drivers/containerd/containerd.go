@@ -0,0 +1,197 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	dockerdriver "github.com/fnproject/fn/api/agent/drivers/docker"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultRegistry is the registry of an image that names none, which
+	// containerd needs named where docker does not
+	defaultRegistry = "docker.io"
+
+	// containerListTimeout is how long listing leaked containers may take
+	containerListTimeout = 60 * time.Second
+)
+
+// ContainerdDriver implements drivers.Driver via the containerd API
+type ContainerdDriver struct {
+	conf     drivers.Config
+	client   containerdClient
+	hostname string
+	auther   dockerdriver.Auther
+
+	instanceId string
+
+	imgPuller ImagePuller
+}
+
+// NewContainerd creates a containerd driver, connected to the containerd of
+// the config. Containers left over by another instance of the driver, with
+// the same label tag, are removed.
+func NewContainerd(conf drivers.Config) (*ContainerdDriver, error) {
+	if conf.ContainerdAddress == "" {
+		return nil, errors.New("containerd driver needs the address of containerd")
+	}
+	if conf.ContainerdNamespace == "" {
+		return nil, errors.New("containerd driver needs a containerd namespace")
+	}
+
+	client, err := newClient(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	drv, err := newContainerdDriver(conf, client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	drv.killLeakedContainers(context.Background())
+	return drv, nil
+}
+
+func newContainerdDriver(conf drivers.Config, client containerdClient) (*ContainerdDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't resolve hostname: %v", err)
+	}
+
+	auther, err := dockerdriver.NewRegistryAuther()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't initialize registry: %v", err)
+	}
+
+	// This is for testing purposes. Tests override with custom id
+	instanceId := conf.InstanceId
+	if instanceId == "" {
+		instanceId = id.New().String()
+	}
+
+	return &ContainerdDriver{
+		conf:       conf,
+		client:     client,
+		hostname:   hostname,
+		auther:     auther,
+		instanceId: instanceId,
+		imgPuller:  NewImagePuller(client),
+	}, nil
+}
+
+func (drv *ContainerdDriver) killLeakedContainers(ctx context.Context) {
+
+	// Label Tag is used to isolate this cleanup, as it is for docker, in case
+	// other agents share the namespace
+	if drv.conf.ContainerLabelTag == "" {
+		return
+	}
+
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "killLeakedContainers"})
+
+	ctx, cancel := context.WithTimeout(ctx, containerListTimeout)
+	defer cancel()
+
+	containers, err := drv.client.ListContainers(ctx, map[string]string{
+		dockerdriver.FnAgentClassifierLabel: drv.conf.ContainerLabelTag,
+	})
+	if err != nil {
+		log.WithError(err).Error("cannot list containers")
+		return
+	}
+
+	for _, item := range containers {
+		// skip containers that belong to our current running agent
+		if item.labels[dockerdriver.FnAgentInstanceLabel] == drv.instanceId {
+			continue
+		}
+
+		logger := log.WithFields(logrus.Fields{"container_id": item.id})
+		logger.Info("Terminating dangling containerd container")
+
+		// If this fails, we log and continue.
+		if err := drv.client.DeleteContainer(ctx, item.id); err != nil {
+			logger.WithError(err).Error("cannot remove container")
+		}
+	}
+}
+
+func (drv *ContainerdDriver) Close() error {
+	return drv.client.Close()
+}
+
+func (drv *ContainerdDriver) SetPullImageRetryPolicy(policy common.BackOffConfig, checker drivers.RetryErrorChecker) error {
+	return drv.imgPuller.SetRetryPolicy(policy, checker)
+}
+
+func (drv *ContainerdDriver) CreateCookie(ctx context.Context, task drivers.ContainerTask) (drivers.Cookie, error) {
+
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "CreateCookie"})
+
+	cookie := &cookie{
+		spec: containerSpec{
+			readonlyRootfs: drv.conf.EnableReadOnlyRootFs,
+		},
+		task: task,
+		drv:  drv,
+	}
+
+	cookie.configureLabels(log)
+	cookie.configureMem(log)
+	cookie.configureCmd(log)
+	cookie.configureEnv(log)
+	cookie.configureCPU(log)
+	cookie.configurePIDs(log)
+	cookie.configureULimits(log)
+	cookie.configureTmpFs(log)
+	cookie.configureVolumes(log)
+	cookie.configureWorkDir(log)
+	cookie.configureIOFS(log)
+	cookie.configureNetwork(log)
+	cookie.configureHostname(log)
+	cookie.configureImage(log)
+	cookie.configureSecurity(log)
+
+	return cookie, nil
+}
+
+func (drv *ContainerdDriver) GetSlotKeyExtensions(extn map[string]string) string {
+	return ""
+}
+
+// imageRef returns the reference of image that containerd knows it by, with
+// its registry and tag or digest in full
+func imageRef(image string) string {
+	reg, repo, tag := drivers.ParseImage(image)
+	if reg == "" {
+		reg = defaultRegistry
+	}
+	if strings.Contains(tag, ":") {
+		// a digest, eg. sha256:...
+		return path.Join(reg, repo) + "@" + tag
+	}
+	return path.Join(reg, repo) + ":" + tag
+}
+
+var _ drivers.Driver = &ContainerdDriver{}
+
+func init() {
+	drivers.Register("containerd", func(config drivers.Config) (drivers.Driver, error) {
+		drv, err := NewContainerd(config)
+		if err != nil {
+			return nil, err
+		}
+		return drv, nil
+	})
+}
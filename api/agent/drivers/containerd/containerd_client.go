@@ -0,0 +1,108 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+var (
+	// errNoSuchImage is returned for an image that containerd does not have
+	// unpacked, or that a registry does not have
+	errNoSuchImage = errors.New("no such image")
+)
+
+type containerdClient interface {
+	// Each of these are a github.com/containerd/containerd client call, or a
+	// few of them, in the namespace of the driver
+
+	GetImage(ctx context.Context, ref string) (*image, error)
+	Pull(ctx context.Context, ref string, auth *docker.AuthConfiguration) error
+	CreateContainer(ctx context.Context, id string, spec *containerSpec) error
+	DeleteContainer(ctx context.Context, id string) error
+	ListContainers(ctx context.Context, labels map[string]string) ([]containerInfo, error)
+	StartTask(ctx context.Context, id string, stdio taskIO) (<-chan exitStatus, error)
+	KillTask(ctx context.Context, id string) error
+	PauseTask(ctx context.Context, id string) error
+	ResumeTask(ctx context.Context, id string) error
+	Close() error
+}
+
+// image is an image that containerd has unpacked
+type image struct {
+	ref     string
+	volumes map[string]struct{}
+}
+
+// containerInfo is a container listed by containerd
+type containerInfo struct {
+	id     string
+	labels map[string]string
+}
+
+// containerSpec is what the driver sets for a container, which the client
+// makes an OCI spec of on top of the config of its image
+type containerSpec struct {
+	image    string
+	args     []string // replace the cmd of the image, if any
+	env      []string
+	workDir  string
+	user     string
+	hostname string
+	labels   map[string]string
+
+	readonlyRootfs bool
+	network        network
+	// unprivileged drops every capability, and new privileges
+	unprivileged bool
+
+	// memory is in bytes, and limits swap with it
+	memory    int64
+	cpuQuota  int64
+	cpuPeriod int64
+	pids      int64
+	rlimits   []rlimit
+	mounts    []mount
+}
+
+// network is how a container is networked
+type network int
+
+const (
+	// networkNone is a network namespace with only a loopback
+	networkNone network = iota
+	// networkCNI is a network namespace that the CNI networks of the driver
+	// are set up in
+	networkCNI
+	// networkHost shares the network namespace of the host
+	networkHost
+)
+
+type rlimit struct {
+	typ  string
+	soft uint64
+	hard uint64
+}
+
+type mount struct {
+	typ         string
+	source      string
+	destination string
+	options     []string
+}
+
+// taskIO are the streams of a task, any of which may be nil
+type taskIO struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// exitStatus is how a task exited
+type exitStatus struct {
+	code      uint32
+	oomKilled bool
+	err       error
+}
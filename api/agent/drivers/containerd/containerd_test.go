@@ -0,0 +1,451 @@
+package containerd
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	dockerdriver "github.com/fnproject/fn/api/agent/drivers/docker"
+	"github.com/fnproject/fn/api/agent/drivers/stats"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fsouza/go-dockerclient"
+)
+
+type taskContainerdTest struct {
+	id         string
+	image      string
+	cmd        string
+	env        map[string]string
+	memory     uint64
+	cpus       uint64
+	pids       uint64
+	openFiles  *uint64
+	tmpFsSize  uint64
+	iofs       string
+	disableNet bool
+}
+
+func (f *taskContainerdTest) Command() string            { return f.cmd }
+func (f *taskContainerdTest) EnvVars() map[string]string { return f.env }
+func (f *taskContainerdTest) Id() string                 { return f.id }
+func (f *taskContainerdTest) Image() string              { return f.image }
+func (f *taskContainerdTest) Logger() (stdout, stderr io.Writer) {
+	return common.NoopReadWriteCloser{}, common.NoopReadWriteCloser{}
+}
+func (f *taskContainerdTest) WriteStat(context.Context, stats.Stat)                      {}
+func (f *taskContainerdTest) Volumes() [][2]string                                       { return nil }
+func (f *taskContainerdTest) Memory() uint64                                             { return f.memory }
+func (f *taskContainerdTest) CPUs() uint64                                               { return f.cpus }
+func (f *taskContainerdTest) FsSize() uint64                                             { return 0 }
+func (f *taskContainerdTest) PIDs() uint64                                               { return f.pids }
+func (f *taskContainerdTest) OpenFiles() *uint64                                         { return f.openFiles }
+func (f *taskContainerdTest) LockedMemory() *uint64                                      { return nil }
+func (f *taskContainerdTest) PendingSignals() *uint64                                    { return nil }
+func (f *taskContainerdTest) MessageQueue() *uint64                                      { return nil }
+func (f *taskContainerdTest) TmpFsSize() uint64                                          { return f.tmpFsSize }
+func (f *taskContainerdTest) WorkDir() string                                            { return "" }
+func (f *taskContainerdTest) Close()                                                     {}
+func (f *taskContainerdTest) WrapClose(func(func()) func())                              {}
+func (f *taskContainerdTest) WrapBeforeCall(func(drivers.BeforeCall) drivers.BeforeCall) {}
+func (f *taskContainerdTest) WrapAfterCall(func(drivers.AfterCall) drivers.AfterCall)    {}
+func (f *taskContainerdTest) Input() io.Reader                                           { return common.NoopReadWriteCloser{} }
+func (f *taskContainerdTest) Extensions() map[string]string                              { return nil }
+func (f *taskContainerdTest) LoggerConfig() drivers.LoggerConfig                         { return drivers.LoggerConfig{} }
+func (f *taskContainerdTest) UDSAgentPath() string                                       { return f.iofs }
+func (f *taskContainerdTest) UDSDockerPath() string                                      { return f.iofs }
+func (f *taskContainerdTest) UDSDockerDest() string                                      { return "/tmp/iofs" }
+func (f *taskContainerdTest) DisableNet() bool                                           { return f.disableNet }
+
+func (f *taskContainerdTest) BeforeCall(context.Context, *models.Call, drivers.CallExtensions) error {
+	return nil
+}
+func (f *taskContainerdTest) AfterCall(context.Context, *models.Call, drivers.CallExtensions) error {
+	return nil
+}
+
+// fakeClient is a containerd with images and containers in memory, whose
+// tasks exit when the tests say
+type fakeClient struct {
+	lock sync.Mutex
+
+	images     map[string]*image
+	containers map[string]*containerSpec
+	tasks      map[string]chan exitStatus
+	paused     map[string]bool
+
+	pullErr  error
+	numPulls uint64
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		images:     make(map[string]*image),
+		containers: make(map[string]*containerSpec),
+		tasks:      make(map[string]chan exitStatus),
+		paused:     make(map[string]bool),
+	}
+}
+
+func (c *fakeClient) GetImage(ctx context.Context, ref string) (*image, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	img, ok := c.images[ref]
+	if !ok {
+		return nil, errNoSuchImage
+	}
+	return img, nil
+}
+
+func (c *fakeClient) Pull(ctx context.Context, ref string, auth *docker.AuthConfiguration) error {
+	time.Sleep(100 * time.Millisecond)
+	atomic.AddUint64(&c.numPulls, 1)
+	if c.pullErr != nil {
+		return c.pullErr
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.images[ref] = &image{ref: ref}
+	return nil
+}
+
+func (c *fakeClient) CreateContainer(ctx context.Context, id string, spec *containerSpec) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.images[spec.image]; !ok {
+		return errNoSuchImage
+	}
+	c.containers[id] = spec
+	return nil
+}
+
+func (c *fakeClient) DeleteContainer(ctx context.Context, id string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.containers, id)
+	delete(c.tasks, id)
+	return nil
+}
+
+func (c *fakeClient) ListContainers(ctx context.Context, labels map[string]string) ([]containerInfo, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var infos []containerInfo
+Containers:
+	for id, spec := range c.containers {
+		for k, v := range labels {
+			if spec.labels[k] != v {
+				continue Containers
+			}
+		}
+		infos = append(infos, containerInfo{id: id, labels: spec.labels})
+	}
+	return infos, nil
+}
+
+func (c *fakeClient) StartTask(ctx context.Context, id string, stdio taskIO) (<-chan exitStatus, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	exits := make(chan exitStatus, 1)
+	c.tasks[id] = exits
+	return exits, nil
+}
+
+// exit makes the task of a container exit with status
+func (c *fakeClient) exit(id string, status exitStatus) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.tasks[id] <- status
+}
+
+func (c *fakeClient) KillTask(ctx context.Context, id string) error {
+	c.exit(id, exitStatus{code: 137})
+	return nil
+}
+
+func (c *fakeClient) PauseTask(ctx context.Context, id string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.paused[id] = true
+	return nil
+}
+
+func (c *fakeClient) ResumeTask(ctx context.Context, id string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.paused[id] = false
+	return nil
+}
+
+func (c *fakeClient) Close() error { return nil }
+
+func newTestDriver(t *testing.T, conf drivers.Config) (*ContainerdDriver, *fakeClient) {
+	client := newFakeClient()
+	drv, err := newContainerdDriver(conf, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return drv, client
+}
+
+func createTask(id string) *taskContainerdTest {
+	return &taskContainerdTest{id: id, image: "fnproject/hello"}
+}
+
+// prepare pulls the image of a cookie, if needed, and creates its container
+func prepare(ctx context.Context, t *testing.T, ck drivers.Cookie) {
+	needsPull, err := ck.ValidateImage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needsPull {
+		if err := ck.PullImage(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if needsPull, err := ck.ValidateImage(ctx); needsPull || err != nil {
+			t.Fatalf("expected the image to be pulled, got %v %v", needsPull, err)
+		}
+	}
+	if err := ck.CreateContainer(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContainerdSpec(t *testing.T) {
+	drv, _ := newTestDriver(t, drivers.Config{
+		EnableReadOnlyRootFs: true,
+		MaxTmpFsInodes:       1024,
+		ContainerLabelTag:    "test",
+		InstanceId:           "instance",
+	})
+
+	openFiles := uint64(512)
+	task := &taskContainerdTest{
+		id:         "spec",
+		image:      "localhost:5000/hello:0.0.1",
+		cmd:        "serve --port 0",
+		env:        map[string]string{"FN_FORMAT": "http-stream"},
+		memory:     128 * 1024 * 1024,
+		cpus:       500,
+		pids:       50,
+		openFiles:  &openFiles,
+		tmpFsSize:  10,
+		iofs:       "/iofs/spec",
+		disableNet: true,
+	}
+
+	ck, err := drv.CreateCookie(context.Background(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := containerSpec{
+		image:    "localhost:5000/hello:0.0.1",
+		args:     []string{"serve", "--port", "0"},
+		env:      []string{"FN_FORMAT=http-stream"},
+		user:     dockerdriver.FnDockerUser,
+		hostname: drv.hostname,
+		labels: map[string]string{
+			dockerdriver.FnAgentClassifierLabel: "test",
+			dockerdriver.FnAgentInstanceLabel:   "instance",
+		},
+		readonlyRootfs: true,
+		unprivileged:   true,
+		memory:         128 * 1024 * 1024,
+		cpuQuota:       50000,
+		cpuPeriod:      100000,
+		pids:           50,
+		rlimits:        []rlimit{{typ: "RLIMIT_NOFILE", soft: 512, hard: 512}},
+		mounts: []mount{
+			{typ: "tmpfs", source: "tmpfs", destination: "/tmp", options: []string{"nosuid", "nodev", "noexec", "size=10m", "nr_inodes=1024"}},
+			{typ: "bind", source: "/iofs/spec", destination: "/tmp/iofs", options: []string{"rbind", "rw"}},
+		},
+	}
+	if spec := ck.ContainerOptions().(containerSpec); !reflect.DeepEqual(spec, expected) {
+		t.Fatalf("expected spec %+v, got %+v", expected, spec)
+	}
+}
+
+func TestContainerdNetwork(t *testing.T) {
+	for _, tc := range []struct {
+		hostNetwork bool
+		disableNet  bool
+		expected    network
+	}{
+		{expected: networkCNI},
+		{disableNet: true, expected: networkNone},
+		{hostNetwork: true, expected: networkHost},
+		{hostNetwork: true, disableNet: true, expected: networkNone},
+	} {
+		drv, _ := newTestDriver(t, drivers.Config{ContainerdHostNetwork: tc.hostNetwork})
+
+		task := &taskContainerdTest{id: "net", image: "hello", disableNet: tc.disableNet}
+		ck, err := drv.CreateCookie(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if spec := ck.ContainerOptions().(containerSpec); spec.network != tc.expected {
+			t.Fatalf("expected network %v with host network %v and disabled net %v, got %v",
+				tc.expected, tc.hostNetwork, tc.disableNet, spec.network)
+		}
+	}
+}
+
+func TestContainerdRun(t *testing.T) {
+	drv, client := newTestDriver(t, drivers.Config{})
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		exit   exitStatus
+		status string
+	}{
+		{exitStatus{code: 0}, drivers.StatusSuccess},
+		{exitStatus{code: 3}, drivers.StatusError},
+		{exitStatus{code: 137, oomKilled: true}, drivers.StatusKilled},
+	} {
+		task := createTask("run")
+		ck, err := drv.CreateCookie(ctx, task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prepare(ctx, t, ck)
+
+		if _, ok := client.containers[task.id]; !ok {
+			t.Fatalf("expected the container to be created")
+		}
+
+		waiter, err := ck.Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ck.Freeze(ctx); err != nil || !client.paused[task.id] {
+			t.Fatalf("expected the task to be paused, got %v", err)
+		}
+		if err := ck.Unfreeze(ctx); err != nil || client.paused[task.id] {
+			t.Fatalf("expected the task to be resumed, got %v", err)
+		}
+
+		client.exit(task.id, test.exit)
+		res := waiter.Wait(ctx)
+		if res.Status() != test.status {
+			t.Fatalf("exit %+v: expected status %s, got %s %v", test.exit, test.status, res.Status(), res.Error())
+		}
+
+		if err := ck.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := client.containers[task.id]; ok {
+			t.Fatalf("expected the container to be deleted")
+		}
+	}
+
+	// the image is pulled once, for the first call
+	if client.numPulls != 1 {
+		t.Fatalf("expected one pull, got %d", client.numPulls)
+	}
+}
+
+func TestContainerdRunTimeout(t *testing.T) {
+	drv, client := newTestDriver(t, drivers.Config{})
+
+	task := createTask("timeout")
+	ck, err := drv.CreateCookie(context.Background(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepare(context.Background(), t, ck)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	waiter, err := ck.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the fake kills the task with a SIGKILL exit code, which is not an oom
+	res := waiter.Wait(ctx)
+	if res.Status() != drivers.StatusTimeout || res.Error() != context.DeadlineExceeded {
+		t.Fatalf("expected the task to time out, got %s %v", res.Status(), res.Error())
+	}
+
+	if err := ck.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.tasks) != 0 {
+		t.Fatalf("expected the task to be deleted, got %v", client.tasks)
+	}
+}
+
+func TestContainerdImageWithVolume(t *testing.T) {
+	drv, client := newTestDriver(t, drivers.Config{})
+	ctx := context.Background()
+
+	task := createTask("volume")
+	client.images[imageRef(task.image)] = &image{
+		ref:     imageRef(task.image),
+		volumes: map[string]struct{}{"/data": {}},
+	}
+
+	ck, err := drv.CreateCookie(ctx, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ck.ValidateImage(ctx); err != dockerdriver.ErrImageWithVolume {
+		t.Fatalf("expected error `%v`, got `%v`", dockerdriver.ErrImageWithVolume, err)
+	}
+
+	drv.conf.ImageEnableVolume = true
+	ck, err = drv.CreateCookie(ctx, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ck.ValidateImage(ctx); err != nil {
+		t.Fatalf("expected volumes to be allowed, got `%v`", err)
+	}
+}
+
+func TestContainerdKillLeakedContainers(t *testing.T) {
+	drv, client := newTestDriver(t, drivers.Config{ContainerLabelTag: "test", InstanceId: "current"})
+
+	client.containers["leaked"] = &containerSpec{labels: map[string]string{
+		dockerdriver.FnAgentClassifierLabel: "test",
+		dockerdriver.FnAgentInstanceLabel:   "previous",
+	}}
+	client.containers["running"] = &containerSpec{labels: map[string]string{
+		dockerdriver.FnAgentClassifierLabel: "test",
+		dockerdriver.FnAgentInstanceLabel:   "current",
+	}}
+	client.containers["other"] = &containerSpec{}
+
+	drv.killLeakedContainers(context.Background())
+
+	if _, ok := client.containers["leaked"]; ok {
+		t.Fatalf("expected the leaked container to be deleted")
+	}
+	if len(client.containers) != 2 {
+		t.Fatalf("expected the containers of this agent and others to be kept, got %v", client.containers)
+	}
+}
+
+func TestImageRef(t *testing.T) {
+	for image, ref := range map[string]string{
+		"busybox":                          "docker.io/library/busybox:latest",
+		"fnproject/hello:0.0.1":            "docker.io/fnproject/hello:0.0.1",
+		"localhost:5000/hello":             "localhost:5000/hello:latest",
+		"registry.example.com/a/b:1":       "registry.example.com/a/b:1",
+		"fnproject/hello@sha256:0123abcdf": "docker.io/fnproject/hello@sha256:0123abcdf",
+	} {
+		if got := imageRef(image); got != ref {
+			t.Fatalf("image %s: expected %s, got %s", image, ref, got)
+		}
+	}
+}
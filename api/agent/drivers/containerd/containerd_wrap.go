@@ -0,0 +1,404 @@
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"syscall"
+
+	ctrd "github.com/containerd/containerd"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/remotes/docker"
+	gocni "github.com/containerd/go-cni"
+	"github.com/containerd/typeurl"
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+	dockerclient "github.com/fsouza/go-dockerclient"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// cniLabel marks a container whose network namespace is set up with CNI, so
+// that it is torn down with the container, by this or a later instance
+const cniLabel = "fn.containerd.cni"
+
+func newClient(conf drivers.Config) (containerdClient, error) {
+	var cni gocni.CNI
+	if !conf.ContainerdHostNetwork {
+		var err error
+		cni, err = newCNI(conf)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load CNI networks from %s, set up a network there or opt in to the host network: %v", conf.ContainerdCNIConfDir, err)
+		}
+	}
+
+	client, err := ctrd.New(conf.ContainerdAddress)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect to containerd: %v", err)
+	}
+	return &containerdWrap{client: client, namespace: conf.ContainerdNamespace, cni: cni}, nil
+}
+
+// newCNI loads the first network config of the config dir, with a loopback
+func newCNI(conf drivers.Config) (gocni.CNI, error) {
+	cni, err := gocni.New(
+		gocni.WithPluginConfDir(conf.ContainerdCNIConfDir),
+		gocni.WithPluginDir([]string{conf.ContainerdCNIBinDir}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := cni.Load(gocni.WithLoNetwork, gocni.WithDefaultConf); err != nil {
+		return nil, err
+	}
+	return cni, cni.Status()
+}
+
+// containerdWrap implements containerdClient with *ctrd.Client, in the
+// namespace of the driver
+type containerdWrap struct {
+	client    *ctrd.Client
+	namespace string
+	cni       gocni.CNI // nil if containers may only share the host network
+}
+
+func (w *containerdWrap) ctx(ctx context.Context) context.Context {
+	return namespaces.WithNamespace(ctx, w.namespace)
+}
+
+func (w *containerdWrap) GetImage(ctx context.Context, ref string) (*image, error) {
+	ctx = w.ctx(ctx)
+
+	img, err := w.client.GetImage(ctx, ref)
+	if errdefs.IsNotFound(err) {
+		return nil, errNoSuchImage
+	} else if err != nil {
+		return nil, err
+	}
+
+	unpacked, err := img.IsUnpacked(ctx, ctrd.DefaultSnapshotter)
+	if err != nil {
+		return nil, err
+	}
+	if !unpacked {
+		return nil, errNoSuchImage
+	}
+
+	desc, err := img.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := content.ReadBlob(ctx, img.ContentStore(), desc)
+	if err != nil {
+		return nil, err
+	}
+	var config ocispec.Image
+	if err := json.Unmarshal(blob, &config); err != nil {
+		return nil, err
+	}
+
+	return &image{ref: ref, volumes: config.Config.Volumes}, nil
+}
+
+func (w *containerdWrap) Pull(ctx context.Context, ref string, auth *dockerclient.AuthConfiguration) error {
+	ctx = w.ctx(ctx)
+
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Credentials: func(host string) (string, string, error) {
+			if auth == nil {
+				return "", "", nil
+			}
+			if auth.IdentityToken != "" {
+				// a refresh token, that an empty username stands for
+				return "", auth.IdentityToken, nil
+			}
+			return auth.Username, auth.Password, nil
+		},
+	})
+
+	_, err := w.client.Pull(ctx, ref, ctrd.WithPullUnpack, ctrd.WithResolver(resolver))
+	if errdefs.IsNotFound(err) {
+		return errNoSuchImage
+	}
+	return err
+}
+
+func (w *containerdWrap) CreateContainer(ctx context.Context, id string, spec *containerSpec) error {
+	ctx = w.ctx(ctx)
+
+	img, err := w.client.GetImage(ctx, spec.image)
+	if errdefs.IsNotFound(err) {
+		return errNoSuchImage
+	} else if err != nil {
+		return err
+	}
+
+	opts := []oci.SpecOpts{oci.WithImageConfig(img)}
+	if len(spec.args) > 0 {
+		opts = []oci.SpecOpts{oci.WithImageConfigArgs(img, spec.args)}
+	}
+	opts = append(opts, oci.WithEnv(spec.env), oci.WithHostname(spec.hostname))
+	if spec.workDir != "" {
+		opts = append(opts, oci.WithProcessCwd(spec.workDir))
+	}
+	if spec.user != "" {
+		opts = append(opts, oci.WithUser(spec.user))
+	}
+	if spec.readonlyRootfs {
+		opts = append(opts, oci.WithRootFSReadonly())
+	}
+
+	labels := make(map[string]string, len(spec.labels)+1)
+	for k, v := range spec.labels {
+		labels[k] = v
+	}
+	switch spec.network {
+	case networkHost:
+		if w.cni != nil {
+			return errors.New("containerd driver has not opted in to the host network")
+		}
+		opts = append(opts, oci.WithHostNamespace(specs.NetworkNamespace), oci.WithHostResolvconf, oci.WithHostHostsFile)
+	case networkCNI:
+		if w.cni == nil {
+			return errors.New("containerd driver has no CNI networks")
+		}
+		opts = append(opts, oci.WithHostResolvconf, oci.WithHostHostsFile)
+		labels[cniLabel] = "true"
+	}
+	opts = append(opts, withContainerSpec(spec))
+
+	_, err = w.client.NewContainer(ctx, id,
+		ctrd.WithImage(img),
+		ctrd.WithNewSnapshot(id, img),
+		ctrd.WithNewSpec(opts...),
+		ctrd.WithContainerLabels(labels),
+	)
+	return err
+}
+
+// netnsPath is the network namespace of a task, that only exists while the
+// task does
+func netnsPath(task ctrd.Task) string {
+	return fmt.Sprintf("/proc/%d/ns/net", task.Pid())
+}
+
+// withContainerSpec sets the limits, mounts and privileges of spec, that
+// there are no oci.SpecOpts for
+func withContainerSpec(spec *containerSpec) oci.SpecOpts {
+	return func(_ context.Context, _ oci.Client, _ *containers.Container, s *oci.Spec) error {
+		if s.Linux == nil {
+			s.Linux = &specs.Linux{}
+		}
+		if s.Linux.Resources == nil {
+			s.Linux.Resources = &specs.LinuxResources{}
+		}
+		res := s.Linux.Resources
+
+		if spec.memory != 0 {
+			memory := spec.memory
+			var swappiness uint64 // disables host swap
+			res.Memory = &specs.LinuxMemory{Limit: &memory, Swap: &memory, Swappiness: &swappiness}
+		}
+		if spec.cpuQuota != 0 {
+			quota, period := spec.cpuQuota, uint64(spec.cpuPeriod)
+			res.CPU = &specs.LinuxCPU{Quota: &quota, Period: &period}
+		}
+		if spec.pids != 0 {
+			res.Pids = &specs.LinuxPids{Limit: spec.pids}
+		}
+
+		for _, rl := range spec.rlimits {
+			s.Process.Rlimits = append(s.Process.Rlimits, specs.POSIXRlimit{Type: rl.typ, Soft: rl.soft, Hard: rl.hard})
+		}
+		for _, m := range spec.mounts {
+			s.Mounts = append(s.Mounts, specs.Mount{Type: m.typ, Source: m.source, Destination: m.destination, Options: m.options})
+		}
+
+		if spec.unprivileged {
+			s.Process.Capabilities = &specs.LinuxCapabilities{}
+			s.Process.NoNewPrivileges = true
+		}
+		return nil
+	}
+}
+
+func (w *containerdWrap) DeleteContainer(ctx context.Context, id string) error {
+	ctx = w.ctx(ctx)
+
+	container, err := w.client.LoadContainer(ctx, id)
+	if errdefs.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	labels, err := container.Labels(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the networks are torn down before the task, whose namespace they are in,
+	// or without a namespace if the task is gone, to release their addresses
+	var netns string
+	task, err := container.Task(ctx, nil)
+	if err == nil {
+		netns = netnsPath(task)
+	} else if !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	if labels[cniLabel] != "" && w.cni != nil {
+		if err := w.cni.Remove(ctx, id, netns); err != nil {
+			return err
+		}
+	}
+
+	if task != nil {
+		if _, err := task.Delete(ctx, ctrd.WithProcessKill); err != nil && !errdefs.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return container.Delete(ctx, ctrd.WithSnapshotCleanup)
+}
+
+func (w *containerdWrap) ListContainers(ctx context.Context, labels map[string]string) ([]containerInfo, error) {
+	ctx = w.ctx(ctx)
+
+	var filters []string
+	for k, v := range labels {
+		filters = append(filters, fmt.Sprintf("labels.%q==%q", k, v))
+	}
+
+	containers, err := w.client.Containers(ctx, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]containerInfo, 0, len(containers))
+	for _, c := range containers {
+		info, err := c.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, containerInfo{id: info.ID, labels: info.Labels})
+	}
+	return infos, nil
+}
+
+func (w *containerdWrap) StartTask(ctx context.Context, id string, stdio taskIO) (<-chan exitStatus, error) {
+	ctx = w.ctx(ctx)
+
+	container, err := w.client.LoadContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := container.Labels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := container.NewTask(ctx, cio.NewCreator(cio.WithStreams(stdio.stdin, stdio.stdout, stdio.stderr)))
+	if err != nil {
+		return nil, err
+	}
+
+	// the network namespace of a created task is set up before its process
+	// starts, and is torn down with the container
+	if labels[cniLabel] != "" && w.cni != nil {
+		if _, err := w.cni.Setup(ctx, id, netnsPath(task)); err != nil {
+			task.Delete(w.ctx(common.BackgroundContext(ctx)), ctrd.WithProcessKill)
+			return nil, fmt.Errorf("couldn't set up CNI networks: %v", err)
+		}
+	}
+
+	// waiting outlives the call that starts the task, until the task exits
+	waitCtx, cancel := context.WithCancel(w.ctx(common.BackgroundContext(ctx)))
+
+	// the kernel reports an oom kill before the task exits
+	events, _ := w.client.Subscribe(waitCtx, `topic=="/tasks/oom"`)
+
+	statusC, err := task.Wait(waitCtx)
+	if err == nil {
+		err = task.Start(ctx)
+	}
+	if err != nil {
+		cancel()
+		task.Delete(w.ctx(common.BackgroundContext(ctx)), ctrd.WithProcessKill)
+		return nil, err
+	}
+
+	exits := make(chan exitStatus, 1)
+	go func() {
+		defer cancel()
+
+		var oomKilled bool
+		for {
+			select {
+			case env := <-events:
+				if env == nil {
+					events = nil
+					continue
+				}
+				ev, err := typeurl.UnmarshalAny(env.Event)
+				if oom, ok := ev.(*apievents.TaskOOM); err == nil && ok && oom.ContainerID == id {
+					oomKilled = true
+				}
+			case status := <-statusC:
+				code, _, err := status.Result()
+				exits <- exitStatus{code: code, oomKilled: oomKilled, err: err}
+				return
+			}
+		}
+	}()
+	return exits, nil
+}
+
+func (w *containerdWrap) task(ctx context.Context, id string) (ctrd.Task, error) {
+	container, err := w.client.LoadContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return container.Task(ctx, nil)
+}
+
+func (w *containerdWrap) KillTask(ctx context.Context, id string) error {
+	ctx = w.ctx(ctx)
+	task, err := w.task(ctx, id)
+	if err != nil {
+		return err
+	}
+	return task.Kill(ctx, syscall.SIGKILL)
+}
+
+func (w *containerdWrap) PauseTask(ctx context.Context, id string) error {
+	ctx = w.ctx(ctx)
+	task, err := w.task(ctx, id)
+	if err != nil {
+		return err
+	}
+	return task.Pause(ctx)
+}
+
+func (w *containerdWrap) ResumeTask(ctx context.Context, id string) error {
+	ctx = w.ctx(ctx)
+	task, err := w.task(ctx, id)
+	if err != nil {
+		return err
+	}
+	return task.Resume(ctx)
+}
+
+func (w *containerdWrap) Close() error {
+	return w.client.Close()
+}
+
+var _ containerdClient = &containerdWrap{}
@@ -0,0 +1,442 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	dockerdriver "github.com/fnproject/fn/api/agent/drivers/docker"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+)
+
+// A cookie identifies a unique request to run a task.
+type cookie struct {
+	// container spec created by Driver.CreateCookie, required for Driver.CreateContainer()
+	spec containerSpec
+	// task associated with this cookie
+	task drivers.ContainerTask
+	// pointer to containerd driver
+	drv *ContainerdDriver
+
+	// contains found image if ValidateImage() is called
+	image *image
+
+	// true if CreateContainer() created the container
+	created bool
+
+	// exits gets the exit status of the task started by Run()
+	exits <-chan exitStatus
+}
+
+func (c *cookie) configureImage(log logrus.FieldLogger) {
+	c.spec.image = imageRef(c.task.Image())
+}
+
+func (c *cookie) configureLabels(log logrus.FieldLogger) {
+	if c.drv.conf.ContainerLabelTag == "" {
+		return
+	}
+
+	c.spec.labels = map[string]string{
+		dockerdriver.FnAgentClassifierLabel: c.drv.conf.ContainerLabelTag,
+		dockerdriver.FnAgentInstanceLabel:   c.drv.instanceId,
+	}
+}
+
+func (c *cookie) configureMem(log logrus.FieldLogger) {
+	if c.task.Memory() == 0 {
+		return
+	}
+
+	// the limit is of memory and swap, which disables swap
+	c.spec.memory = int64(c.task.Memory())
+}
+
+func (c *cookie) configurePIDs(log logrus.FieldLogger) {
+	pids := c.task.PIDs()
+	if pids == 0 {
+		return
+	}
+
+	log.WithFields(logrus.Fields{"pids": pids, "call_id": c.task.Id()}).Debug("setting PIDs")
+	c.spec.pids = int64(pids)
+}
+
+func (c *cookie) configureULimits(log logrus.FieldLogger) {
+	c.configureULimit("RLIMIT_NOFILE", c.task.OpenFiles(), log)
+	c.configureULimit("RLIMIT_MEMLOCK", c.task.LockedMemory(), log)
+	c.configureULimit("RLIMIT_SIGPENDING", c.task.PendingSignals(), log)
+	c.configureULimit("RLIMIT_MSGQUEUE", c.task.MessageQueue(), log)
+}
+
+func (c *cookie) configureULimit(name string, value *uint64, log logrus.FieldLogger) {
+	if value == nil {
+		return
+	}
+
+	log = log.WithFields(logrus.Fields{"call_id": c.task.Id(), "ulimitName": name, "ulimitValue": *value})
+
+	if int64(*value) < 0 {
+		log.Warnf("ulimit value too big (ulimit ignored): %s", name)
+		return
+	}
+
+	log.Debugf("setting ulimit %s", name)
+	c.spec.rlimits = append(c.spec.rlimits, rlimit{typ: name, soft: *value, hard: *value})
+}
+
+func (c *cookie) configureTmpFs(log logrus.FieldLogger) {
+	// if RO Root is NOT enabled and TmpFsSize does not have any limit, then we do not need
+	// any tmpfs in the container since function can freely write whereever it wants.
+	if c.task.TmpFsSize() == 0 && !c.drv.conf.EnableReadOnlyRootFs {
+		return
+	}
+
+	// the options docker mounts a tmpfs with
+	options := []string{"nosuid", "nodev", "noexec"}
+	if c.task.TmpFsSize() != 0 {
+		options = append(options, fmt.Sprintf("size=%dm", c.task.TmpFsSize()))
+		if c.drv.conf.MaxTmpFsInodes != 0 {
+			options = append(options, fmt.Sprintf("nr_inodes=%d", c.drv.conf.MaxTmpFsInodes))
+		}
+	}
+
+	log.WithFields(logrus.Fields{"target": "/tmp", "options": options, "call_id": c.task.Id()}).Debug("setting tmpfs")
+	c.spec.mounts = append(c.spec.mounts, mount{typ: "tmpfs", source: "tmpfs", destination: "/tmp", options: options})
+}
+
+func (c *cookie) configureIOFS(log logrus.FieldLogger) {
+	path := c.task.UDSDockerPath()
+	if path == "" {
+		// TODO this should be required soon-ish
+		return
+	}
+
+	log.WithFields(logrus.Fields{"source": path, "destination": c.task.UDSDockerDest(), "call_id": c.task.Id()}).Debug("setting bind")
+	c.spec.mounts = append(c.spec.mounts, bind(path, c.task.UDSDockerDest()))
+}
+
+func (c *cookie) configureVolumes(log logrus.FieldLogger) {
+	for _, mapping := range c.task.Volumes() {
+		hostDir := mapping[0]
+		containerDir := mapping[1]
+		c.spec.mounts = append(c.spec.mounts, bind(hostDir, containerDir))
+		log.WithFields(logrus.Fields{"volumes": hostDir + ":" + containerDir, "call_id": c.task.Id()}).Debug("setting volumes")
+	}
+}
+
+func bind(source, destination string) mount {
+	return mount{typ: "bind", source: source, destination: destination, options: []string{"rbind", "rw"}}
+}
+
+func (c *cookie) configureCPU(log logrus.FieldLogger) {
+	// Translate milli cpus into CPUQuota & CPUPeriod, as the docker driver does
+	if c.task.CPUs() == 0 {
+		return
+	}
+
+	quota := int64(c.task.CPUs() * 100)
+	period := int64(100000)
+
+	log.WithFields(logrus.Fields{"quota": quota, "period": period, "call_id": c.task.Id()}).Debug("setting CPU")
+	c.spec.cpuQuota = quota
+	c.spec.cpuPeriod = period
+}
+
+func (c *cookie) configureWorkDir(log logrus.FieldLogger) {
+	wd := c.task.WorkDir()
+	if wd == "" {
+		return
+	}
+
+	log.WithFields(logrus.Fields{"wd": wd, "call_id": c.task.Id()}).Debug("setting work dir")
+	c.spec.workDir = wd
+}
+
+func (c *cookie) configureNetwork(log logrus.FieldLogger) {
+	// containerd has no networks of its own, a container is set up with CNI
+	// unless the driver opts in to the network of the host
+	switch {
+	case c.task.DisableNet():
+		c.spec.network = networkNone
+	case c.drv.conf.ContainerdHostNetwork:
+		c.spec.network = networkHost
+	default:
+		c.spec.network = networkCNI
+	}
+}
+
+func (c *cookie) configureHostname(log logrus.FieldLogger) {
+	log.WithFields(logrus.Fields{"hostname": c.drv.hostname, "call_id": c.task.Id()}).Debug("setting hostname")
+	c.spec.hostname = c.drv.hostname
+}
+
+func (c *cookie) configureCmd(log logrus.FieldLogger) {
+	if c.task.Command() == "" {
+		return
+	}
+
+	cmd := strings.Fields(c.task.Command())
+	log.WithFields(logrus.Fields{"call_id": c.task.Id(), "cmd": cmd, "len": len(cmd)}).Debug("containerd command")
+	c.spec.args = cmd
+}
+
+func (c *cookie) configureEnv(log logrus.FieldLogger) {
+	for name, val := range c.task.EnvVars() {
+		c.spec.env = append(c.spec.env, name+"="+val)
+	}
+}
+
+func (c *cookie) configureSecurity(log logrus.FieldLogger) {
+	if c.drv.conf.DisableUnprivilegedContainers {
+		return
+	}
+	c.spec.user = dockerdriver.FnDockerUser
+	c.spec.unprivileged = true
+	log.WithFields(logrus.Fields{"user": c.spec.user, "call_id": c.task.Id()}).Debug("setting security")
+}
+
+// implements Cookie
+func (c *cookie) Close(ctx context.Context) error {
+	if !c.created {
+		return nil
+	}
+
+	err := c.drv.client.DeleteContainer(ctx, c.task.Id())
+	if err != nil {
+		common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error removing container")
+	}
+	return err
+}
+
+// implements Cookie
+func (c *cookie) Run(ctx context.Context) (drivers.WaitResult, error) {
+	log := common.Logger(ctx)
+
+	if !c.created {
+		log.Fatal("invalid usage: container not created")
+	}
+
+	stdio := taskIO{stdin: c.task.Input()}
+	stdio.stdout, stdio.stderr = c.task.Logger()
+	if _, ok := stdio.stdin.(common.NoopReadWriteCloser); ok {
+		stdio.stdin = nil
+	}
+	if _, ok := stdio.stdout.(common.NoopReadWriteCloser); ok {
+		stdio.stdout = nil
+	}
+	if _, ok := stdio.stderr.(common.NoopReadWriteCloser); ok {
+		stdio.stderr = nil
+	}
+
+	exits, err := c.drv.client.StartTask(ctx, c.task.Id(), stdio)
+	if err != nil && ctx.Err() == nil {
+		// if there's just a timeout making the containerd calls, the wait below will rewrite it to timeout
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error starting task")
+		return nil, err
+	}
+
+	c.exits = exits
+	return &waitResult{cookie: c}, nil
+}
+
+// implements Cookie
+func (c *cookie) ContainerOptions() interface{} {
+	return c.spec
+}
+
+// implements Cookie
+func (c *cookie) Freeze(ctx context.Context) error {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "Freeze"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("containerd pause")
+
+	err := c.drv.client.PauseTask(ctx, c.task.Id())
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error pausing task")
+	}
+	return err
+}
+
+// implements Cookie
+func (c *cookie) Unfreeze(ctx context.Context) error {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "Unfreeze"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("containerd resume")
+
+	err := c.drv.client.ResumeTask(ctx, c.task.Id())
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error resuming task")
+	}
+	return err
+}
+
+func (c *cookie) authImage(ctx context.Context) (*docker.AuthConfiguration, error) {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "AuthImage"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("containerd auth image")
+
+	config, err := c.drv.auther.DockerAuth(ctx, c.task.Image())
+	if err != nil {
+		return nil, err
+	}
+
+	if task, ok := c.task.(dockerdriver.Auther); ok {
+		_, span := trace.StartSpan(ctx, "containerd_auth")
+		authConfig, err := task.DockerAuth(ctx, c.task.Image())
+		span.End()
+		if err != nil {
+			return nil, err
+		}
+		if authConfig != nil {
+			config = authConfig
+		}
+	}
+
+	return config, nil
+}
+
+// implements Cookie
+func (c *cookie) ValidateImage(ctx context.Context) (bool, error) {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "ValidateImage"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id(), "image": c.task.Image()}).Debug("containerd get image")
+
+	if c.image != nil {
+		return false, nil
+	}
+
+	img, err := c.drv.client.GetImage(ctx, c.spec.image)
+	if err == errNoSuchImage {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	c.image = img
+
+	// check image doesn't have Volumes
+	if !c.drv.conf.ImageEnableVolume && len(img.volumes) > 0 {
+		return false, dockerdriver.ErrImageWithVolume
+	}
+	return false, nil
+}
+
+// implements Cookie
+func (c *cookie) PullImage(ctx context.Context) error {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "PullImage"})
+	if c.image != nil {
+		return nil
+	}
+
+	cfg, err := c.authImage(ctx)
+	if err != nil {
+		return err
+	}
+
+	log = common.Logger(ctx).WithFields(logrus.Fields{"registry": cfg.ServerAddress, "username": cfg.Username})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id(), "image": c.task.Image()}).Debug("containerd pull")
+	ctx = common.WithLogger(ctx, log)
+
+	errC := c.drv.imgPuller.PullImage(ctx, cfg, c.task.Image(), c.spec.image)
+	return <-errC
+}
+
+// implements Cookie
+func (c *cookie) CreateContainer(ctx context.Context) error {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "CreateContainer"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id(), "image": c.task.Image()}).Debug("containerd create container")
+
+	if c.image == nil {
+		log.Fatal("invalid usage: image not validated")
+	}
+	if c.created {
+		return nil
+	}
+
+	err := c.drv.client.CreateContainer(ctx, c.task.Id(), &c.spec)
+
+	// As for docker, an image removed since it was pulled is a busy server,
+	// that another runner is likely to get past
+	if err == errNoSuchImage {
+		log.WithError(err).Error("Cannot CreateContainer image likely removed")
+		return models.ErrCallTimeoutServerBusy
+	}
+
+	if err != nil {
+		log.WithError(err).Error("Could not create container")
+		return err
+	}
+
+	c.created = true
+	return nil
+}
+
+var _ drivers.Cookie = &cookie{}
+
+type runResult struct {
+	err    error
+	status string
+}
+
+func (r *runResult) Error() error   { return r.err }
+func (r *runResult) Status() string { return r.status }
+
+// waitResult implements drivers.WaitResult
+type waitResult struct {
+	cookie *cookie
+}
+
+// implements drivers.WaitResult
+func (w *waitResult) Wait(ctx context.Context) drivers.RunResult {
+	status, err := w.wait(ctx)
+	return &runResult{
+		status: status,
+		err:    err,
+	}
+}
+
+func (w *waitResult) wait(ctx context.Context) (status string, err error) {
+	c := w.cookie
+
+	var exit exitStatus
+	select {
+	case exit = <-c.exits:
+	case <-ctx.Done():
+		// the task dies with the call, the container is deleted on Close
+		err := c.drv.client.KillTask(common.BackgroundContext(ctx), c.task.Id())
+		if err == nil && c.exits != nil {
+			exit = <-c.exits
+		}
+		recordWaitTaskResult(ctx, exit)
+
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return drivers.StatusTimeout, context.DeadlineExceeded
+		default:
+			return drivers.StatusCancelled, context.Canceled
+		}
+	}
+
+	defer recordWaitTaskResult(ctx, exit)
+
+	if exit.err != nil {
+		// plumb up i/o errors
+		return drivers.StatusError, exit.err
+	}
+
+	switch {
+	case exit.oomKilled:
+		common.Logger(ctx).Error("containerd oom")
+		err := errors.New("container out of memory, you may want to raise fn.memory for this function (default: 128MB)")
+		return drivers.StatusKilled, models.NewAPIError(http.StatusBadGateway, err)
+	case exit.code == 0:
+		return drivers.StatusSuccess, nil
+	default:
+		return drivers.StatusError, models.NewAPIError(http.StatusBadGateway, fmt.Errorf("container exit code %d", exit.code))
+	}
+}
@@ -0,0 +1,9 @@
+// Package containerd provides a Driver that runs functions as containers of
+// containerd, rather than of a docker daemon. Images are pulled into the
+// namespace of the driver, with the registry credentials of the docker
+// driver, and a container gets the same limits, tmpfs and iofs bind mount as
+// a docker container would. containerd has no networks, so a container gets a
+// network namespace of its own that the first CNI network config of the
+// driver is set up in, or, if its network is disabled, has only a loopback.
+// Only a driver that opts in to the host network shares it with containers.
+package containerd
@@ -0,0 +1,49 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// ImagePuller dedups concurrent pulls of an image, as the docker driver does.
+// A pull of an image that is already being pulled listens for the result of
+// the ongoing pull, rather than fetching the same manifests and layers again.
+type ImagePuller interface {
+	PullImage(ctx context.Context, cfg *docker.AuthConfiguration, img, ref string) chan error
+	SetRetryPolicy(policy common.BackOffConfig, checker drivers.RetryErrorChecker) error
+}
+
+type imagePuller struct {
+	*drivers.ImagePuller
+
+	client containerdClient
+}
+
+func NewImagePuller(client containerdClient) ImagePuller {
+	return &imagePuller{
+		ImagePuller: drivers.NewImagePuller(pullError, func(ctx context.Context, reason string) {
+			recordRetry(ctx, "containerd_pull_image", reason)
+		}),
+		client: client,
+	}
+}
+
+func (i *imagePuller) PullImage(ctx context.Context, cfg *docker.AuthConfiguration, img, ref string) chan error {
+	key := fmt.Sprintf("%s %+v", ref, cfg)
+	return i.ImagePuller.PullImage(ctx, key, img, func(ctx context.Context) error {
+		return i.client.Pull(ctx, ref, cfg)
+	})
+}
+
+func pullError(err error) (int, string) {
+	if err == errNoSuchImage {
+		return http.StatusNotFound, err.Error()
+	}
+	return http.StatusBadGateway, err.Error()
+}
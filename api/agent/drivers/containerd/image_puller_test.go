@@ -0,0 +1,89 @@
+package containerd
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// Lets do concurrent pulls for an image with two different tags. This should results in only
+// two calls to pull.
+func TestImagePullConcurrent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(10*time.Second))
+	defer cancel()
+
+	client := newFakeClient()
+	puller := NewImagePuller(client)
+
+	cfg := docker.AuthConfiguration{}
+	img := "foo"
+	ref1 := "docker.io/library/foo:1.0.0"
+	ref2 := "docker.io/library/foo:1.0.1"
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+
+	for _, ref := range []string{ref1, ref2} {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(ref string) {
+				defer wg.Done()
+				errs <- <-puller.PullImage(ctx, &cfg, img, ref)
+			}(ref)
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("err received %v", err)
+		}
+	}
+
+	// Should be two pulls
+	if client.numPulls != 2 || ctx.Err() != nil {
+		t.Fatalf("fail numOfPulls=%d ctx=%v", client.numPulls, ctx.Err())
+	}
+}
+
+// Lets do concurrent pulls for an image the registry does not have.
+func TestImagePullNotFound(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(10*time.Second))
+	defer cancel()
+
+	client := newFakeClient()
+	client.pullErr = errNoSuchImage
+	puller := NewImagePuller(client)
+
+	cfg := docker.AuthConfiguration{}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- <-puller.PullImage(ctx, &cfg, "foo", "docker.io/library/foo:latest")
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if !models.IsFuncError(err) || models.GetAPIErrorCode(err) != http.StatusNotFound {
+			t.Fatalf("expected a 404 func error, got %v", err)
+		}
+	}
+
+	if client.numPulls != 1 || ctx.Err() != nil {
+		t.Fatalf("fail numOfPulls=%d ctx=%v", client.numPulls, ctx.Err())
+	}
+}
@@ -0,0 +1,85 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	apiNameKey    = common.MakeKey("api_name")
+	apiStatusKey  = common.MakeKey("api_status")
+	exitStatusKey = common.MakeKey("exit_status")
+
+	containerdRetriesMeasure = common.MakeMeasure("containerd_api_retries", "containerd api retries", "")
+	containerdExitMeasure    = common.MakeMeasure("containerd_exits", "containerd exit counts", "")
+)
+
+// record a retry attempt for the api with status/reason provided
+func recordRetry(ctx context.Context, apiName, apiStatus string) {
+
+	ctx, err := tag.New(ctx,
+		tag.Upsert(apiNameKey, apiName),
+		tag.Upsert(apiStatusKey, apiStatus),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatalf("cannot add tags %v=%v %v=%v", apiNameKey, apiName, apiStatusKey, apiStatus)
+	}
+
+	stats.Record(ctx, containerdRetriesMeasure.M(0))
+}
+
+func recordWaitTaskResult(ctx context.Context, status exitStatus) {
+
+	// Tag the metric with error-code or context-cancel/deadline info
+	exitStr := fmt.Sprintf("exit_%d", status.code)
+	if status.oomKilled {
+		exitStr = "oom"
+	} else if ctx.Err() != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			exitStr = "ctx_deadline"
+		case context.Canceled:
+			exitStr = "ctx_canceled"
+		}
+	}
+
+	newCtx, err := tag.New(ctx,
+		tag.Upsert(apiNameKey, "containerd_wait_task"),
+		tag.Upsert(exitStatusKey, exitStr),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatalf("cannot add tag %v=%v or tag %v=containerd_wait_task", exitStatusKey, exitStr, apiNameKey)
+	}
+	stats.Record(newCtx, containerdExitMeasure.M(0))
+}
+
+// RegisterViews creates and registers views with provided tag keys
+func RegisterViews(tagKeys []string) {
+
+	defaultTags := []tag.Key{apiNameKey, apiStatusKey}
+	exitTags := []tag.Key{apiNameKey, exitStatusKey}
+
+	// add extra tags if not already in default tags for req/resp
+	for _, key := range tagKeys {
+		if key != "api_name" && key != "api_status" {
+			defaultTags = append(defaultTags, common.MakeKey(key))
+		}
+		if key != "api_name" && key != "exit_status" {
+			exitTags = append(exitTags, common.MakeKey(key))
+		}
+	}
+
+	err := view.Register(
+		common.CreateViewWithTags(containerdRetriesMeasure, view.Count(), defaultTags),
+		common.CreateViewWithTags(containerdExitMeasure, view.Count(), exitTags),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot register view")
+	}
+}
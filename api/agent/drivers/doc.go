@@ -7,6 +7,16 @@
 //
 // The docker driver runs functions as Docker containers.
 //
+// Containerd Driver
+//
+// The containerd driver runs functions as containers of containerd, for nodes
+// without a docker daemon.
+//
+// Process Driver
+//
+// The process driver runs functions as child processes, each in namespaces
+// and a cgroup v2 of its own. It needs no container runtime.
+//
 // Mock Driver
 //
 // The mock driver pretends to run functions but doesn't actually run them. This
@@ -508,4 +508,10 @@ func (c *cookie) CreateContainer(ctx context.Context) error {
 	return nil
 }
 
+// implements PreforkPoolCookie
+func (c *cookie) FromPreforkPool() bool {
+	return c.poolId != ""
+}
+
 var _ drivers.Cookie = &cookie{}
+var _ drivers.PreforkPoolCookie = &cookie{}
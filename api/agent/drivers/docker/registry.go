@@ -1,10 +1,12 @@
 package docker
 
 import (
+	"context"
 	"net/url"
 	"os"
 	"strings"
 
+	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fsouza/go-dockerclient"
 	"github.com/sirupsen/logrus"
 )
@@ -30,6 +32,29 @@ func registryFromEnv() (map[string]driverAuthConfig, error) {
 	return preprocessAuths(auths)
 }
 
+// RegistryAuther is an Auther of the registry credentials in FN_DOCKER_AUTH, or
+// in the docker config files, for drivers that pull images without docker
+type RegistryAuther struct {
+	auths map[string]driverAuthConfig
+}
+
+// NewRegistryAuther returns an Auther of the registry credentials in the env
+func NewRegistryAuther() (*RegistryAuther, error) {
+	auths, err := registryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryAuther{auths: auths}, nil
+}
+
+// DockerAuth implements Auther
+func (a *RegistryAuther) DockerAuth(ctx context.Context, image string) (*docker.AuthConfiguration, error) {
+	reg, _, _ := drivers.ParseImage(image)
+	return findRegistryConfig(reg, a.auths), nil
+}
+
+var _ Auther = &RegistryAuther{}
+
 func preprocessAuths(auths *docker.AuthConfigurations) (map[string]driverAuthConfig, error) {
 	drvAuths := make(map[string]driverAuthConfig)
 
@@ -56,6 +56,14 @@ type Cookie interface {
 	ContainerOptions() interface{}
 }
 
+// PreforkPoolCookie may be implemented by a Cookie whose container can take
+// its namespaces from a container of a prefork pool, rather than have its own
+// set up as it starts.
+type PreforkPoolCookie interface {
+	// FromPreforkPool tells whether the container uses a prefork pool container
+	FromPreforkPool() bool
+}
+
 type WaitResult interface {
 	// Wait may be called to await the result of a container's execution. If the
 	// provided context is canceled and the container does not return first, the
@@ -266,6 +274,13 @@ type Config struct {
 	ImageCleanExemptTags          string `json:"image_clean_exempt_tags"`
 	ImageEnableVolume             bool   `json:"image_enable_volume"`
 	DisableUnprivilegedContainers bool   `json:"disable_unprivileged_containers"`
+	ProcessEntrypointDir          string `json:"process_entrypoint_dir"`
+	ProcessCgroupRoot             string `json:"process_cgroup_root"`
+	ContainerdAddress             string `json:"containerd_address"`
+	ContainerdNamespace           string `json:"containerd_namespace"`
+	ContainerdCNIConfDir          string `json:"containerd_cni_conf_dir"`
+	ContainerdCNIBinDir           string `json:"containerd_cni_bin_dir"`
+	ContainerdHostNetwork         bool   `json:"containerd_host_network"`
 }
 
 // https://github.com/fsouza/go-dockerclient/blob/master/misc.go#L166
@@ -0,0 +1,156 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// PullFunc pulls an image once, with the context of the oldest of the calls
+// that wait on the pull
+type PullFunc func(ctx context.Context) error
+
+// PullErrorFunc returns the http status and message that the calls waiting
+// on a failed pull get, of the error of its last try
+type PullErrorFunc func(err error) (int, string)
+
+// PullRetryRecorder records that a pull is retried, for the stats of a driver
+type PullRetryRecorder func(ctx context.Context, reason string)
+
+// ImagePuller dedups concurrent pulls of an image, and retries them as its
+// retry policy allows. Neither docker nor containerd serialize fetches of
+// manifests and configs, and only docker serializes layer-blob fetches. For
+// instance, a single layer image, when merely two docker-pulls are initiated
+// at the same time, this causes 9 HTTP-GET requests from docker to the
+// repository where 4 extra & unnecessary HTTP GETs are initiated. Below is a
+// simple listener/follower serialization, where any new requests are added as
+// listeners to the ongoing pull of the same key.
+type ImagePuller struct {
+	pullError   PullErrorFunc
+	recordRetry PullRetryRecorder
+
+	lock      sync.Mutex
+	transfers map[string]*transfer
+
+	// backoff/retry settings
+	isRetriable RetryErrorChecker
+	backOffCfg  common.BackOffConfig
+}
+
+type transfer struct {
+	ctx context.Context // oldest context
+
+	key  string
+	img  string
+	pull PullFunc
+
+	listeners []chan error
+}
+
+// NewImagePuller returns an ImagePuller that does not retry pulls until its
+// retry policy is set
+func NewImagePuller(pullError PullErrorFunc, recordRetry PullRetryRecorder) *ImagePuller {
+	return &ImagePuller{
+		pullError:   pullError,
+		recordRetry: recordRetry,
+		transfers:   make(map[string]*transfer),
+		isRetriable: func(error) (bool, string) { return false, "" },
+	}
+}
+
+func (i *ImagePuller) SetRetryPolicy(policy common.BackOffConfig, checker RetryErrorChecker) error {
+	i.isRetriable = checker
+	i.backOffCfg = policy
+	return nil
+}
+
+// PullImage pulls img with pull, unless a pull of the same key is ongoing, in
+// which case it waits on that pull instead. The returned channel gets the
+// error of a failed pull, and is closed once the pull is done.
+func (i *ImagePuller) PullImage(ctx context.Context, key, img string, pull PullFunc) chan error {
+
+	i.lock.Lock()
+
+	trx, ok := i.transfers[key]
+	if !ok {
+		trx = &transfer{
+			ctx:       ctx,
+			key:       key,
+			img:       img,
+			pull:      pull,
+			listeners: make([]chan error, 0, 1),
+		}
+		i.transfers[key] = trx
+	}
+
+	errC := make(chan error, 1)
+	trx.listeners = append(trx.listeners, errC)
+
+	i.lock.Unlock()
+
+	// First time call for this key, start a pull
+	if !ok {
+		go i.startTransfer(trx)
+	}
+
+	return errC
+}
+
+func (i *ImagePuller) pullWithRetry(trx *transfer) error {
+	backoff := common.NewBackOff(i.backOffCfg)
+	timer := common.NewTimer(time.Duration(i.backOffCfg.MinDelay) * time.Millisecond)
+	defer timer.Stop()
+
+	for {
+		err := trx.pull(trx.ctx)
+		ok, reason := i.isRetriable(err)
+		if !ok {
+			return err
+		}
+
+		delay, ok := backoff.NextBackOff()
+		if !ok {
+			return err
+		}
+
+		timer.Reset(delay)
+
+		select {
+		case <-timer.C:
+			i.recordRetry(trx.ctx, reason)
+		case <-trx.ctx.Done():
+			return trx.ctx.Err()
+		}
+	}
+}
+
+func (i *ImagePuller) startTransfer(trx *transfer) {
+	var ferr error
+
+	err := i.pullWithRetry(trx)
+	if err != nil {
+		common.Logger(trx.ctx).WithError(err).Info("Failed to pull image")
+
+		code, msg := i.pullError(err)
+		err := models.NewAPIError(code, fmt.Errorf("Failed to pull image '%s': %s", trx.img, msg))
+		ferr = models.NewFuncError(err)
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	// notify any listeners
+	for _, ch := range trx.listeners {
+		if ferr != nil {
+			ch <- ferr
+		}
+		close(ch)
+	}
+
+	// unregister the pull
+	delete(i.transfers, trx.key)
+}
@@ -0,0 +1,192 @@
+// +build linux
+
+package process
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// cpuPeriod is the period of cpu.max in usecs, the one docker uses
+	cpuPeriod = 100000
+
+	// cgroupPoll is how often a cgroup is checked for having frozen or thawed,
+	// or emptied to be removed
+	cgroupPoll = time.Millisecond
+
+	// cgroupRemoveTimeout is how long the processes of a killed cgroup are
+	// waited for to exit, before it is left behind
+	cgroupRemoveTimeout = 5 * time.Second
+)
+
+// cgroup is the cgroup v2 directory of a function process
+type cgroup struct {
+	path string
+}
+
+// setupCgroupRoot creates root, and enables the controllers of the limits of
+// function processes for the cgroups below it
+func setupCgroupRoot(root string) error {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+	cg := &cgroup{path: root}
+	for _, ctrl := range []string{"memory", "cpu", "pids"} {
+		if err := cg.write("cgroup.subtree_control", "+"+ctrl); err != nil {
+			return fmt.Errorf("cannot enable the %s controller in %s: %v", ctrl, root, err)
+		}
+	}
+	return nil
+}
+
+func newCgroup(root, name string) (*cgroup, error) {
+	cg := &cgroup{path: filepath.Join(root, name)}
+	if err := os.Mkdir(cg.path, 0755); err != nil {
+		return nil, err
+	}
+	return cg, nil
+}
+
+func (cg *cgroup) write(file, value string) error {
+	return ioutil.WriteFile(filepath.Join(cg.path, file), []byte(value), 0644)
+}
+
+// setLimits limits the memory in bytes, the milli-CPUs and the PIDs of the
+// cgroup, where 0 is unlimited
+func (cg *cgroup) setLimits(memory, cpus, pids uint64) error {
+	mem := limit(memory)
+	if err := cg.write("memory.max", mem); err != nil {
+		return err
+	}
+	// no swap either, as docker sets the memory and swap limits the same
+	if _, err := os.Stat(filepath.Join(cg.path, "memory.swap.max")); err == nil && memory != 0 {
+		if err := cg.write("memory.swap.max", "0"); err != nil {
+			return err
+		}
+	}
+
+	// eg: 8000 milli-CPUs are a quota of 8 * 100000 usecs in a 100000 usec period
+	quota := limit(cpus * cpuPeriod / 1000)
+	if err := cg.write("cpu.max", fmt.Sprintf("%s %d", quota, cpuPeriod)); err != nil {
+		return err
+	}
+
+	return cg.write("pids.max", limit(pids))
+}
+
+func limit(v uint64) string {
+	if v == 0 {
+		return "max"
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+func (cg *cgroup) addProc(pid int) error {
+	return cg.write("cgroup.procs", strconv.Itoa(pid))
+}
+
+// freeze freezes the processes of the cgroup, or thaws them, and waits until
+// the kernel reports they are
+func (cg *cgroup) freeze(ctx context.Context, frozen bool) error {
+	want := "0"
+	if frozen {
+		want = "1"
+	}
+	if err := cg.write("cgroup.freeze", want); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(cgroupPoll)
+	defer ticker.Stop()
+	for {
+		events, err := cg.keyValues("cgroup.events")
+		if os.IsNotExist(err) {
+			// nothing to wait for
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if events["frozen"] == want {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// oomKilled reports whether the kernel killed a process of the cgroup for
+// going over its memory limit
+func (cg *cgroup) oomKilled() bool {
+	events, err := cg.keyValues("memory.events")
+	if err != nil {
+		return false
+	}
+	n, _ := strconv.ParseUint(events["oom_kill"], 10, 64)
+	return n > 0
+}
+
+// kill kills every process of the cgroup
+func (cg *cgroup) kill() error {
+	// cgroup.kill needs linux 5.14, older kernels get a signal per process
+	if _, err := os.Stat(filepath.Join(cg.path, "cgroup.kill")); err == nil {
+		return cg.write("cgroup.kill", "1")
+	}
+	procs, err := ioutil.ReadFile(filepath.Join(cg.path, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+	for _, p := range strings.Fields(string(procs)) {
+		if pid, err := strconv.Atoi(p); err == nil {
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+	return nil
+}
+
+// remove removes the cgroup, once the processes it had are gone
+func (cg *cgroup) remove() error {
+	deadline := time.Now().Add(cgroupRemoveTimeout)
+	for {
+		// a cgroup is removed with rmdir, its interface files with it
+		err := os.Remove(cg.path)
+		if err == nil || os.IsNotExist(err) {
+			return nil
+		}
+		if pe, ok := err.(*os.PathError); !ok || pe.Err != syscall.EBUSY || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(cgroupPoll)
+	}
+}
+
+// keyValues reads a flat keyed interface file of the cgroup
+func (cg *cgroup) keyValues(file string) (map[string]string, error) {
+	f, err := os.Open(filepath.Join(cg.path, file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	kvs := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			kvs[fields[0]] = fields[1]
+		}
+	}
+	return kvs, scanner.Err()
+}
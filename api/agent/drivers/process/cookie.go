@@ -0,0 +1,313 @@
+// +build linux
+
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// A cookie identifies a unique request to run a task.
+type cookie struct {
+	// command of the process created by Driver.CreateCookie
+	cmd *exec.Cmd
+	// task associated with this cookie
+	task drivers.ContainerTask
+	// pointer to process driver
+	drv *ProcessDriver
+
+	// contains the cgroup of the process if CreateContainer() is called
+	cgroup *cgroup
+
+	// done is closed once the process started by Run() has exited, with err
+	// set to what waiting for it returned
+	done chan struct{}
+	err  error
+
+	closeOnce sync.Once
+}
+
+func (c *cookie) configureEnv(log logrus.FieldLogger) {
+	env := c.task.EnvVars()
+	c.cmd.Env = make([]string, 0, len(env)+1)
+
+	for name, val := range env {
+		if name == "FN_LISTENER" {
+			val = c.listener(val)
+		}
+		c.cmd.Env = append(c.cmd.Env, name+"="+val)
+	}
+	if _, ok := env["PATH"]; !ok {
+		c.cmd.Env = append(c.cmd.Env, "PATH="+defaultPath)
+	}
+}
+
+// listener moves the unix socket that an FDK listens on from the iofs mount
+// of a container to the iofs directory of the agent, which the process shares
+func (c *cookie) listener(addr string) string {
+	path := strings.TrimPrefix(addr, "unix:")
+	if path == addr || c.task.UDSAgentPath() == "" || filepath.Dir(path) != c.task.UDSDockerDest() {
+		return addr
+	}
+	return "unix:" + filepath.Join(c.task.UDSAgentPath(), filepath.Base(path))
+}
+
+func (c *cookie) configureWorkDir(log logrus.FieldLogger) {
+	wd := c.task.WorkDir()
+	if wd == "" {
+		return
+	}
+
+	log.WithFields(logrus.Fields{"wd": wd, "call_id": c.task.Id()}).Debug("setting work dir")
+	c.cmd.Dir = wd
+}
+
+func (c *cookie) configureNetwork(log logrus.FieldLogger) {
+	if c.task.DisableNet() {
+		// a network namespace of its own has only a loopback, which is down
+		c.cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+}
+
+func (c *cookie) configureSecurity(log logrus.FieldLogger) {
+	if c.drv.conf.DisableUnprivilegedContainers {
+		return
+	}
+	c.cmd.SysProcAttr.Credential = &syscall.Credential{Uid: FnUserId, Gid: FnGroupId}
+	log.WithFields(logrus.Fields{"uid": FnUserId, "gid": FnGroupId, "call_id": c.task.Id()}).Debug("setting security")
+}
+
+// implements Cookie
+func (c *cookie) Close(ctx context.Context) error {
+	var err error
+	c.closeOnce.Do(func() {
+		if c.done != nil {
+			c.kill()
+			<-c.done
+		}
+		if c.cgroup != nil {
+			err = c.cgroup.remove()
+			if err != nil {
+				common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error removing cgroup")
+			}
+		}
+	})
+	return err
+}
+
+// kill kills the process, and any it started
+func (c *cookie) kill() {
+	if c.cgroup != nil {
+		c.cgroup.kill()
+	}
+	c.cmd.Process.Kill()
+}
+
+// implements Cookie
+func (c *cookie) Run(ctx context.Context) (drivers.WaitResult, error) {
+	log := common.Logger(ctx)
+
+	if c.cgroup == nil {
+		log.Fatal("invalid usage: process cgroup not created")
+	}
+
+	stdout, stderr := c.task.Logger()
+	c.cmd.Stdout = stdout
+	c.cmd.Stderr = stderr
+
+	// the process waits at its gate until it is in its cgroup, so that the
+	// entrypoint runs limited from its first instruction
+	gateR, gateW, err := os.Pipe()
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error creating process gate")
+		return nil, err
+	}
+	defer gateW.Close()
+	c.cmd.ExtraFiles = []*os.File{gateR}
+	c.cmd.Path, c.cmd.Args = gateArgs(c.cmd.Path, c.cmd.Args)
+
+	err = c.cmd.Start()
+	gateR.Close()
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error starting process")
+		return nil, err
+	}
+
+	c.done = make(chan struct{})
+	go func() {
+		c.err = c.cmd.Wait()
+		close(c.done)
+	}()
+
+	err = c.cgroup.addProc(c.cmd.Process.Pid)
+	if err == nil {
+		_, err = gateW.Write([]byte{1})
+	}
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error adding process to cgroup")
+		c.kill()
+		<-c.done
+		return nil, err
+	}
+
+	return &waitResult{cookie: c}, nil
+}
+
+// implements Cookie
+func (c *cookie) ContainerOptions() interface{} {
+	return c.cmd
+}
+
+// implements Cookie
+func (c *cookie) Freeze(ctx context.Context) error {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "Freeze"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("cgroup freeze")
+
+	err := c.cgroup.freeze(ctx, true)
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error freezing process")
+	}
+	return err
+}
+
+// implements Cookie
+func (c *cookie) Unfreeze(ctx context.Context) error {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "Unfreeze"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("cgroup thaw")
+
+	err := c.cgroup.freeze(ctx, false)
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error thawing process")
+	}
+	return err
+}
+
+// implements Cookie. Entrypoints are never pulled, an image that names none
+// is an error.
+func (c *cookie) ValidateImage(ctx context.Context) (bool, error) {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "ValidateImage"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id(), "image": c.task.Image()}).Debug("process find entrypoint")
+
+	path, err := c.drv.entrypoint(c.task.Image())
+	if err != nil {
+		return false, err
+	}
+	c.cmd.Path = path
+	return false, nil
+}
+
+// implements Cookie
+func (c *cookie) PullImage(ctx context.Context) error {
+	return ErrEntrypointNotFound
+}
+
+// implements Cookie
+func (c *cookie) CreateContainer(ctx context.Context) error {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "CreateContainer"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id(), "image": c.task.Image()}).Debug("process create cgroup")
+
+	if c.cmd.Path == "" {
+		log.Fatal("invalid usage: image not validated")
+	}
+	if c.cgroup != nil {
+		return nil
+	}
+
+	cg, err := newCgroup(c.drv.conf.ProcessCgroupRoot, c.task.Id())
+	if err != nil {
+		log.WithError(err).Error("Could not create cgroup")
+		return err
+	}
+
+	err = cg.setLimits(c.task.Memory(), c.task.CPUs(), c.task.PIDs())
+	if err != nil {
+		log.WithError(err).Error("Could not limit cgroup")
+		cg.remove()
+		return err
+	}
+
+	c.cgroup = cg
+	return nil
+}
+
+var _ drivers.Cookie = &cookie{}
+
+// waitResult implements drivers.WaitResult
+type waitResult struct {
+	cookie *cookie
+}
+
+type runResult struct {
+	err    error
+	status string
+}
+
+func (r *runResult) Error() error   { return r.err }
+func (r *runResult) Status() string { return r.status }
+
+// implements drivers.WaitResult
+func (w *waitResult) Wait(ctx context.Context) drivers.RunResult {
+	status, err := w.wait(ctx)
+	return &runResult{
+		status: status,
+		err:    err,
+	}
+}
+
+func (w *waitResult) wait(ctx context.Context) (status string, err error) {
+	c := w.cookie
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		// the process dies with the task
+		c.kill()
+		<-c.done
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return drivers.StatusTimeout, context.DeadlineExceeded
+		default:
+			return drivers.StatusCancelled, context.Canceled
+		}
+	}
+
+	if c.cgroup.oomKilled() {
+		common.Logger(ctx).Error("process oom")
+		err := errors.New("container out of memory, you may want to raise fn.memory for this function (default: 128MB)")
+		return drivers.StatusKilled, models.NewAPIError(http.StatusBadGateway, err)
+	}
+
+	if exitErr, ok := c.err.(*exec.ExitError); ok {
+		return drivers.StatusError, models.NewAPIError(http.StatusBadGateway, fmt.Errorf("container exit code %d", exitCode(exitErr)))
+	} else if c.err != nil {
+		// plumb up i/o errors
+		return drivers.StatusError, c.err
+	}
+	return drivers.StatusSuccess, nil
+}
+
+// exitCode returns the exit code of a process as a shell would, 128 plus the
+// signal for one that was killed
+func exitCode(err *exec.ExitError) int {
+	ws, ok := err.Sys().(syscall.WaitStatus)
+	if !ok {
+		return -1
+	}
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ws.ExitStatus()
+}
@@ -0,0 +1,15 @@
+// Package process provides a Driver that runs the entrypoint of a function as
+// a child process of the agent, rather than in a container. The image of a
+// function names an executable in the entrypoint directory of the driver.
+// Each process gets mount, pid, ipc and uts namespaces, a network namespace if
+// its network is disabled, and a cgroup v2 limited to the memory, CPUs and
+// PIDs of its function, that it is in before its entrypoint runs. It listens
+// on the iofs unix socket as an FDK in a docker container would. The driver
+// only needs a Linux kernel, not a docker daemon.
+//
+// A process is not confined to a root filesystem of its own. Its mount
+// namespace keeps the mounts it makes from the host, but it sees the files of
+// the host as its user may, and as root, with unprivileged containers
+// disabled, may change them. Functions that must not see the host need the
+// agent to run in a container or VM of their own, or another driver.
+package process
@@ -0,0 +1,49 @@
+// +build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+const (
+	// gateArg0 is the name that a function process is started with, as a
+	// re-exec of the agent that waits to be let through its gate
+	gateArg0 = "fn-process-gate"
+
+	// gateFd is the descriptor of the read end of the gate, the first of the
+	// extra files of the command
+	gateFd = 3
+)
+
+// A function process starts as a re-exec of the agent, that waits on a pipe
+// until the agent has added it to its cgroup, and only then execs the
+// entrypoint. The entrypoint never runs outside of its limits.
+func init() {
+	if len(os.Args) > 2 && os.Args[0] == gateArg0 {
+		gate(os.Args[1], os.Args[2:])
+	}
+}
+
+// gate waits for the agent to write to the gate, and execs path with args. If
+// the agent closes the gate without writing to it, the process exits.
+func gate(path string, args []string) {
+	gate := os.NewFile(gateFd, "gate")
+	var b [1]byte
+	n, _ := gate.Read(b[:])
+	gate.Close()
+	if n != 1 {
+		os.Exit(1)
+	}
+
+	err := syscall.Exec(path, args, os.Environ())
+	fmt.Fprintf(os.Stderr, "cannot exec %s: %v\n", path, err)
+	os.Exit(1)
+}
+
+// gateArgs returns the path and args that run path with args through the gate
+func gateArgs(path string, args []string) (string, []string) {
+	return "/proc/self/exe", append([]string{gateArg0, path}, args...)
+}
@@ -0,0 +1,159 @@
+// +build linux
+
+package process
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// FnUserId and FnGroupId run function processes, unless unprivileged
+	// containers are disabled, as they do docker containers
+	FnUserId  = 1000
+	FnGroupId = 1000
+
+	// defaultPath is the PATH of a function process whose env has none, the
+	// one docker gives containers
+	defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+var (
+	// ErrEntrypointNotFound is returned for the image of a function that does
+	// not name an executable in the entrypoint directory
+	ErrEntrypointNotFound = models.NewAPIError(http.StatusBadRequest, errors.New("image is not an entrypoint of the process driver"))
+)
+
+// ProcessDriver implements drivers.Driver by running the entrypoint of each
+// function as a child process, in namespaces and a cgroup v2 of its own
+type ProcessDriver struct {
+	conf drivers.Config
+}
+
+// NewProcess creates a process driver, and the cgroup root it creates the
+// cgroups of function processes under. Function processes left over in it,
+// by an agent that did not shut down, are killed.
+func NewProcess(conf drivers.Config) (*ProcessDriver, error) {
+	if conf.ProcessEntrypointDir == "" {
+		return nil, errors.New("process driver needs a directory of entrypoints")
+	}
+	if conf.ProcessCgroupRoot == "" {
+		return nil, errors.New("process driver needs a cgroup root")
+	}
+	if err := setupCgroupRoot(conf.ProcessCgroupRoot); err != nil {
+		return nil, err
+	}
+
+	drv := &ProcessDriver{conf: conf}
+	drv.killLeakedProcesses()
+	return drv, nil
+}
+
+func (drv *ProcessDriver) killLeakedProcesses() {
+	log := logrus.WithFields(logrus.Fields{"stack": "killLeakedProcesses"})
+
+	dirs, err := ioutil.ReadDir(drv.conf.ProcessCgroupRoot)
+	if err != nil {
+		log.WithError(err).Error("cannot list cgroups")
+		return
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		cg := &cgroup{path: filepath.Join(drv.conf.ProcessCgroupRoot, dir.Name())}
+		log.WithFields(logrus.Fields{"cgroup": cg.path}).Info("killing leaked processes")
+		if err := cg.kill(); err != nil {
+			log.WithError(err).WithFields(logrus.Fields{"cgroup": cg.path}).Error("cannot kill leaked processes")
+			continue
+		}
+		if err := cg.remove(); err != nil {
+			log.WithError(err).WithFields(logrus.Fields{"cgroup": cg.path}).Error("cannot remove leaked cgroup")
+		}
+	}
+}
+
+// entrypoint returns the path of the executable that image names in the
+// entrypoint directory
+func (drv *ProcessDriver) entrypoint(image string) (string, error) {
+	name := filepath.Clean("/" + image)
+	if name == "/" || name != "/"+image {
+		// images may not name paths outside of the directory, or name them in
+		// more than one way
+		return "", ErrEntrypointNotFound
+	}
+
+	path := filepath.Join(drv.conf.ProcessEntrypointDir, name)
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() || fi.Mode()&0111 == 0 {
+		return "", ErrEntrypointNotFound
+	}
+	return path, nil
+}
+
+func (drv *ProcessDriver) CreateCookie(ctx context.Context, task drivers.ContainerTask) (drivers.Cookie, error) {
+
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "CreateCookie"})
+
+	cmd := &exec.Cmd{
+		Args: append([]string{task.Image()}, strings.Fields(task.Command())...),
+		Dir:  "/",
+		SysProcAttr: &syscall.SysProcAttr{
+			// a pid namespace of its own makes the process its init, which takes
+			// the processes it starts down with it
+			Cloneflags: syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+			// a mount namespace unshared, rather than cloned, has its mounts made
+			// private, so that none of those of the process reach the host
+			Unshareflags: syscall.CLONE_NEWNS,
+		},
+	}
+
+	cookie := &cookie{
+		cmd:  cmd,
+		task: task,
+		drv:  drv,
+	}
+
+	cookie.configureEnv(log)
+	cookie.configureWorkDir(log)
+	cookie.configureNetwork(log)
+	cookie.configureSecurity(log)
+
+	return cookie, nil
+}
+
+func (drv *ProcessDriver) SetPullImageRetryPolicy(policy common.BackOffConfig, checker drivers.RetryErrorChecker) error {
+	return nil
+}
+
+func (drv *ProcessDriver) GetSlotKeyExtensions(extn map[string]string) string {
+	return ""
+}
+
+func (drv *ProcessDriver) Close() error {
+	return nil
+}
+
+var _ drivers.Driver = &ProcessDriver{}
+
+func init() {
+	drivers.Register("process", func(config drivers.Config) (drivers.Driver, error) {
+		drv, err := NewProcess(config)
+		if err != nil {
+			return nil, err
+		}
+		return drv, nil
+	})
+}
@@ -0,0 +1,15 @@
+// +build !linux
+
+package process
+
+import (
+	"errors"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+)
+
+func init() {
+	drivers.Register("process", func(config drivers.Config) (drivers.Driver, error) {
+		return nil, errors.New("process driver is only supported on linux")
+	})
+}
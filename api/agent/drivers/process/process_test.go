@@ -0,0 +1,412 @@
+// +build linux
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/agent/drivers/stats"
+	"github.com/fnproject/fn/api/models"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	helperEnv     = "FN_PROCESS_TEST_HELPER"
+	iofsMountDest = "/tmp/iofs"
+)
+
+type taskProcessTest struct {
+	id      string
+	cmd     string
+	env     map[string]string
+	iofs    string
+	errors  io.Writer
+	memory  uint64
+	cpus    uint64
+	pids    uint64
+	workDir string
+}
+
+func (f *taskProcessTest) Command() string                                            { return f.cmd }
+func (f *taskProcessTest) EnvVars() map[string]string                                 { return f.env }
+func (f *taskProcessTest) Id() string                                                 { return f.id }
+func (f *taskProcessTest) Image() string                                              { return "helper" }
+func (f *taskProcessTest) Logger() (stdout, stderr io.Writer)                         { return f.errors, f.errors }
+func (f *taskProcessTest) WriteStat(context.Context, stats.Stat)                      {}
+func (f *taskProcessTest) Volumes() [][2]string                                       { return nil }
+func (f *taskProcessTest) Memory() uint64                                             { return f.memory }
+func (f *taskProcessTest) CPUs() uint64                                               { return f.cpus }
+func (f *taskProcessTest) FsSize() uint64                                             { return 0 }
+func (f *taskProcessTest) PIDs() uint64                                               { return f.pids }
+func (f *taskProcessTest) OpenFiles() *uint64                                         { return nil }
+func (f *taskProcessTest) LockedMemory() *uint64                                      { return nil }
+func (f *taskProcessTest) PendingSignals() *uint64                                    { return nil }
+func (f *taskProcessTest) MessageQueue() *uint64                                      { return nil }
+func (f *taskProcessTest) TmpFsSize() uint64                                          { return 0 }
+func (f *taskProcessTest) WorkDir() string                                            { return f.workDir }
+func (f *taskProcessTest) Close()                                                     {}
+func (f *taskProcessTest) WrapClose(func(func()) func())                              {}
+func (f *taskProcessTest) WrapBeforeCall(func(drivers.BeforeCall) drivers.BeforeCall) {}
+func (f *taskProcessTest) WrapAfterCall(func(drivers.AfterCall) drivers.AfterCall)    {}
+func (f *taskProcessTest) Input() io.Reader                                           { return nil }
+func (f *taskProcessTest) Extensions() map[string]string                              { return nil }
+func (f *taskProcessTest) LoggerConfig() drivers.LoggerConfig                         { return drivers.LoggerConfig{} }
+func (f *taskProcessTest) UDSAgentPath() string                                       { return f.iofs }
+func (f *taskProcessTest) UDSDockerPath() string                                      { return f.iofs }
+func (f *taskProcessTest) UDSDockerDest() string                                      { return iofsMountDest }
+func (f *taskProcessTest) DisableNet() bool                                           { return false }
+
+func (f *taskProcessTest) BeforeCall(context.Context, *models.Call, drivers.CallExtensions) error {
+	return nil
+}
+func (f *taskProcessTest) AfterCall(context.Context, *models.Call, drivers.CallExtensions) error {
+	return nil
+}
+
+// TestHelperProcess is the entrypoint that the tests run, as an FDK listening
+// on FN_LISTENER, or exiting with the code in its args
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv(helperEnv) != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) == 2 && args[1] == "cgroup" {
+		// the cgroup that the entrypoint starts in
+		b, _ := ioutil.ReadFile("/proc/self/cgroup")
+		os.Stderr.Write(b)
+		os.Exit(0)
+	}
+	if len(args) == 2 {
+		fmt.Fprintln(os.Stderr, "exiting")
+		var code int
+		fmt.Sscan(args[1], &code)
+		os.Exit(code)
+	}
+
+	ln, err := net.Listen("unix", strings.TrimPrefix(os.Getenv("FN_LISTENER"), "unix:"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	fmt.Fprintln(os.Stderr, "listening")
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Exit(2)
+		}
+		conn.Close()
+	}
+}
+
+// testDriver returns a process driver whose entrypoint is the test binary,
+// with a cgroup root that is a plain directory
+func testDriver(t *testing.T) (*ProcessDriver, string) {
+	if os.Getuid() != 0 {
+		t.Skip("the process driver needs to be root to create namespaces")
+	}
+
+	dir, err := ioutil.TempDir("", "process-driver")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entrypoints := filepath.Join(dir, "entrypoints")
+	if err := os.Mkdir(entrypoints, 0755); err != nil {
+		t.Fatal(err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(self, filepath.Join(entrypoints, "helper")); err != nil {
+		t.Fatal(err)
+	}
+
+	drv, err := NewProcess(drivers.Config{
+		ProcessEntrypointDir:          entrypoints,
+		ProcessCgroupRoot:             filepath.Join(dir, "cgroup"),
+		DisableUnprivilegedContainers: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return drv, dir
+}
+
+// cgroupV2Root returns a cgroup root below the cgroup v2 of the test, that
+// the controllers of the driver are delegated to, or skips the test
+func cgroupV2Root(t *testing.T) string {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs("/sys/fs/cgroup", &fs); err != nil || fs.Type != unix.CGROUP2_SUPER_MAGIC {
+		t.Skip("/sys/fs/cgroup is not a cgroup v2")
+	}
+
+	b, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil || !strings.HasPrefix(string(b), "0::") {
+		t.Skip("the test is not in a cgroup v2")
+	}
+	self := strings.TrimSpace(strings.TrimPrefix(string(b), "0::"))
+
+	root := filepath.Join("/sys/fs/cgroup", self, fmt.Sprintf("fn-process-test-%d", os.Getpid()))
+	if err := setupCgroupRoot(root); err != nil {
+		os.Remove(root)
+		t.Skipf("the controllers of the driver are not delegated to the cgroup of the test: %v", err)
+	}
+	return root
+}
+
+func testTask(dir, id string, args ...string) *taskProcessTest {
+	iofs := filepath.Join(dir, "iofs-"+id)
+	os.Mkdir(iofs, 0755)
+	return &taskProcessTest{
+		id:   id,
+		cmd:  strings.Join(append([]string{"-test.run=TestHelperProcess", "--"}, args...), " "),
+		iofs: iofs,
+		env: map[string]string{
+			helperEnv:     "1",
+			"FN_LISTENER": "unix:" + filepath.Join(iofsMountDest, "lsnr.sock"),
+		},
+		errors: &bytes.Buffer{},
+		memory: 128 * 1024 * 1024,
+		cpus:   500,
+		pids:   50,
+	}
+}
+
+// clearCgroup removes the files that a test wrote in a cgroup, which the
+// kernel would not have let it create in the first place
+func clearCgroup(t *testing.T, cg *cgroup) {
+	files, err := ioutil.ReadDir(cg.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		os.Remove(filepath.Join(cg.path, f.Name()))
+	}
+}
+
+func readCgroupFile(t *testing.T, cg *cgroup, file string) string {
+	b, err := ioutil.ReadFile(filepath.Join(cg.path, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRunProcess(t *testing.T) {
+	drv, dir := testDriver(t)
+	defer os.RemoveAll(dir)
+
+	task := testTask(dir, "run")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ck, err := drv.CreateCookie(ctx, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needsPull, err := ck.ValidateImage(ctx); needsPull || err != nil {
+		t.Fatalf("expected the entrypoint to be found, got %v %v", needsPull, err)
+	}
+	if err := ck.CreateContainer(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	cg := ck.(*cookie).cgroup
+	for file, expected := range map[string]string{
+		"memory.max": "134217728",
+		"cpu.max":    "50000 100000",
+		"pids.max":   "50",
+	} {
+		if got := readCgroupFile(t, cg, file); got != expected {
+			t.Fatalf("expected %s to be %q, got %q", file, expected, got)
+		}
+	}
+
+	waiter, err := ck.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if procs := readCgroupFile(t, cg, "cgroup.procs"); procs == "" {
+		t.Fatalf("expected the process to be added to its cgroup")
+	}
+
+	// the FDK listens in the iofs directory of the agent
+	sock := filepath.Join(task.iofs, "lsnr.sock")
+	var conn net.Conn
+	for i := 0; i < 500 && conn == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		conn, _ = net.Dial("unix", sock)
+	}
+	if conn == nil {
+		t.Fatalf("process did not listen on %s: %s", sock, task.errors)
+	}
+	conn.Close()
+
+	if err := ck.Freeze(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := readCgroupFile(t, cg, "cgroup.freeze"); got != "1" {
+		t.Fatalf("expected the cgroup to be frozen, got %q", got)
+	}
+	if err := ck.Unfreeze(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := readCgroupFile(t, cg, "cgroup.freeze"); got != "0" {
+		t.Fatalf("expected the cgroup to be thawed, got %q", got)
+	}
+
+	cancel()
+	res := waiter.Wait(ctx)
+	if res.Status() != drivers.StatusCancelled || res.Error() != context.Canceled {
+		t.Fatalf("expected the process to be cancelled, got %s %v", res.Status(), res.Error())
+	}
+
+	clearCgroup(t, cg)
+	if err := ck.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cg.path); !os.IsNotExist(err) {
+		t.Fatalf("expected the cgroup to be removed, got %v", err)
+	}
+	if !strings.Contains(task.errors.(*bytes.Buffer).String(), "listening") {
+		t.Fatalf("expected the output of the process to be logged, got %q", task.errors)
+	}
+}
+
+func TestRunProcessExit(t *testing.T) {
+	drv, dir := testDriver(t)
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		code   string
+		status string
+	}{
+		{"0", drivers.StatusSuccess},
+		{"3", drivers.StatusError},
+	} {
+		task := testTask(dir, "exit"+test.code, test.code)
+		ctx := context.Background()
+
+		ck, err := drv.CreateCookie(ctx, task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ck.ValidateImage(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := ck.CreateContainer(ctx); err != nil {
+			t.Fatal(err)
+		}
+		waiter, err := ck.Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := waiter.Wait(ctx)
+		if res.Status() != test.status {
+			t.Fatalf("exit %s: expected status %s, got %s %v", test.code, test.status, res.Status(), res.Error())
+		}
+		if test.status == drivers.StatusError && !strings.Contains(res.Error().Error(), "exit code 3") {
+			t.Fatalf("exit %s: expected the exit code in the error, got %v", test.code, res.Error())
+		}
+
+		clearCgroup(t, ck.(*cookie).cgroup)
+		if err := ck.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProcessEntrypoint(t *testing.T) {
+	drv, dir := testDriver(t)
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "outside"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "entrypoints", "data"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if path, err := drv.entrypoint("helper"); err != nil || path != filepath.Join(dir, "entrypoints", "helper") {
+		t.Fatalf("expected the helper entrypoint, got %q %v", path, err)
+	}
+	for _, image := range []string{"", "missing", "data", "../outside", "/helper", "./helper", "helper/"} {
+		if _, err := drv.entrypoint(image); err != ErrEntrypointNotFound {
+			t.Fatalf("image %q: expected error `%v`, got `%v`", image, ErrEntrypointNotFound, err)
+		}
+	}
+}
+
+func TestRunProcessCgroupV2(t *testing.T) {
+	drv, dir := testDriver(t)
+	defer os.RemoveAll(dir)
+
+	root := cgroupV2Root(t)
+	defer os.Remove(root)
+	drv.conf.ProcessCgroupRoot = root
+
+	task := testTask(dir, "cgroupv2", "cgroup")
+	ctx := context.Background()
+
+	ck, err := drv.CreateCookie(ctx, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ck.ValidateImage(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := ck.CreateContainer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer ck.Close(ctx)
+
+	cg := ck.(*cookie).cgroup
+	for file, expected := range map[string]string{
+		"memory.max": "134217728\n",
+		"cpu.max":    "50000 100000\n",
+		"pids.max":   "50\n",
+	} {
+		if got := readCgroupFile(t, cg, file); got != expected {
+			t.Fatalf("expected %s to be %q, got %q", file, expected, got)
+		}
+	}
+
+	waiter, err := ck.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := waiter.Wait(ctx); res.Status() != drivers.StatusSuccess {
+		t.Fatalf("expected the process to succeed, got %s %v: %s", res.Status(), res.Error(), task.errors)
+	}
+
+	// the entrypoint is in its cgroup from its first instruction
+	expected := "0::" + strings.TrimPrefix(cg.path, "/sys/fs/cgroup")
+	if out := task.errors.(*bytes.Buffer).String(); !strings.Contains(out, expected) {
+		t.Fatalf("expected the entrypoint to start in its cgroup %s, got %q", cg.path, out)
+	}
+
+	if err := ck.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cg.path); !os.IsNotExist(err) {
+		t.Fatalf("expected the cgroup to be removed, got %v", err)
+	}
+}
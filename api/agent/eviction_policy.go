@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// EvictionLRU evicts the hot containers that were used least recently first
+	EvictionLRU = "lru"
+	// EvictionLFU evicts the hot containers of the functions called least often first
+	EvictionLFU = "lfu"
+	// EvictionCost evicts the hot containers that are cheapest to start again,
+	// for the memory they free, first
+	EvictionCost = "cost"
+	// EvictionPriority evicts the hot containers of the functions with the
+	// lowest eviction priority annotation first
+	EvictionPriority = "priority"
+)
+
+// EvictCandidate is a hot container that may be evicted, with what an
+// eviction policy knows of it and of its slot queue.
+type EvictCandidate struct {
+	// Memory is the memory in MB that evicting the container frees
+	Memory uint64
+	// CPU is the milli-CPUs that evicting the container frees
+	CPU uint64
+	// LastUsed is when the container last became idle
+	LastUsed time.Time
+	// Calls is how many calls the slot queue of the container has had
+	Calls uint64
+	// ColdStart is how long the containers of the slot queue are expected to
+	// take to start, 0 if none has started yet
+	ColdStart time.Duration
+	// Priority is the eviction priority of the function of the container
+	Priority int
+	// Paused is whether the container is frozen. The evictor evicts paused
+	// containers before running ones, whatever the policy.
+	Paused bool
+}
+
+// EvictionPolicy decides the order in which the evictor evicts the hot
+// containers it may evict.
+type EvictionPolicy interface {
+	// Less reports whether the container a should be evicted before b
+	Less(a, b *EvictCandidate) bool
+}
+
+// NewEvictionPolicy returns the eviction policy with name, one of EvictionLRU,
+// EvictionLFU, EvictionCost and EvictionPriority. An empty name is the LRU policy.
+func NewEvictionPolicy(name string) (EvictionPolicy, error) {
+	switch name {
+	case "", EvictionLRU:
+		return &lruEviction{}, nil
+	case EvictionLFU:
+		return &lfuEviction{}, nil
+	case EvictionCost:
+		return &costEviction{}, nil
+	case EvictionPriority:
+		return &priorityEviction{}, nil
+	}
+	return nil, fmt.Errorf("unknown eviction policy %q", name)
+}
+
+// lruEviction evicts the containers idle the longest first
+type lruEviction struct{}
+
+func (p *lruEviction) Less(a, b *EvictCandidate) bool {
+	return a.LastUsed.Before(b.LastUsed)
+}
+
+// lfuEviction evicts the containers of the functions with the fewest calls
+// first, and the containers idle the longest among those with as many.
+type lfuEviction struct{}
+
+func (p *lfuEviction) Less(a, b *EvictCandidate) bool {
+	if a.Calls != b.Calls {
+		return a.Calls < b.Calls
+	}
+	return a.LastUsed.Before(b.LastUsed)
+}
+
+// costEviction evicts the containers with the shortest expected cold start
+// per MB of memory they free first, and the containers idle the longest
+// among those which cost as much. A container of a function that has not been
+// seen to start counts as costing nothing to start again.
+type costEviction struct{}
+
+func (p *costEviction) Less(a, b *EvictCandidate) bool {
+	// a.ColdStart/a.Memory < b.ColdStart/b.Memory, without the division
+	costA := uint64(a.ColdStart) * b.Memory
+	costB := uint64(b.ColdStart) * a.Memory
+	if costA != costB {
+		return costA < costB
+	}
+	return a.LastUsed.Before(b.LastUsed)
+}
+
+// priorityEviction evicts the containers of the functions with the lowest
+// priority first, and the containers idle the longest among those with the
+// same priority.
+type priorityEviction struct{}
+
+func (p *priorityEviction) Less(a, b *EvictCandidate) bool {
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+	return a.LastUsed.Before(b.LastUsed)
+}
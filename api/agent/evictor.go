@@ -1,10 +1,13 @@
 package agent
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
 
 	"github.com/sirupsen/logrus"
 )
@@ -14,7 +17,8 @@ import (
 // A starved request can call PerformEviction() to scan the evictable
 // hot containers and if a number of these can be evicted to satisfy
 // memory+cpu needs of the starved request, then those hot-containers
-// are evicted.
+// are evicted. The EvictionPolicy of the evictor decides which of them
+// go first.
 
 type tokenKey struct {
 	id     string
@@ -24,8 +28,11 @@ type tokenKey struct {
 }
 
 type EvictToken struct {
+	lastUsed  int64 // unix nanos, 64-bit aligned for atomic access
 	key       tokenKey
 	evictable uint32
+	paused    uint32
+	priority  int32
 	C         chan struct{}
 	DoneChan  chan struct{}
 }
@@ -42,6 +49,30 @@ type Evictor interface {
 	// and returns a slice of channels for evictions performed. The callers
 	// can wait on these channel to ensure evictions are completed.
 	PerformEviction(slotId string, mem, cpu uint64) []chan struct{}
+
+	// PerformPriorityEviction performs evictions like PerformEviction, of the
+	// containers whose last call had no higher priority than priority.
+	PerformPriorityEviction(slotId string, priority int, mem, cpu uint64) []chan struct{}
+
+	// RecordCall records a call for slotId, whose function has priority, for
+	// the eviction policy to weigh the hot containers of slotId by.
+	RecordCall(slotId string, priority int)
+
+	// RecordColdStart records a hot container of slotId taking dur to start
+	// and returns true if a container of slotId was evicted since the last one
+	// started, which makes the eviction the cause of this start.
+	RecordColdStart(slotId string, dur time.Duration) bool
+
+	// DeleteSlot forgets what was recorded for slotId, once its slot queue is gone.
+	DeleteSlot(slotId string)
+}
+
+// slotUsage is what the evictor knows about the calls of a slot queue
+type slotUsage struct {
+	calls     uint64
+	coldStart time.Duration
+	priority  int
+	evicted   bool
 }
 
 type evictor struct {
@@ -49,12 +80,22 @@ type evictor struct {
 	id     uint64
 	tokens map[string]*EvictToken
 	slots  []tokenKey
+	usage  map[string]*slotUsage
+	policy EvictionPolicy
 }
 
+// NewEvictor returns an evictor with the LRU eviction policy
 func NewEvictor() Evictor {
+	return NewEvictorWithPolicy(&lruEviction{})
+}
+
+// NewEvictorWithPolicy returns an evictor which evicts in the order policy decides
+func NewEvictorWithPolicy(policy EvictionPolicy) Evictor {
 	return &evictor{
 		tokens: make(map[string]*EvictToken),
 		slots:  make([]tokenKey, 0),
+		usage:  make(map[string]*slotUsage),
+		policy: policy,
 	}
 }
 
@@ -71,11 +112,27 @@ func (token *EvictToken) SetEvictable(isEvictable bool) {
 	val := uint32(0)
 	if isEvictable {
 		val = 1
+		atomic.StoreInt64(&token.lastUsed, time.Now().UnixNano())
 	}
 
 	atomic.StoreUint32(&token.evictable, val)
 }
 
+// SetPaused marks the container as frozen, which makes it evicted before
+// the running ones
+func (token *EvictToken) SetPaused(isPaused bool) {
+	val := uint32(0)
+	if isPaused {
+		val = 1
+	}
+	atomic.StoreUint32(&token.paused, val)
+}
+
+// SetPriority sets the priority of the last call of the container
+func (token *EvictToken) SetPriority(priority int) {
+	atomic.StoreInt32(&token.priority, int32(priority))
+}
+
 func (tok *EvictToken) isEligible() bool {
 	// if no resource limits are in place, then this
 	// function is not eligible.
@@ -95,6 +152,7 @@ func (e *evictor) CreateEvictToken(slotId string, mem, cpu uint64) *EvictToken {
 	}
 
 	token := &EvictToken{
+		lastUsed: time.Now().UnixNano(),
 		key:      key,
 		C:        make(chan struct{}),
 		DoneChan: make(chan struct{}),
@@ -140,6 +198,10 @@ func (e *evictor) DeleteEvictToken(token *EvictToken) {
 }
 
 func (e *evictor) PerformEviction(slotId string, mem, cpu uint64) []chan struct{} {
+	return e.PerformPriorityEviction(slotId, models.PriorityHigh, mem, cpu)
+}
+
+func (e *evictor) PerformPriorityEviction(slotId string, priority int, mem, cpu uint64) []chan struct{} {
 	var notifyChans []chan struct{}
 
 	// if no resources are defined for this function, then
@@ -154,7 +216,8 @@ func (e *evictor) PerformEviction(slotId string, mem, cpu uint64) []chan struct{
 	totalCpu := uint64(0)
 	isSatisfied := false
 
-	var keys []string
+	var keys []tokenKey
+	var candidates []*EvictCandidate
 	var completionChans []chan struct{}
 
 	e.lock.Lock()
@@ -165,13 +228,49 @@ func (e *evictor) PerformEviction(slotId string, mem, cpu uint64) []chan struct{
 			continue
 		}
 		// descend into map to verify evictable state
-		if atomic.LoadUint32(&e.tokens[val.id].evictable) == 0 {
+		tok := e.tokens[val.id]
+		if atomic.LoadUint32(&tok.evictable) == 0 {
 			continue
 		}
+		// nor evict for calls of a lower priority than the container served
+		if int(atomic.LoadInt32(&tok.priority)) > priority {
+			continue
+		}
+
+		candidate := &EvictCandidate{
+			Memory:   val.memory,
+			CPU:      val.cpu,
+			LastUsed: time.Unix(0, atomic.LoadInt64(&tok.lastUsed)),
+			Paused:   atomic.LoadUint32(&tok.paused) == 1,
+		}
+		if usage, ok := e.usage[val.slotId]; ok {
+			candidate.Calls = usage.calls
+			candidate.ColdStart = usage.coldStart
+			candidate.Priority = usage.priority
+		}
+		keys = append(keys, val)
+		candidates = append(candidates, candidate)
+	}
+
+	// order the candidates as the policy would evict them, paused ones first
+	// and ties in the order the tokens were created
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := candidates[order[i]], candidates[order[j]]
+		if a.Paused != b.Paused {
+			return a.Paused
+		}
+		return e.policy.Less(a, b)
+	})
 
-		totalMemory += val.memory
-		totalCpu += val.cpu
-		keys = append(keys, val.id)
+	evicted := make(map[string]bool)
+	for _, i := range order {
+		totalMemory += keys[i].memory
+		totalCpu += keys[i].cpu
+		evicted[keys[i].id] = true
 
 		// did we satisfy the need?
 		if totalMemory >= mem && totalCpu >= cpu {
@@ -183,26 +282,25 @@ func (e *evictor) PerformEviction(slotId string, mem, cpu uint64) []chan struct{
 	// If we can satisfy the need, then let's commit/perform eviction
 	if isSatisfied {
 
-		notifyChans = make([]chan struct{}, 0, len(keys))
-		completionChans = make([]chan struct{}, 0, len(keys))
+		notifyChans = make([]chan struct{}, 0, len(evicted))
+		completionChans = make([]chan struct{}, 0, len(evicted))
 
-		idx := 0
-		for _, id := range keys {
-
-			// do not initialize idx, we continue where we left off
-			// since keys are in order from above.
-			for ; idx < len(e.slots); idx++ {
-				if id == e.slots[idx].id {
-					e.slots = append(e.slots[:idx], e.slots[idx+1:]...)
-					break
-				}
+		slots := e.slots[:0]
+		for _, val := range e.slots {
+			if !evicted[val.id] {
+				slots = append(slots, val)
+				continue
 			}
 
-			notifyChans = append(notifyChans, e.tokens[id].C)
-			completionChans = append(completionChans, e.tokens[id].DoneChan)
+			notifyChans = append(notifyChans, e.tokens[val.id].C)
+			completionChans = append(completionChans, e.tokens[val.id].DoneChan)
 
-			delete(e.tokens, id)
+			delete(e.tokens, val.id)
+			if usage, ok := e.usage[val.slotId]; ok {
+				usage.evicted = true
+			}
 		}
+		e.slots = slots
 	}
 
 	e.lock.Unlock()
@@ -213,3 +311,45 @@ func (e *evictor) PerformEviction(slotId string, mem, cpu uint64) []chan struct{
 
 	return completionChans
 }
+
+// getUsage returns the usage of slotId, which must be called with the lock held
+func (e *evictor) getUsage(slotId string) *slotUsage {
+	usage, ok := e.usage[slotId]
+	if !ok {
+		usage = &slotUsage{}
+		e.usage[slotId] = usage
+	}
+	return usage
+}
+
+func (e *evictor) RecordCall(slotId string, priority int) {
+	e.lock.Lock()
+	usage := e.getUsage(slotId)
+	usage.calls++
+	usage.priority = priority
+	e.lock.Unlock()
+}
+
+func (e *evictor) RecordColdStart(slotId string, dur time.Duration) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	usage := e.getUsage(slotId)
+
+	// the expected cold start moves a quarter of the way to each one seen
+	if usage.coldStart == 0 {
+		usage.coldStart = dur
+	} else {
+		usage.coldStart += (dur - usage.coldStart) / 4
+	}
+
+	evicted := usage.evicted
+	usage.evicted = false
+	return evicted
+}
+
+func (e *evictor) DeleteSlot(slotId string) {
+	e.lock.Lock()
+	delete(e.usage, slotId)
+	e.lock.Unlock()
+}
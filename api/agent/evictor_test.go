@@ -1,7 +1,11 @@
 package agent
 
 import (
+	"fmt"
 	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
 )
 
 func getACall(slot string, mem, cpu int) (string, uint64, uint64) {
@@ -141,3 +145,155 @@ func TestEvictorSimple03(t *testing.T) {
 	evictor.DeleteEvictToken(token2)
 	evictor.DeleteEvictToken(token3)
 }
+
+// slotsUsed is the number of slots the evictor keeps usage for
+func slotsUsed(e Evictor) int {
+	ev := e.(*evictor)
+	ev.lock.Lock()
+	defer ev.lock.Unlock()
+	return len(ev.usage)
+}
+
+func TestEvictorPolicies(t *testing.T) {
+	for _, test := range []struct {
+		policy  string
+		evicted int // which of the three tokens is evicted
+	}{
+		// token0 is used last, token1 first
+		{EvictionLRU, 1},
+		// slot0 has the fewest calls
+		{EvictionLFU, 0},
+		// slot2 is the quickest to start again for its memory
+		{EvictionCost, 2},
+		// slot1 has the lowest priority
+		{EvictionPriority, 1},
+	} {
+		policy, err := NewEvictionPolicy(test.policy)
+		if err != nil {
+			t.Fatalf("%s: %v", test.policy, err)
+		}
+		evictor := NewEvictorWithPolicy(policy)
+
+		evictor.RecordCall("slot0", 5)
+		evictor.RecordCall("slot1", 1)
+		evictor.RecordCall("slot1", 1)
+		evictor.RecordCall("slot2", 5)
+		evictor.RecordCall("slot2", 5)
+		evictor.RecordCall("slot2", 5)
+		evictor.RecordColdStart("slot0", 2*time.Second)
+		evictor.RecordColdStart("slot1", 4*time.Second)
+		evictor.RecordColdStart("slot2", 4*time.Second)
+
+		token0 := evictor.CreateEvictToken("slot0", 128, 100)
+		token1 := evictor.CreateEvictToken("slot1", 128, 100)
+		token2 := evictor.CreateEvictToken("slot2", 512, 100)
+		tokens := []*EvictToken{token0, token1, token2}
+
+		token1.SetEvictable(true)
+		time.Sleep(time.Millisecond)
+		token2.SetEvictable(true)
+		time.Sleep(time.Millisecond)
+		token0.SetEvictable(true)
+
+		if len(evictor.PerformEviction("foo", 128, 100)) != 1 {
+			t.Fatalf("%s: we should be able to evict one token", test.policy)
+		}
+		for i, token := range tokens {
+			if token.isEvicted() != (i == test.evicted) {
+				t.Fatalf("%s: token%d evicted=%v, expected token%d to be evicted", test.policy, i, token.isEvicted(), test.evicted)
+			}
+		}
+
+		slot := fmt.Sprintf("slot%d", test.evicted)
+		if !evictor.RecordColdStart(slot, time.Second) {
+			t.Fatalf("%s: the cold start of %s should be caused by its eviction", test.policy, slot)
+		}
+		if evictor.RecordColdStart(slot, time.Second) {
+			t.Fatalf("%s: only one cold start of %s should be caused by its eviction", test.policy, slot)
+		}
+
+		for _, token := range tokens {
+			evictor.DeleteEvictToken(token)
+		}
+
+		// the slot queues are gone, and with them what was known about them
+		for _, slot := range []string{"slot0", "slot1", "slot2"} {
+			evictor.DeleteSlot(slot)
+		}
+		if n := slotsUsed(evictor); n != 0 {
+			t.Fatalf("%s: expected no usage left once the slots are deleted, got %d", test.policy, n)
+		}
+	}
+
+	if _, err := NewEvictionPolicy("fifo"); err == nil {
+		t.Fatalf("an unknown policy should be an error")
+	}
+}
+
+func TestEvictorPriority(t *testing.T) {
+	evictor := NewEvictor()
+
+	token0 := evictor.CreateEvictToken("slot0", 1, 100)
+	token1 := evictor.CreateEvictToken("slot1", 1, 100)
+
+	token0.SetPriority(models.PriorityHigh)
+	token1.SetPriority(models.PriorityNormal)
+	token0.SetEvictable(true)
+	token1.SetEvictable(true)
+
+	if len(evictor.PerformPriorityEviction("foo", models.PriorityLow, 1, 100)) > 0 {
+		t.Fatalf("a low priority call should not evict higher priority containers")
+	}
+	if len(evictor.PerformPriorityEviction("foo", models.PriorityNormal, 1, 200)) > 0 {
+		t.Fatalf("a normal priority call should not evict a high priority container")
+	}
+	if len(evictor.PerformPriorityEviction("foo", models.PriorityNormal, 1, 100)) != 1 {
+		t.Fatalf("a normal priority call should evict a normal priority container")
+	}
+	if token0.isEvicted() || !token1.isEvicted() {
+		t.Fatalf("only the normal priority container should be evicted")
+	}
+	if len(evictor.PerformPriorityEviction("foo", models.PriorityHigh, 1, 100)) != 1 {
+		t.Fatalf("a high priority call should evict a high priority container")
+	}
+
+	evictor.DeleteEvictToken(token0)
+	evictor.DeleteEvictToken(token1)
+}
+
+func TestEvictorPaused(t *testing.T) {
+	evictor := NewEvictor()
+
+	token0 := evictor.CreateEvictToken("slot0", 1, 100)
+	token1 := evictor.CreateEvictToken("slot1", 1, 100)
+	token2 := evictor.CreateEvictToken("slot2", 1, 100)
+
+	// token0 is the least recently used, but token2 is paused
+	token0.SetEvictable(true)
+	time.Sleep(time.Millisecond)
+	token1.SetEvictable(true)
+	time.Sleep(time.Millisecond)
+	token2.SetEvictable(true)
+	token2.SetPaused(true)
+
+	if len(evictor.PerformEviction("foo", 1, 100)) != 1 {
+		t.Fatalf("We should be able to evict")
+	}
+	if token0.isEvicted() || token1.isEvicted() || !token2.isEvicted() {
+		t.Fatalf("only the paused container should be evicted")
+	}
+
+	// once resumed, the policy decides again
+	token1.SetPaused(true)
+	token1.SetPaused(false)
+	if len(evictor.PerformEviction("foo", 1, 100)) != 1 {
+		t.Fatalf("We should be able to evict")
+	}
+	if !token0.isEvicted() || token1.isEvicted() {
+		t.Fatalf("the least recently used container should be evicted")
+	}
+
+	evictor.DeleteEvictToken(token0)
+	evictor.DeleteEvictToken(token1)
+	evictor.DeleteEvictToken(token2)
+}
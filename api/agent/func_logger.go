@@ -20,12 +20,13 @@ var (
 // setupLogger returns a ReadWriteCloser that may have:
 // * [always] writes bytes to a size limited buffer, that can be read from using io.Reader
 // * [always] writes bytes per line to stderr as DEBUG
+// * [if any] writes bytes to each of sinks, see logSinks
 //
 // To prevent write failures from failing the call or any other writes,
 // multiWriteCloser ignores errors. Close will flush the line writers
 // appropriately.  The returned io.ReadWriteCloser is not safe for use after
 // calling Close.
-func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call) io.ReadWriteCloser {
+func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call, sinks ...io.WriteCloser) io.ReadWriteCloser {
 	lbuf := bufPool.Get().(*bytes.Buffer)
 	dbuf := logPool.Get().(*bytes.Buffer)
 
@@ -42,7 +43,7 @@ func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call
 	limitw := &nopCloser{newLimitWriter(int(maxSize), dbuf)}
 
 	// order matters, in that closer should be last and limit should be next to last
-	mw := make(multiWriteCloser, 0, 3)
+	mw := make(multiWriteCloser, 0, 3+len(sinks))
 
 	if debug {
 		// accumulate all line writers, wrap in same line writer (to re-use buffer)
@@ -54,10 +55,25 @@ func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call
 		mw = append(mw, linew)
 	}
 
+	mw = append(mw, sinks...)
+
 	mw = append(mw, limitw, &fCloser{close})
 	return &rwc{mw, dbuf}
 }
 
+// logSinks returns the writers that ship the logs of c off the agent, to the
+// syslog url of its app and to the followers of its fn
+func (a *agent) logSinks(c *models.Call) []io.WriteCloser {
+	var sinks []io.WriteCloser
+	if sw := a.syslog.writer(c); sw != nil {
+		sinks = append(sinks, sw)
+	}
+	if fw := a.logHub.writer(c); fw != nil {
+		sinks = append(sinks, fw)
+	}
+	return sinks
+}
+
 // implements io.ReadWriteCloser, fmt.Stringer and Bytes()
 // TODO WriteString and ReadFrom would be handy to implement,
 // ReadFrom is a little involved.
@@ -48,17 +48,20 @@ func (x LogResponseMsg_Container_Request_Line_Source) String() string {
 }
 
 func (LogResponseMsg_Container_Request_Line_Source) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{12, 0, 0, 0, 0}
+	return fileDescriptor_48eceea7e2abc593, []int{15, 0, 0, 0, 0}
 }
 
 // Request to allocate a slot for a call
 type TryCall struct {
-	ModelsCallJson       string            `protobuf:"bytes,1,opt,name=models_call_json,json=modelsCallJson,proto3" json:"models_call_json,omitempty"`
-	SlotHashId           string            `protobuf:"bytes,2,opt,name=slot_hash_id,json=slotHashId,proto3" json:"slot_hash_id,omitempty"`
-	Extensions           map[string]string `protobuf:"bytes,3,rep,name=extensions,proto3" json:"extensions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
-	XXX_unrecognized     []byte            `json:"-"`
-	XXX_sizecache        int32             `json:"-"`
+	ModelsCallJson string            `protobuf:"bytes,1,opt,name=models_call_json,json=modelsCallJson,proto3" json:"models_call_json,omitempty"`
+	SlotHashId     string            `protobuf:"bytes,2,opt,name=slot_hash_id,json=slotHashId,proto3" json:"slot_hash_id,omitempty"`
+	Extensions     map[string]string `protobuf:"bytes,3,rep,name=extensions,proto3" json:"extensions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// The body is streamed rather than buffered, so it is only sent once the
+	// runner sends CallAcknowledged, after which the call cannot be retried.
+	StreamBody           bool     `protobuf:"varint,4,opt,name=stream_body,json=streamBody,proto3" json:"stream_body,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *TryCall) Reset()         { *m = TryCall{} }
@@ -107,6 +110,46 @@ func (m *TryCall) GetExtensions() map[string]string {
 	return nil
 }
 
+func (m *TryCall) GetStreamBody() bool {
+	if m != nil {
+		return m.StreamBody
+	}
+	return false
+}
+
+// Sent by the runner, for a call with a streamed body, once it has committed
+// to run the call and is ready for its body.
+type CallAcknowledged struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CallAcknowledged) Reset()         { *m = CallAcknowledged{} }
+func (m *CallAcknowledged) String() string { return proto.CompactTextString(m) }
+func (*CallAcknowledged) ProtoMessage()    {}
+func (*CallAcknowledged) Descriptor() ([]byte, []int) {
+	return fileDescriptor_48eceea7e2abc593, []int{1}
+}
+
+func (m *CallAcknowledged) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CallAcknowledged.Unmarshal(m, b)
+}
+func (m *CallAcknowledged) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CallAcknowledged.Marshal(b, m, deterministic)
+}
+func (m *CallAcknowledged) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CallAcknowledged.Merge(m, src)
+}
+func (m *CallAcknowledged) XXX_Size() int {
+	return xxx_messageInfo_CallAcknowledged.Size(m)
+}
+func (m *CallAcknowledged) XXX_DiscardUnknown() {
+	xxx_messageInfo_CallAcknowledged.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CallAcknowledged proto.InternalMessageInfo
+
 // Data sent C2S and S2C - as soon as the runner sees the first of these it
 // will start running. If empty content, there must be one of these with eof.
 // The runner will send these for the body of the response, AFTER it has sent
@@ -123,7 +166,7 @@ func (m *DataFrame) Reset()         { *m = DataFrame{} }
 func (m *DataFrame) String() string { return proto.CompactTextString(m) }
 func (*DataFrame) ProtoMessage()    {}
 func (*DataFrame) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{1}
+	return fileDescriptor_48eceea7e2abc593, []int{2}
 }
 
 func (m *DataFrame) XXX_Unmarshal(b []byte) error {
@@ -170,7 +213,7 @@ func (m *HttpHeader) Reset()         { *m = HttpHeader{} }
 func (m *HttpHeader) String() string { return proto.CompactTextString(m) }
 func (*HttpHeader) ProtoMessage()    {}
 func (*HttpHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{2}
+	return fileDescriptor_48eceea7e2abc593, []int{3}
 }
 
 func (m *HttpHeader) XXX_Unmarshal(b []byte) error {
@@ -217,7 +260,7 @@ func (m *HttpRespMeta) Reset()         { *m = HttpRespMeta{} }
 func (m *HttpRespMeta) String() string { return proto.CompactTextString(m) }
 func (*HttpRespMeta) ProtoMessage()    {}
 func (*HttpRespMeta) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{3}
+	return fileDescriptor_48eceea7e2abc593, []int{4}
 }
 
 func (m *HttpRespMeta) XXX_Unmarshal(b []byte) error {
@@ -267,7 +310,7 @@ func (m *CallResultStart) Reset()         { *m = CallResultStart{} }
 func (m *CallResultStart) String() string { return proto.CompactTextString(m) }
 func (*CallResultStart) ProtoMessage()    {}
 func (*CallResultStart) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{4}
+	return fileDescriptor_48eceea7e2abc593, []int{5}
 }
 
 func (m *CallResultStart) XXX_Unmarshal(b []byte) error {
@@ -345,7 +388,7 @@ func (m *CallFinished) Reset()         { *m = CallFinished{} }
 func (m *CallFinished) String() string { return proto.CompactTextString(m) }
 func (*CallFinished) ProtoMessage()    {}
 func (*CallFinished) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{5}
+	return fileDescriptor_48eceea7e2abc593, []int{6}
 }
 
 func (m *CallFinished) XXX_Unmarshal(b []byte) error {
@@ -485,7 +528,7 @@ func (m *ClientMsg) Reset()         { *m = ClientMsg{} }
 func (m *ClientMsg) String() string { return proto.CompactTextString(m) }
 func (*ClientMsg) ProtoMessage()    {}
 func (*ClientMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{6}
+	return fileDescriptor_48eceea7e2abc593, []int{7}
 }
 
 func (m *ClientMsg) XXX_Unmarshal(b []byte) error {
@@ -556,6 +599,7 @@ type RunnerMsg struct {
 	//	*RunnerMsg_ResultStart
 	//	*RunnerMsg_Data
 	//	*RunnerMsg_Finished
+	//	*RunnerMsg_Acknowledged
 	Body                 isRunnerMsg_Body `protobuf_oneof:"body"`
 	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
 	XXX_unrecognized     []byte           `json:"-"`
@@ -566,7 +610,7 @@ func (m *RunnerMsg) Reset()         { *m = RunnerMsg{} }
 func (m *RunnerMsg) String() string { return proto.CompactTextString(m) }
 func (*RunnerMsg) ProtoMessage()    {}
 func (*RunnerMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{7}
+	return fileDescriptor_48eceea7e2abc593, []int{8}
 }
 
 func (m *RunnerMsg) XXX_Unmarshal(b []byte) error {
@@ -603,12 +647,18 @@ type RunnerMsg_Finished struct {
 	Finished *CallFinished `protobuf:"bytes,3,opt,name=finished,proto3,oneof"`
 }
 
+type RunnerMsg_Acknowledged struct {
+	Acknowledged *CallAcknowledged `protobuf:"bytes,4,opt,name=acknowledged,proto3,oneof"`
+}
+
 func (*RunnerMsg_ResultStart) isRunnerMsg_Body() {}
 
 func (*RunnerMsg_Data) isRunnerMsg_Body() {}
 
 func (*RunnerMsg_Finished) isRunnerMsg_Body() {}
 
+func (*RunnerMsg_Acknowledged) isRunnerMsg_Body() {}
+
 func (m *RunnerMsg) GetBody() isRunnerMsg_Body {
 	if m != nil {
 		return m.Body
@@ -637,12 +687,20 @@ func (m *RunnerMsg) GetFinished() *CallFinished {
 	return nil
 }
 
+func (m *RunnerMsg) GetAcknowledged() *CallAcknowledged {
+	if x, ok := m.GetBody().(*RunnerMsg_Acknowledged); ok {
+		return x.Acknowledged
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*RunnerMsg) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
 		(*RunnerMsg_ResultStart)(nil),
 		(*RunnerMsg_Data)(nil),
 		(*RunnerMsg_Finished)(nil),
+		(*RunnerMsg_Acknowledged)(nil),
 	}
 }
 
@@ -677,7 +735,7 @@ func (m *RunnerStatus) Reset()         { *m = RunnerStatus{} }
 func (m *RunnerStatus) String() string { return proto.CompactTextString(m) }
 func (*RunnerStatus) ProtoMessage()    {}
 func (*RunnerStatus) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{8}
+	return fileDescriptor_48eceea7e2abc593, []int{9}
 }
 
 func (m *RunnerStatus) XXX_Unmarshal(b []byte) error {
@@ -845,6 +903,117 @@ func (m *RunnerStatus) GetInitStartTime() int64 {
 	return 0
 }
 
+// Request to start hot containers for a call ahead of its calls
+type PreWarmMsg struct {
+	ModelsCallJson       string            `protobuf:"bytes,1,opt,name=models_call_json,json=modelsCallJson,proto3" json:"models_call_json,omitempty"`
+	SlotHashId           string            `protobuf:"bytes,2,opt,name=slot_hash_id,json=slotHashId,proto3" json:"slot_hash_id,omitempty"`
+	Extensions           map[string]string `protobuf:"bytes,3,rep,name=extensions,proto3" json:"extensions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Containers           int32             `protobuf:"varint,4,opt,name=containers,proto3" json:"containers,omitempty"`
+	KeepWarm             int64             `protobuf:"varint,5,opt,name=keep_warm,json=keepWarm,proto3" json:"keep_warm,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *PreWarmMsg) Reset()         { *m = PreWarmMsg{} }
+func (m *PreWarmMsg) String() string { return proto.CompactTextString(m) }
+func (*PreWarmMsg) ProtoMessage()    {}
+func (*PreWarmMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_48eceea7e2abc593, []int{10}
+}
+
+func (m *PreWarmMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PreWarmMsg.Unmarshal(m, b)
+}
+func (m *PreWarmMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PreWarmMsg.Marshal(b, m, deterministic)
+}
+func (m *PreWarmMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PreWarmMsg.Merge(m, src)
+}
+func (m *PreWarmMsg) XXX_Size() int {
+	return xxx_messageInfo_PreWarmMsg.Size(m)
+}
+func (m *PreWarmMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_PreWarmMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PreWarmMsg proto.InternalMessageInfo
+
+func (m *PreWarmMsg) GetModelsCallJson() string {
+	if m != nil {
+		return m.ModelsCallJson
+	}
+	return ""
+}
+
+func (m *PreWarmMsg) GetSlotHashId() string {
+	if m != nil {
+		return m.SlotHashId
+	}
+	return ""
+}
+
+func (m *PreWarmMsg) GetExtensions() map[string]string {
+	if m != nil {
+		return m.Extensions
+	}
+	return nil
+}
+
+func (m *PreWarmMsg) GetContainers() int32 {
+	if m != nil {
+		return m.Containers
+	}
+	return 0
+}
+
+func (m *PreWarmMsg) GetKeepWarm() int64 {
+	if m != nil {
+		return m.KeepWarm
+	}
+	return 0
+}
+
+type PreWarmStatus struct {
+	Started              int32    `protobuf:"varint,1,opt,name=started,proto3" json:"started,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PreWarmStatus) Reset()         { *m = PreWarmStatus{} }
+func (m *PreWarmStatus) String() string { return proto.CompactTextString(m) }
+func (*PreWarmStatus) ProtoMessage()    {}
+func (*PreWarmStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_48eceea7e2abc593, []int{11}
+}
+
+func (m *PreWarmStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PreWarmStatus.Unmarshal(m, b)
+}
+func (m *PreWarmStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PreWarmStatus.Marshal(b, m, deterministic)
+}
+func (m *PreWarmStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PreWarmStatus.Merge(m, src)
+}
+func (m *PreWarmStatus) XXX_Size() int {
+	return xxx_messageInfo_PreWarmStatus.Size(m)
+}
+func (m *PreWarmStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_PreWarmStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PreWarmStatus proto.InternalMessageInfo
+
+func (m *PreWarmStatus) GetStarted() int32 {
+	if m != nil {
+		return m.Started
+	}
+	return 0
+}
+
 type ConfigMsg struct {
 	Config               map[string]string `protobuf:"bytes,1,rep,name=config,proto3" json:"config,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
@@ -856,7 +1025,7 @@ func (m *ConfigMsg) Reset()         { *m = ConfigMsg{} }
 func (m *ConfigMsg) String() string { return proto.CompactTextString(m) }
 func (*ConfigMsg) ProtoMessage()    {}
 func (*ConfigMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{9}
+	return fileDescriptor_48eceea7e2abc593, []int{12}
 }
 
 func (m *ConfigMsg) XXX_Unmarshal(b []byte) error {
@@ -894,7 +1063,7 @@ func (m *ConfigStatus) Reset()         { *m = ConfigStatus{} }
 func (m *ConfigStatus) String() string { return proto.CompactTextString(m) }
 func (*ConfigStatus) ProtoMessage()    {}
 func (*ConfigStatus) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{10}
+	return fileDescriptor_48eceea7e2abc593, []int{13}
 }
 
 func (m *ConfigStatus) XXX_Unmarshal(b []byte) error {
@@ -931,7 +1100,7 @@ func (m *LogRequestMsg) Reset()         { *m = LogRequestMsg{} }
 func (m *LogRequestMsg) String() string { return proto.CompactTextString(m) }
 func (*LogRequestMsg) ProtoMessage()    {}
 func (*LogRequestMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{11}
+	return fileDescriptor_48eceea7e2abc593, []int{14}
 }
 
 func (m *LogRequestMsg) XXX_Unmarshal(b []byte) error {
@@ -1023,7 +1192,7 @@ func (m *LogRequestMsg_Start) Reset()         { *m = LogRequestMsg_Start{} }
 func (m *LogRequestMsg_Start) String() string { return proto.CompactTextString(m) }
 func (*LogRequestMsg_Start) ProtoMessage()    {}
 func (*LogRequestMsg_Start) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{11, 0}
+	return fileDescriptor_48eceea7e2abc593, []int{14, 0}
 }
 
 func (m *LogRequestMsg_Start) XXX_Unmarshal(b []byte) error {
@@ -1062,7 +1231,7 @@ func (m *LogRequestMsg_Ack) Reset()         { *m = LogRequestMsg_Ack{} }
 func (m *LogRequestMsg_Ack) String() string { return proto.CompactTextString(m) }
 func (*LogRequestMsg_Ack) ProtoMessage()    {}
 func (*LogRequestMsg_Ack) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{11, 1}
+	return fileDescriptor_48eceea7e2abc593, []int{14, 1}
 }
 
 func (m *LogRequestMsg_Ack) XXX_Unmarshal(b []byte) error {
@@ -1094,7 +1263,7 @@ func (m *LogRequestMsg_Ready) Reset()         { *m = LogRequestMsg_Ready{} }
 func (m *LogRequestMsg_Ready) String() string { return proto.CompactTextString(m) }
 func (*LogRequestMsg_Ready) ProtoMessage()    {}
 func (*LogRequestMsg_Ready) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{11, 2}
+	return fileDescriptor_48eceea7e2abc593, []int{14, 2}
 }
 
 func (m *LogRequestMsg_Ready) XXX_Unmarshal(b []byte) error {
@@ -1127,7 +1296,7 @@ func (m *LogResponseMsg) Reset()         { *m = LogResponseMsg{} }
 func (m *LogResponseMsg) String() string { return proto.CompactTextString(m) }
 func (*LogResponseMsg) ProtoMessage()    {}
 func (*LogResponseMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{12}
+	return fileDescriptor_48eceea7e2abc593, []int{15}
 }
 
 func (m *LogResponseMsg) XXX_Unmarshal(b []byte) error {
@@ -1170,7 +1339,7 @@ func (m *LogResponseMsg_Container) Reset()         { *m = LogResponseMsg_Contain
 func (m *LogResponseMsg_Container) String() string { return proto.CompactTextString(m) }
 func (*LogResponseMsg_Container) ProtoMessage()    {}
 func (*LogResponseMsg_Container) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{12, 0}
+	return fileDescriptor_48eceea7e2abc593, []int{15, 0}
 }
 
 func (m *LogResponseMsg_Container) XXX_Unmarshal(b []byte) error {
@@ -1232,7 +1401,7 @@ func (m *LogResponseMsg_Container_Request) Reset()         { *m = LogResponseMsg
 func (m *LogResponseMsg_Container_Request) String() string { return proto.CompactTextString(m) }
 func (*LogResponseMsg_Container_Request) ProtoMessage()    {}
 func (*LogResponseMsg_Container_Request) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{12, 0, 0}
+	return fileDescriptor_48eceea7e2abc593, []int{15, 0, 0}
 }
 
 func (m *LogResponseMsg_Container_Request) XXX_Unmarshal(b []byte) error {
@@ -1281,7 +1450,7 @@ func (m *LogResponseMsg_Container_Request_Line) Reset()         { *m = LogRespon
 func (m *LogResponseMsg_Container_Request_Line) String() string { return proto.CompactTextString(m) }
 func (*LogResponseMsg_Container_Request_Line) ProtoMessage()    {}
 func (*LogResponseMsg_Container_Request_Line) Descriptor() ([]byte, []int) {
-	return fileDescriptor_48eceea7e2abc593, []int{12, 0, 0, 0}
+	return fileDescriptor_48eceea7e2abc593, []int{15, 0, 0, 0}
 }
 
 func (m *LogResponseMsg_Container_Request_Line) XXX_Unmarshal(b []byte) error {
@@ -1327,6 +1496,7 @@ func init() {
 	proto.RegisterEnum("LogResponseMsg_Container_Request_Line_Source", LogResponseMsg_Container_Request_Line_Source_name, LogResponseMsg_Container_Request_Line_Source_value)
 	proto.RegisterType((*TryCall)(nil), "TryCall")
 	proto.RegisterMapType((map[string]string)(nil), "TryCall.ExtensionsEntry")
+	proto.RegisterType((*CallAcknowledged)(nil), "CallAcknowledged")
 	proto.RegisterType((*DataFrame)(nil), "DataFrame")
 	proto.RegisterType((*HttpHeader)(nil), "HttpHeader")
 	proto.RegisterType((*HttpRespMeta)(nil), "HttpRespMeta")
@@ -1336,6 +1506,9 @@ func init() {
 	proto.RegisterType((*RunnerMsg)(nil), "RunnerMsg")
 	proto.RegisterType((*RunnerStatus)(nil), "RunnerStatus")
 	proto.RegisterMapType((map[string]string)(nil), "RunnerStatus.CustomStatusEntry")
+	proto.RegisterType((*PreWarmMsg)(nil), "PreWarmMsg")
+	proto.RegisterMapType((map[string]string)(nil), "PreWarmMsg.ExtensionsEntry")
+	proto.RegisterType((*PreWarmStatus)(nil), "PreWarmStatus")
 	proto.RegisterType((*ConfigMsg)(nil), "ConfigMsg")
 	proto.RegisterMapType((map[string]string)(nil), "ConfigMsg.ConfigEntry")
 	proto.RegisterType((*ConfigStatus)(nil), "ConfigStatus")
@@ -1353,90 +1526,99 @@ func init() {
 func init() { proto.RegisterFile("runner.proto", fileDescriptor_48eceea7e2abc593) }
 
 var fileDescriptor_48eceea7e2abc593 = []byte{
-	// 1326 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0x4f, 0x8f, 0xdb, 0x44,
-	0x14, 0x5f, 0xc7, 0x89, 0x93, 0xbc, 0xfc, 0xdd, 0xa1, 0x5d, 0x8c, 0xa9, 0x68, 0x08, 0xa5, 0x8a,
-	0x60, 0xeb, 0xd2, 0xa5, 0x95, 0x4a, 0x25, 0x40, 0x25, 0xbb, 0x55, 0x8a, 0x5a, 0x5a, 0x4d, 0xb6,
-	0x70, 0x5c, 0xcd, 0xda, 0xb3, 0x89, 0x89, 0x63, 0x87, 0x99, 0xf1, 0xd2, 0x95, 0x38, 0x70, 0x83,
-	0xaf, 0xc0, 0x05, 0x89, 0x23, 0x77, 0x3e, 0x07, 0x47, 0x3e, 0x0a, 0x67, 0x34, 0x7f, 0xe2, 0xfc,
-	0xdb, 0x6e, 0xbb, 0x12, 0x37, 0xbf, 0xdf, 0xef, 0xcd, 0xbc, 0x37, 0xcf, 0xbf, 0xf7, 0x3c, 0x86,
-	0x3a, 0xcb, 0x92, 0x84, 0x32, 0x7f, 0xc6, 0x52, 0x91, 0x7a, 0xef, 0x8e, 0xd2, 0x74, 0x14, 0xd3,
-	0xdb, 0xca, 0x3a, 0xce, 0x4e, 0x6e, 0xd3, 0xe9, 0x4c, 0x9c, 0x19, 0xf2, 0xda, 0x3a, 0xc9, 0x05,
-	0xcb, 0x02, 0xa1, 0xd9, 0xee, 0xdf, 0x16, 0x94, 0x0f, 0xd9, 0x59, 0x9f, 0xc4, 0x31, 0xea, 0x41,
-	0x7b, 0x9a, 0x86, 0x34, 0xe6, 0x47, 0x01, 0x89, 0xe3, 0xa3, 0xef, 0x79, 0x9a, 0xb8, 0x56, 0xc7,
-	0xea, 0x55, 0x71, 0x53, 0xe3, 0xd2, 0xeb, 0x6b, 0x9e, 0x26, 0xa8, 0x03, 0x75, 0x1e, 0xa7, 0xe2,
-	0x68, 0x4c, 0xf8, 0xf8, 0x28, 0x0a, 0xdd, 0x82, 0xf2, 0x02, 0x89, 0x0d, 0x08, 0x1f, 0x3f, 0x0e,
-	0xd1, 0x7d, 0x00, 0xfa, 0x52, 0xd0, 0x84, 0x47, 0x69, 0xc2, 0x5d, 0xbb, 0x63, 0xf7, 0x6a, 0x7b,
-	0xae, 0x6f, 0x22, 0xf9, 0x07, 0x39, 0x75, 0x90, 0x08, 0x76, 0x86, 0x97, 0x7c, 0xbd, 0xcf, 0xa1,
-	0xb5, 0x46, 0xa3, 0x36, 0xd8, 0x13, 0x7a, 0x66, 0x72, 0x91, 0x8f, 0xe8, 0x0a, 0x94, 0x4e, 0x49,
-	0x9c, 0x51, 0x13, 0x59, 0x1b, 0x0f, 0x0a, 0xf7, 0xad, 0xee, 0x1d, 0xa8, 0xee, 0x13, 0x41, 0x1e,
-	0x31, 0x32, 0xa5, 0x08, 0x41, 0x31, 0x24, 0x82, 0xa8, 0x95, 0x75, 0xac, 0x9e, 0xe5, 0x66, 0x34,
-	0x3d, 0x51, 0x0b, 0x2b, 0x58, 0x3e, 0x76, 0xef, 0x02, 0x0c, 0x84, 0x98, 0x0d, 0x28, 0x09, 0x29,
-	0x7b, 0xd3, 0x60, 0xdd, 0x6f, 0xa1, 0x2e, 0x57, 0x61, 0xca, 0x67, 0x4f, 0xa9, 0x20, 0xe8, 0x3a,
-	0xd4, 0xb8, 0x20, 0x22, 0xe3, 0x47, 0x41, 0x1a, 0x52, 0xb5, 0xbe, 0x84, 0x41, 0x43, 0xfd, 0x34,
-	0xa4, 0xe8, 0x43, 0x28, 0x8f, 0x55, 0x08, 0xee, 0x16, 0x54, 0x3d, 0x6a, 0xfe, 0x22, 0x2c, 0x9e,
-	0x73, 0xdd, 0x2f, 0xa0, 0x25, 0x6b, 0x84, 0x29, 0xcf, 0x62, 0x31, 0x14, 0x84, 0x09, 0xf4, 0x01,
-	0x14, 0xc7, 0x42, 0xcc, 0xdc, 0xb0, 0x63, 0xf5, 0x6a, 0x7b, 0x0d, 0x7f, 0x39, 0xee, 0x60, 0x0b,
-	0x2b, 0xf2, 0x2b, 0x07, 0x8a, 0x53, 0x2a, 0x48, 0xf7, 0xf7, 0x22, 0xd4, 0xe5, 0x06, 0x8f, 0xa2,
-	0x24, 0xe2, 0x63, 0x1a, 0x22, 0x17, 0xca, 0x3c, 0x0b, 0x02, 0xca, 0xb9, 0x4a, 0xaa, 0x82, 0xe7,
-	0xa6, 0x64, 0x42, 0x2a, 0x48, 0x14, 0x73, 0x73, 0xb4, 0xb9, 0x89, 0xae, 0x41, 0x95, 0x32, 0x96,
-	0x32, 0x99, 0xb8, 0x6b, 0xab, 0xa3, 0x2c, 0x00, 0xe4, 0x41, 0x45, 0x19, 0x43, 0xc1, 0xdc, 0xa2,
-	0x5a, 0x98, 0xdb, 0x72, 0x65, 0xc0, 0x28, 0x11, 0x34, 0x7c, 0x28, 0xdc, 0x92, 0x22, 0x17, 0x80,
-	0x64, 0xb9, 0x3c, 0x92, 0x62, 0x1d, 0xcd, 0xe6, 0x00, 0xea, 0x40, 0x2d, 0x48, 0xa7, 0xb3, 0x98,
-	0x6a, 0xbe, 0xac, 0xf8, 0x65, 0x08, 0xed, 0xc2, 0x36, 0x0f, 0xc6, 0x34, 0xcc, 0x62, 0xca, 0xf6,
-	0x33, 0x46, 0x44, 0x94, 0x26, 0x6e, 0xa5, 0x63, 0xf5, 0x6c, 0xbc, 0x49, 0x48, 0x6f, 0xfa, 0x92,
-	0x06, 0x99, 0x34, 0x72, 0xef, 0xaa, 0xf6, 0xde, 0x20, 0xf2, 0x33, 0xbf, 0xe0, 0x94, 0xb9, 0xa0,
-	0x2a, 0xb5, 0x00, 0xa4, 0x08, 0xa2, 0x29, 0x19, 0x51, 0xb7, 0xa6, 0x45, 0xa0, 0x0c, 0x74, 0x17,
-	0xae, 0xaa, 0x87, 0xe7, 0x59, 0x1c, 0x7f, 0x47, 0x22, 0x91, 0x47, 0xa9, 0xab, 0x28, 0xe7, 0x93,
-	0xa8, 0x07, 0xad, 0x40, 0xb0, 0xe7, 0x8c, 0xce, 0x72, 0xff, 0x86, 0xf2, 0x5f, 0x87, 0xe5, 0x09,
-	0x02, 0xc1, 0xfa, 0xaa, 0x7e, 0xb9, 0x6f, 0x53, 0x9f, 0x60, 0x83, 0x40, 0x37, 0xa0, 0x11, 0x25,
-	0x91, 0x16, 0xcd, 0x61, 0x34, 0xa5, 0x6e, 0x4b, 0x79, 0xae, 0x82, 0xdd, 0x21, 0x54, 0xfb, 0x71,
-	0x44, 0x13, 0xf1, 0x94, 0x8f, 0xd0, 0x35, 0xb0, 0x05, 0xd3, 0x6a, 0xaf, 0xed, 0x55, 0xe6, 0x0d,
-	0x3a, 0xd8, 0xc2, 0x12, 0x46, 0x1d, 0xd3, 0x3f, 0x05, 0x45, 0x83, 0x9f, 0x77, 0x96, 0x54, 0x9d,
-	0x64, 0xa4, 0xea, 0x8e, 0xd3, 0xf0, 0xac, 0xfb, 0x9b, 0x05, 0x55, 0xac, 0x66, 0x92, 0xdc, 0xf5,
-	0x1e, 0xd4, 0x99, 0xd2, 0xef, 0x91, 0x7a, 0xb9, 0x66, 0xfb, 0xb6, 0xbf, 0x26, 0xec, 0xc1, 0x16,
-	0xae, 0xb1, 0x25, 0x9d, 0xbf, 0x36, 0x1c, 0xfa, 0x18, 0x2a, 0x27, 0x46, 0xd7, 0x4a, 0x96, 0xb2,
-	0x1b, 0x96, 0xc5, 0x3e, 0xd8, 0xc2, 0xb9, 0x43, 0x9e, 0xdb, 0x3f, 0x0e, 0xd4, 0x75, 0x6e, 0x43,
-	0xd5, 0x8d, 0x68, 0x07, 0x1c, 0x12, 0x88, 0xe8, 0x54, 0x77, 0x74, 0x09, 0x1b, 0x4b, 0xe2, 0x27,
-	0x24, 0x8a, 0xcd, 0xde, 0x15, 0x6c, 0x2c, 0xd4, 0x84, 0x42, 0x14, 0x1a, 0xa5, 0x17, 0xa2, 0x70,
-	0xb9, 0x6f, 0x4a, 0x17, 0xf4, 0x8d, 0x73, 0x51, 0xdf, 0x94, 0x2f, 0xea, 0x9b, 0xca, 0x85, 0x7d,
-	0x53, 0x7d, 0x4d, 0xdf, 0xc0, 0x66, 0xdf, 0xec, 0x80, 0x13, 0x10, 0xd9, 0x1f, 0x4a, 0xbe, 0x15,
-	0x6c, 0x2c, 0xf4, 0x11, 0xb4, 0x19, 0xfd, 0x21, 0xa3, 0x5c, 0x70, 0x4c, 0x03, 0x1a, 0x9d, 0xd2,
-	0x50, 0x49, 0xb7, 0x88, 0x37, 0x70, 0xa9, 0xda, 0x39, 0x36, 0x20, 0x49, 0x28, 0xcb, 0xd4, 0x50,
-	0xae, 0xeb, 0x30, 0xea, 0x42, 0x7d, 0x12, 0x66, 0xd3, 0x19, 0x7f, 0x96, 0xec, 0x47, 0x7c, 0xa2,
-	0x04, 0x5b, 0xc4, 0x2b, 0xd8, 0xf9, 0x9d, 0xdc, 0xba, 0x54, 0x27, 0xb7, 0x5f, 0xd5, 0xc9, 0xbb,
-	0xb0, 0x1d, 0xf1, 0x6f, 0xa8, 0xf8, 0x31, 0x65, 0x93, 0xfd, 0x88, 0x93, 0x63, 0x99, 0xeb, 0xb6,
-	0x3a, 0xf8, 0x26, 0x81, 0xfa, 0x50, 0x0f, 0x32, 0x2e, 0xd2, 0xa9, 0x56, 0x87, 0x8b, 0xd4, 0x70,
-	0xbe, 0xee, 0x2f, 0x4b, 0xc6, 0xef, 0x2f, 0x79, 0xe8, 0x6f, 0xd6, 0xca, 0xa2, 0x57, 0x0f, 0x82,
-	0xb7, 0x2e, 0x39, 0x08, 0xae, 0x5c, 0x62, 0x10, 0x5c, 0x7d, 0xe3, 0x41, 0xb0, 0x73, 0xce, 0x20,
-	0xf0, 0xbe, 0x84, 0xed, 0x8d, 0x63, 0x5d, 0xea, 0x5b, 0x7b, 0x0a, 0xd5, 0x7e, 0x9a, 0x9c, 0x44,
-	0x23, 0xd9, 0xf3, 0x3e, 0x38, 0x81, 0x32, 0x5c, 0x4b, 0x15, 0x70, 0xc7, 0xcf, 0x39, 0xf3, 0xa4,
-	0xeb, 0x66, 0xbc, 0xbc, 0xcf, 0xa0, 0xb6, 0x04, 0x5f, 0x2a, 0x6e, 0x13, 0xea, 0x7a, 0xa9, 0x4e,
-	0xbc, 0xfb, 0x67, 0x01, 0x1a, 0x4f, 0xd2, 0x11, 0xd6, 0x32, 0x94, 0xc9, 0xec, 0x42, 0x69, 0x79,
-	0xf2, 0x5c, 0xf1, 0x57, 0x68, 0x7f, 0x3e, 0x7d, 0xb4, 0x13, 0xba, 0x09, 0x36, 0x09, 0x26, 0x66,
-	0xec, 0xa0, 0x35, 0xdf, 0x87, 0xc1, 0x44, 0x8e, 0x43, 0x12, 0x48, 0xcd, 0x96, 0x18, 0x25, 0xe1,
-	0x99, 0x19, 0x3d, 0xeb, 0xbb, 0x62, 0xc9, 0xc9, 0x5d, 0x95, 0x93, 0xf7, 0x13, 0x94, 0xf4, 0x58,
-	0xbb, 0xbf, 0x56, 0x99, 0xce, 0x79, 0xd9, 0xfc, 0xcf, 0x35, 0xf2, 0x4a, 0x60, 0x3f, 0x0c, 0x26,
-	0x5e, 0x19, 0x4a, 0x2a, 0xad, 0x7c, 0x18, 0xfe, 0x6b, 0x43, 0x53, 0x85, 0xe7, 0xb3, 0x34, 0xe1,
-	0x54, 0x16, 0xeb, 0x56, 0x7e, 0x4b, 0x92, 0xd9, 0xbd, 0xe3, 0xaf, 0xd2, 0x32, 0x31, 0x41, 0xa2,
-	0x84, 0x32, 0x3d, 0x83, 0xbd, 0xbf, 0x6c, 0xf5, 0xda, 0x35, 0x26, 0xa5, 0x46, 0x66, 0xb3, 0x38,
-	0x0a, 0x94, 0xf2, 0x1e, 0x87, 0x26, 0xbb, 0x55, 0x10, 0xbd, 0x07, 0x70, 0x92, 0x25, 0x81, 0x71,
-	0x31, 0xd7, 0xc5, 0x05, 0xa2, 0x27, 0x98, 0xd9, 0xf2, 0xb1, 0x1e, 0xbf, 0x6a, 0x82, 0xe5, 0x10,
-	0xba, 0x67, 0x92, 0x2c, 0xaa, 0x24, 0xdf, 0x7f, 0x65, 0x92, 0xbe, 0x29, 0xac, 0x49, 0xf6, 0x97,
-	0x02, 0x94, 0x0d, 0x22, 0x87, 0xa8, 0x99, 0x54, 0x79, 0x9a, 0x0b, 0x00, 0x3d, 0xc8, 0x3f, 0x3e,
-	0x32, 0xc0, 0xcd, 0xd7, 0x06, 0xf0, 0x9f, 0x44, 0x09, 0x35, 0x51, 0xfe, 0xb0, 0xa0, 0x28, 0x4d,
-	0x19, 0x42, 0x44, 0x53, 0xca, 0x05, 0x99, 0xce, 0x54, 0x08, 0x1b, 0x2f, 0x00, 0x74, 0x00, 0x0e,
-	0x4f, 0x33, 0x16, 0xe8, 0xd7, 0xd5, 0xdc, 0xbb, 0xf5, 0x66, 0x41, 0xfc, 0xa1, 0x5a, 0x84, 0xcd,
-	0xe2, 0xfc, 0x56, 0x6b, 0x2f, 0x6e, 0xb5, 0xdd, 0x0e, 0x38, 0xda, 0x0b, 0x01, 0x38, 0xc3, 0xc3,
-	0xfd, 0x67, 0x2f, 0x0e, 0xdb, 0x5b, 0xe6, 0xf9, 0x00, 0xe3, 0xb6, 0xb5, 0xf7, 0x73, 0x01, 0x9a,
-	0x7a, 0xa4, 0x3d, 0x97, 0x37, 0xff, 0x20, 0x8d, 0xd1, 0x0d, 0x70, 0x0e, 0x92, 0x91, 0xbc, 0xc7,
-	0x80, 0x9f, 0x5f, 0x09, 0x3c, 0xf0, 0xf3, 0x0f, 0x79, 0xcf, 0xfa, 0xc4, 0x42, 0x77, 0xc1, 0x99,
-	0x7f, 0x37, 0x7d, 0xfd, 0x2f, 0xe1, 0xcf, 0xff, 0x25, 0xfc, 0x03, 0xf9, 0xa3, 0xe1, 0x35, 0x56,
-	0x66, 0x65, 0xd7, 0xfe, 0xb5, 0x60, 0xa1, 0x5d, 0x68, 0x69, 0xe9, 0x66, 0x8c, 0x6a, 0x56, 0x06,
-	0x99, 0x4f, 0x04, 0xaf, 0xe1, 0x2f, 0x77, 0x30, 0xba, 0x03, 0x30, 0x14, 0x8c, 0x92, 0xe9, 0x93,
-	0x74, 0xc4, 0x51, 0x73, 0xb5, 0x41, 0xbc, 0xd6, 0x5a, 0x9d, 0x54, 0x5a, 0x77, 0xa0, 0xac, 0x17,
-	0xef, 0xa1, 0xb7, 0x37, 0xf2, 0x1a, 0xaa, 0x7f, 0x9c, 0xb5, 0xc4, 0x8e, 0x1d, 0xc5, 0x7f, 0xfa,
-	0x5f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x64, 0x4e, 0xf9, 0x4b, 0x3e, 0x0d, 0x00, 0x00,
+	// 1469 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0xcd, 0x73, 0x1b, 0xc5,
+	0x12, 0xb7, 0xb4, 0xfa, 0x6c, 0x7d, 0x58, 0x9e, 0x97, 0xf8, 0xed, 0x53, 0x52, 0x2f, 0x7a, 0x7a,
+	0x21, 0x08, 0x70, 0x36, 0xc4, 0x24, 0x45, 0x08, 0x05, 0x94, 0x23, 0x3b, 0xa5, 0x50, 0x09, 0x71,
+	0x8d, 0x1c, 0x72, 0x54, 0x8d, 0x77, 0xc7, 0xf2, 0xa2, 0xd5, 0x8e, 0x98, 0x99, 0x75, 0xe2, 0x2a,
+	0xee, 0x70, 0xe5, 0x1f, 0xa0, 0x8a, 0x63, 0xee, 0xfc, 0x25, 0x1c, 0xf8, 0x37, 0xb8, 0x71, 0xa6,
+	0xe6, 0x43, 0xab, 0x2f, 0xc7, 0x89, 0x8b, 0xdc, 0xb6, 0x7f, 0xdd, 0x33, 0xdd, 0x3d, 0xdb, 0xbf,
+	0xee, 0x19, 0xa8, 0xf2, 0x24, 0x8e, 0x29, 0xf7, 0x26, 0x9c, 0x49, 0xd6, 0xbc, 0x32, 0x64, 0x6c,
+	0x18, 0xd1, 0x5b, 0x5a, 0x3a, 0x4c, 0x8e, 0x6e, 0xd1, 0xf1, 0x44, 0x9e, 0x5a, 0xe5, 0xd5, 0x65,
+	0xa5, 0x90, 0x3c, 0xf1, 0xa5, 0xd1, 0xb6, 0xff, 0xcc, 0x40, 0xf1, 0x80, 0x9f, 0x76, 0x49, 0x14,
+	0xa1, 0x0e, 0x34, 0xc6, 0x2c, 0xa0, 0x91, 0x18, 0xf8, 0x24, 0x8a, 0x06, 0xdf, 0x09, 0x16, 0xbb,
+	0x99, 0x56, 0xa6, 0x53, 0xc6, 0x75, 0x83, 0x2b, 0xab, 0xaf, 0x05, 0x8b, 0x51, 0x0b, 0xaa, 0x22,
+	0x62, 0x72, 0x70, 0x4c, 0xc4, 0xf1, 0x20, 0x0c, 0xdc, 0xac, 0xb6, 0x02, 0x85, 0xf5, 0x88, 0x38,
+	0x7e, 0x14, 0xa0, 0x7b, 0x00, 0xf4, 0xa5, 0xa4, 0xb1, 0x08, 0x59, 0x2c, 0x5c, 0xa7, 0xe5, 0x74,
+	0x2a, 0xdb, 0xae, 0x67, 0x3d, 0x79, 0x7b, 0xa9, 0x6a, 0x2f, 0x96, 0xfc, 0x14, 0xcf, 0xd9, 0xa2,
+	0x6b, 0x50, 0x11, 0x92, 0x53, 0x32, 0x1e, 0x1c, 0xb2, 0xe0, 0xd4, 0xcd, 0xb5, 0x32, 0x9d, 0x12,
+	0x06, 0x03, 0x3d, 0x60, 0xc1, 0x69, 0xf3, 0x0b, 0x58, 0x5f, 0x5a, 0x8f, 0x1a, 0xe0, 0x8c, 0xe8,
+	0xa9, 0x0d, 0x56, 0x7d, 0xa2, 0x4b, 0x90, 0x3f, 0x21, 0x51, 0x42, 0x6d, 0x68, 0x46, 0xb8, 0x9f,
+	0xbd, 0x97, 0x69, 0x23, 0x68, 0xa8, 0x18, 0x76, 0xfc, 0x51, 0xcc, 0x5e, 0x44, 0x34, 0x18, 0xd2,
+	0xa0, 0x7d, 0x1b, 0xca, 0xbb, 0x44, 0x92, 0x87, 0x9c, 0x8c, 0x29, 0x42, 0x90, 0x0b, 0x88, 0x24,
+	0x7a, 0xb7, 0x2a, 0xd6, 0xdf, 0xca, 0x01, 0x65, 0x47, 0x7a, 0xb3, 0x12, 0x56, 0x9f, 0xed, 0x3b,
+	0x00, 0x3d, 0x29, 0x27, 0x3d, 0x4a, 0x02, 0xca, 0xdf, 0x36, 0x80, 0xf6, 0xb7, 0x50, 0x55, 0xab,
+	0x30, 0x15, 0x93, 0x27, 0x54, 0x12, 0x93, 0x2c, 0x91, 0x89, 0x18, 0xf8, 0x2c, 0xa0, 0x7a, 0x7d,
+	0x1e, 0x83, 0x81, 0xba, 0x2c, 0xa0, 0xe8, 0x3d, 0x28, 0x1e, 0x6b, 0x17, 0xc2, 0xcd, 0xea, 0x43,
+	0xac, 0x78, 0x33, 0xb7, 0x78, 0xaa, 0x6b, 0x7f, 0x09, 0xeb, 0x2a, 0x29, 0x4c, 0x45, 0x12, 0xc9,
+	0xbe, 0x24, 0x5c, 0xa2, 0xff, 0x43, 0xee, 0x58, 0xca, 0x89, 0x1b, 0xb4, 0x32, 0x9d, 0xca, 0x76,
+	0xcd, 0x9b, 0xf7, 0xdb, 0x5b, 0xc3, 0x5a, 0xf9, 0xa0, 0x00, 0xb9, 0x31, 0x95, 0xa4, 0xfd, 0x4b,
+	0x0e, 0xaa, 0x6a, 0x83, 0x87, 0x61, 0x1c, 0x8a, 0x63, 0x1a, 0x20, 0x17, 0x8a, 0x22, 0xf1, 0x7d,
+	0x2a, 0x84, 0x0e, 0xaa, 0x84, 0xa7, 0xa2, 0xd2, 0x04, 0x54, 0x92, 0x30, 0x12, 0x36, 0xb5, 0xa9,
+	0x88, 0xae, 0x42, 0x99, 0x72, 0xce, 0xb8, 0x0a, 0xdc, 0x75, 0x74, 0x2a, 0x33, 0x00, 0x35, 0xa1,
+	0xa4, 0x85, 0xbe, 0xe4, 0xfa, 0xa7, 0x96, 0x71, 0x2a, 0xab, 0x95, 0x3e, 0xa7, 0x44, 0xd2, 0x60,
+	0x47, 0xba, 0x79, 0xad, 0x9c, 0x01, 0x4a, 0x2b, 0x54, 0x4a, 0x5a, 0x5b, 0x30, 0xda, 0x14, 0x40,
+	0x2d, 0xa8, 0xf8, 0x6c, 0x3c, 0x89, 0xa8, 0xd1, 0x17, 0xb5, 0x7e, 0x1e, 0x42, 0x5b, 0xb0, 0x21,
+	0xfc, 0x63, 0x1a, 0x24, 0x11, 0xe5, 0xbb, 0x09, 0x27, 0x32, 0x64, 0xb1, 0x5b, 0x6a, 0x65, 0x3a,
+	0x0e, 0x5e, 0x55, 0x28, 0x6b, 0xfa, 0x92, 0xfa, 0x89, 0x12, 0x52, 0xeb, 0xb2, 0xb1, 0x5e, 0x51,
+	0xa4, 0x39, 0x3f, 0x13, 0x94, 0xbb, 0xa0, 0x4f, 0x6a, 0x06, 0xa8, 0x22, 0x08, 0xc7, 0x64, 0x48,
+	0xdd, 0x8a, 0x29, 0x02, 0x2d, 0xa0, 0x3b, 0x70, 0x59, 0x7f, 0xec, 0x27, 0x51, 0xf4, 0x9c, 0x84,
+	0x32, 0xf5, 0x52, 0xd5, 0x5e, 0xce, 0x56, 0xa2, 0x0e, 0xac, 0xfb, 0x92, 0xef, 0x73, 0x3a, 0x49,
+	0xed, 0x6b, 0xda, 0x7e, 0x19, 0x56, 0x19, 0xf8, 0x92, 0x77, 0xf5, 0xf9, 0xa5, 0xb6, 0x75, 0x93,
+	0xc1, 0x8a, 0x02, 0x5d, 0x87, 0x5a, 0x18, 0x87, 0xa6, 0x68, 0x0e, 0xc2, 0x31, 0x75, 0xd7, 0xb5,
+	0xe5, 0x22, 0xd8, 0xee, 0x43, 0xb9, 0x1b, 0x85, 0x34, 0x96, 0x4f, 0xc4, 0x10, 0x5d, 0x05, 0x47,
+	0x72, 0x53, 0xed, 0x95, 0xed, 0xd2, 0x94, 0xd5, 0xbd, 0x35, 0xac, 0x60, 0xd4, 0xb2, 0xfc, 0xc9,
+	0x6a, 0x35, 0x78, 0x29, 0xb3, 0x54, 0xd5, 0x29, 0x8d, 0xaa, 0x3a, 0xc5, 0xed, 0xf6, 0xef, 0x19,
+	0x28, 0x63, 0xdd, 0xc8, 0xd4, 0xae, 0x77, 0xa1, 0xca, 0x75, 0xfd, 0x0e, 0xf4, 0xcf, 0xb5, 0xdb,
+	0x37, 0xbc, 0xa5, 0xc2, 0xee, 0xad, 0xe1, 0x0a, 0x9f, 0x89, 0x6f, 0x76, 0x87, 0x3e, 0x82, 0xd2,
+	0x91, 0xad, 0x6b, 0xd7, 0xb1, 0x6c, 0x98, 0x2f, 0xf6, 0xde, 0x1a, 0x4e, 0x0d, 0xd0, 0xa7, 0x50,
+	0x25, 0x73, 0xad, 0x41, 0x97, 0x6a, 0x65, 0x7b, 0xc3, 0x5b, 0xee, 0x19, 0xbd, 0x35, 0xbc, 0x60,
+	0x98, 0x26, 0xf5, 0x47, 0x01, 0xaa, 0x26, 0xa9, 0xbe, 0xa6, 0x31, 0xda, 0x84, 0x02, 0xf1, 0x65,
+	0x78, 0x62, 0x5a, 0x41, 0x1e, 0x5b, 0x49, 0xe1, 0x47, 0x24, 0x8c, 0x6c, 0x50, 0x25, 0x6c, 0x25,
+	0x54, 0x87, 0x6c, 0x18, 0x58, 0x8a, 0x64, 0xc3, 0x60, 0x9e, 0x70, 0xf9, 0x73, 0x08, 0x57, 0x38,
+	0x8f, 0x70, 0xc5, 0xf3, 0x08, 0x57, 0x3a, 0x97, 0x70, 0xe5, 0x37, 0x10, 0x0e, 0x56, 0x09, 0xb7,
+	0x09, 0x05, 0x9f, 0x28, 0x62, 0xe9, 0xba, 0x2f, 0x61, 0x2b, 0xa1, 0x0f, 0xa1, 0xc1, 0xe9, 0xf7,
+	0x09, 0x15, 0x52, 0x60, 0xea, 0xd3, 0xf0, 0x84, 0x06, 0xba, 0xe6, 0x73, 0x78, 0x05, 0x57, 0xe5,
+	0x3e, 0xc5, 0x7a, 0x24, 0x0e, 0xd4, 0x31, 0xd5, 0xb4, 0xe9, 0x32, 0x8c, 0xda, 0x50, 0x1d, 0x05,
+	0xc9, 0x78, 0x22, 0x9e, 0xc6, 0xbb, 0xa1, 0x18, 0xe9, 0x4a, 0xcf, 0xe1, 0x05, 0xec, 0xec, 0x16,
+	0xb0, 0x7e, 0xa1, 0x16, 0xd0, 0x78, 0x5d, 0x0b, 0xd8, 0x82, 0x8d, 0x50, 0x7c, 0x43, 0xe5, 0x0b,
+	0xc6, 0x47, 0xbb, 0xa1, 0x20, 0x87, 0x2a, 0xd6, 0x0d, 0x9d, 0xf8, 0xaa, 0x02, 0x75, 0xa1, 0xea,
+	0x27, 0x42, 0xb2, 0xb1, 0xa9, 0x0e, 0x17, 0xe9, 0xae, 0x7e, 0xcd, 0x9b, 0x2f, 0x19, 0xaf, 0x3b,
+	0x67, 0x61, 0x26, 0xe4, 0xc2, 0xa2, 0xd7, 0x77, 0x90, 0x7f, 0x5d, 0xb0, 0x83, 0x5c, 0xba, 0x40,
+	0x07, 0xb9, 0xfc, 0xd6, 0x1d, 0x64, 0xf3, 0x8c, 0x0e, 0xd2, 0xfc, 0x0a, 0x36, 0x56, 0xd2, 0xba,
+	0xd0, 0xe0, 0xfe, 0x39, 0x0b, 0xb0, 0xcf, 0xe9, 0x73, 0xc2, 0xc7, 0xaa, 0x5d, 0xbc, 0xcb, 0xdb,
+	0xca, 0xe7, 0x67, 0xdc, 0x56, 0xae, 0x78, 0x33, 0x67, 0xe7, 0x5e, 0x58, 0xfe, 0x0b, 0xe0, 0xb3,
+	0x58, 0x92, 0x30, 0x56, 0x53, 0x3a, 0x67, 0x46, 0xf8, 0x0c, 0x41, 0x57, 0xa0, 0x3c, 0xa2, 0x74,
+	0x32, 0x78, 0x41, 0xf8, 0x58, 0x33, 0xd8, 0xc1, 0x25, 0x05, 0xa8, 0xbd, 0xff, 0xe9, 0x65, 0xe6,
+	0x03, 0xa8, 0xd9, 0x28, 0x6d, 0x65, 0xa8, 0xb9, 0x6d, 0x98, 0x6a, 0x2f, 0x13, 0x53, 0xb1, 0x7d,
+	0x02, 0xe5, 0x2e, 0x8b, 0x8f, 0xc2, 0xa1, 0x3a, 0x3c, 0x0f, 0x0a, 0xbe, 0x16, 0xdc, 0x8c, 0x4e,
+	0x76, 0xd3, 0x4b, 0x75, 0xf6, 0xcb, 0xe4, 0x69, 0xad, 0x9a, 0x9f, 0x41, 0x65, 0x0e, 0xbe, 0x50,
+	0x88, 0x75, 0xa8, 0x9a, 0xa5, 0x26, 0xc2, 0xf6, 0xab, 0x2c, 0xd4, 0x1e, 0xb3, 0x21, 0x36, 0x2c,
+	0x56, 0xc1, 0x6c, 0x41, 0x7e, 0xbe, 0xe3, 0x5f, 0xf2, 0x16, 0xd4, 0xde, 0xb4, 0xeb, 0x1b, 0x23,
+	0x74, 0x03, 0x1c, 0xe2, 0x8f, 0x6c, 0xbb, 0x47, 0x4b, 0xb6, 0x3b, 0xfe, 0x48, 0x8d, 0x21, 0xe2,
+	0x2b, 0xca, 0xe7, 0x39, 0x25, 0xc1, 0xa9, 0xeb, 0x9c, 0xb9, 0x2b, 0x56, 0x3a, 0xb5, 0xab, 0x36,
+	0x6a, 0xfe, 0x00, 0x79, 0x33, 0x4e, 0xee, 0x2d, 0x9d, 0x4c, 0xeb, 0xac, 0x68, 0xde, 0xf1, 0x19,
+	0x35, 0xf3, 0xe0, 0xec, 0xf8, 0xa3, 0x66, 0x11, 0xf2, 0x3a, 0xac, 0x74, 0x96, 0xfc, 0xe5, 0x40,
+	0x5d, 0xbb, 0x17, 0x13, 0x16, 0x0b, 0xaa, 0x0e, 0xeb, 0x66, 0x7a, 0x3b, 0x55, 0xd1, 0xfd, 0xc7,
+	0x5b, 0x54, 0x7b, 0xdd, 0x69, 0xdd, 0x99, 0xd9, 0xd7, 0xfc, 0xcd, 0x81, 0x72, 0x8a, 0x29, 0xa6,
+	0x92, 0xc9, 0x24, 0x0a, 0x7d, 0x4d, 0xdc, 0x47, 0x81, 0x8d, 0x6e, 0x11, 0x54, 0x05, 0x7d, 0x94,
+	0xc4, 0xbe, 0x35, 0xb1, 0x6c, 0x99, 0x21, 0x66, 0x00, 0xd8, 0x2d, 0x1f, 0x99, 0xe9, 0x55, 0xc6,
+	0xf3, 0x10, 0xba, 0x6b, 0x83, 0xcc, 0xe9, 0x20, 0xff, 0xf7, 0xda, 0x20, 0x3d, 0x7b, 0xb0, 0x36,
+	0xd8, 0x1f, 0xb3, 0x50, 0xb4, 0x88, 0x9a, 0x41, 0xb6, 0xd1, 0xa7, 0x61, 0xce, 0x00, 0x74, 0x3f,
+	0x1d, 0xfa, 0xca, 0xc1, 0x8d, 0x37, 0x3a, 0xf0, 0x1e, 0x87, 0x31, 0xb5, 0x5e, 0x7e, 0xcd, 0x40,
+	0x4e, 0x89, 0xca, 0x85, 0x0c, 0xc7, 0x54, 0x48, 0x32, 0x9e, 0x68, 0x17, 0x0e, 0x9e, 0x01, 0x68,
+	0x0f, 0x0a, 0x82, 0x25, 0xdc, 0x37, 0xbf, 0xab, 0xbe, 0x7d, 0xf3, 0xed, 0x9c, 0x78, 0x7d, 0xbd,
+	0x08, 0xdb, 0xc5, 0xe9, 0x6b, 0xc2, 0x99, 0xbd, 0x26, 0xda, 0x2d, 0x28, 0x18, 0x2b, 0x04, 0x50,
+	0xe8, 0x1f, 0xec, 0x3e, 0x7d, 0x76, 0xd0, 0x58, 0xb3, 0xdf, 0x7b, 0x18, 0x37, 0x32, 0xdb, 0xaf,
+	0xb2, 0x50, 0x37, 0x13, 0x61, 0x5f, 0x3d, 0xd3, 0x7c, 0x16, 0xa1, 0xeb, 0x50, 0xd8, 0x8b, 0x87,
+	0xea, 0xfe, 0x08, 0x5e, 0x7a, 0x15, 0x6b, 0x82, 0x97, 0x5e, 0xa0, 0x3a, 0x99, 0x8f, 0x33, 0xe8,
+	0x0e, 0x14, 0xa6, 0xd7, 0x0e, 0xcf, 0x3c, 0xfc, 0xbc, 0xe9, 0xc3, 0xcf, 0xdb, 0x53, 0xaf, 0xc2,
+	0x66, 0x6d, 0x61, 0xd4, 0xb4, 0x9d, 0x9f, 0xb2, 0x19, 0xb4, 0x05, 0xeb, 0xa6, 0x74, 0x13, 0x4e,
+	0x8d, 0x56, 0x39, 0x99, 0x76, 0x84, 0x66, 0xcd, 0x9b, 0x67, 0x30, 0xba, 0x0d, 0xd0, 0xd7, 0xcf,
+	0xb1, 0xc7, 0x6c, 0x28, 0x50, 0x7d, 0x91, 0x20, 0xcd, 0xf5, 0xa5, 0x73, 0xd2, 0x61, 0xdd, 0x86,
+	0xa2, 0x59, 0xbc, 0x8d, 0xfe, 0xbd, 0x12, 0x57, 0x5f, 0x3f, 0x48, 0x97, 0x02, 0x43, 0xef, 0x43,
+	0xc9, 0x30, 0x9f, 0x49, 0x54, 0x99, 0xeb, 0xc5, 0xcd, 0xba, 0xb7, 0xd0, 0xf2, 0x0e, 0x0b, 0x7a,
+	0xa3, 0x4f, 0xfe, 0x1e, 0x00, 0x30, 0x6a, 0x74, 0x6d, 0x14, 0x0f, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1453,8 +1635,6 @@ const _ = grpc.SupportPackageIsVersion4
 type RunnerProtocolClient interface {
 	Engage(ctx context.Context, opts ...grpc.CallOption) (RunnerProtocol_EngageClient, error)
 	// Rather than rely on Prometheus for this, expose status that's specific to the runner lifecycle through this.
-	//
-	// Deprecated: Do not use.
 	Status(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RunnerStatus, error)
 	// Configure the remote runner by passing config data.
 	ConfigureRunner(ctx context.Context, in *ConfigMsg, opts ...grpc.CallOption) (*ConfigStatus, error)
@@ -1465,6 +1645,9 @@ type RunnerProtocolClient interface {
 	// Output from the container is sent back via RunnerStatus.Details
 	// as before.
 	Status2(ctx context.Context, in *_struct.Struct, opts ...grpc.CallOption) (*RunnerStatus, error)
+	// Start idle hot containers for a function ahead of its calls, as many
+	// as the runner has resources for.
+	StartHot(ctx context.Context, in *PreWarmMsg, opts ...grpc.CallOption) (*PreWarmStatus, error)
 }
 
 type runnerProtocolClient struct {
@@ -1565,12 +1748,19 @@ func (c *runnerProtocolClient) Status2(ctx context.Context, in *_struct.Struct,
 	return out, nil
 }
 
+func (c *runnerProtocolClient) StartHot(ctx context.Context, in *PreWarmMsg, opts ...grpc.CallOption) (*PreWarmStatus, error) {
+	out := new(PreWarmStatus)
+	err := c.cc.Invoke(ctx, "/RunnerProtocol/StartHot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RunnerProtocolServer is the server API for RunnerProtocol service.
 type RunnerProtocolServer interface {
 	Engage(RunnerProtocol_EngageServer) error
 	// Rather than rely on Prometheus for this, expose status that's specific to the runner lifecycle through this.
-	//
-	// Deprecated: Do not use.
 	Status(context.Context, *empty.Empty) (*RunnerStatus, error)
 	// Configure the remote runner by passing config data.
 	ConfigureRunner(context.Context, *ConfigMsg) (*ConfigStatus, error)
@@ -1581,6 +1771,9 @@ type RunnerProtocolServer interface {
 	// Output from the container is sent back via RunnerStatus.Details
 	// as before.
 	Status2(context.Context, *_struct.Struct) (*RunnerStatus, error)
+	// Start idle hot containers for a function ahead of its calls, as many
+	// as the runner has resources for.
+	StartHot(context.Context, *PreWarmMsg) (*PreWarmStatus, error)
 }
 
 // UnimplementedRunnerProtocolServer can be embedded to have forward compatible implementations.
@@ -1602,6 +1795,9 @@ func (*UnimplementedRunnerProtocolServer) StreamLogs(srv RunnerProtocol_StreamLo
 func (*UnimplementedRunnerProtocolServer) Status2(ctx context.Context, req *_struct.Struct) (*RunnerStatus, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Status2 not implemented")
 }
+func (*UnimplementedRunnerProtocolServer) StartHot(ctx context.Context, req *PreWarmMsg) (*PreWarmStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartHot not implemented")
+}
 
 func RegisterRunnerProtocolServer(s *grpc.Server, srv RunnerProtocolServer) {
 	s.RegisterService(&_RunnerProtocol_serviceDesc, srv)
@@ -1713,6 +1909,24 @@ func _RunnerProtocol_Status2_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RunnerProtocol_StartHot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PreWarmMsg)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RunnerProtocolServer).StartHot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/RunnerProtocol/StartHot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RunnerProtocolServer).StartHot(ctx, req.(*PreWarmMsg))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _RunnerProtocol_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "RunnerProtocol",
 	HandlerType: (*RunnerProtocolServer)(nil),
@@ -1729,6 +1943,10 @@ var _RunnerProtocol_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Status2",
 			Handler:    _RunnerProtocol_Status2_Handler,
 		},
+		{
+			MethodName: "StartHot",
+			Handler:    _RunnerProtocol_StartHot_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -11,6 +11,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -118,6 +119,23 @@ func (cl *client) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error
 	return &fn, nil
 }
 
+func (cl *client) GetStateMachine(ctx context.Context, appID, name string, version int64) (*models.StoredStateMachine, error) {
+	ctx, span := trace.StartSpan(ctx, "hybrid_client_get_state_machine")
+	defer span.End()
+
+	query := map[string]string{"app_id": appID}
+	if version != 0 {
+		query["version"] = strconv.FormatInt(version, 10)
+	}
+
+	var sm models.StoredStateMachine
+	err := cl.do(ctx, nil, &sm, "GET", query, "statemachines", url.PathEscape(name))
+	if err != nil {
+		return nil, err
+	}
+	return &sm, nil
+}
+
 type httpErr struct {
 	code int
 	error
@@ -40,6 +40,12 @@ func (cl *nopDataStore) GetAppByID(ctx context.Context, appID string) (*models.A
 	return nil, errors.New("should not call GetAppByID on a NOP data store")
 }
 
+func (cl *nopDataStore) GetStateMachine(ctx context.Context, appID, name string, version int64) (*models.StoredStateMachine, error) {
+	ctx, span := trace.StartSpan(ctx, "nop_datastore_get_state_machine")
+	defer span.End()
+	return nil, errors.New("should not call GetStateMachine on a NOP data store")
+}
+
 func (cl *nopDataStore) Close() error {
 	return nil
 }
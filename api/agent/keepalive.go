@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// KeepAliveFixed keeps idle containers alive for the idle timeout of their function
+	KeepAliveFixed = "fixed"
+	// KeepAliveHybridHistogram keeps idle containers alive and pre-warms them
+	// from a histogram of the inter-arrival times of the calls to each function
+	KeepAliveHybridHistogram = "hybrid-histogram"
+)
+
+// KeepAlivePolicy decides, for each slot queue, how long its idle hot
+// containers stay alive and when a container should be started ahead of its
+// next call.
+type KeepAlivePolicy interface {
+	// Arrived records a call for the slot queue with key arriving at now
+	Arrived(key string, now time.Time)
+
+	// Window returns the keep-alive window of the slot queue with key, whose
+	// function has idleTimeout configured.
+	Window(key string, idleTimeout time.Duration) KeepAliveWindow
+
+	// Forget drops what was recorded for the slot queue with key, once it is gone
+	Forget(key string)
+}
+
+// KeepAliveWindow tells the agent how to keep the containers of a slot queue.
+//
+// With no PreWarm, a container stays alive for KeepAlive once idle. With a
+// PreWarm, containers are unloaded once idle, and if the slot queue has no
+// container PreWarm after its last call, one is started which stays alive for
+// KeepAlive waiting for the next call.
+type KeepAliveWindow struct {
+	PreWarm   time.Duration
+	KeepAlive time.Duration
+}
+
+// NewKeepAlivePolicy returns the keep-alive policy with name, one of
+// KeepAliveFixed and KeepAliveHybridHistogram. An empty name is the fixed policy.
+func NewKeepAlivePolicy(name string) (KeepAlivePolicy, error) {
+	switch name {
+	case "", KeepAliveFixed:
+		return NewFixedKeepAlive(), nil
+	case KeepAliveHybridHistogram:
+		return NewHybridHistogramKeepAlive(), nil
+	}
+	return nil, fmt.Errorf("unknown keep-alive policy %q", name)
+}
+
+type fixedKeepAlive struct{}
+
+// NewFixedKeepAlive returns a policy that keeps idle containers alive for the
+// idle timeout of their function and never pre-warms.
+func NewFixedKeepAlive() KeepAlivePolicy {
+	return fixedKeepAlive{}
+}
+
+func (fixedKeepAlive) Arrived(key string, now time.Time) {}
+
+func (fixedKeepAlive) Forget(key string) {}
+
+func (fixedKeepAlive) Window(key string, idleTimeout time.Duration) KeepAliveWindow {
+	return KeepAliveWindow{KeepAlive: idleTimeout}
+}
+
+const (
+	// histogramBin is the width of each bin of inter-arrival times
+	histogramBin = time.Minute
+	// histogramBins is how many bins a histogram has, inter-arrival times
+	// past the last bin are out of bounds
+	histogramBins = 240
+	// histogramMinSamples is how many inter-arrival times a histogram needs
+	// before it is used
+	histogramMinSamples = 10
+	// histogramMaxOutOfBounds is the share of inter-arrival times that may be
+	// out of bounds before a histogram is no longer used
+	histogramMaxOutOfBounds = 0.5
+	// histogramMinCV is the coefficient of variation of the bin counts below
+	// which a histogram shows no pattern worth following
+	histogramMinCV = 2
+	// histogramHead and histogramTail are the percentiles of inter-arrival
+	// times that bound the window
+	histogramHead = 0.05
+	histogramTail = 0.99
+	// histogramMargin widens the window on both sides, in percent
+	histogramMargin = 10
+)
+
+// histogram counts the inter-arrival times of the calls of a slot queue
+type histogram struct {
+	last        time.Time
+	bins        [histogramBins]uint64
+	samples     uint64
+	outOfBounds uint64
+}
+
+func (h *histogram) arrived(now time.Time) {
+	if !h.last.IsZero() && now.After(h.last) {
+		bin := int(now.Sub(h.last) / histogramBin)
+		if bin < histogramBins {
+			h.bins[bin]++
+		} else {
+			h.outOfBounds++
+		}
+		h.samples++
+	}
+	if now.After(h.last) {
+		h.last = now
+	}
+}
+
+// representative returns true if the histogram has seen enough calls, most
+// of them in bounds, with a clear enough pattern to predict the next
+func (h *histogram) representative() bool {
+	inBounds := h.samples - h.outOfBounds
+	if h.samples < histogramMinSamples || float64(h.outOfBounds) > histogramMaxOutOfBounds*float64(h.samples) {
+		return false
+	}
+
+	mean := float64(inBounds) / histogramBins
+	var variance float64
+	for _, n := range h.bins {
+		variance += (float64(n) - mean) * (float64(n) - mean)
+	}
+	variance /= histogramBins
+	return math.Sqrt(variance)/mean >= histogramMinCV
+}
+
+// percentile returns the bin that the in bounds inter-arrival times up to p fall in
+func (h *histogram) percentile(p float64) int {
+	rank := uint64(math.Ceil(p * float64(h.samples-h.outOfBounds)))
+	var seen uint64
+	for i, n := range h.bins {
+		seen += n
+		if seen >= rank && seen > 0 {
+			return i
+		}
+	}
+	return histogramBins - 1
+}
+
+type hybridHistogramKeepAlive struct {
+	lock       sync.Mutex
+	histograms map[string]*histogram
+}
+
+// NewHybridHistogramKeepAlive returns a policy that follows the hybrid
+// histogram policy of Shahrad et al., "Serverless in the Wild" (ATC '20). It
+// keeps a histogram of the inter-arrival times of the calls of each slot
+// queue, and once the histogram is representative, pre-warms a container
+// around the 5th percentile of them and keeps it alive until about the 99th.
+// Slot queues without a representative histogram are kept as the fixed policy does.
+func NewHybridHistogramKeepAlive() KeepAlivePolicy {
+	return &hybridHistogramKeepAlive{
+		histograms: make(map[string]*histogram),
+	}
+}
+
+func (p *hybridHistogramKeepAlive) Arrived(key string, now time.Time) {
+	p.lock.Lock()
+	h, ok := p.histograms[key]
+	if !ok {
+		h = &histogram{}
+		p.histograms[key] = h
+	}
+	h.arrived(now)
+	p.lock.Unlock()
+}
+
+func (p *hybridHistogramKeepAlive) Forget(key string) {
+	p.lock.Lock()
+	delete(p.histograms, key)
+	p.lock.Unlock()
+}
+
+func (p *hybridHistogramKeepAlive) Window(key string, idleTimeout time.Duration) KeepAliveWindow {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	h, ok := p.histograms[key]
+	if !ok || !h.representative() {
+		return KeepAliveWindow{KeepAlive: idleTimeout}
+	}
+
+	head := time.Duration(h.percentile(histogramHead)) * histogramBin
+	tail := time.Duration(h.percentile(histogramTail)+1) * histogramBin
+
+	preWarm := head - head*histogramMargin/100
+	keepAlive := tail + tail*histogramMargin/100 - preWarm
+	return KeepAliveWindow{PreWarm: preWarm, KeepAlive: keepAlive}
+}
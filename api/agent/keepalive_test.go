@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewKeepAlivePolicy(t *testing.T) {
+	for _, name := range []string{"", KeepAliveFixed, KeepAliveHybridHistogram} {
+		if _, err := NewKeepAlivePolicy(name); err != nil {
+			t.Fatalf("unexpected error for policy %q: %v", name, err)
+		}
+	}
+	if _, err := NewKeepAlivePolicy("forever"); err == nil {
+		t.Fatal("expected an unknown policy to fail")
+	}
+}
+
+func TestFixedKeepAlive(t *testing.T) {
+	p := NewFixedKeepAlive()
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		p.Arrived("key", now.Add(time.Duration(i)*10*time.Minute))
+	}
+	if w := p.Window("key", 30*time.Second); w.PreWarm != 0 || w.KeepAlive != 30*time.Second {
+		t.Fatalf("expected the idle timeout as keep-alive, got %+v", w)
+	}
+}
+
+func TestHybridHistogramKeepAlive(t *testing.T) {
+	idle := 30 * time.Second
+	now := time.Now()
+
+	arrive := func(p KeepAlivePolicy, key string, every ...time.Duration) {
+		for _, d := range every {
+			now = now.Add(d)
+			p.Arrived(key, now)
+		}
+	}
+	repeat := func(d time.Duration, n int) []time.Duration {
+		out := make([]time.Duration, n)
+		for i := range out {
+			out[i] = d
+		}
+		return out
+	}
+
+	p := NewHybridHistogramKeepAlive()
+
+	// too few calls to follow
+	arrive(p, "few", repeat(10*time.Minute, 5)...)
+	if w := p.Window("few", idle); w.PreWarm != 0 || w.KeepAlive != idle {
+		t.Fatalf("expected the idle timeout with few calls, got %+v", w)
+	}
+
+	// called every 10 minutes: pre-warm a little before and keep until a little after
+	arrive(p, "periodic", repeat(10*time.Minute+10*time.Second, 50)...)
+	w := p.Window("periodic", idle)
+	if w.PreWarm != 9*time.Minute || w.KeepAlive != 3*time.Minute+6*time.Second {
+		t.Fatalf("unexpected window for a periodic function %+v", w)
+	}
+	if w.PreWarm > 10*time.Minute || w.PreWarm+w.KeepAlive < 11*time.Minute {
+		t.Fatalf("expected the window %+v to cover the next call", w)
+	}
+
+	// called in quick succession: no pre-warm, keep alive for the tail
+	arrive(p, "busy", repeat(time.Second, 199)...)
+	arrive(p, "busy", 3*time.Minute+time.Second)
+	w = p.Window("busy", idle)
+	if w.PreWarm != 0 || w.KeepAlive != 66*time.Second {
+		t.Fatalf("unexpected window for a busy function %+v", w)
+	}
+
+	// spread evenly over the histogram there is no pattern to follow
+	var spread []time.Duration
+	for i := 0; i < histogramBins; i++ {
+		spread = append(spread, time.Duration(i)*histogramBin+time.Second)
+	}
+	arrive(p, "spread", spread...)
+	if w := p.Window("spread", idle); w.PreWarm != 0 || w.KeepAlive != idle {
+		t.Fatalf("expected the idle timeout with no pattern, got %+v", w)
+	}
+
+	// called less often than the histogram covers
+	arrive(p, "rare", repeat(5*time.Hour, 20)...)
+	if w := p.Window("rare", idle); w.PreWarm != 0 || w.KeepAlive != idle {
+		t.Fatalf("expected the idle timeout with calls out of bounds, got %+v", w)
+	}
+
+	// once the slot queue is gone its histogram goes too
+	p.Forget("periodic")
+	if w := p.Window("periodic", idle); w.PreWarm != 0 || w.KeepAlive != idle {
+		t.Fatalf("expected the idle timeout once forgotten, got %+v", w)
+	}
+	if _, ok := p.(*hybridHistogramKeepAlive).histograms["periodic"]; ok {
+		t.Fatalf("expected the histogram to be dropped")
+	}
+}
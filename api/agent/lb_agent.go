@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -25,6 +26,7 @@ type lbAgent struct {
 	placer        pool.Placer
 	callOverrider CallOverrider
 	shutWg        *common.WaitGroup
+	limiter       *concurrencyLimiter
 	callOpts      []CallOpt
 }
 
@@ -104,10 +106,11 @@ func NewLBAgent(rp pool.RunnerPool, p pool.Placer, options ...LBAgentOption) (Ag
 	}
 
 	a := &lbAgent{
-		cfg:    *cfg,
-		rp:     rp,
-		placer: p,
-		shutWg: common.NewWaitGroup(),
+		cfg:     *cfg,
+		rp:      rp,
+		placer:  p,
+		shutWg:  common.NewWaitGroup(),
+		limiter: newConcurrencyLimiter(),
 	}
 
 	// Allow overriding config
@@ -225,6 +228,12 @@ func (a *lbAgent) Submit(callI Call) error {
 		return a.handleCallEnd(ctx, call, err, false)
 	}
 
+	release, err := a.limiter.acquireCall(ctx, call, int(a.cfg.MaxConcurrencyQueue))
+	if err != nil {
+		return a.handleCallEnd(ctx, call, err, false)
+	}
+	defer release()
+
 	err = call.Start(ctx)
 	if err != nil {
 		return a.handleCallEnd(ctx, call, err, false)
@@ -239,6 +248,116 @@ func (a *lbAgent) Submit(callI Call) error {
 	return a.placeCall(ctx, call)
 }
 
+// implements Agent
+// PreWarm asks the runners of the pool in turn to start the containers that
+// the runners before them had no resources for.
+func (a *lbAgent) PreWarm(ctx context.Context, callI Call, n int, keepWarm time.Duration) (int, error) {
+	call := callI.(*call)
+
+	if !a.shutWg.AddSession(1) {
+		return 0, models.ErrCallTimeoutServerBusy
+	}
+	defer a.shutWg.DoneSession()
+
+	runners, err := a.rp.Runners(ctx, call)
+	if err != nil {
+		return 0, err
+	}
+
+	log := common.Logger(ctx).WithField("fn_id", call.FnID)
+	started := 0
+	for _, r := range runners {
+		if started >= n {
+			break
+		}
+		pw, ok := r.(pool.PreWarmer)
+		if !ok {
+			continue
+		}
+		k, err := pw.PreWarm(ctx, call, n-started, keepWarm)
+		if err != nil {
+			log.WithError(err).WithField("runner_addr", r.Address()).Info("Failed to pre-warm on runner")
+			continue
+		}
+		started += k
+	}
+	return started, nil
+}
+
+// FollowLogs implements LogFollower, with the runners that run the calls
+// of the fn. Runners that join the pool are followed as they do.
+func (a *lbAgent) FollowLogs(ctx context.Context, appID, fnID string, lines chan<- *models.LogLine) error {
+	log := common.Logger(ctx).WithField("fn_id", fnID)
+	call := &logFollowCall{model: &models.Call{AppID: appID, FnID: fnID}}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	following := make(map[string]bool)
+	done := make(chan string)
+	ticker := time.NewTicker(followLogsRefresh)
+	defer ticker.Stop()
+
+	for {
+		runners, err := a.rp.Runners(ctx, call)
+		if err != nil {
+			log.WithError(err).Info("Failed to get runners to follow logs on")
+		}
+		for _, r := range runners {
+			lf, ok := r.(pool.LogFollower)
+			if !ok || following[r.Address()] {
+				continue
+			}
+			following[r.Address()] = true
+
+			wg.Add(1)
+			go func(r pool.Runner, lf pool.LogFollower) {
+				defer wg.Done()
+				err := lf.FollowLogs(ctx, appID, fnID, lines)
+				if err != nil {
+					log.WithError(err).WithField("runner_addr", r.Address()).Info("Failed to follow logs on runner")
+				}
+				select {
+				case done <- r.Address():
+				case <-ctx.Done():
+				}
+			}(r, lf)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case addr := <-done:
+			// followed again with the next runners
+			delete(following, addr)
+		case <-ticker.C:
+		}
+	}
+}
+
+var _ LogFollower = &lbAgent{}
+
+// logFollowCall is what the runners to follow the logs of a fn on are listed
+// for. It has no request, response or logs of its own, as nothing runs.
+type logFollowCall struct {
+	model *models.Call
+}
+
+func (c *logFollowCall) SlotHashId() string                     { return "" }
+func (c *logFollowCall) Extensions() map[string]string          { return nil }
+func (c *logFollowCall) RequestBody() io.ReadCloser             { return nil }
+func (c *logFollowCall) ResponseWriter() http.ResponseWriter    { return nil }
+func (c *logFollowCall) StdErr() io.ReadWriteCloser             { return nil }
+func (c *logFollowCall) Model() *models.Call                    { return c.model }
+func (c *logFollowCall) AddUserExecutionTime(dur time.Duration) {}
+func (c *logFollowCall) GetUserExecutionTime() *time.Duration   { return nil }
+
+var _ pool.RunnerCall = &logFollowCall{}
+
 func (a *lbAgent) placeDetachCall(ctx context.Context, call *call) error {
 	errPlace := make(chan error, 1)
 	rw := call.respWriter.(*DetachedResponseWriter)
@@ -271,7 +390,8 @@ func (a *lbAgent) spawnPlaceCall(ctx context.Context, call *call, errCh chan err
 }
 
 // setRequestGetBody sets GetBody function on the given http.Request if it is missing.  GetBody allows
-// reading from the request body without mutating the state of the request.
+// reading from the request body without mutating the state of the request. Bodies larger than
+// MaxBufferedRequestSize are not buffered, but streamed to the runner that the call is placed on.
 func (a *lbAgent) setRequestBody(ctx context.Context, call *call) (*bytes.Buffer, error) {
 
 	r := call.req
@@ -279,6 +399,16 @@ func (a *lbAgent) setRequestBody(ctx context.Context, call *call) (*bytes.Buffer
 		return nil, nil
 	}
 
+	if a.streamsBody(r) {
+		max := a.cfg.MaxStreamedRequestSize
+		if max > 0 && r.ContentLength > 0 && uint64(r.ContentLength) > max {
+			return nil, models.ErrRequestContentTooBig
+		}
+		r.Body = common.NewClampReadCloser(r.Body, max, models.ErrRequestContentTooBig)
+		call.streamBody = true
+		return nil, nil
+	}
+
 	buf := bufPool.Get().(*bytes.Buffer)
 	buf.Reset()
 
@@ -312,6 +442,13 @@ func (a *lbAgent) setRequestBody(ctx context.Context, call *call) (*bytes.Buffer
 	}
 }
 
+// streamsBody returns whether the body of r is too large, or of an unknown
+// length, to be buffered
+func (a *lbAgent) streamsBody(r *http.Request) bool {
+	max := a.cfg.MaxBufferedRequestSize
+	return max > 0 && (r.ContentLength < 0 || uint64(r.ContentLength) > max)
+}
+
 // implements Agent
 func (a *lbAgent) Enqueue(context.Context, *models.Call) error {
 	logrus.Error("Enqueue not implemented")
@@ -320,6 +457,7 @@ func (a *lbAgent) Enqueue(context.Context, *models.Call) error {
 
 func (a *lbAgent) handleCallEnd(ctx context.Context, call *call, err error, isForwarded bool) error {
 	if isForwarded {
+		ctx = withStartType(ctx, call.StartType)
 		call.End(ctx, err)
 		statsStopRun(ctx)
 		if err == nil {
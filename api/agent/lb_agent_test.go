@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -20,6 +22,7 @@ type mockRunner struct {
 	maxCalls  int32 // Max concurrent calls
 	curCalls  int32 // Current calls
 	procCalls int32 // Processed calls
+	preWarmed int32 // Pre-warmed containers, up to maxCalls
 	addr      string
 }
 
@@ -87,6 +90,17 @@ func (r *mockRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, e
 	return true, nil
 }
 
+func (r *mockRunner) PreWarm(ctx context.Context, call pool.RunnerCall, n int, keepWarm time.Duration) (int, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	started := 0
+	for started < n && r.preWarmed < r.maxCalls {
+		r.preWarmed++
+		started++
+	}
+	return started, nil
+}
+
 func (r *mockRunner) Close(context.Context) error {
 	go func() {
 		r.wg.Wait()
@@ -295,3 +309,167 @@ func TestWithLBCallOptions(t *testing.T) {
 		t.Fatalf("Expected %s got %s", expected, actualType)
 	}
 }
+
+func TestLBAgentPreWarm(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	rp := setupMockRunnerPool([]string{"171.19.0.1", "171.19.0.2", "171.19.0.3"}, 0, 2)
+	a, err := NewLBAgent(rp, pool.NewNaivePlacer(&cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	call, err := a.GetCall(FromModel(&models.Call{Type: models.TypeSync, FnID: "fn"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each runner has room for two, the first runners are filled first
+	started, err := a.PreWarm(context.Background(), call, 5, time.Minute)
+	if err != nil || started != 5 {
+		t.Fatalf("expected 5 containers started, got %d: %v", started, err)
+	}
+	for i, expected := range []int32{2, 2, 1} {
+		if n := rp.runners[i].(*mockRunner).preWarmed; n != expected {
+			t.Fatalf("expected runner %d to pre-warm %d containers, got %d", i, expected, n)
+		}
+	}
+
+	started, err = a.PreWarm(context.Background(), call, 5, time.Minute)
+	if err != nil || started != 1 {
+		t.Fatalf("expected only 1 more container started, got %d: %v", started, err)
+	}
+}
+
+func TestLBAgentStreamedBody(t *testing.T) {
+	cfg, err := NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.MaxBufferedRequestSize = 4
+	cfg.MaxStreamedRequestSize = 8
+
+	a, err := NewLBAgent(nil, nil, WithLBAgentConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lbAgent := a.(*lbAgent)
+
+	for i, test := range []struct {
+		body          string
+		contentLength int64
+		streamed      bool
+		err           error
+		readErr       error
+	}{
+		{"abc", 3, false, nil, nil},
+		{"abc", -1, true, nil, nil},
+		{"abcdef", 6, true, nil, nil},
+		{"abcdefghij", 10, false, models.ErrRequestContentTooBig, nil},
+		{"abcdefghij", -1, true, nil, models.ErrRequestContentTooBig},
+	} {
+		req, err := http.NewRequest("POST", "http://127.0.0.1/invoke/fn", strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// as with a request to the server, the body can only be read once
+		req.GetBody = nil
+		req.ContentLength = test.contentLength
+
+		c, err := a.GetCall(FromModel(&models.Call{Type: models.TypeSync, FnID: "fn"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		call := c.(*call)
+		call.req = req
+
+		buf, err := lbAgent.setRequestBody(context.Background(), call)
+		if buf != nil {
+			bufPool.Put(buf)
+		}
+		if err != test.err {
+			t.Fatalf("Test %d: expected error %v, got %v", i, test.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		if call.StreamsBody() != test.streamed {
+			t.Fatalf("Test %d: expected streamed=%v", i, test.streamed)
+		}
+		if test.streamed && req.GetBody != nil {
+			t.Fatalf("Test %d: expected a streamed body not to be buffered", i)
+		}
+
+		b, err := ioutil.ReadAll(call.RequestBody())
+		if err != test.readErr {
+			t.Fatalf("Test %d: expected read error %v, got %v", i, test.readErr, err)
+		}
+		if err == nil && string(b) != test.body {
+			t.Fatalf("Test %d: expected body %q, got %q", i, test.body, b)
+		}
+	}
+
+	// with no buffer limit, every body is buffered
+	lbAgent.cfg.MaxBufferedRequestSize = 0
+	req, err := http.NewRequest("POST", "http://127.0.0.1/invoke/fn", strings.NewReader("abcdefghij"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.GetBody = nil
+	req.ContentLength = -1
+	c, err := a.GetCall(FromModel(&models.Call{Type: models.TypeSync, FnID: "fn"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*call).req = req
+	buf, err := lbAgent.setRequestBody(context.Background(), c.(*call))
+	if err != nil || buf == nil || c.(*call).StreamsBody() {
+		t.Fatalf("expected the body to be buffered, got %v", err)
+	}
+	bufPool.Put(buf)
+}
+
+func (r *mockRunner) FollowLogs(ctx context.Context, appID, fnID string, lines chan<- *models.LogLine) error {
+	select {
+	case lines <- &models.LogLine{AppID: appID, FnID: fnID, Line: r.addr}:
+	case <-ctx.Done():
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func TestLBAgentFollowLogs(t *testing.T) {
+	cfg := pool.NewPlacerConfig()
+	addrs := []string{"171.19.0.1", "171.19.0.2", "171.19.0.3"}
+	a, err := NewLBAgent(setupMockRunnerPool(addrs, 0, 1), pool.NewNaivePlacer(&cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan *models.LogLine)
+	done := make(chan error, 1)
+	go func() {
+		done <- a.(LogFollower).FollowLogs(ctx, "app", "fn", lines)
+	}()
+
+	// a line from each runner
+	seen := make(map[string]bool)
+	for len(seen) < len(addrs) {
+		select {
+		case line := <-lines:
+			if line.AppID != "app" || line.FnID != "fn" {
+				t.Fatalf("expected a line of fn, got %+v", line)
+			}
+			seen[line.Line] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for lines, got %v", seen)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error following logs: %v", err)
+	}
+}
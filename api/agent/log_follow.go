@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	runner "github.com/fnproject/fn/api/agent/grpc"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+const (
+	// followLogsQueueSize is the number of lines queued to a follower, past
+	// which lines are dropped for it rather than holding up the calls that log
+	// them
+	followLogsQueueSize = 256
+
+	// followLogsMaxBatch is the most lines a runner sends in a message
+	followLogsMaxBatch = 64
+
+	// followLogsRefresh is how often an LB looks for runners to follow
+	followLogsRefresh = 5 * time.Second
+
+	// logStreamFnIDKey is the config of the start of a log stream that names
+	// the fn to follow
+	logStreamFnIDKey = "fn_id"
+)
+
+// LogFollower is an Agent that can follow the logs of the calls of a fn as
+// they run
+type LogFollower interface {
+	// FollowLogs sends the lines logged by the calls of fnID of appID to
+	// lines, until ctx is done
+	FollowLogs(ctx context.Context, appID, fnID string, lines chan<- *models.LogLine) error
+}
+
+// fnLogHub hands the lines that calls log to the followers of their fns
+type fnLogHub struct {
+	mu        sync.RWMutex
+	followers map[string]map[chan *models.LogLine]struct{}
+}
+
+func newFnLogHub() *fnLogHub {
+	return &fnLogHub{followers: make(map[string]map[chan *models.LogLine]struct{})}
+}
+
+// follow queues the lines logged by the calls of fnID to lines, until the
+// returned func is called
+func (h *fnLogHub) follow(fnID string, lines chan *models.LogLine) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fs, ok := h.followers[fnID]
+	if !ok {
+		fs = make(map[chan *models.LogLine]struct{})
+		h.followers[fnID] = fs
+	}
+	fs[lines] = struct{}{}
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(fs, lines)
+		if len(fs) == 0 {
+			delete(h.followers, fnID)
+		}
+	}
+}
+
+func (h *fnLogHub) following(fnID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.followers[fnID]) > 0
+}
+
+// publish queues line to the followers of its fn, dropping it for those that
+// are behind
+func (h *fnLogHub) publish(line *models.LogLine) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for lines := range h.followers[line.FnID] {
+		select {
+		case lines <- line:
+		default:
+		}
+	}
+}
+
+// writer returns a writer that publishes each line written to it as logged by
+// c. It is safe to call on a nil hub.
+func (h *fnLogHub) writer(c *models.Call) io.WriteCloser {
+	if h == nil {
+		return nil
+	}
+	return newLineWriter(&fnLogWriter{hub: h, appID: c.AppID, fnID: c.FnID, callID: c.ID})
+}
+
+// fnLogWriter publishes each line written to it, while its fn has followers.
+// It must be wrapped with a lineWriter.
+type fnLogWriter struct {
+	hub    *fnLogHub
+	appID  string
+	fnID   string
+	callID string
+}
+
+func (w *fnLogWriter) Write(line []byte) (int, error) {
+	if w.hub.following(w.fnID) {
+		w.hub.publish(&models.LogLine{
+			AppID:  w.appID,
+			FnID:   w.fnID,
+			CallID: w.callID,
+			Time:   common.DateTime(time.Now()),
+			Line:   string(bytes.TrimRight(line, "\r\n")),
+		})
+	}
+	return len(line), nil
+}
+
+func (w *fnLogWriter) Close() error { return nil }
+
+// FollowLogs implements LogFollower, with the calls that this agent runs
+func (a *agent) FollowLogs(ctx context.Context, appID, fnID string, lines chan<- *models.LogLine) error {
+	queue := make(chan *models.LogLine, followLogsQueueSize)
+	stop := a.logHub.follow(fnID, queue)
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line := <-queue:
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+var _ LogFollower = &agent{}
+
+// StreamLogs implements LogStreamer, with the calls that this agent runs. The
+// collector starts the stream with the fn to follow, in the fn_id config of
+// Start, and sends Ready once it takes lines. The lines queued since are sent
+// as a message, and the next message waits for the collector to Ack it.
+func (a *agent) StreamLogs(stream runner.RunnerProtocol_StreamLogsServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	fnID := msg.GetStart().GetConfig()[logStreamFnIDKey]
+	if fnID == "" {
+		return errors.New("log stream must start with the fn_id to follow")
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	ready := make(chan struct{}, 1)
+	acks := make(chan struct{}, 1)
+	go func() {
+		defer cancel()
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			switch msg.Body.(type) {
+			case *runner.LogRequestMsg_Ready_:
+				notify(ready)
+			case *runner.LogRequestMsg_Ack_:
+				notify(acks)
+			}
+		}
+	}()
+
+	queue := make(chan *models.LogLine, followLogsQueueSize)
+	stop := a.logHub.follow(fnID, queue)
+	defer stop()
+
+	pushing, inflight := false, false
+	for {
+		var next chan *models.LogLine
+		if pushing && !inflight {
+			next = queue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ready:
+			pushing = true
+		case <-acks:
+			inflight = false
+		case line := <-next:
+			batch := []*models.LogLine{line}
+		Batch:
+			for len(batch) < followLogsMaxBatch {
+				select {
+				case line := <-queue:
+					batch = append(batch, line)
+				default:
+					break Batch
+				}
+			}
+			if err := stream.Send(logResponseMsg(batch)); err != nil {
+				return err
+			}
+			inflight = true
+		}
+	}
+}
+
+var _ LogStreamer = &agent{}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// logResponseMsg groups lines by their calls, keeping them in order
+func logResponseMsg(lines []*models.LogLine) *runner.LogResponseMsg {
+	msg := &runner.LogResponseMsg{}
+	var ctr *runner.LogResponseMsg_Container
+	var req *runner.LogResponseMsg_Container_Request
+	for _, line := range lines {
+		if ctr == nil || ctr.ApplicationId != line.AppID || ctr.FunctionId != line.FnID {
+			ctr = &runner.LogResponseMsg_Container{ApplicationId: line.AppID, FunctionId: line.FnID}
+			msg.Data = append(msg.Data, ctr)
+			req = nil
+		}
+		if req == nil || req.RequestId != line.CallID {
+			req = &runner.LogResponseMsg_Container_Request{RequestId: line.CallID}
+			ctr.Data = append(ctr.Data, req)
+		}
+		req.Data = append(req.Data, &runner.LogResponseMsg_Container_Request_Line{
+			Timestamp: time.Time(line.Time).UnixNano() / int64(time.Millisecond),
+			Source:    runner.LogResponseMsg_Container_Request_Line_STDERR,
+			Data:      []byte(line.Line),
+		})
+	}
+	return msg
+}
+
+// logLines is the inverse of logResponseMsg
+func logLines(msg *runner.LogResponseMsg) []*models.LogLine {
+	var lines []*models.LogLine
+	for _, ctr := range msg.GetData() {
+		for _, req := range ctr.GetData() {
+			for _, line := range req.GetData() {
+				ms := line.GetTimestamp()
+				lines = append(lines, &models.LogLine{
+					AppID:  ctr.GetApplicationId(),
+					FnID:   ctr.GetFunctionId(),
+					CallID: req.GetRequestId(),
+					Time:   common.DateTime(time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))),
+					Line:   string(line.GetData()),
+				})
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	runner "github.com/fnproject/fn/api/agent/grpc"
+	"github.com/fnproject/fn/api/models"
+	"google.golang.org/grpc"
+)
+
+// waitFollowing waits until fnID is followed on h
+func waitFollowing(t *testing.T, h *fnLogHub, fnID string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !h.following(fnID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s to be followed", fnID)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFollowLogs(t *testing.T) {
+	a := &agent{logHub: newFnLogHub()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines := make(chan *models.LogLine, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- a.FollowLogs(ctx, "app", "fn", lines)
+	}()
+	waitFollowing(t, a.logHub, "fn")
+
+	w := a.logHub.writer(&models.Call{ID: "call", AppID: "app", FnID: "fn"})
+	other := a.logHub.writer(&models.Call{ID: "other", AppID: "app", FnID: "other"})
+	other.Write([]byte("not followed\n"))
+	w.Write([]byte("first\nsecond"))
+	w.Close()
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case line := <-lines:
+			if line.Line != expected || line.CallID != "call" || line.AppID != "app" || line.FnID != "fn" {
+				t.Fatalf("expected line %q of call, got %+v", expected, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for line %q", expected)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error following logs: %v", err)
+	}
+	if a.logHub.following("fn") {
+		t.Fatal("expected fn to no longer be followed")
+	}
+}
+
+// mockLogStream is the runner end of a log stream
+type mockLogStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	recv chan *runner.LogRequestMsg
+	sent chan *runner.LogResponseMsg
+}
+
+func (s *mockLogStream) Context() context.Context { return s.ctx }
+
+func (s *mockLogStream) Send(msg *runner.LogResponseMsg) error {
+	s.sent <- msg
+	return nil
+}
+
+func (s *mockLogStream) Recv() (*runner.LogRequestMsg, error) {
+	msg, ok := <-s.recv
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func TestStreamLogs(t *testing.T) {
+	a := &agent{logHub: newFnLogHub()}
+	stream := &mockLogStream{
+		ctx:  context.Background(),
+		recv: make(chan *runner.LogRequestMsg, 10),
+		sent: make(chan *runner.LogResponseMsg, 10),
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.StreamLogs(stream)
+	}()
+
+	stream.recv <- &runner.LogRequestMsg{Body: &runner.LogRequestMsg_Start_{Start: &runner.LogRequestMsg_Start{
+		Config: map[string]string{logStreamFnIDKey: "fn"},
+	}}}
+	waitFollowing(t, a.logHub, "fn")
+
+	w := a.logHub.writer(&models.Call{ID: "call", AppID: "app", FnID: "fn"})
+	w.Write([]byte("first\nsecond\n"))
+
+	receive := func() []*models.LogLine {
+		select {
+		case msg := <-stream.sent:
+			return logLines(msg)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for log message")
+		}
+		return nil
+	}
+
+	// nothing is pushed before the collector is ready
+	select {
+	case msg := <-stream.sent:
+		t.Fatalf("expected no message before ready, got %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	stream.recv <- &runner.LogRequestMsg{Body: &runner.LogRequestMsg_Ready_{Ready: &runner.LogRequestMsg_Ready{}}}
+	lines := receive()
+	if len(lines) != 2 || lines[0].Line != "first" || lines[1].Line != "second" || lines[1].CallID != "call" {
+		t.Fatalf("expected the two lines of call, got %+v", lines)
+	}
+
+	// nor before the last message is acked
+	w.Write([]byte("third\n"))
+	select {
+	case msg := <-stream.sent:
+		t.Fatalf("expected no message before ack, got %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	stream.recv <- &runner.LogRequestMsg{Body: &runner.LogRequestMsg_Ack_{Ack: &runner.LogRequestMsg_Ack{}}}
+	if lines := receive(); len(lines) != 1 || lines[0].Line != "third" {
+		t.Fatalf("expected the third line, got %+v", lines)
+	}
+
+	close(stream.recv)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error streaming logs: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the stream to end")
+	}
+}
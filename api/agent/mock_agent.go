@@ -1,7 +1,11 @@
 package agent
 
 import (
+	"context"
 	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/fnext"
 	"github.com/stretchr/testify/mock"
 )
@@ -50,6 +54,26 @@ func (m *MockAgent) Submit(c Call) error {
 	return m.Called(c).Error(0)
 }
 
+func (m *MockAgent) PreWarm(ctx context.Context, c Call, n int, keepWarm time.Duration) (int, error) {
+	args := m.Called(c, n, keepWarm)
+	return args.Int(0), args.Error(1)
+}
+
+// FollowLogs sends the lines returned by the mock to lines
+func (m *MockAgent) FollowLogs(ctx context.Context, appID, fnID string, lines chan<- *models.LogLine) error {
+	args := m.Called(appID, fnID)
+	if l, ok := args.Get(0).([]*models.LogLine); ok {
+		for _, line := range l {
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+	return args.Error(1)
+}
+
 func (m *MockAgent) Close() error {
 	return m.Called().Error(0)
 }
@@ -217,6 +217,28 @@ func (ch *callHandle) enqueueMsgStrict(msg *runner.RunnerMsg) error {
 	return err
 }
 
+// ackingReader acknowledges a call with a streamed body to the LB when the
+// body is first read, which is once the call has a slot and cannot be NACKed.
+// The LB holds the body back until then.
+type ackingReader struct {
+	ch   *callHandle
+	r    io.ReadCloser
+	once sync.Once
+}
+
+func (a *ackingReader) Read(p []byte) (int, error) {
+	a.once.Do(func() {
+		a.ch.enqueueMsgStrict(&runner.RunnerMsg{
+			Body: &runner.RunnerMsg_Acknowledged{Acknowledged: &runner.CallAcknowledged{}},
+		})
+	})
+	return a.r.Read(p)
+}
+
+func (a *ackingReader) Close() error {
+	return a.r.Close()
+}
+
 // enqueueCallResponse enqueues a Submit() response to the LB
 // and initiates a graceful shutdown of the session.
 func (ch *callHandle) enqueueCallResponse(err error) {
@@ -643,6 +665,11 @@ func (pr *pureRunner) Submit(Call) error {
 	return errors.New("Submit cannot be called directly in a Pure Runner.")
 }
 
+// implements Agent
+func (pr *pureRunner) PreWarm(ctx context.Context, call Call, n int, keepWarm time.Duration) (int, error) {
+	return pr.a.PreWarm(ctx, call, n, keepWarm)
+}
+
 // implements Agent
 func (pr *pureRunner) Close() error {
 	// First stop accepting requests
@@ -714,7 +741,12 @@ func (pr *pureRunner) handleTryCall(tc *runner.TryCall, state *callHandle) error
 	c.StartedAt = common.DateTime(time.Time{})
 	c.CompletedAt = common.DateTime(time.Time{})
 
-	agentCall, err := pr.a.GetCall(FromModelAndInput(&c, state.pipeToFnR),
+	var input io.ReadCloser = state.pipeToFnR
+	if tc.StreamBody {
+		input = &ackingReader{ch: state, r: state.pipeToFnR}
+	}
+
+	agentCall, err := pr.a.GetCall(FromModelAndInput(&c, input),
 		WithLogger(common.NoopReadWriteCloser{}),
 		WithWriter(state),
 		WithContext(state.sctx),
@@ -828,6 +860,51 @@ func (pr *pureRunner) ConfigureRunner(ctx context.Context, config *runner.Config
 	return pr.configFunc(ctx, config)
 }
 
+// startHotError translates err into a gRPC status, API errors keep their code
+func startHotError(err error) error {
+	if code := models.GetAPIErrorCode(err); code != 0 {
+		return status.Errorf(codes.Code(code), err.Error())
+	}
+	return status.Errorf(codes.Unknown, err.Error())
+}
+
+// implements RunnerProtocolServer
+func (pr *pureRunner) StartHot(ctx context.Context, msg *runner.PreWarmMsg) (*runner.PreWarmStatus, error) {
+	var c models.Call
+	err := json.Unmarshal([]byte(msg.ModelsCallJson), &c)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid call: %v", err)
+	}
+
+	// Status image is reserved for internal Status checks.
+	if pr.status.imageName != "" && c.Image == pr.status.imageName {
+		return nil, status.Errorf(codes.Code(models.ErrFnsInvalidImage.Code()), models.ErrFnsInvalidImage.Error())
+	}
+
+	agentCall, err := pr.a.GetCall(FromModel(&c),
+		WithLogger(common.NoopReadWriteCloser{}),
+		WithContext(ctx),
+		WithExtensions(msg.GetExtensions()),
+	)
+	if err != nil {
+		return nil, startHotError(err)
+	}
+
+	if msg.SlotHashId != "" {
+		hashID, err := hex.DecodeString(msg.SlotHashId)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid slot hash id: %v", err)
+		}
+		agentCall.(*call).slotHashId = string(hashID[:])
+	}
+
+	started, err := pr.a.PreWarm(ctx, agentCall, int(msg.Containers), time.Duration(msg.KeepWarm))
+	if err != nil {
+		return nil, startHotError(err)
+	}
+	return &runner.PreWarmStatus{Started: int32(started)}, nil
+}
+
 // implements RunnerProtocolServer
 func (pr *pureRunner) StreamLogs(logStream runner.RunnerProtocol_StreamLogsServer) error {
 	if pr.logStreamer != nil {
@@ -991,6 +1068,11 @@ func NewPureRunner(cancel context.CancelFunc, addr string, options ...PureRunner
 	}
 	pr.status.setAgent(pr.a)
 
+	// the agent follows the logs of its calls, if nothing else does
+	if ls, ok := pr.a.(LogStreamer); ok && pr.logStreamer == nil {
+		pr.logStreamer = ls
+	}
+
 	pr.gRPCOptions = append(pr.gRPCOptions, grpc.StreamInterceptor(grpcutil.RIDStreamServerInterceptor))
 	pr.gRPCOptions = append(pr.gRPCOptions, grpc.UnaryInterceptor(grpcutil.RIDUnaryServerInterceptor))
 	pr.gRPCOptions = append(pr.gRPCOptions, grpc.StatsHandler(&ocgrpc.ServerHandler{}))
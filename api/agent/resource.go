@@ -38,6 +38,11 @@ type ResourceUtilization struct {
 	MemUsed uint64
 	// Memory available in bytes
 	MemAvail uint64
+	// Memory in use by paused containers in bytes, part of MemUsed
+	MemPaused uint64
+	// Memory in use past what is available in bytes, once paused containers
+	// that it was overcommitted from are resumed, to be reclaimed by eviction
+	MemOvercommitted uint64
 }
 
 // A simple resource (memory, cpu, disk, etc.) tracker for scheduling.
@@ -68,6 +73,13 @@ type resourceTracker struct {
 	ramTotal uint64
 	// ramUsed is ram reserved for running containers including hot/idle
 	ramUsed uint64
+	// ramPaused is the part of ramUsed reserved for paused containers. Frozen
+	// processes keep their memory, so it stays reserved, but these containers
+	// are the first the evictor reclaims it from.
+	ramPaused uint64
+	// pausedOvercommit is the percentage of ramPaused that is available to
+	// other containers too, as frozen processes rarely use all they reserve
+	pausedOvercommit uint64
 	// cpuTotal is the total usable cpu for functions
 	cpuTotal uint64
 	// cpuUsed is cpu reserved for running containers including hot/idle
@@ -82,6 +94,9 @@ func NewResourceTracker(cfg *Config) ResourceTracker {
 
 	obj.initializeMemory(cfg)
 	obj.initializeCPU(cfg)
+	if cfg != nil {
+		obj.pausedOvercommit = minUint64(cfg.PausedMemoryOvercommit, 100)
+	}
 	return obj
 }
 
@@ -90,6 +105,9 @@ type ResourceToken interface {
 	io.Closer
 	Error() error
 	NeededCapacity() (uint64, models.MilliCPUs)
+	// SetPaused counts the memory of the token as reserved for a paused
+	// container, or no longer, in the utilization of its tracker.
+	SetPaused(paused bool)
 }
 
 type resourceToken struct {
@@ -98,6 +116,7 @@ type resourceToken struct {
 	needCpu   models.MilliCPUs
 	needMem   uint64
 	decrement func()
+	setPaused func(paused bool)
 }
 
 func (t *resourceToken) Error() error {
@@ -108,6 +127,12 @@ func (t *resourceToken) NeededCapacity() (uint64, models.MilliCPUs) {
 	return t.needMem, t.needCpu
 }
 
+func (t *resourceToken) SetPaused(paused bool) {
+	if t.setPaused != nil {
+		t.setPaused(paused)
+	}
+}
+
 func (t *resourceToken) Close() error {
 	t.once.Do(func() {
 		if t.decrement != nil {
@@ -117,9 +142,25 @@ func (t *resourceToken) Close() error {
 	return nil
 }
 
+// ramLimitLocked is the memory that may be reserved, which is more than
+// ramTotal by the overcommitted part of the memory of paused containers
+func (a *resourceTracker) ramLimitLocked() uint64 {
+	return a.ramTotal + a.ramPaused*a.pausedOvercommit/100
+}
+
+// ramAvailLocked is the memory left to reserve. It is none while resumed
+// containers keep more reserved than the limit.
+func (a *resourceTracker) ramAvailLocked() uint64 {
+	limit := a.ramLimitLocked()
+	if a.ramUsed >= limit {
+		return 0
+	}
+	return limit - a.ramUsed
+}
+
 func (a *resourceTracker) isResourceAvailableLocked(memory uint64, cpuQuota models.MilliCPUs) bool {
 
-	availMem := a.ramTotal - a.ramUsed
+	availMem := a.ramAvailLocked()
 	availCPU := a.cpuTotal - a.cpuUsed
 
 	return availMem >= memory && availCPU >= uint64(cpuQuota)
@@ -132,11 +173,15 @@ func (a *resourceTracker) GetUtilization() ResourceUtilization {
 
 	util.CpuUsed = models.MilliCPUs(a.cpuUsed)
 	util.MemUsed = a.ramUsed
+	util.MemPaused = a.ramPaused
+	util.MemAvail = a.ramAvailLocked()
+	if limit := a.ramLimitLocked(); a.ramUsed > limit {
+		util.MemOvercommitted = a.ramUsed - limit
+	}
 
 	a.cond.L.Unlock()
 
 	util.CpuAvail = models.MilliCPUs(a.cpuTotal) - util.CpuUsed
-	util.MemAvail = a.ramTotal - util.MemUsed
 
 	return util
 }
@@ -152,9 +197,16 @@ func (a *resourceTracker) allocResourcesLocked(memory uint64, cpuQuota models.Mi
 	a.ramUsed += memory
 	a.cpuUsed += uint64(cpuQuota)
 
+	// paused is protected by cond.L
+	var paused bool
+
 	return &resourceToken{decrement: func() {
 
 		a.cond.L.Lock()
+		if paused {
+			a.ramPaused -= memory
+			paused = false
+		}
 		a.ramUsed -= memory
 		a.cpuUsed -= uint64(cpuQuota)
 		a.cond.L.Unlock()
@@ -163,6 +215,21 @@ func (a *resourceTracker) allocResourcesLocked(memory uint64, cpuQuota models.Mi
 		// spurious wake up is unlikely to impact much performance. Simpler to use
 		// one cond variable for the time being.
 		a.cond.Broadcast()
+	}, setPaused: func(isPaused bool) {
+
+		a.cond.L.Lock()
+		if isPaused && !paused {
+			a.ramPaused += memory
+		} else if !isPaused && paused {
+			a.ramPaused -= memory
+		}
+		paused = isPaused
+		a.cond.L.Unlock()
+
+		// pausing makes part of its memory available to the waiters
+		if isPaused {
+			a.cond.Broadcast()
+		}
 	}}
 }
 
@@ -182,7 +249,7 @@ func (a *resourceTracker) GetResourceTokenNB(ctx context.Context, memory uint64,
 
 	a.cond.L.Lock()
 
-	availMem := a.ramTotal - a.ramUsed
+	availMem := a.ramAvailLocked()
 	availCPU := a.cpuTotal - a.cpuUsed
 
 	if availMem >= memory && availCPU >= uint64(cpuQuota) {
@@ -179,3 +179,122 @@ func TestResourceGetSimpleNB(t *testing.T) {
 		t.Fatalf("faulty state CPU %#v", vals)
 	}
 }
+
+func TestResourcePaused(t *testing.T) {
+
+	var vals trackerVals
+	trI := NewResourceTracker(nil)
+	tr := trI.(*resourceTracker)
+
+	vals.setDefaults()
+	setTrackerTestVals(tr, &vals)
+
+	ctx := context.Background()
+	tok1 := trI.GetResourceTokenNB(ctx, 1024, 1000)
+	tok2 := trI.GetResourceTokenNB(ctx, 512, 1000)
+	if tok1.Error() != nil || tok2.Error() != nil {
+		t.Fatalf("empty system should hand out tokens")
+	}
+
+	tok1.SetPaused(true)
+	tok1.SetPaused(true)
+	tok2.SetPaused(true)
+	tok2.SetPaused(false)
+
+	util := trI.GetUtilization()
+	if util.MemUsed != 1536*Mem1MB || util.MemPaused != 1024*Mem1MB {
+		t.Fatalf("paused memory should be counted once and stay in use %#v", util)
+	}
+
+	// closing a paused token releases its paused memory too
+	tok1.Close()
+	tok2.Close()
+
+	util = trI.GetUtilization()
+	if util.MemUsed != 0 || util.MemPaused != 0 {
+		t.Fatalf("faulty state MEM %#v", util)
+	}
+
+	// tokens that failed have nothing to pause
+	vals.mu = vals.mt
+	setTrackerTestVals(tr, &vals)
+	tok := trI.GetResourceTokenNB(ctx, 1024, 1000)
+	if tok.Error() == nil {
+		t.Fatalf("full system should not hand out token")
+	}
+	tok.SetPaused(true)
+	if util := trI.GetUtilization(); util.MemPaused != 0 {
+		t.Fatalf("faulty state MEM %#v", util)
+	}
+}
+
+func TestResourcePausedOvercommit(t *testing.T) {
+
+	var vals trackerVals
+	trI := NewResourceTracker(&Config{PausedMemoryOvercommit: 50})
+	tr := trI.(*resourceTracker)
+
+	vals.setDefaults()
+	setTrackerTestVals(tr, &vals)
+
+	ctx := context.Background()
+	tok1 := trI.GetResourceTokenNB(ctx, 3072, 1000)
+	if tok1.Error() != nil {
+		t.Fatalf("empty system should hand out tokens")
+	}
+	if tok := trI.GetResourceTokenNB(ctx, 2048, 1000); tok.Error() == nil {
+		t.Fatalf("running containers should not be overcommitted")
+	}
+
+	// pausing the first token makes half of its memory available again, to a
+	// waiter as well
+	got := make(chan ResourceToken, 1)
+	go func() {
+		got <- trI.GetResourceToken(ctx, 2048, 1000)
+	}()
+	select {
+	case <-got:
+		t.Fatalf("token should not be handed out before the first is paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tok1.SetPaused(true)
+
+	var tok2 ResourceToken
+	select {
+	case tok2 = <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("pausing should hand out the token to the waiter")
+	}
+	if tok2 == nil {
+		t.Fatalf("paused memory should be overcommitted")
+	}
+
+	util := trI.GetUtilization()
+	if util.MemUsed != 5*Mem1GB || util.MemAvail != 512*Mem1MB || util.MemOvercommitted != 0 {
+		t.Fatalf("faulty state MEM %#v", util)
+	}
+
+	// no more than the overcommitted part is handed out
+	tok3 := trI.GetResourceTokenNB(ctx, 1024, 1000)
+	if tok3.Error() == nil {
+		t.Fatalf("paused memory should be overcommitted only in part")
+	}
+	if needMem, _ := tok3.NeededCapacity(); needMem != 512 {
+		t.Fatalf("expected to need 512MB, got %d", needMem)
+	}
+
+	// resuming leaves the tracker past its total, for eviction to reclaim
+	tok1.SetPaused(false)
+	util = trI.GetUtilization()
+	if util.MemAvail != 0 || util.MemOvercommitted != Mem1GB {
+		t.Fatalf("resumed memory should be overcommitted %#v", util)
+	}
+
+	tok2.Close()
+	util = trI.GetUtilization()
+	if util.MemAvail != Mem1GB || util.MemOvercommitted != 0 {
+		t.Fatalf("faulty state MEM %#v", util)
+	}
+	tok1.Close()
+}
@@ -31,6 +31,9 @@ import (
 var (
 	ErrorRunnerClosed    = errors.New("Runner is closed")
 	ErrorPureRunnerNoEOF = errors.New("Purerunner missing EOF response")
+	// ErrorRunnerBusyCommitted is returned in place of a too busy error from a
+	// runner that had already committed to the call, which cannot be retried
+	ErrorRunnerBusyCommitted = errors.New("Runner was too busy after committing to the call")
 )
 
 const (
@@ -175,6 +178,91 @@ func (r *gRPCRunner) Status(ctx context.Context) (*pool.RunnerStatus, error) {
 	return TranslateGRPCStatusToRunnerStatus(status), err
 }
 
+// implements PreWarmer
+func (r *gRPCRunner) PreWarm(ctx context.Context, call pool.RunnerCall, n int, keepWarm time.Duration) (int, error) {
+	if !r.shutWg.AddSession(1) {
+		return 0, ErrorRunnerClosed
+	}
+	defer r.shutWg.DoneSession()
+
+	modelJSON, err := json.Marshal(call.Model())
+	if err != nil {
+		return 0, err
+	}
+
+	rid := common.RequestIDFromContext(ctx)
+	if rid != "" {
+		// Create a new gRPC metadata where we store the request ID
+		mp := metadata.Pairs(common.RequestIDContextKey, rid)
+		ctx = metadata.NewOutgoingContext(ctx, mp)
+	}
+
+	status, err := r.client.StartHot(ctx, &pb.PreWarmMsg{
+		ModelsCallJson: string(modelJSON),
+		SlotHashId:     hex.EncodeToString([]byte(call.SlotHashId())),
+		Extensions:     call.Extensions(),
+		Containers:     int32(n),
+		KeepWarm:       int64(keepWarm),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(status.GetStarted()), nil
+}
+
+var _ pool.PreWarmer = &gRPCRunner{}
+
+// implements LogFollower
+func (r *gRPCRunner) FollowLogs(ctx context.Context, appID, fnID string, lines chan<- *models.LogLine) error {
+	rid := common.RequestIDFromContext(ctx)
+	if rid != "" {
+		// Create a new gRPC metadata where we store the request ID
+		mp := metadata.Pairs(common.RequestIDContextKey, rid)
+		ctx = metadata.NewOutgoingContext(ctx, mp)
+	}
+
+	// unlike calls, following does not hold up Close, which ends the stream
+	stream, err := r.client.StreamLogs(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = stream.Send(&pb.LogRequestMsg{Body: &pb.LogRequestMsg_Start_{Start: &pb.LogRequestMsg_Start{
+		Config: map[string]string{logStreamFnIDKey: fnID},
+	}}})
+	if err == nil {
+		err = stream.Send(&pb.LogRequestMsg{Body: &pb.LogRequestMsg_Ready_{Ready: &pb.LogRequestMsg_Ready{}}})
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, line := range logLines(msg) {
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		err = stream.Send(&pb.LogRequestMsg{Body: &pb.LogRequestMsg_Ack_{Ack: &pb.LogRequestMsg_Ack{}}})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+var _ pool.LogFollower = &gRPCRunner{}
+
 // implements Runner
 func (r *gRPCRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, error) {
 	log := common.Logger(ctx).WithField("runner_addr", r.address)
@@ -194,6 +282,18 @@ func (r *gRPCRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, e
 		return true, err
 	}
 
+	// a streamed body can only be sent once, so it is held back until the
+	// runner acknowledges that it runs the call
+	var acked chan struct{}
+	var sendErr chan error
+	if bs, ok := call.(pool.BodyStreamer); ok && bs.StreamsBody() {
+		acked = make(chan struct{})
+		sendErr = make(chan error, 1)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+	}
+
 	rid := common.RequestIDFromContext(ctx)
 	if rid != "" {
 		// Create a new gRPC metadata where we store the request ID
@@ -212,6 +312,7 @@ func (r *gRPCRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, e
 		ModelsCallJson: string(modelJSON),
 		SlotHashId:     hex.EncodeToString([]byte(call.SlotHashId())),
 		Extensions:     call.Extensions(),
+		StreamBody:     acked != nil,
 	}}})
 	if err != nil {
 		// We are going to retry on a different runner, it is ok to log this error as Info
@@ -230,13 +331,17 @@ func (r *gRPCRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, e
 
 	recvDone := make(chan error, 1)
 
-	go receiveFromRunner(ctx, runnerConnection, r.address, call, recvDone)
-	go sendToRunner(ctx, runnerConnection, r.address, call)
+	go receiveFromRunner(ctx, runnerConnection, r.address, call, recvDone, acked)
+	go sendToRunner(ctx, runnerConnection, r.address, call, acked, sendErr)
 
 	select {
 	case <-ctx.Done():
 		log.Infof("Engagement Context ended ctxErr=%v", ctx.Err())
 		return true, ctx.Err()
+	case err := <-sendErr:
+		// the runner has part of a streamed body that cannot be sent again,
+		// so the call fails rather than being placed elsewhere
+		return true, err
 	case recvErr := <-recvDone:
 		if isTooBusy(recvErr) {
 			// Try on next runner
@@ -246,14 +351,26 @@ func (r *gRPCRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, e
 	}
 }
 
-func sendToRunner(ctx context.Context, protocolClient pb.RunnerProtocol_EngageClient, runnerAddress string, call pool.RunnerCall) {
+// sendToRunner sends the request body of call to the runner. If acked is not nil, the body is
+// streamed: nothing is read from it until acked is closed, and a failure to read it is queued
+// to done rather than sent to the runner as the end of the body.
+func sendToRunner(ctx context.Context, protocolClient pb.RunnerProtocol_EngageClient, runnerAddress string, call pool.RunnerCall, acked chan struct{}, done chan error) {
 	var errorMsg string
 	var infoMsg string
-	bodyReader := call.RequestBody()
-	writeBuffer := make([]byte, MaxDataChunk)
 	_, span := trace.StartSpan(ctx, "send_to_runner", trace.WithSpanKind(trace.SpanKindClient))
 	defer span.End()
 	log := common.Logger(ctx).WithField("runner_addr", runnerAddress)
+
+	if acked != nil {
+		select {
+		case <-acked:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	bodyReader := call.RequestBody()
+	writeBuffer := make([]byte, MaxDataChunk)
 	// IMPORTANT: IO Read below can fail in multiple go-routine cases (in retry
 	// case especially if receiveFromRunner go-routine receives a NACK while sendToRunner is
 	// already blocked on a read) or in the case of reading the http body multiple times (retries.)
@@ -269,6 +386,10 @@ func sendToRunner(ctx context.Context, protocolClient pb.RunnerProtocol_EngageCl
 			errorMsg = "Failed to receive data from http client body"
 			span.SetStatus(trace.Status{Code: int32(trace.StatusCodeDataLoss), Message: errorMsg})
 			log.WithError(err).Error(errorMsg)
+			if acked != nil {
+				tryQueueError(err, done)
+				return
+			}
 		}
 
 		// any IO error or n == 0 is an EOF for pure-runner
@@ -359,7 +480,7 @@ func cloneHeaders(src http.Header) http.Header {
 	return dst
 }
 
-func receiveFromRunner(ctx context.Context, protocolClient pb.RunnerProtocol_EngageClient, runnerAddress string, c pool.RunnerCall, done chan error) {
+func receiveFromRunner(ctx context.Context, protocolClient pb.RunnerProtocol_EngageClient, runnerAddress string, c pool.RunnerCall, done chan error, acked chan struct{}) {
 	var errorMsg string
 	var infoMsg string
 	w := c.ResponseWriter()
@@ -372,12 +493,22 @@ func receiveFromRunner(ctx context.Context, protocolClient pb.RunnerProtocol_Eng
 	clonedHeaders := cloneHeaders(w.Header())
 	isPartialWrite := false
 
+	// once the runner acks the call or sends any of its result, the call was
+	// placed, and a too busy error must not place it on another runner
+	committed := false
+	fail := func(err error) {
+		if committed && isTooBusy(err) {
+			err = ErrorRunnerBusyCommitted
+		}
+		tryQueueError(err, done)
+	}
+
 DataLoop:
 	for {
 		msg, err := protocolClient.Recv()
 		if err != nil {
 			log.WithError(err).Info("Receive error from runner")
-			tryQueueError(err, done)
+			fail(err)
 			return
 		}
 
@@ -386,11 +517,23 @@ DataLoop:
 		// Process HTTP header/status message. This may not arrive depending on
 		// pure runners behavior. (Eg. timeout & no IO received from function)
 		case *pb.RunnerMsg_ResultStart:
+			committed = true
 			switch meta := body.ResultStart.Meta.(type) {
 			case *pb.CallResultStart_Http:
 				infoMsg = fmt.Sprintf("Received meta http result from runner Status=%v", meta.Http.StatusCode)
 				span.Annotate([]trace.Attribute{trace.StringAttribute("status", infoMsg)}, "")
 				log.Debugf(infoMsg)
+				// the runner tells how the call's container came to be available
+				for _, header := range meta.Http.Headers {
+					if http.CanonicalHeaderKey(header.Key) == models.StartTypeHeader {
+						c.Model().StartType = header.Value
+						w.Header().Set(models.StartTypeHeader, header.Value)
+					} else if c.Model().Stream {
+						// a streamed response goes out as its data arrives, so
+						// the headers of the fn must be in place before then
+						w.Header().Add(header.Key, header.Value)
+					}
+				}
 				// for _, header := range meta.Http.Headers {
 				// 	clonedHeaders.Add(header.Key, header.Value)
 				// 	w.Header().Add(header.Key, header.Value)
@@ -405,11 +548,21 @@ DataLoop:
 				log.Errorf(errorMsg)
 			}
 
+		// Arrives once the runner is committed to a call with a streamed body.
+		case *pb.RunnerMsg_Acknowledged:
+			log.Debug("Received call acknowledgement from runner")
+			committed = true
+			if acked != nil {
+				close(acked)
+				acked = nil
+			}
+
 		// May arrive if function has output. We ignore EOF.
 		case *pb.RunnerMsg_Data:
 			infoMsg = fmt.Sprintf("Received data from runner len=%d isEOF=%v", len(body.Data.Data), body.Data.Eof)
 			span.Annotate([]trace.Attribute{trace.StringAttribute("status", infoMsg)}, "")
 			log.Debugf(infoMsg)
+			committed = true
 			if !isPartialWrite {
 				// WARNING: blocking write
 				n, err := w.Write(body.Data.Data)
@@ -449,7 +602,7 @@ DataLoop:
 			span.SetStatus(trace.Status{Code: body.Finished.GetErrorCode(), Message: body.Finished.GetErrorStr()})
 			if !body.Finished.Success {
 				err := parseError(body.Finished)
-				tryQueueError(err, done)
+				fail(err)
 			}
 			break DataLoop
 
@@ -468,7 +621,7 @@ DataLoop:
 		}
 		if err != nil {
 			log.WithError(err).Infof("Call Waiting EOF received error")
-			tryQueueError(err, done)
+			fail(err)
 			break
 		}
 
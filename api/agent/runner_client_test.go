@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pb "github.com/fnproject/fn/api/agent/grpc"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	pool "github.com/fnproject/fn/api/runnerpool"
+	"google.golang.org/grpc"
+)
+
+// mockEngageStream is the lb end of an engagement with a runner, that the
+// test plays the runner of
+type mockEngageStream struct {
+	grpc.ClientStream
+	recv chan *pb.RunnerMsg
+	errs chan error
+	sent chan *pb.ClientMsg
+}
+
+func newMockEngageStream() *mockEngageStream {
+	return &mockEngageStream{
+		recv: make(chan *pb.RunnerMsg, 10),
+		errs: make(chan error, 1),
+		sent: make(chan *pb.ClientMsg, 10),
+	}
+}
+
+func (s *mockEngageStream) Send(msg *pb.ClientMsg) error {
+	s.sent <- msg
+	return nil
+}
+
+// Recv receives the queued messages before any error
+func (s *mockEngageStream) Recv() (*pb.RunnerMsg, error) {
+	select {
+	case msg, ok := <-s.recv:
+		if !ok {
+			return nil, io.EOF
+		}
+		return msg, nil
+	default:
+	}
+	select {
+	case msg, ok := <-s.recv:
+		if !ok {
+			return nil, io.EOF
+		}
+		return msg, nil
+	case err := <-s.errs:
+		return nil, err
+	}
+}
+
+func (s *mockEngageStream) expectSent(t *testing.T) *pb.ClientMsg {
+	select {
+	case msg := <-s.sent:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a message to the runner")
+	}
+	return nil
+}
+
+type mockEngageClient struct {
+	pb.RunnerProtocolClient
+	stream *mockEngageStream
+}
+
+func (c *mockEngageClient) Engage(ctx context.Context, opts ...grpc.CallOption) (pb.RunnerProtocol_EngageClient, error) {
+	return c.stream, nil
+}
+
+type mockStreamingRunnerCall struct {
+	mockRunnerCall
+}
+
+func (c *mockStreamingRunnerCall) StreamsBody() bool {
+	return true
+}
+
+func tryExecOnMockStream(call pool.RunnerCall, stream *mockEngageStream) (chan bool, chan error) {
+	r := &gRPCRunner{shutWg: common.NewWaitGroup(), address: "mock", client: &mockEngageClient{stream: stream}}
+	placed, errs := make(chan bool, 1), make(chan error, 1)
+	go func() {
+		ok, err := r.TryExec(context.Background(), call)
+		placed <- ok
+		errs <- err
+	}()
+	return placed, errs
+}
+
+func newMockRunnerCall(body string) mockRunnerCall {
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(body))
+	return mockRunnerCall{r: req, rw: httptest.NewRecorder(), model: &models.Call{ID: "call"}}
+}
+
+func TestTryExecBusyBeforeAck(t *testing.T) {
+	call := &mockStreamingRunnerCall{newMockRunnerCall("body")}
+	stream := newMockEngageStream()
+	placed, errs := tryExecOnMockStream(call, stream)
+
+	if try := stream.expectSent(t).GetTry(); try == nil || !try.StreamBody {
+		t.Fatalf("expected a try of a streamed body first, got %v", try)
+	}
+	stream.recv <- &pb.RunnerMsg{Body: &pb.RunnerMsg_Finished{Finished: &pb.CallFinished{
+		Success:   false,
+		ErrorCode: int32(http.StatusServiceUnavailable),
+		ErrorStr:  "busy",
+	}}}
+	close(stream.recv)
+
+	if <-placed {
+		t.Fatal("expected a call the runner is too busy for to not be placed")
+	}
+	if err := <-errs; err != models.ErrCallTimeoutServerBusy {
+		t.Fatalf("expected a too busy error, got %v", err)
+	}
+	select {
+	case msg := <-stream.sent:
+		t.Fatalf("expected no body to be sent without an ack, got %v", msg)
+	default:
+	}
+}
+
+func TestTryExecBusyAfterAck(t *testing.T) {
+	call := &mockStreamingRunnerCall{newMockRunnerCall("body")}
+	stream := newMockEngageStream()
+	placed, errs := tryExecOnMockStream(call, stream)
+
+	stream.expectSent(t)
+	stream.recv <- &pb.RunnerMsg{Body: &pb.RunnerMsg_Acknowledged{Acknowledged: &pb.CallAcknowledged{}}}
+
+	// the body is only streamed once the runner is committed to the call
+	var body []byte
+	for {
+		data := stream.expectSent(t).GetData()
+		if data == nil {
+			t.Fatal("expected the body to be sent after the ack")
+		}
+		body = append(body, data.Data...)
+		if data.Eof {
+			break
+		}
+	}
+	if string(body) != "body" {
+		t.Fatalf("expected the body to be sent, got %q", body)
+	}
+
+	stream.recv <- &pb.RunnerMsg{Body: &pb.RunnerMsg_Data{Data: &pb.DataFrame{Data: []byte("partial")}}}
+	stream.errs <- models.ErrCallTimeoutServerBusy
+
+	if !<-placed {
+		t.Fatal("expected a call that failed mid-stream to be placed")
+	}
+	if err := <-errs; err != ErrorRunnerBusyCommitted {
+		t.Fatalf("expected a committed call to fail hard, got %v", err)
+	}
+	if out := call.rw.(*httptest.ResponseRecorder).Body.String(); out != "partial" {
+		t.Fatalf("expected the streamed response to be written, got %q", out)
+	}
+}
+
+func TestTryExecBusyAfterData(t *testing.T) {
+	call := newMockRunnerCall("")
+	stream := newMockEngageStream()
+	placed, errs := tryExecOnMockStream(&call, stream)
+
+	stream.recv <- &pb.RunnerMsg{Body: &pb.RunnerMsg_Data{Data: &pb.DataFrame{Data: []byte("partial")}}}
+	stream.recv <- &pb.RunnerMsg{Body: &pb.RunnerMsg_Finished{Finished: &pb.CallFinished{
+		Success:   false,
+		ErrorCode: int32(http.StatusServiceUnavailable),
+		ErrorStr:  "busy",
+	}}}
+	close(stream.recv)
+
+	if !<-placed {
+		t.Fatal("expected a call with part of its response written to be placed")
+	}
+	if err := <-errs; err != ErrorRunnerBusyCommitted {
+		t.Fatalf("expected a committed call to fail hard, got %v", err)
+	}
+}
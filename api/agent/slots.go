@@ -9,7 +9,10 @@ import (
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
+
+	"github.com/fnproject/fn/api/models"
 )
 
 //
@@ -44,17 +47,23 @@ type slotToken struct {
 }
 
 type slotCaller struct {
-	id     string
-	notify chan error      // notification to caller
-	done   <-chan struct{} // caller done
+	id       string
+	notify   chan error      // notification to caller
+	done     <-chan struct{} // caller done
+	preWarm  bool            // no caller, the container is started ahead of calls
+	keepWarm time.Duration   // how long a pre-warmed container waits for its first call
+	priority int             // priority of the call
 }
 
 // LIFO queue that exposes input/output channels along
 // with runner/waiter tracking for agent
 type slotQueue struct {
+	lastArrival int64 // unix nanos of the last call, accessed atomically
+
 	key       string
 	cond      *sync.Cond
 	slots     []*slotToken
+	waiters   [priorityClasses]int // callers in startDequeuer by priority, protected by cond.L
 	nextId    uint64
 	signaller chan *slotCaller
 	statsLock sync.Mutex // protects stats below
@@ -103,17 +112,24 @@ func (a *slotQueue) acquireSlot(s *slotToken) bool {
 	return true
 }
 
-func (a *slotQueue) startDequeuer(ctx context.Context) chan *slotToken {
+// startDequeuer returns a channel that offers the slots of the queue to a
+// caller of priority. No slots are offered while a caller of a higher priority
+// is waiting for them.
+func (a *slotQueue) startDequeuer(ctx context.Context, priority int) chan *slotToken {
 
-	isWaiting := false
+	class := priorityClass(priority)
 	output := make(chan *slotToken)
 
+	a.cond.L.Lock()
+	a.waiters[class]++
+	a.cond.L.Unlock()
+
 	go func() {
 		<-ctx.Done()
 		a.cond.L.Lock()
-		if isWaiting {
-			a.cond.Broadcast()
-		}
+		a.waiters[class]--
+		// wake everyone, the callers we outranked may go ahead now
+		a.cond.Broadcast()
 		a.cond.L.Unlock()
 	}()
 
@@ -121,11 +137,9 @@ func (a *slotQueue) startDequeuer(ctx context.Context) chan *slotToken {
 		for {
 			a.cond.L.Lock()
 
-			isWaiting = true
-			for len(a.slots) <= 0 && (ctx.Err() == nil) {
+			for (len(a.slots) <= 0 || a.isOutranked(class)) && (ctx.Err() == nil) {
 				a.cond.Wait()
 			}
-			isWaiting = false
 
 			if ctx.Err() != nil {
 				a.cond.L.Unlock()
@@ -146,6 +160,30 @@ func (a *slotQueue) startDequeuer(ctx context.Context) chan *slotToken {
 	return output
 }
 
+// priorityClasses is the number of call priorities
+const priorityClasses = models.PriorityHigh - models.PriorityLow + 1
+
+// priorityClass returns the index of priority in slotQueue.waiters
+func priorityClass(priority int) int {
+	if priority < models.PriorityLow {
+		priority = models.PriorityLow
+	} else if priority > models.PriorityHigh {
+		priority = models.PriorityHigh
+	}
+	return priority - models.PriorityLow
+}
+
+// isOutranked returns true if a caller of a higher priority class than class
+// is waiting. It must be called with cond.L held.
+func (a *slotQueue) isOutranked(class int) bool {
+	for i := class + 1; i < priorityClasses; i++ {
+		if a.waiters[i] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *slotQueue) queueSlot(slot Slot) *slotToken {
 
 	token := &slotToken{slot, make(chan struct{}), 0, 0}
@@ -160,6 +198,23 @@ func (a *slotQueue) queueSlot(slot Slot) *slotToken {
 	return token
 }
 
+// queuePausedSlot queues a slot of a paused container behind the others, as
+// the slots are dequeued from the end and the running containers should be
+// used first
+func (a *slotQueue) queuePausedSlot(slot Slot) *slotToken {
+
+	token := &slotToken{slot, make(chan struct{}), 0, 0}
+
+	a.cond.L.Lock()
+	token.id = a.nextId
+	a.slots = append([]*slotToken{token}, a.slots...)
+	a.nextId += 1
+	a.cond.L.Unlock()
+
+	a.cond.Broadcast()
+	return token
+}
+
 // isIdle() returns true is there's no activity for this slot queue. This
 // means no one is waiting, running or starting.
 func (a *slotQueue) isIdle() bool {
@@ -180,6 +235,30 @@ func (a *slotQueue) isIdle() bool {
 	return isIdle
 }
 
+// hasContainers() returns true if any container of this slot queue is waiting,
+// starting or running.
+func (a *slotQueue) hasContainers() bool {
+	stats := a.getStats()
+	return stats.containers() != 0
+}
+
+// containers returns the number of containers waiting, starting or running
+func (cur *slotQueueStats) containers() uint64 {
+	return cur.containerStates[ContainerStateWait] +
+		cur.containerStates[ContainerStateStart] +
+		cur.containerStates[ContainerStateIdle] +
+		cur.containerStates[ContainerStatePaused] +
+		cur.containerStates[ContainerStateBusy]
+}
+
+func (a *slotQueue) arrived(now time.Time) {
+	atomic.StoreInt64(&a.lastArrival, now.UnixNano())
+}
+
+func (a *slotQueue) getLastArrival() int64 {
+	return atomic.LoadInt64(&a.lastArrival)
+}
+
 func (a *slotQueue) getStats() slotQueueStats {
 	var out slotQueueStats
 	a.statsLock.Lock()
@@ -47,7 +47,7 @@ func checkGetTokenId(t *testing.T, a *slotQueue, dur time.Duration, id uint64) e
 	ctx, cancel := context.WithTimeout(context.Background(), dur)
 	defer cancel()
 
-	outChan := a.startDequeuer(ctx)
+	outChan := a.startDequeuer(ctx, models.PriorityNormal)
 
 	for {
 		select {
@@ -207,6 +207,20 @@ func TestSlotNewContainerLogic1(t *testing.T) {
 	}
 }
 
+func TestSlotContainers(t *testing.T) {
+	cur := statsHelperSet(5, 1, 1, 2, 3, 4)
+	cur.containerStates[ContainerStateDone] = 7
+	if n := cur.containers(); n != 10 {
+		t.Fatalf("Should count 10 containers, not those done, got %d cur: %#v", n, cur)
+	}
+
+	cur = statsHelperSet(5, 1, 0, 0, 0, 0)
+	cur.containerStates[ContainerStateDone] = 7
+	if n := cur.containers(); n != 0 {
+		t.Fatalf("Should count no containers, got %d cur: %#v", n, cur)
+	}
+}
+
 func TestSlotQueueBasic3(t *testing.T) {
 
 	slotName := "test3"
@@ -308,3 +322,72 @@ func BenchmarkSlotKey(b *testing.B) {
 		_ = getSlotQueueKey(call, "")
 	}
 }
+
+func TestSlotQueuePriority(t *testing.T) {
+	obj := NewSlotQueue("test4")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	highCtx, highCancel := context.WithCancel(ctx)
+	defer highCancel()
+
+	high := obj.startDequeuer(highCtx, models.PriorityHigh)
+	low := obj.startDequeuer(ctx, models.PriorityLow)
+
+	obj.queueSlot(NewTestSlot(1))
+
+	select {
+	case z := <-high:
+		if !obj.acquireSlot(z) || z.id != 0 {
+			t.Fatalf("high priority caller should acquire the slot: %#v", z)
+		}
+	case z := <-low:
+		t.Fatalf("low priority caller should not get a slot while a high one waits: %#v", z)
+	case <-ctx.Done():
+		t.Fatalf("high priority caller should get a slot")
+	}
+
+	obj.queueSlot(NewTestSlot(2))
+
+	select {
+	case z := <-low:
+		t.Fatalf("low priority caller should not get a slot while a high one waits: %#v", z)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// once the high priority caller is gone, the low one goes ahead
+	highCancel()
+
+	select {
+	case z := <-low:
+		if !obj.acquireSlot(z) || z.id != 1 {
+			t.Fatalf("low priority caller should acquire the slot: %#v", z)
+		}
+	case <-ctx.Done():
+		t.Fatalf("low priority caller should get a slot")
+	}
+}
+
+func TestSlotQueuePaused(t *testing.T) {
+	obj := NewSlotQueue("test-paused")
+	timeout := time.Duration(500) * time.Millisecond
+
+	obj.queueSlot(NewTestSlot(0))
+	obj.queueSlot(NewTestSlot(1))
+	paused := obj.queuePausedSlot(NewTestSlot(2))
+	if paused.id != 2 {
+		t.Fatalf("paused slotToken should get the next id: %#v", paused)
+	}
+
+	// the running containers are used before the paused one, however recently
+	// it was queued
+	if err := checkGetTokenId(t, obj, timeout, 1); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := checkGetTokenId(t, obj, timeout, 0); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := checkGetTokenId(t, obj, timeout, 2); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
@@ -17,6 +17,8 @@ var (
 	containerStateKey    = common.MakeKey("container_state")
 	callStatusKey        = common.MakeKey("call_status")
 	containerUDSStateKey = common.MakeKey("container_uds_state")
+	startTypeKey         = common.MakeKey("start_type")
+	evictionPolicyKey    = common.MakeKey("eviction_policy")
 
 	// tri-state values below: error/true/false
 	statusCallCacheKey    = common.MakeKey("cached")
@@ -31,6 +33,26 @@ var (
 	ImageNameMetricKey = common.MakeKey("image_name")
 )
 
+// withStartType tags the stats recorded with ctx with the start type of a
+// call, if it has one
+func withStartType(ctx context.Context, startType string) context.Context {
+	if startType == "" {
+		return ctx
+	}
+	ctx, err := tag.New(ctx,
+		tag.Upsert(startTypeKey, startType),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	return ctx
+}
+
+// statsSyslogDropped records lines of user logs that could not be shipped to syslog
+func statsSyslogDropped(ctx context.Context, n int) {
+	stats.Record(ctx, syslogDroppedMeasure.M(int64(n)))
+}
+
 func statsCalls(ctx context.Context) {
 	stats.Record(ctx, callsMeasure.M(1))
 }
@@ -95,9 +117,10 @@ func statsContainerUDSInitLatency(ctx context.Context, start time.Time, end time
 	stats.Record(ctx, containerUDSInitLatencyMeasure.M(int64(dur/time.Millisecond)))
 }
 
-func statsContainerEvicted(ctx context.Context, containerState string) {
+func statsContainerEvicted(ctx context.Context, containerState, policy string) {
 	ctx, err := tag.New(ctx,
 		tag.Upsert(containerStateKey, containerState),
+		tag.Upsert(evictionPolicyKey, evictionPolicyName(policy)),
 	)
 	if err != nil {
 		logrus.Fatal(err)
@@ -106,11 +129,43 @@ func statsContainerEvicted(ctx context.Context, containerState string) {
 	stats.Record(ctx, containerEvictedMeasure.M(0))
 }
 
+// statsContainerPauseLatency records how long freezing an idle container took
+func statsContainerPauseLatency(ctx context.Context, dur time.Duration) {
+	stats.Record(ctx, containerPauseLatencyMeasure.M(int64(dur/time.Millisecond)))
+}
+
+// statsContainerUnpauseLatency records how long unfreezing a paused container took
+func statsContainerUnpauseLatency(ctx context.Context, dur time.Duration) {
+	stats.Record(ctx, containerUnpauseLatencyMeasure.M(int64(dur/time.Millisecond)))
+}
+
+// statsEvictionColdStart records a cold start caused by an earlier eviction
+func statsEvictionColdStart(ctx context.Context, policy string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(evictionPolicyKey, evictionPolicyName(policy)),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	stats.Record(ctx, evictionColdStartMeasure.M(0))
+}
+
+// evictionPolicyName is the name of policy to tag stats with, the default
+// if it is not set
+func evictionPolicyName(policy string) string {
+	if policy == "" {
+		return EvictionLRU
+	}
+	return policy
+}
+
 func statsUtilization(ctx context.Context, util ResourceUtilization) {
 	stats.Record(ctx, utilCpuUsedMeasure.M(int64(util.CpuUsed)))
 	stats.Record(ctx, utilCpuAvailMeasure.M(int64(util.CpuAvail)))
 	stats.Record(ctx, utilMemUsedMeasure.M(int64(util.MemUsed)))
 	stats.Record(ctx, utilMemAvailMeasure.M(int64(util.MemAvail)))
+	stats.Record(ctx, utilMemPausedMeasure.M(int64(util.MemPaused)))
 }
 
 func statsCallLatency(ctx context.Context, dur time.Duration, callStatus string) {
@@ -170,13 +225,19 @@ const (
 	errorsMetricName     = "errors"
 	serverBusyMetricName = "server_busy"
 
+	syslogDroppedMetricName = "syslog_dropped"
+
 	containerEvictedMetricName        = "container_evictions"
+	evictionColdStartMetricName       = "eviction_cold_starts"
 	containerUDSInitLatencyMetricName = "container_uds_init_latency"
+	containerPauseLatencyMetricName   = "container_pause_latency"
+	containerUnpauseLatencyMetricName = "container_unpause_latency"
 
-	utilCpuUsedMetricName  = "util_cpu_used"
-	utilCpuAvailMetricName = "util_cpu_avail"
-	utilMemUsedMetricName  = "util_mem_used"
-	utilMemAvailMetricName = "util_mem_avail"
+	utilCpuUsedMetricName   = "util_cpu_used"
+	utilCpuAvailMetricName  = "util_cpu_avail"
+	utilMemUsedMetricName   = "util_mem_used"
+	utilMemAvailMetricName  = "util_mem_avail"
+	utilMemPausedMetricName = "util_mem_paused"
 
 	// Reported By LB
 	runnerSchedLatencyMetricName = "lb_runner_sched_latency"
@@ -196,6 +257,7 @@ var (
 	timedoutMeasure                = common.MakeMeasure(timedoutMetricName, "calls timed out in agent", "")
 	errorsMeasure                  = common.MakeMeasure(errorsMetricName, "calls errored in agent", "")
 	serverBusyMeasure              = common.MakeMeasure(serverBusyMetricName, "calls where server was too busy in agent", "")
+	syslogDroppedMeasure           = common.MakeMeasure(syslogDroppedMetricName, "user log lines dropped by agent before reaching syslog", "")
 	dockerMeasures                 = initDockerMeasures()
 	containerGaugeMeasures         = initContainerGaugeMeasures()
 	containerTimeMeasures          = initContainerTimeMeasures()
@@ -203,8 +265,12 @@ var (
 	utilCpuAvailMeasure            = common.MakeMeasure(utilCpuAvailMetricName, "agent cpu available", "")
 	utilMemUsedMeasure             = common.MakeMeasure(utilMemUsedMetricName, "agent memory in use", "By")
 	utilMemAvailMeasure            = common.MakeMeasure(utilMemAvailMetricName, "agent memory available", "By")
+	utilMemPausedMeasure           = common.MakeMeasure(utilMemPausedMetricName, "agent memory in use by paused containers", "By")
 	containerEvictedMeasure        = common.MakeMeasure(containerEvictedMetricName, "containers evicted", "")
+	evictionColdStartMeasure       = common.MakeMeasure(evictionColdStartMetricName, "cold starts caused by evictions", "")
 	containerUDSInitLatencyMeasure = common.MakeMeasure(containerUDSInitLatencyMetricName, "container UDS Init-Wait Latency", "msecs")
+	containerPauseLatencyMeasure   = common.MakeMeasure(containerPauseLatencyMetricName, "container Pause Latency", "msecs")
+	containerUnpauseLatencyMeasure = common.MakeMeasure(containerUnpauseLatencyMetricName, "container Unpause Latency", "msecs")
 
 	// Reported By LB: How long does a runner scheduler wait for a committed call? eg. wait/launch/pull containers
 	runnerSchedLatencyMeasure = common.MakeMeasure(runnerSchedLatencyMetricName, "Runner Scheduler Latency Reported By LBAgent", "msecs")
@@ -217,11 +283,11 @@ var (
 )
 
 func RegisterLBAgentViews(tagKeys []string, latencyDist []float64) {
-	// add call_status tag for call latency
-	callLatencyTags := make([]string, 0, len(tagKeys)+1)
-	callLatencyTags = append(callLatencyTags, "call_status")
+	// add call_status and start_type tags for call latency
+	callLatencyTags := make([]string, 0, len(tagKeys)+2)
+	callLatencyTags = append(callLatencyTags, "call_status", "start_type")
 	for _, key := range tagKeys {
-		if key != "call_status" {
+		if key != "call_status" && key != "start_type" {
 			callLatencyTags = append(callLatencyTags, key)
 		}
 	}
@@ -236,21 +302,39 @@ func RegisterLBAgentViews(tagKeys []string, latencyDist []float64) {
 	}
 }
 
+// withStartTypeTag adds the start_type tag to tagKeys, for the views of calls
+// that ran
+func withStartTypeTag(tagKeys []string) []string {
+	startTypeTags := make([]string, 0, len(tagKeys)+1)
+	startTypeTags = append(startTypeTags, "start_type")
+	for _, key := range tagKeys {
+		if key != "start_type" {
+			startTypeTags = append(startTypeTags, key)
+		}
+	}
+	return startTypeTags
+}
+
 // RegisterAgentViews creates and registers all agent views
 func RegisterAgentViews(tagKeys []string, latencyDist []float64) {
+	// add start_type tag for the calls that ran
+	startTypeTags := withStartTypeTag(tagKeys)
+
 	err := view.Register(
 		common.CreateView(queuedMeasure, view.Sum(), tagKeys),
 		common.CreateView(callsMeasure, view.Sum(), tagKeys),
 		common.CreateView(runningMeasure, view.Sum(), tagKeys),
-		common.CreateView(completedMeasure, view.Sum(), tagKeys),
-		common.CreateView(canceledMeasure, view.Sum(), tagKeys),
-		common.CreateView(timedoutMeasure, view.Sum(), tagKeys),
-		common.CreateView(errorsMeasure, view.Sum(), tagKeys),
+		common.CreateView(completedMeasure, view.Sum(), startTypeTags),
+		common.CreateView(canceledMeasure, view.Sum(), startTypeTags),
+		common.CreateView(timedoutMeasure, view.Sum(), startTypeTags),
+		common.CreateView(errorsMeasure, view.Sum(), startTypeTags),
 		common.CreateView(serverBusyMeasure, view.Sum(), tagKeys),
+		common.CreateView(syslogDroppedMeasure, view.Sum(), tagKeys),
 		common.CreateView(utilCpuUsedMeasure, view.LastValue(), tagKeys),
 		common.CreateView(utilCpuAvailMeasure, view.LastValue(), tagKeys),
 		common.CreateView(utilMemUsedMeasure, view.LastValue(), tagKeys),
 		common.CreateView(utilMemAvailMeasure, view.LastValue(), tagKeys),
+		common.CreateView(utilMemPausedMeasure, view.LastValue(), tagKeys),
 	)
 
 	if err != nil {
@@ -334,15 +418,24 @@ func RegisterContainerViews(tagKeys []string, latencyDist []float64) {
 		}
 	}
 
-	// add container state tag for evictions
-	evictTags := make([]string, 0, len(tagKeys)+1)
-	evictTags = append(evictTags, "container_state")
+	// add container state and eviction policy tags for evictions
+	evictTags := make([]string, 0, len(tagKeys)+2)
+	evictTags = append(evictTags, "container_state", "eviction_policy")
 	for _, key := range tagKeys {
-		if key != "container_state" {
+		if key != "container_state" && key != "eviction_policy" {
 			evictTags = append(evictTags, key)
 		}
 	}
 
+	// add eviction policy tag for the cold starts evictions cause
+	evictColdStartTags := make([]string, 0, len(tagKeys)+1)
+	evictColdStartTags = append(evictColdStartTags, "eviction_policy")
+	for _, key := range tagKeys {
+		if key != "eviction_policy" {
+			evictColdStartTags = append(evictColdStartTags, key)
+		}
+	}
+
 	// add container uds_state tag for uds-wait
 	udsInitTags := make([]string, 0, len(tagKeys)+1)
 	udsInitTags = append(udsInitTags, "container_uds_state")
@@ -354,7 +447,10 @@ func RegisterContainerViews(tagKeys []string, latencyDist []float64) {
 
 	err := view.Register(
 		common.CreateView(containerEvictedMeasure, view.Count(), evictTags),
+		common.CreateView(evictionColdStartMeasure, view.Count(), evictColdStartTags),
 		common.CreateView(containerUDSInitLatencyMeasure, view.Distribution(latencyDist...), udsInitTags),
+		common.CreateView(containerPauseLatencyMeasure, view.Distribution(latencyDist...), tagKeys),
+		common.CreateView(containerUnpauseLatencyMeasure, view.Distribution(latencyDist...), tagKeys),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot register view")
@@ -0,0 +1,315 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+const (
+	// user-level messages, at error severity, as docker logs stderr
+	syslogPriority = 1*8 + 3
+
+	// syslogSDID names the structured data element that carries the IDs of a
+	// call. RFC5424 requires a private SD-ID to name an enterprise number, and
+	// this is the one RFC5612 reserves for documentation.
+	syslogSDID = "fn@32473"
+
+	// syslogMaxAppName is the longest APP-NAME of RFC5424, past which app
+	// names are cut
+	syslogMaxAppName = 48
+
+	// syslogQueueSize is the number of lines queued to a destination, past
+	// which lines are dropped rather than holding up the calls that log them
+	syslogQueueSize = 1024
+
+	syslogDialTimeout  = 5 * time.Second
+	syslogWriteTimeout = 5 * time.Second
+	// syslogRetryDelay is how long lines are dropped after a destination could
+	// not be reached, before it is dialed again
+	syslogRetryDelay = time.Second
+	// syslogIdleTimeout is how long a destination keeps its connection
+	// without any lines to send
+	syslogIdleTimeout = time.Minute
+)
+
+// syslogPool ships the lines that calls log to the syslog URLs of their apps.
+// It keeps a destination per URL, with a queue of lines and a connection that
+// the calls of every app logging to it share.
+type syslogPool struct {
+	hostname string
+
+	mu     sync.Mutex
+	dests  map[string]*syslogDest
+	closed bool
+
+	dropped uint64
+}
+
+func newSyslogPool() *syslogPool {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &syslogPool{
+		hostname: hostname,
+		dests:    make(map[string]*syslogDest),
+	}
+}
+
+// writer returns a writer that ships each line written to it to the syslog
+// URL of c, or nil if c has none. It is safe to call on a nil pool.
+func (p *syslogPool) writer(c *models.Call) io.WriteCloser {
+	if p == nil {
+		return nil
+	}
+	u := strings.TrimSpace(c.SyslogURL)
+	if u == "" {
+		return nil
+	}
+
+	// APP-NAME is the name of the app, and the structured data has its ID
+	appName := c.AppName
+	if appName == "" {
+		appName = "-"
+	} else if len(appName) > syslogMaxAppName {
+		appName = appName[:syslogMaxAppName]
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s - - [%s app_id=%s fn_id=%s call_id=%s] ",
+		p.hostname, appName, syslogSDID, sdParam(c.AppID), sdParam(c.FnID), sdParam(c.ID))
+
+	return newLineWriter(&syslogWriter{pool: p, url: u, header: b.Bytes()})
+}
+
+// Dropped returns the number of lines that could not be shipped
+func (p *syslogPool) Dropped() uint64 {
+	return atomic.LoadUint64(&p.dropped)
+}
+
+func (p *syslogPool) drop(n int) {
+	atomic.AddUint64(&p.dropped, uint64(n))
+	statsSyslogDropped(context.Background(), n)
+}
+
+// send queues a message to the destination of u, dropping it if the queue is
+// full
+func (p *syslogPool) send(u string, msg []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		p.drop(1)
+		return
+	}
+
+	d, ok := p.dests[u]
+	if !ok {
+		var err error
+		d, err = newSyslogDest(u)
+		if err != nil {
+			// apps validate their syslog urls, but not those stored before they did
+			common.Logger(context.Background()).WithError(err).WithField("syslog_url", u).Debug("Invalid syslog url")
+			p.drop(1)
+			return
+		}
+		p.dests[u] = d
+		go d.run(p, u)
+	}
+
+	select {
+	case d.lines <- msg:
+	default:
+		p.drop(1)
+	}
+}
+
+// remove removes the destination of u if it has nothing queued, and returns
+// whether it did
+func (p *syslogPool) remove(u string, d *syslogDest) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(d.lines) > 0 {
+		return false
+	}
+	if p.dests[u] == d {
+		delete(p.dests, u)
+	}
+	return true
+}
+
+// close stops every destination, dropping what they have queued
+func (p *syslogPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	for u, d := range p.dests {
+		close(d.done)
+		delete(p.dests, u)
+	}
+}
+
+// sdParam quotes a structured data parameter value, escaping the characters
+// RFC5424 requires to be
+func sdParam(v string) string {
+	return `"` + sdEscaper.Replace(v) + `"`
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogWriter formats each line written to it as an RFC5424 message, and
+// queues it to its destination. It must be wrapped with a lineWriter.
+type syslogWriter struct {
+	pool   *syslogPool
+	url    string
+	header []byte
+}
+
+func (w *syslogWriter) Write(line []byte) (int, error) {
+	n := len(line)
+	line = bytes.TrimRight(line, "\r\n")
+
+	var b bytes.Buffer
+	b.Grow(len(w.header) + len(line) + 48)
+	fmt.Fprintf(&b, "<%d>1 %s ", syslogPriority, time.Now().UTC().Format("2006-01-02T15:04:05.000000Z07:00"))
+	b.Write(w.header)
+	b.Write(line)
+
+	w.pool.send(w.url, b.Bytes())
+	return n, nil
+}
+
+func (w *syslogWriter) Close() error { return nil }
+
+// syslogDest is a syslog endpoint, with the lines queued to it
+type syslogDest struct {
+	network string
+	addr    string
+	tls     *tls.Config
+	// stream destinations frame each message with its length, RFC6587
+	// octet-counting, datagram ones send a message per datagram
+	stream bool
+
+	lines chan []byte
+	done  chan struct{}
+}
+
+func newSyslogDest(u string) (*syslogDest, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &syslogDest{
+		lines: make(chan []byte, syslogQueueSize),
+		done:  make(chan struct{}),
+	}
+	switch pu.Scheme {
+	case "tcp", "udp":
+		d.network, d.addr = pu.Scheme, pu.Host
+	case "tcp+tls":
+		d.network, d.addr = "tcp", pu.Host
+		d.tls = &tls.Config{ServerName: pu.Hostname()}
+	default:
+		// the url is an app's, so sockets of the host, unix and unixgram,
+		// are not dialed
+		return nil, fmt.Errorf("unsupported syslog scheme %q", pu.Scheme)
+	}
+	if d.addr == "" {
+		return nil, fmt.Errorf("syslog url %q has no address", u)
+	}
+	d.stream = d.network == "tcp"
+	return d, nil
+}
+
+func (d *syslogDest) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+	if d.tls != nil {
+		return tls.DialWithDialer(dialer, d.network, d.addr, d.tls)
+	}
+	return dialer.Dial(d.network, d.addr)
+}
+
+// run sends the lines queued to d until d is closed, or has been idle long
+// enough to be removed from p
+func (d *syslogDest) run(p *syslogPool, u string) {
+	log := common.Logger(context.Background()).WithField("syslog_url", u)
+
+	var conn net.Conn
+	var retryAt time.Time
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	idle := time.NewTimer(syslogIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			p.drop(len(d.lines))
+			return
+		case <-idle.C:
+			if p.remove(u, d) {
+				return
+			}
+			idle.Reset(syslogIdleTimeout)
+		case msg := <-d.lines:
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(syslogIdleTimeout)
+
+			if conn == nil {
+				if time.Now().Before(retryAt) {
+					p.drop(1)
+					continue
+				}
+				var err error
+				conn, err = d.dial()
+				if err != nil {
+					log.WithError(err).Info("Unable to connect to syslog")
+					retryAt = time.Now().Add(syslogRetryDelay)
+					p.drop(1)
+					continue
+				}
+			}
+
+			if err := d.write(conn, msg); err != nil {
+				log.WithError(err).Info("Unable to write to syslog")
+				conn.Close()
+				conn = nil
+				p.drop(1)
+			}
+		}
+	}
+}
+
+func (d *syslogDest) write(conn net.Conn, msg []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err != nil {
+		return err
+	}
+	if d.stream {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+	_, err := conn.Write(msg)
+	return err
+}
@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func syslogCall(url string) *models.Call {
+	return &models.Call{ID: "call", AppID: "app", AppName: "myapp", FnID: "fn", SyslogURL: url}
+}
+
+// readOctetCounted reads a message framed with its length from r
+func readOctetCounted(t *testing.T, r *bufio.Reader) string {
+	size, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatalf("unexpected error reading message length: %v", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(size))
+	if err != nil {
+		t.Fatalf("invalid message length %q", size)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatalf("unexpected error reading message: %v", err)
+	}
+	return string(b)
+}
+
+func checkSyslogMessage(t *testing.T, msg, line string) {
+	if !strings.HasPrefix(msg, fmt.Sprintf("<%d>1 ", syslogPriority)) {
+		t.Fatalf("expected an RFC5424 header, got %q", msg)
+	}
+	sd := fmt.Sprintf(` myapp - - [%s app_id="app" fn_id="fn" call_id="call"] `, syslogSDID)
+	if !strings.Contains(msg, sd) {
+		t.Fatalf("expected structured data %q in %q", sd, msg)
+	}
+	if !strings.HasSuffix(msg, "] "+line) {
+		t.Fatalf("expected the line %q to end %q", line, msg)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	p := newSyslogPool()
+	defer p.close()
+
+	url := "tcp://" + l.Addr().String()
+	for _, c := range []*models.Call{syslogCall(url), syslogCall(url)} {
+		w := p.writer(c)
+		w.Write([]byte("first line\nsecond"))
+		w.Write([]byte(" line\n"))
+		w.Close()
+	}
+
+	// the calls share a connection
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	for i := 0; i < 2; i++ {
+		checkSyslogMessage(t, readOctetCounted(t, r), "first line")
+		checkSyslogMessage(t, readOctetCounted(t, r), "second line")
+	}
+	if p.Dropped() != 0 {
+		t.Fatalf("expected no lines dropped, got %d", p.Dropped())
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	p := newSyslogPool()
+	defer p.close()
+
+	w := p.writer(syslogCall("udp://" + conn.LocalAddr().String()))
+	w.Write([]byte("a line\nno newline"))
+	w.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 1024)
+	for _, line := range []string{"a line", "no newline"} {
+		n, _, err := conn.ReadFrom(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSyslogMessage(t, string(b[:n]), line)
+	}
+}
+
+func TestSyslogDropped(t *testing.T) {
+	// nothing listens on the port once it is closed
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	p := newSyslogPool()
+	defer p.close()
+
+	w := p.writer(syslogCall("tcp://" + addr))
+	w.Write([]byte("one\ntwo\nthree\n"))
+	w.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Dropped() != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 3 lines dropped, got %d", p.Dropped())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSyslogUnix(t *testing.T) {
+	p := newSyslogPool()
+	defer p.close()
+
+	// apps stored before their syslog urls were validated may name a socket
+	// of the host, which is never dialed
+	for _, url := range []string{"unix:///dev/log", "unixgram:///dev/log"} {
+		w := p.writer(syslogCall(url))
+		w.Write([]byte("a line\n"))
+		w.Close()
+	}
+	if p.Dropped() != 2 || len(p.dests) != 0 {
+		t.Fatalf("expected the lines to be dropped without a destination, got %d dropped and %d destinations", p.Dropped(), len(p.dests))
+	}
+}
+
+func TestSyslogNoURL(t *testing.T) {
+	p := newSyslogPool()
+	defer p.close()
+	if w := p.writer(syslogCall("")); w != nil {
+		t.Fatal("expected no writer for a call without a syslog url")
+	}
+
+	var nilPool *syslogPool
+	if w := nilPool.writer(syslogCall("tcp://127.0.0.1:1")); w != nil {
+		t.Fatal("expected no writer from a nil pool")
+	}
+}
+
+func TestSyslogHeader(t *testing.T) {
+	for _, test := range []struct {
+		appName  string
+		expected string
+	}{
+		{"myapp", "myapp"},
+		{"", "-"},
+		{strings.Repeat("a", 60), strings.Repeat("a", syslogMaxAppName)},
+	} {
+		p := newSyslogPool()
+		u := "tcp://127.0.0.1:514"
+		d := &syslogDest{lines: make(chan []byte, 1)}
+		p.dests[u] = d
+
+		c := syslogCall(u)
+		c.AppName = test.appName
+		w := p.writer(c)
+		w.Write([]byte("hello\n"))
+		msg := string(<-d.lines)
+
+		// PRI VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+		fields := strings.SplitN(msg, " ", 7)
+		if len(fields) != 7 {
+			t.Fatalf("expected an RFC5424 message, got %q", msg)
+		}
+		if pri := fmt.Sprintf("<%d>1", syslogPriority); fields[0] != pri {
+			t.Fatalf("expected PRI and VERSION %q, got %q", pri, fields[0])
+		}
+		if _, err := time.Parse(time.RFC3339Nano, fields[1]); err != nil {
+			t.Fatalf("expected an RFC3339 TIMESTAMP, got %q: %v", fields[1], err)
+		}
+		if fields[2] != p.hostname {
+			t.Fatalf("expected HOSTNAME %q, got %q", p.hostname, fields[2])
+		}
+		if fields[3] != test.expected {
+			t.Fatalf("expected APP-NAME %q, got %q", test.expected, fields[3])
+		}
+		if fields[4] != "-" || fields[5] != "-" {
+			t.Fatalf("expected no PROCID and MSGID, got %q and %q", fields[4], fields[5])
+		}
+		sd := fmt.Sprintf(`[%s app_id="app" fn_id="fn" call_id="call"] hello`, syslogSDID)
+		if fields[6] != sd {
+			t.Fatalf("expected STRUCTURED-DATA and MSG %q, got %q", sd, fields[6])
+		}
+	}
+}
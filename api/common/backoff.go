@@ -100,3 +100,43 @@ func (b *backoff) NextBackOff() (time.Duration, bool) {
 	}
 	return time.Duration(delay) * time.Millisecond, true
 }
+
+type rateBackoff struct {
+	lock       sync.Mutex
+	next       float64
+	rate       float64
+	maxRetries uint64
+	attempts   uint64
+}
+
+// NewRateBackOff returns a BackOff that waits interval before the first retry
+// and multiplies the wait by rate for each retry after that, for at most
+// maxRetries retries. Unlike NewBackOff there is no jitter, the delays are
+// those of a states language Retry.
+func NewRateBackOff(interval time.Duration, rate float64, maxRetries uint64) BackOff {
+	if rate < 1.0 {
+		rate = 1.0
+	}
+	return &rateBackoff{
+		next:       float64(interval),
+		rate:       rate,
+		maxRetries: maxRetries,
+	}
+}
+
+func (b *rateBackoff) NextBackOff() (time.Duration, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.attempts >= b.maxRetries {
+		return 0, false
+	}
+	b.attempts++
+
+	delay := b.next
+	if delay >= float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64), true
+	}
+	b.next *= b.rate
+	return time.Duration(delay), true
+}
@@ -105,3 +105,22 @@ func TestBackoff5(t *testing.T) {
 	checkRange(t, bck, true, 0, 31000)
 	checkRange(t, bck, false, 0, 0)
 }
+
+func TestRateBackoff(t *testing.T) {
+	bck := NewRateBackOff(100*time.Millisecond, 2.0, 3)
+
+	checkRange(t, bck, true, 100, 100)
+	checkRange(t, bck, true, 200, 200)
+	checkRange(t, bck, true, 400, 400)
+	for i := 0; i < 4; i++ {
+		checkRange(t, bck, false, 0, 0)
+	}
+
+	bck = NewRateBackOff(100*time.Millisecond, 0.5, 2)
+	checkRange(t, bck, true, 100, 100)
+	checkRange(t, bck, true, 100, 100)
+	checkRange(t, bck, false, 0, 0)
+
+	bck = NewRateBackOff(time.Second, 2.0, 0)
+	checkRange(t, bck, false, 0, 0)
+}
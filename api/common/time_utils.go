@@ -10,3 +10,8 @@ func MinDuration(f, s time.Duration) time.Duration {
 	}
 	return s
 }
+
+// UnixMilli returns t as the number of milliseconds since the unix epoch
+func UnixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
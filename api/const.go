@@ -13,12 +13,13 @@ const (
 	CallID string = "call_id"
 	// FnID is the url path parameter for fn id
 	FnID string = "fn_id"
+	// ExecutionID is the url path parameter for execution id
+	ExecutionID string = "execution_id"
+	// StateMachineName is the url path parameter for the name of a stored state machine
+	StateMachineName string = "statemachine_name"
 	// TriggerSource is the triggers source parameter
 	TriggerSource string = "trigger_source"
 
-	// SpikeCnt is the spike num parameter
-	SpikeCnt string = "spike_cnt"
-
 	//TriggerType is the trigger type parameter - only used in hybrid API
 	TriggerType string = "trigger_type"
 )
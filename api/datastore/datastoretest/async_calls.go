@@ -0,0 +1,350 @@
+package datastoretest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+// AsyncQueueFunc provides an instance of an async queue
+type AsyncQueueFunc func(*testing.T) models.AsyncQueue
+
+func validAsyncCall() *models.AsyncCall {
+	return &models.AsyncCall{
+		ID:      id.New().String(),
+		AppID:   "app",
+		FnID:    "fn",
+		Method:  "POST",
+		URL:     "http://127.0.0.1:8080/invoke/fn",
+		Headers: models.Headers{"Content-Type": []string{"application/json"}},
+		Body:    `{"hello":"world"}`,
+	}
+}
+
+// drainAsyncQueue finishes every call that is visible, so that each test
+// reserves only the calls it pushed
+func drainAsyncQueue(ctx context.Context, t *testing.T, aq models.AsyncQueue) {
+	for {
+		call, err := aq.Reserve(ctx, time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error reserving call: %v", err)
+		}
+		if call == nil {
+			return
+		}
+		call.Status = models.AsyncCallStatusFailed
+		if _, err := aq.Finish(ctx, call); err != nil {
+			t.Fatalf("unexpected error finishing call: %v", err)
+		}
+	}
+}
+
+// RunAsyncQueueTest runs the async queue correctness tests
+func RunAsyncQueueTest(t *testing.T, aqf AsyncQueueFunc) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	aq := aqf(t)
+	ctx := context.Background()
+
+	t.Run("AsyncCalls", func(t *testing.T) {
+
+		t.Run("push without ID", func(t *testing.T) {
+			call := validAsyncCall()
+			call.ID = ""
+			_, err := aq.Push(ctx, call)
+			if err != models.ErrAsyncCallsMissingID {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrAsyncCallsMissingID, err)
+			}
+		})
+
+		t.Run("push twice", func(t *testing.T) {
+			call := validAsyncCall()
+			if _, err := aq.Push(ctx, call); err != nil {
+				t.Fatalf("error when pushing perfectly good call: %s", err)
+			}
+			_, err := aq.Push(ctx, call)
+			if err != models.ErrAsyncCallsAlreadyExists {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrAsyncCallsAlreadyExists, err)
+			}
+		})
+
+		t.Run("push and get", func(t *testing.T) {
+			call, err := aq.Push(ctx, validAsyncCall())
+			if err != nil {
+				t.Fatalf("error when pushing perfectly good call: %s", err)
+			}
+			if call.Status != models.AsyncCallStatusQueued {
+				t.Fatalf("expected call to be queued, got %s", call.Status)
+			}
+
+			got, err := aq.GetAsyncCall(ctx, call.ID)
+			if err != nil {
+				t.Fatalf("unexpected error getting call: %v", err)
+			}
+			if got.Method != call.Method || got.URL != call.URL || got.Body != call.Body ||
+				http.Header(got.Headers).Get("Content-Type") != "application/json" {
+				t.Fatalf("expected to get the right call:\n%+v\nbut got:\n%+v", call, got)
+			}
+
+			_, err = aq.GetAsyncCall(ctx, "nope")
+			if err != models.ErrAsyncCallsNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrAsyncCallsNotFound, err)
+			}
+		})
+
+		t.Run("reserve hides the call", func(t *testing.T) {
+			drainAsyncQueue(ctx, t, aq)
+
+			call, err := aq.Push(ctx, validAsyncCall())
+			if err != nil {
+				t.Fatalf("error when pushing perfectly good call: %s", err)
+			}
+
+			got, err := aq.Reserve(ctx, time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error reserving call: %v", err)
+			}
+			if got == nil || got.ID != call.ID {
+				t.Fatalf("expected to reserve call %s, got %+v", call.ID, got)
+			}
+			if got.Status != models.AsyncCallStatusRunning || got.Attempts != 1 {
+				t.Fatalf("expected a running call on its first attempt, got %+v", got)
+			}
+
+			again, err := aq.Reserve(ctx, time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error reserving call: %v", err)
+			}
+			if again != nil {
+				t.Fatalf("expected no visible call, got %+v", again)
+			}
+		})
+
+		t.Run("visibility timeout", func(t *testing.T) {
+			drainAsyncQueue(ctx, t, aq)
+
+			call, err := aq.Push(ctx, validAsyncCall())
+			if err != nil {
+				t.Fatalf("error when pushing perfectly good call: %s", err)
+			}
+
+			// a worker that reserves the call and goes away
+			if _, err := aq.Reserve(ctx, 10*time.Millisecond); err != nil {
+				t.Fatalf("unexpected error reserving call: %v", err)
+			}
+			time.Sleep(20 * time.Millisecond)
+
+			got, err := aq.Reserve(ctx, time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error reserving call: %v", err)
+			}
+			if got == nil || got.ID != call.ID || got.Attempts != 2 {
+				t.Fatalf("expected to reserve call %s again on its second attempt, got %+v", call.ID, got)
+			}
+		})
+
+		t.Run("release", func(t *testing.T) {
+			drainAsyncQueue(ctx, t, aq)
+
+			call, err := aq.Push(ctx, validAsyncCall())
+			if err != nil {
+				t.Fatalf("error when pushing perfectly good call: %s", err)
+			}
+			reserved, err := aq.Reserve(ctx, time.Minute)
+			if err != nil || reserved == nil {
+				t.Fatalf("expected to reserve a call, got %+v: %v", reserved, err)
+			}
+
+			if err := aq.Release(ctx, call.ID, "nope", 0); err != models.ErrAsyncCallsNotReserved {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrAsyncCallsNotReserved, err)
+			}
+			if err := aq.Release(ctx, call.ID, reserved.Reservation, 0); err != nil {
+				t.Fatalf("unexpected error releasing call: %v", err)
+			}
+			got, err := aq.GetAsyncCall(ctx, call.ID)
+			if err != nil {
+				t.Fatalf("unexpected error getting call: %v", err)
+			}
+			if got.Status != models.AsyncCallStatusQueued {
+				t.Fatalf("expected a released call to be queued, got %s", got.Status)
+			}
+
+			got, err = aq.Reserve(ctx, time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error reserving call: %v", err)
+			}
+			if got == nil || got.ID != call.ID {
+				t.Fatalf("expected to reserve released call %s, got %+v", call.ID, got)
+			}
+
+			if err := aq.Release(ctx, "nope", got.Reservation, 0); err != models.ErrAsyncCallsNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrAsyncCallsNotFound, err)
+			}
+		})
+
+		t.Run("finish", func(t *testing.T) {
+			drainAsyncQueue(ctx, t, aq)
+
+			call, err := aq.Push(ctx, validAsyncCall())
+			if err != nil {
+				t.Fatalf("error when pushing perfectly good call: %s", err)
+			}
+			call, err = aq.Reserve(ctx, 10*time.Millisecond)
+			if err != nil {
+				t.Fatalf("unexpected error reserving call: %v", err)
+			}
+
+			call.Status = models.AsyncCallStatusRunning
+			if _, err := aq.Finish(ctx, call); err != models.ErrAsyncCallsInvalidStatus {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrAsyncCallsInvalidStatus, err)
+			}
+
+			call.Status = models.AsyncCallStatusSucceeded
+			call.ResultStatus = 200
+			call.ResultHeaders = models.Headers{"Content-Type": []string{"text/plain"}}
+			call.Result = "hello"
+			if _, err := aq.Finish(ctx, call); err != nil {
+				t.Fatalf("unexpected error finishing call: %v", err)
+			}
+
+			got, err := aq.GetAsyncCall(ctx, call.ID)
+			if err != nil {
+				t.Fatalf("unexpected error getting call: %v", err)
+			}
+			if got.Status != models.AsyncCallStatusSucceeded || got.ResultStatus != 200 || got.Result != "hello" ||
+				http.Header(got.ResultHeaders).Get("Content-Type") != "text/plain" {
+				t.Fatalf("expected a finished call with its result, got %+v", got)
+			}
+
+			// a finished call is not run again, even once its visibility timeout is over
+			time.Sleep(20 * time.Millisecond)
+			again, err := aq.Reserve(ctx, time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error reserving call: %v", err)
+			}
+			if again != nil {
+				t.Fatalf("expected no visible call, got %+v", again)
+			}
+		})
+
+		t.Run("stale reservation", func(t *testing.T) {
+			drainAsyncQueue(ctx, t, aq)
+
+			if _, err := aq.Push(ctx, validAsyncCall()); err != nil {
+				t.Fatalf("error when pushing perfectly good call: %s", err)
+			}
+
+			// a worker whose visibility timeout is over while it still runs the call
+			slow, err := aq.Reserve(ctx, 10*time.Millisecond)
+			if err != nil || slow == nil {
+				t.Fatalf("expected to reserve a call, got %+v: %v", slow, err)
+			}
+			time.Sleep(20 * time.Millisecond)
+			other, err := aq.Reserve(ctx, time.Minute)
+			if err != nil || other == nil || other.ID != slow.ID {
+				t.Fatalf("expected to reserve the call again, got %+v: %v", other, err)
+			}
+			if other.Reservation == slow.Reservation {
+				t.Fatalf("expected a new reservation, got %q again", other.Reservation)
+			}
+
+			// the first worker gets to neither finish nor release it
+			slow.Status = models.AsyncCallStatusFailed
+			if _, err := aq.Finish(ctx, slow); err != models.ErrAsyncCallsNotReserved {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrAsyncCallsNotReserved, err)
+			}
+			if err := aq.Release(ctx, slow.ID, slow.Reservation, 0); err != models.ErrAsyncCallsNotReserved {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrAsyncCallsNotReserved, err)
+			}
+
+			other.Status = models.AsyncCallStatusSucceeded
+			if _, err := aq.Finish(ctx, other); err != nil {
+				t.Fatalf("unexpected error finishing call: %v", err)
+			}
+			got, err := aq.GetAsyncCall(ctx, other.ID)
+			if err != nil {
+				t.Fatalf("unexpected error getting call: %v", err)
+			}
+			if got.Status != models.AsyncCallStatusSucceeded {
+				t.Fatalf("expected the call to be finished by the worker that has it, got %s", got.Status)
+			}
+		})
+
+		t.Run("dead letters", func(t *testing.T) {
+			drainAsyncQueue(ctx, t, aq)
+
+			call := validAsyncCall()
+			call.FnID = id.New().String()
+			if _, err := aq.Push(ctx, call); err != nil {
+				t.Fatalf("error when pushing perfectly good call: %s", err)
+			}
+			if _, err := aq.Push(ctx, validAsyncCall()); err != nil {
+				t.Fatalf("error when pushing perfectly good call: %s", err)
+			}
+
+			reserved, err := aq.Reserve(ctx, time.Minute)
+			if err != nil || reserved == nil {
+				t.Fatalf("expected to reserve a call, got %+v: %v", reserved, err)
+			}
+			reserved.Status = models.AsyncCallStatusDead
+			reserved.Error = "gave up"
+			if _, err := aq.Finish(ctx, reserved); err != nil {
+				t.Fatalf("unexpected error finishing call: %v", err)
+			}
+
+			list, err := aq.GetAsyncCalls(ctx, &models.AsyncCallFilter{FnID: call.FnID, Status: models.AsyncCallStatusDead})
+			if err != nil {
+				t.Fatalf("unexpected error listing calls: %v", err)
+			}
+			if len(list.Items) != 1 || list.Items[0].ID != call.ID || list.Items[0].Error != "gave up" {
+				t.Fatalf("expected the dead call in the list, got %+v", list.Items)
+			}
+
+			list, err = aq.GetAsyncCalls(ctx, &models.AsyncCallFilter{Status: models.AsyncCallStatusQueued})
+			if err != nil {
+				t.Fatalf("unexpected error listing calls: %v", err)
+			}
+			if len(list.Items) != 1 {
+				t.Fatalf("expected one queued call, got %+v", list.Items)
+			}
+		})
+
+		t.Run("paging", func(t *testing.T) {
+			fnID := id.New().String()
+			for i := 0; i < 3; i++ {
+				call := validAsyncCall()
+				call.FnID = fnID
+				if _, err := aq.Push(ctx, call); err != nil {
+					t.Fatalf("error when pushing perfectly good call: %s", err)
+				}
+			}
+
+			filter := &models.AsyncCallFilter{FnID: fnID, PerPage: 2}
+			list, err := aq.GetAsyncCalls(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error listing calls: %v", err)
+			}
+			if len(list.Items) != 2 || list.NextCursor == "" {
+				t.Fatalf("expected a full first page with a cursor, got %+v", list)
+			}
+
+			filter.Cursor = list.NextCursor
+			list, err = aq.GetAsyncCalls(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error listing calls: %v", err)
+			}
+			if len(list.Items) != 1 || list.NextCursor != "" {
+				t.Fatalf("expected the last call and no cursor, got %+v", list)
+			}
+		})
+	})
+}
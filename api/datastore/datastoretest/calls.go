@@ -0,0 +1,178 @@
+package datastoretest
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+// CallLogStore keeps both the records and the logs of calls
+type CallLogStore interface {
+	models.CallStore
+	models.LogStore
+}
+
+// CallLogStoreFunc provides an instance of a call and log store
+type CallLogStoreFunc func(*testing.T) CallLogStore
+
+func validCall(fnID string) *models.Call {
+	// stores keep times to the millisecond
+	now := time.Now().Truncate(time.Millisecond)
+	return &models.Call{
+		ID:          id.New().String(),
+		AppID:       "app",
+		FnID:        fnID,
+		Status:      "success",
+		Config:      models.Config{"SECRET": "shh"},
+		CreatedAt:   common.DateTime(now),
+		StartedAt:   common.DateTime(now.Add(time.Millisecond)),
+		CompletedAt: common.DateTime(now.Add(2 * time.Millisecond)),
+	}
+}
+
+// RunCallLogStoreTest runs the call and log store correctness tests
+func RunCallLogStoreTest(t *testing.T, sf CallLogStoreFunc) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	s := sf(t)
+	ctx := context.Background()
+
+	t.Run("Calls", func(t *testing.T) {
+
+		t.Run("insert without IDs", func(t *testing.T) {
+			call := validCall("fn")
+			call.ID = ""
+			if err := s.InsertCall(ctx, call); err != models.ErrDatastoreEmptyCallID {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrDatastoreEmptyCallID, err)
+			}
+			call = validCall("")
+			if err := s.InsertCall(ctx, call); err != models.ErrDatastoreEmptyFnID {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrDatastoreEmptyFnID, err)
+			}
+		})
+
+		t.Run("insert and get", func(t *testing.T) {
+			fnID := id.New().String()
+			call := validCall(fnID)
+			call.Status = "error"
+			call.Error = "boom"
+			if err := s.InsertCall(ctx, call); err != nil {
+				t.Fatalf("error when storing perfectly good call: %s", err)
+			}
+
+			got, err := s.GetCall(ctx, fnID, call.ID)
+			if err != nil {
+				t.Fatalf("unexpected error getting call: %v", err)
+			}
+			if got.ID != call.ID || got.AppID != call.AppID || got.Status != call.Status || got.Error != call.Error {
+				t.Fatalf("expected to get the right call:\n%+v\nbut got:\n%+v", call, got)
+			}
+			if !time.Time(got.CompletedAt).After(time.Time(got.StartedAt)) {
+				t.Fatalf("expected the call timings to be kept, got %+v", got)
+			}
+			if got.Config != nil {
+				t.Fatalf("expected the call config to not be kept, got %+v", got.Config)
+			}
+
+			if _, err := s.GetCall(ctx, "other", call.ID); err != models.ErrCallNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrCallNotFound, err)
+			}
+		})
+
+		t.Run("list", func(t *testing.T) {
+			fnID := id.New().String()
+			var calls []*models.Call
+			for i := 0; i < 3; i++ {
+				call := validCall(fnID)
+				call.CreatedAt = common.DateTime(time.Time(call.CreatedAt).Add(time.Duration(i) * time.Second))
+				if err := s.InsertCall(ctx, call); err != nil {
+					t.Fatalf("error when storing perfectly good call: %s", err)
+				}
+				calls = append(calls, call)
+			}
+			// another fn's
+			if err := s.InsertCall(ctx, validCall(id.New().String())); err != nil {
+				t.Fatalf("error when storing perfectly good call: %s", err)
+			}
+
+			if _, err := s.GetCalls(ctx, &models.CallFilter{}); err != models.ErrDatastoreEmptyFnID {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrDatastoreEmptyFnID, err)
+			}
+
+			filter := &models.CallFilter{FnID: fnID, PerPage: 2}
+			list, err := s.GetCalls(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error listing calls: %v", err)
+			}
+			if len(list.Items) != 2 || list.NextCursor == "" {
+				t.Fatalf("expected a full first page with a cursor, got %+v", list)
+			}
+			if list.Items[0].ID != calls[2].ID || list.Items[1].ID != calls[1].ID {
+				t.Fatalf("expected the newest calls first, got %s, %s", list.Items[0].ID, list.Items[1].ID)
+			}
+
+			filter.Cursor = list.NextCursor
+			list, err = s.GetCalls(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error listing calls: %v", err)
+			}
+			if len(list.Items) != 1 || list.Items[0].ID != calls[0].ID || list.NextCursor != "" {
+				t.Fatalf("expected the oldest call and no cursor, got %+v", list)
+			}
+
+			filter = &models.CallFilter{FnID: fnID, FromTime: calls[0].CreatedAt, ToTime: calls[2].CreatedAt}
+			list, err = s.GetCalls(ctx, filter)
+			if err != nil {
+				t.Fatalf("unexpected error listing calls: %v", err)
+			}
+			if len(list.Items) != 1 || list.Items[0].ID != calls[1].ID {
+				t.Fatalf("expected only the call between the times, got %+v", list.Items)
+			}
+		})
+	})
+
+	t.Run("Logs", func(t *testing.T) {
+		fnID := id.New().String()
+		call := validCall(fnID)
+
+		if _, err := s.GetLog(ctx, fnID, call.ID); err != models.ErrCallLogNotFound {
+			t.Fatalf("expected error `%v`, but it was `%v`", models.ErrCallLogNotFound, err)
+		}
+
+		if err := s.InsertLog(ctx, call, strings.NewReader("first line\n")); err != nil {
+			t.Fatalf("unexpected error inserting log: %v", err)
+		}
+		// overwrites
+		if err := s.InsertLog(ctx, call, bytes.NewBufferString("line one\nline two\n")); err != nil {
+			t.Fatalf("unexpected error inserting log: %v", err)
+		}
+
+		r, err := s.GetLog(ctx, fnID, call.ID)
+		if err != nil {
+			t.Fatalf("unexpected error getting log: %v", err)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("unexpected error reading log: %v", err)
+		}
+		if string(b) != "line one\nline two\n" {
+			t.Fatalf("expected the last log inserted, got %q", b)
+		}
+
+		if _, err := s.GetLog(ctx, "other", call.ID); err != models.ErrCallLogNotFound {
+			t.Fatalf("expected error `%v`, but it was `%v`", models.ErrCallLogNotFound, err)
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package datastoretest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// ExecutionStoreFunc provides an instance of an execution store
+type ExecutionStoreFunc func(*testing.T) models.ExecutionStore
+
+func validExecution() *models.Execution {
+	return &models.Execution{
+		StateMachine: &models.StateMachine{
+			StartAt: "start",
+			States: map[string]*models.State{
+				"start": {Type: models.StateTypeTask, AppName: "app", FuncName: "/fn", End: true},
+			},
+		},
+		Status:       models.ExecutionStatusRunning,
+		CurrentState: "start",
+		Input:        `{"hello":"world"}`,
+	}
+}
+
+// RunExecutionsTest runs the execution store correctness tests
+func RunExecutionsTest(t *testing.T, esf ExecutionStoreFunc) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	es := esf(t)
+	ctx := context.Background()
+
+	t.Run("Executions", func(t *testing.T) {
+
+		t.Run("insert with ID", func(t *testing.T) {
+			exec := validExecution()
+			exec.ID = "abc"
+			_, err := es.InsertExecution(ctx, exec)
+			if err != models.ErrExecutionIDProvided {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionIDProvided, err)
+			}
+		})
+
+		t.Run("insert without state machine", func(t *testing.T) {
+			exec := validExecution()
+			exec.StateMachine = nil
+			_, err := es.InsertExecution(ctx, exec)
+			if err != models.ErrExecutionsMissingStateMachine {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsMissingStateMachine, err)
+			}
+		})
+
+		t.Run("insert and get", func(t *testing.T) {
+			exec, err := es.InsertExecution(ctx, validExecution())
+			if err != nil {
+				t.Fatalf("error when storing perfectly good execution: %s", err)
+			}
+			if exec.ID == "" {
+				t.Fatal("expected execution to be assigned an ID")
+			}
+			if time.Time(exec.CreatedAt).IsZero() {
+				t.Fatal("expected execution to have a creation time")
+			}
+
+			got, err := es.GetExecutionByID(ctx, exec.ID)
+			if err != nil {
+				t.Fatalf("unexpected error getting execution: %v", err)
+			}
+			if got.Status != exec.Status || got.Input != exec.Input || got.CurrentState != exec.CurrentState {
+				t.Fatalf("expected to get the right execution:\n%+v\nbut got:\n%+v", exec, got)
+			}
+			if got.StateMachine == nil || got.StateMachine.StartAt != "start" || got.StateMachine.States["start"] == nil {
+				t.Fatalf("expected state machine to round trip, got %+v", got.StateMachine)
+			}
+		})
+
+		t.Run("get missing", func(t *testing.T) {
+			_, err := es.GetExecutionByID(ctx, "")
+			if err != models.ErrExecutionsMissingID {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsMissingID, err)
+			}
+			_, err = es.GetExecutionByID(ctx, "notreal")
+			if err != models.ErrExecutionsNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsNotFound, err)
+			}
+		})
+
+		t.Run("update", func(t *testing.T) {
+			exec, err := es.InsertExecution(ctx, validExecution())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			exec.Status = models.ExecutionStatusSucceeded
+			exec.CurrentState = ""
+			exec.Output = "done"
+			exec.Input = "ignored"
+			updated, err := es.UpdateExecution(ctx, exec)
+			if err != nil {
+				t.Fatalf("unexpected error updating execution: %v", err)
+			}
+			if updated.Status != models.ExecutionStatusSucceeded || updated.Output != "done" || updated.CurrentState != "" {
+				t.Fatalf("expected execution to be updated, got %+v", updated)
+			}
+			if updated.Input == "ignored" {
+				t.Fatal("expected input to be immutable")
+			}
+
+			exec.Status = "bogus"
+			_, err = es.UpdateExecution(ctx, exec)
+			if err != models.ErrExecutionsInvalidStatus {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsInvalidStatus, err)
+			}
+
+			_, err = es.UpdateExecution(ctx, &models.Execution{ID: "notreal", Status: models.ExecutionStatusFailed})
+			if err != models.ErrExecutionsNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsNotFound, err)
+			}
+		})
+
+		t.Run("list by status with paging", func(t *testing.T) {
+			var ids []string
+			for i := 0; i < 3; i++ {
+				exec := validExecution()
+				exec.Status = models.ExecutionStatusFailed
+				exec, err := es.InsertExecution(ctx, exec)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				ids = append(ids, exec.ID)
+			}
+
+			list, err := es.GetExecutions(ctx, &models.ExecutionFilter{Status: models.ExecutionStatusFailed, PerPage: 2})
+			if err != nil {
+				t.Fatalf("unexpected error listing executions: %v", err)
+			}
+			if len(list.Items) != 2 || list.NextCursor == "" {
+				t.Fatalf("expected a page of 2 executions with a cursor, got %d (cursor %q)", len(list.Items), list.NextCursor)
+			}
+
+			list2, err := es.GetExecutions(ctx, &models.ExecutionFilter{Status: models.ExecutionStatusFailed, PerPage: 2, Cursor: list.NextCursor})
+			if err != nil {
+				t.Fatalf("unexpected error listing executions: %v", err)
+			}
+			if len(list2.Items) != 1 || list2.Items[0].ID != ids[2] {
+				t.Fatalf("expected last execution on the second page, got %+v", list2.Items)
+			}
+			for _, e := range append(list.Items, list2.Items...) {
+				if e.Status != models.ExecutionStatusFailed {
+					t.Fatalf("expected only failed executions, got %+v", e)
+				}
+			}
+		})
+
+		t.Run("states", func(t *testing.T) {
+			exec, err := es.InsertExecution(ctx, validExecution())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = es.InsertExecutionState(ctx, &models.ExecutionState{ExecutionID: "notreal", Name: "start", Status: models.ExecutionStatusSucceeded})
+			if err != models.ErrExecutionsNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsNotFound, err)
+			}
+
+			_, err = es.InsertExecutionState(ctx, &models.ExecutionState{ExecutionID: exec.ID, Status: models.ExecutionStatusSucceeded})
+			if err != models.ErrExecutionsMissingStateName {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsMissingStateName, err)
+			}
+
+			for i, name := range []string{"first", "second"} {
+				st, err := es.InsertExecutionState(ctx, &models.ExecutionState{
+					ExecutionID: exec.ID,
+					Name:        name,
+					Type:        models.StateTypeTask,
+					Status:      models.ExecutionStatusSucceeded,
+					Input:       "in",
+					Output:      name,
+					StartedAt:   common.DateTime(time.Now()),
+					CompletedAt: common.DateTime(time.Now()),
+				})
+				if err != nil {
+					t.Fatalf("unexpected error inserting state: %v", err)
+				}
+				if st.Seq != int64(i+1) {
+					t.Fatalf("expected state seq %d, got %d", i+1, st.Seq)
+				}
+			}
+
+			states, err := es.GetExecutionStates(ctx, exec.ID)
+			if err != nil {
+				t.Fatalf("unexpected error getting states: %v", err)
+			}
+			if len(states) != 2 || states[0].Name != "first" || states[1].Output != "second" {
+				t.Fatalf("expected states in insertion order, got %+v", states)
+			}
+		})
+
+		t.Run("advance", func(t *testing.T) {
+			exec, err := es.InsertExecution(ctx, validExecution())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			exec.CurrentState = "second"
+			exec.StateInput = "first out"
+			state := &models.ExecutionState{
+				ExecutionID: exec.ID,
+				Name:        "start",
+				Type:        models.StateTypeTask,
+				Status:      models.ExecutionStatusSucceeded,
+				Input:       exec.Input,
+				Output:      "first out",
+				StartedAt:   common.DateTime(time.Now()),
+				CompletedAt: common.DateTime(time.Now()),
+			}
+			_, err = es.AdvanceExecution(ctx, exec, state)
+			if err != nil {
+				t.Fatalf("unexpected error advancing execution: %v", err)
+			}
+
+			got, err := es.GetExecutionByID(ctx, exec.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.CurrentState != "second" || got.StateInput != "first out" {
+				t.Fatalf("expected execution to be at the second state with the first output, got %+v", got)
+			}
+			states, err := es.GetExecutionStates(ctx, exec.ID)
+			if err != nil {
+				t.Fatalf("unexpected error getting states: %v", err)
+			}
+			if len(states) != 1 || states[0].Seq != 1 || states[0].Output != "first out" {
+				t.Fatalf("expected the state to be recorded with the execution, got %+v", states)
+			}
+
+			// a node that does not own the execution writes neither
+			stale := got.Clone()
+			stale.Owner = "someone else"
+			stale.CurrentState = "third"
+			_, err = es.AdvanceExecution(ctx, stale, state)
+			if err != models.ErrExecutionsLeaseLost {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsLeaseLost, err)
+			}
+			states, err = es.GetExecutionStates(ctx, exec.ID)
+			if err != nil {
+				t.Fatalf("unexpected error getting states: %v", err)
+			}
+			if len(states) != 1 {
+				t.Fatalf("expected no state to be recorded by a node without the lease, got %+v", states)
+			}
+		})
+
+		t.Run("claim", func(t *testing.T) {
+			exec, err := es.InsertExecution(ctx, validExecution())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = es.ClaimExecution(ctx, "notreal", "a", time.Minute)
+			if err != models.ErrExecutionsNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsNotFound, err)
+			}
+
+			claimed, err := es.ClaimExecution(ctx, exec.ID, "a", time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error claiming execution: %v", err)
+			}
+			if claimed == nil || claimed.Owner != "a" {
+				t.Fatalf("expected execution to be claimed by a, got %+v", claimed)
+			}
+
+			claimed, err = es.ClaimExecution(ctx, exec.ID, "b", time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error claiming execution: %v", err)
+			}
+			if claimed != nil {
+				t.Fatalf("expected execution leased by a not to be claimed, got %+v", claimed)
+			}
+
+			if err := es.RenewExecution(ctx, exec.ID, "b", time.Minute); err != models.ErrExecutionsLeaseLost {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrExecutionsLeaseLost, err)
+			}
+			// a lease that is over may be claimed by another
+			if err := es.RenewExecution(ctx, exec.ID, "a", -time.Second); err != nil {
+				t.Fatalf("unexpected error renewing lease: %v", err)
+			}
+			claimed, err = es.ClaimExecution(ctx, exec.ID, "b", time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error claiming execution: %v", err)
+			}
+			if claimed == nil || claimed.Owner != "b" {
+				t.Fatalf("expected execution with an expired lease to be claimed by b, got %+v", claimed)
+			}
+
+			exec.Owner = "b"
+			exec.Status = models.ExecutionStatusSucceeded
+			exec.CurrentState = ""
+			_, err = es.AdvanceExecution(ctx, exec, nil)
+			if err != nil {
+				t.Fatalf("unexpected error finishing execution: %v", err)
+			}
+			claimed, err = es.ClaimExecution(ctx, exec.ID, "a", time.Minute)
+			if err != nil || claimed != nil {
+				t.Fatalf("expected a finished execution not to be claimed, got %+v, %v", claimed, err)
+			}
+		})
+	})
+}
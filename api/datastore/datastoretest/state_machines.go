@@ -0,0 +1,227 @@
+package datastoretest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func validStoredStateMachine(appID, name string) *models.StoredStateMachine {
+	return &models.StoredStateMachine{
+		AppID: appID,
+		Name:  name,
+		Definition: &models.StateMachine{
+			StartAt: "start",
+			States: map[string]*models.State{
+				"start": {Type: models.StateTypeTask, AppName: "app", FuncName: "/fn", End: true},
+			},
+		},
+	}
+}
+
+func (h *Harness) GivenStateMachineInDb(sm *models.StoredStateMachine) *models.StoredStateMachine {
+	stored, err := h.ds.InsertStateMachine(h.ctx, sm)
+	if err != nil {
+		h.t.Fatalf("Failed to insert state machine %s", err)
+		return nil
+	}
+	return stored
+}
+
+// RunStateMachinesTest runs the stored state machine and statemachine trigger correctness tests
+func RunStateMachinesTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("state_machines", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("insert invalid app ID", func(t *testing.T) {
+			_, err := ds.InsertStateMachine(ctx, validStoredStateMachine("notreal", "sm"))
+			if err != models.ErrAppsNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrAppsNotFound, err)
+			}
+		})
+
+		t.Run("insert with version", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			sm := validStoredStateMachine(testApp.ID, "sm")
+			sm.Version = 3
+			_, err := ds.InsertStateMachine(ctx, sm)
+			if err != models.ErrStateMachinesVersionProvided {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrStateMachinesVersionProvided, err)
+			}
+		})
+
+		t.Run("insert invalid definition", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			sm := validStoredStateMachine(testApp.ID, "sm")
+			sm.Definition.StartAt = "nowhere"
+			_, err := ds.InsertStateMachine(ctx, sm)
+			if models.GetAPIErrorCode(err) != 400 {
+				t.Fatalf("expected a bad request error, but it was `%v`", err)
+			}
+		})
+
+		t.Run("insert adds versions", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			first := h.GivenStateMachineInDb(validStoredStateMachine(testApp.ID, "sm"))
+			if first.ID == "" || first.Version != 1 || time.Time(first.CreatedAt).IsZero() {
+				t.Fatalf("expected first version with an ID and creation time, got %#v", first)
+			}
+
+			next := validStoredStateMachine(testApp.ID, "sm")
+			next.Definition.Comment = "second"
+			second := h.GivenStateMachineInDb(next)
+			if second.Version != 2 || second.ID == first.ID {
+				t.Fatalf("expected a distinct second version, got %#v", second)
+			}
+
+			latest, err := ds.GetStateMachine(ctx, testApp.ID, "sm", 0)
+			if err != nil {
+				t.Fatalf("unexpected error getting latest version: %v", err)
+			}
+			if latest.Version != 2 || latest.Definition == nil || latest.Definition.Comment != "second" {
+				t.Fatalf("expected to get the second version as latest, got %#v", latest)
+			}
+
+			old, err := ds.GetStateMachine(ctx, testApp.ID, "sm", 1)
+			if err != nil {
+				t.Fatalf("unexpected error getting first version: %v", err)
+			}
+			if old.ID != first.ID || old.Definition.StartAt != "start" {
+				t.Fatalf("expected to get the first version, got %#v", old)
+			}
+
+			_, err = ds.GetStateMachine(ctx, testApp.ID, "sm", 3)
+			if err != models.ErrStateMachinesNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrStateMachinesNotFound, err)
+			}
+		})
+
+		t.Run("list latest versions and all versions", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			for _, name := range []string{"b", "a", "c", "a"} {
+				h.GivenStateMachineInDb(validStoredStateMachine(testApp.ID, name))
+			}
+
+			list, err := ds.GetStateMachines(ctx, &models.StateMachineFilter{AppID: testApp.ID, PerPage: 2})
+			if err != nil {
+				t.Fatalf("unexpected error listing state machines: %v", err)
+			}
+			if len(list.Items) != 2 || list.Items[0].Name != "a" || list.Items[0].Version != 2 || list.Items[1].Name != "b" {
+				t.Fatalf("expected latest a then b, got %#v", list.Items)
+			}
+			if list.NextCursor == "" {
+				t.Fatal("expected a cursor for the next page")
+			}
+
+			list, err = ds.GetStateMachines(ctx, &models.StateMachineFilter{AppID: testApp.ID, PerPage: 2, Cursor: list.NextCursor})
+			if err != nil {
+				t.Fatalf("unexpected error listing state machines: %v", err)
+			}
+			if len(list.Items) != 1 || list.Items[0].Name != "c" {
+				t.Fatalf("expected c on the second page, got %#v", list.Items)
+			}
+
+			list, err = ds.GetStateMachines(ctx, &models.StateMachineFilter{AppID: testApp.ID, Name: "a", PerPage: 1})
+			if err != nil {
+				t.Fatalf("unexpected error listing versions: %v", err)
+			}
+			if len(list.Items) != 1 || list.Items[0].Version != 2 {
+				t.Fatalf("expected version 2 first, got %#v", list.Items)
+			}
+			list, err = ds.GetStateMachines(ctx, &models.StateMachineFilter{AppID: testApp.ID, Name: "a", PerPage: 1, Cursor: list.NextCursor})
+			if err != nil {
+				t.Fatalf("unexpected error listing versions: %v", err)
+			}
+			if len(list.Items) != 1 || list.Items[0].Version != 1 {
+				t.Fatalf("expected version 1 second, got %#v", list.Items)
+			}
+		})
+
+		t.Run("remove", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			h.GivenStateMachineInDb(validStoredStateMachine(testApp.ID, "sm"))
+			h.GivenStateMachineInDb(validStoredStateMachine(testApp.ID, "sm"))
+
+			err := ds.RemoveStateMachine(ctx, testApp.ID, "sm")
+			if err != nil {
+				t.Fatalf("unexpected error removing state machine: %v", err)
+			}
+			_, err = ds.GetStateMachine(ctx, testApp.ID, "sm", 1)
+			if err != models.ErrStateMachinesNotFound {
+				t.Fatalf("expected every version to be removed, got `%v`", err)
+			}
+			err = ds.RemoveStateMachine(ctx, testApp.ID, "sm")
+			if err != models.ErrStateMachinesNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrStateMachinesNotFound, err)
+			}
+		})
+
+		t.Run("statemachine trigger", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			trigger := &models.Trigger{
+				Name:         fmt.Sprintf("sm_trigger_%09d", time.Now().Nanosecond()),
+				AppID:        testApp.ID,
+				Type:         models.TriggerTypeStateMachine,
+				Source:       "/workflow",
+				StateMachine: "sm",
+			}
+			_, err := ds.InsertTrigger(ctx, trigger)
+			if err != models.ErrStateMachinesNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrStateMachinesNotFound, err)
+			}
+
+			h.GivenStateMachineInDb(validStoredStateMachine(testApp.ID, "sm"))
+			created := h.GivenTriggerInDb(trigger)
+
+			got, err := ds.GetTriggerBySource(ctx, testApp.ID, models.TriggerTypeStateMachine, "/workflow")
+			if err != nil {
+				t.Fatalf("unexpected error getting trigger: %v", err)
+			}
+			if !got.Equals(created) || got.StateMachine != "sm" {
+				t.Fatalf("expected trigger %#v, got %#v", created, got)
+			}
+
+			err = ds.RemoveStateMachine(ctx, testApp.ID, "sm")
+			if err != nil {
+				t.Fatalf("unexpected error removing state machine: %v", err)
+			}
+			_, err = ds.GetTriggerBySource(ctx, testApp.ID, models.TriggerTypeStateMachine, "/workflow")
+			if err != models.ErrTriggerNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrTriggerNotFound, err)
+			}
+		})
+
+		t.Run("remove app removes state machines", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			h.GivenStateMachineInDb(validStoredStateMachine(testApp.ID, "sm"))
+
+			err := ds.RemoveApp(ctx, testApp.ID)
+			if err != nil {
+				t.Fatalf("expecting no error, got %s", err)
+			}
+			_, err = ds.GetStateMachine(ctx, testApp.ID, "sm", 0)
+			if err != models.ErrStateMachinesNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrStateMachinesNotFound, err)
+			}
+		})
+	})
+}
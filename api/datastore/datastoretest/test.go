@@ -1233,5 +1233,6 @@ func RunAllTests(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 	RunFnsTest(t, dsf, rp)
 	RunTriggersTest(t, dsf, rp)
 	RunTriggerBySourceTests(t, dsf, rp)
+	RunStateMachinesTest(t, dsf, rp)
 
 }
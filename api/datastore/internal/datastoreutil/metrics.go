@@ -118,6 +118,30 @@ func (m *metricds) RemoveFn(ctx context.Context, fnID string) error {
 	return m.ds.RemoveFn(ctx, fnID)
 }
 
+func (m *metricds) InsertStateMachine(ctx context.Context, sm *models.StoredStateMachine) (*models.StoredStateMachine, error) {
+	ctx, span := trace.StartSpan(ctx, "ds_insert_state_machine")
+	defer span.End()
+	return m.ds.InsertStateMachine(ctx, sm)
+}
+
+func (m *metricds) GetStateMachine(ctx context.Context, appID, name string, version int64) (*models.StoredStateMachine, error) {
+	ctx, span := trace.StartSpan(ctx, "ds_get_state_machine")
+	defer span.End()
+	return m.ds.GetStateMachine(ctx, appID, name, version)
+}
+
+func (m *metricds) GetStateMachines(ctx context.Context, filter *models.StateMachineFilter) (*models.StateMachineList, error) {
+	ctx, span := trace.StartSpan(ctx, "ds_get_state_machines")
+	defer span.End()
+	return m.ds.GetStateMachines(ctx, filter)
+}
+
+func (m *metricds) RemoveStateMachine(ctx context.Context, appID, name string) error {
+	ctx, span := trace.StartSpan(ctx, "ds_remove_state_machine")
+	defer span.End()
+	return m.ds.RemoveStateMachine(ctx, appID, name)
+}
+
 // Close calls Close on the underlying Datastore
 func (m *metricds) Close() error {
 	return m.ds.Close()
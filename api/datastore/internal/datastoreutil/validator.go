@@ -138,3 +138,46 @@ func (v *validator) RemoveFn(ctx context.Context, fnID string) error {
 	}
 	return v.Datastore.RemoveFn(ctx, fnID)
 }
+
+func (v *validator) InsertStateMachine(ctx context.Context, sm *models.StoredStateMachine) (*models.StoredStateMachine, error) {
+	if sm == nil {
+		return nil, models.ErrStateMachinesMissingDefinition
+	}
+	if sm.ID != "" {
+		return nil, models.ErrStateMachinesIDProvided
+	}
+	if sm.Version != 0 {
+		return nil, models.ErrStateMachinesVersionProvided
+	}
+	if !time.Time(sm.CreatedAt).IsZero() {
+		return nil, models.ErrCreatedAtProvided
+	}
+	return v.Datastore.InsertStateMachine(ctx, sm)
+}
+
+func (v *validator) GetStateMachine(ctx context.Context, appID, name string, version int64) (*models.StoredStateMachine, error) {
+	if appID == "" {
+		return nil, models.ErrStateMachinesMissingAppID
+	}
+	if name == "" {
+		return nil, models.ErrStateMachinesMissingName
+	}
+	return v.Datastore.GetStateMachine(ctx, appID, name, version)
+}
+
+func (v *validator) GetStateMachines(ctx context.Context, filter *models.StateMachineFilter) (*models.StateMachineList, error) {
+	if filter.AppID == "" {
+		return nil, models.ErrStateMachinesMissingAppID
+	}
+	return v.Datastore.GetStateMachines(ctx, filter)
+}
+
+func (v *validator) RemoveStateMachine(ctx context.Context, appID, name string) error {
+	if appID == "" {
+		return models.ErrStateMachinesMissingAppID
+	}
+	if name == "" {
+		return models.ErrStateMachinesMissingName
+	}
+	return v.Datastore.RemoveStateMachine(ctx, appID, name)
+}
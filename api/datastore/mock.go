@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,6 +19,8 @@ type mock struct {
 	Apps     []*models.App
 	Fns      []*models.Fn
 	Triggers []*models.Trigger
+
+	StateMachines []*models.StoredStateMachine
 }
 
 // NewMock creates a new mock datastore
@@ -48,6 +51,8 @@ func NewMockInit(args ...interface{}) models.Datastore {
 			mocker.Fns = x
 		case []*models.Trigger:
 			mocker.Triggers = x
+		case []*models.StoredStateMachine:
+			mocker.StateMachines = x
 
 		default:
 			panic("not accounted for data type sent to mock init. add it")
@@ -179,9 +184,17 @@ func (m *mock) RemoveApp(ctx context.Context, appID string) error {
 				}
 			}
 
+			var newStateMachines []*models.StoredStateMachine
+			for _, sm := range m.StateMachines {
+				if sm.AppID != appID {
+					newStateMachines = append(newStateMachines, sm)
+				}
+			}
+
 			m.Apps = newApps
 			m.Triggers = newTriggers
 			m.Fns = newFns
+			m.StateMachines = newStateMachines
 			return nil
 
 		}
@@ -314,13 +327,20 @@ func (m *mock) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*mod
 	if err != nil {
 		return nil, err
 	}
-	fn, err := m.GetFnByID(ctx, trigger.FnID)
-	if err != nil {
-		return nil, err
-	}
+	if trigger.Type == models.TriggerTypeStateMachine {
+		_, err := m.GetStateMachine(ctx, trigger.AppID, trigger.StateMachine, 0)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		fn, err := m.GetFnByID(ctx, trigger.FnID)
+		if err != nil {
+			return nil, err
+		}
 
-	if fn.AppID != trigger.AppID {
-		return nil, models.ErrTriggerFnIDNotSameApp
+		if fn.AppID != trigger.AppID {
+			return nil, models.ErrTriggerFnIDNotSameApp
+		}
 	}
 
 	for _, t := range m.Triggers {
@@ -360,6 +380,12 @@ func (m *mock) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*mod
 			if err != nil {
 				return nil, err
 			}
+			if cl.Type == models.TriggerTypeStateMachine {
+				_, err := m.GetStateMachine(ctx, cl.AppID, cl.StateMachine, 0)
+				if err != nil {
+					return nil, err
+				}
+			}
 			*t = *cl
 			return cl.Clone(), nil
 		}
@@ -452,6 +478,140 @@ func (m *mock) RemoveTrigger(ctx context.Context, triggerID string) error {
 	return models.ErrTriggerNotFound
 }
 
+func (m *mock) InsertStateMachine(ctx context.Context, sm *models.StoredStateMachine) (*models.StoredStateMachine, error) {
+	err := sm.Validate()
+	if err != nil {
+		return nil, err
+	}
+	_, err = m.GetAppByID(ctx, sm.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := sm.Clone()
+	cl.ID = id.New().String()
+	cl.CreatedAt = common.DateTime(time.Now())
+	cl.Version = 1
+	if latest, err := m.GetStateMachine(ctx, sm.AppID, sm.Name, 0); err == nil {
+		cl.Version = latest.Version + 1
+	}
+
+	m.StateMachines = append(m.StateMachines, cl)
+	return cl.Clone(), nil
+}
+
+func (m *mock) GetStateMachine(ctx context.Context, appID, name string, version int64) (*models.StoredStateMachine, error) {
+	var found *models.StoredStateMachine
+	for _, sm := range m.StateMachines {
+		if sm.AppID != appID || sm.Name != name {
+			continue
+		}
+		if version == 0 && (found == nil || sm.Version > found.Version) || sm.Version == version {
+			found = sm
+		}
+	}
+	if found == nil {
+		return nil, models.ErrStateMachinesNotFound
+	}
+	return found.Clone(), nil
+}
+
+type sortSM []*models.StoredStateMachine
+
+func (s sortSM) Len() int { return len(s) }
+func (s sortSM) Less(i, j int) bool {
+	if s[i].Name != s[j].Name {
+		return strings.Compare(s[i].Name, s[j].Name) < 0
+	}
+	return s[i].Version > s[j].Version
+}
+func (s sortSM) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (m *mock) GetStateMachines(ctx context.Context, filter *models.StateMachineFilter) (*models.StateMachineList, error) {
+	// by name, then latest version first
+	sort.Sort(sortSM(m.StateMachines))
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+	var cursorVersion int64
+	if cursor != "" && filter.Name != "" {
+		v, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		cursorVersion = v
+	}
+
+	res := []*models.StoredStateMachine{}
+	var lastName string
+	for _, sm := range m.StateMachines {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if sm.AppID != filter.AppID {
+			continue
+		}
+		if filter.Name != "" {
+			if sm.Name != filter.Name {
+				continue
+			}
+			if cursor != "" && sm.Version >= cursorVersion {
+				continue
+			}
+		} else {
+			if sm.Name == lastName || (cursor != "" && sm.Name <= cursor) {
+				lastName = sm.Name
+				continue
+			}
+			lastName = sm.Name
+		}
+		res = append(res, sm.Clone())
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := res[len(res)-1]
+		c := last.Name
+		if filter.Name != "" {
+			c = strconv.FormatInt(last.Version, 10)
+		}
+		nextCursor = base64.RawURLEncoding.EncodeToString([]byte(c))
+	}
+
+	return &models.StateMachineList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) RemoveStateMachine(ctx context.Context, appID, name string) error {
+	var kept []*models.StoredStateMachine
+	for _, sm := range m.StateMachines {
+		if sm.AppID != appID || sm.Name != name {
+			kept = append(kept, sm)
+		}
+	}
+	if len(kept) == len(m.StateMachines) {
+		return models.ErrStateMachinesNotFound
+	}
+	m.StateMachines = kept
+
+	var newTriggers []*models.Trigger
+	for _, t := range m.Triggers {
+		if t.AppID != appID || t.Type != models.TriggerTypeStateMachine || t.StateMachine != name {
+			newTriggers = append(newTriggers, t)
+		}
+	}
+	m.Triggers = newTriggers
+	return nil
+}
+
 func (m *mock) Close() error {
 	return nil
 }
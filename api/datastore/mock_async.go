@@ -0,0 +1,189 @@
+package datastore
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+type mockAsyncQueue struct {
+	lock  sync.Mutex
+	Calls []*models.AsyncCall
+}
+
+var _ models.AsyncQueue = &mockAsyncQueue{}
+
+// NewMockAsyncQueue creates a new in-memory async queue. It is safe for
+// concurrent use, since calls are reserved by many workers at once.
+func NewMockAsyncQueue() models.AsyncQueue {
+	return &mockAsyncQueue{}
+}
+
+func (m *mockAsyncQueue) Push(ctx context.Context, call *models.AsyncCall) (*models.AsyncCall, error) {
+	cl := call.Clone()
+	cl.Status = models.AsyncCallStatusQueued
+	err := cl.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, c := range m.Calls {
+		if c.ID == cl.ID {
+			return nil, models.ErrAsyncCallsAlreadyExists
+		}
+	}
+
+	cl.Attempts = 0
+	cl.VisibleAt = 0
+	cl.Reservation = ""
+	cl.CreatedAt = common.DateTime(time.Now())
+	cl.UpdatedAt = cl.CreatedAt
+
+	m.Calls = append(m.Calls, cl)
+	sort.Sort(sortAC(m.Calls))
+	return cl.Clone(), nil
+}
+
+func (m *mockAsyncQueue) Reserve(ctx context.Context, visibility time.Duration) (*models.AsyncCall, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	for _, c := range m.Calls {
+		if c.Done() || c.VisibleAt > common.UnixMilli(now) {
+			continue
+		}
+		c.Status = models.AsyncCallStatusRunning
+		c.Attempts++
+		c.VisibleAt = common.UnixMilli(now.Add(visibility))
+		c.Reservation = id.New().String()
+		c.UpdatedAt = common.DateTime(now)
+		return c.Clone(), nil
+	}
+	return nil, nil
+}
+
+func (m *mockAsyncQueue) Release(ctx context.Context, callID, reservation string, delay time.Duration) error {
+	if callID == "" {
+		return models.ErrAsyncCallsMissingID
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, c := range m.Calls {
+		if c.ID == callID {
+			if c.Status != models.AsyncCallStatusRunning || c.Reservation != reservation {
+				return models.ErrAsyncCallsNotReserved
+			}
+			now := time.Now()
+			c.Status = models.AsyncCallStatusQueued
+			c.VisibleAt = common.UnixMilli(now.Add(delay))
+			c.Reservation = ""
+			c.UpdatedAt = common.DateTime(now)
+			return nil
+		}
+	}
+	return models.ErrAsyncCallsNotFound
+}
+
+func (m *mockAsyncQueue) Finish(ctx context.Context, call *models.AsyncCall) (*models.AsyncCall, error) {
+	if call.ID == "" {
+		return nil, models.ErrAsyncCallsMissingID
+	}
+	if !call.Done() {
+		return nil, models.ErrAsyncCallsInvalidStatus
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, c := range m.Calls {
+		if c.ID == call.ID {
+			if call.Reservation == "" || c.Reservation != call.Reservation || c.Done() {
+				return nil, models.ErrAsyncCallsNotReserved
+			}
+			c.Status = call.Status
+			c.ResultStatus = call.ResultStatus
+			c.ResultHeaders = call.Clone().ResultHeaders
+			c.Result = call.Result
+			c.Error = call.Error
+			c.UpdatedAt = common.DateTime(time.Now())
+			return c.Clone(), nil
+		}
+	}
+	return nil, models.ErrAsyncCallsNotFound
+}
+
+func (m *mockAsyncQueue) GetAsyncCall(ctx context.Context, callID string) (*models.AsyncCall, error) {
+	if callID == "" {
+		return nil, models.ErrAsyncCallsMissingID
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, c := range m.Calls {
+		if c.ID == callID {
+			return c.Clone(), nil
+		}
+	}
+	return nil, models.ErrAsyncCallsNotFound
+}
+
+type sortAC []*models.AsyncCall
+
+func (s sortAC) Len() int           { return len(s) }
+func (s sortAC) Less(i, j int) bool { return strings.Compare(s[i].ID, s[j].ID) < 0 }
+func (s sortAC) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (m *mockAsyncQueue) GetAsyncCalls(ctx context.Context, filter *models.AsyncCallFilter) (*models.AsyncCallList, error) {
+	if filter == nil {
+		filter = new(models.AsyncCallFilter)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	res := []*models.AsyncCall{}
+	for _, c := range m.Calls {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if strings.Compare(cursor, c.ID) < 0 &&
+			(filter.FnID == "" || filter.FnID == c.FnID) &&
+			(filter.Status == "" || filter.Status == c.Status) {
+			res = append(res, c.Clone())
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.AsyncCallList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
@@ -0,0 +1,280 @@
+package datastore
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+type mockExecutions struct {
+	lock       sync.Mutex
+	Executions []*models.Execution
+	States     []*models.ExecutionState
+}
+
+var _ models.ExecutionStore = &mockExecutions{}
+
+// NewMockExecutionStore creates a new in-memory execution store. Unlike the
+// datastore mock it is safe for concurrent use, since executions are written
+// to from the goroutines that run them.
+func NewMockExecutionStore() models.ExecutionStore {
+	return &mockExecutions{}
+}
+
+func (m *mockExecutions) InsertExecution(ctx context.Context, exec *models.Execution) (*models.Execution, error) {
+	if exec.ID != "" {
+		return nil, models.ErrExecutionIDProvided
+	}
+	err := exec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	cl := exec.Clone()
+	cl.ID = id.New().String()
+	cl.CreatedAt = common.DateTime(time.Now())
+	cl.UpdatedAt = cl.CreatedAt
+
+	m.Executions = append(m.Executions, cl)
+	return cl.Clone(), nil
+}
+
+func (m *mockExecutions) UpdateExecution(ctx context.Context, exec *models.Execution) (*models.Execution, error) {
+	if exec.ID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, e := range m.Executions {
+		if e.ID == exec.ID {
+			cl := e.Clone()
+			cl.Status = exec.Status
+			cl.CurrentState = exec.CurrentState
+			cl.Output = exec.Output
+			cl.Error = exec.Error
+			cl.UpdatedAt = common.DateTime(time.Now())
+			err := cl.Validate()
+			if err != nil {
+				return nil, err
+			}
+			*e = *cl
+			return cl.Clone(), nil
+		}
+	}
+	return nil, models.ErrExecutionsNotFound
+}
+
+func (m *mockExecutions) AdvanceExecution(ctx context.Context, exec *models.Execution, state *models.ExecutionState) (*models.Execution, error) {
+	if exec.ID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+	if state != nil {
+		if err := state.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, e := range m.Executions {
+		if e.ID == exec.ID {
+			if e.Owner != exec.Owner {
+				return nil, models.ErrExecutionsLeaseLost
+			}
+			cl := e.Clone()
+			cl.Status = exec.Status
+			cl.CurrentState = exec.CurrentState
+			cl.StateInput = exec.StateInput
+			cl.Output = exec.Output
+			cl.Error = exec.Error
+			cl.UpdatedAt = common.DateTime(time.Now())
+			err := cl.Validate()
+			if err != nil {
+				return nil, err
+			}
+			*e = *cl
+			if state != nil {
+				m.appendState(state)
+			}
+			return cl.Clone(), nil
+		}
+	}
+	return nil, models.ErrExecutionsNotFound
+}
+
+func (m *mockExecutions) ClaimExecution(ctx context.Context, execID, owner string, lease time.Duration) (*models.Execution, error) {
+	if execID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	for _, e := range m.Executions {
+		if e.ID == execID {
+			if e.Status != models.ExecutionStatusRunning || (e.Owner != "" && e.LeaseUntil > common.UnixMilli(now)) {
+				return nil, nil
+			}
+			e.Owner = owner
+			e.LeaseUntil = common.UnixMilli(now.Add(lease))
+			return e.Clone(), nil
+		}
+	}
+	return nil, models.ErrExecutionsNotFound
+}
+
+func (m *mockExecutions) RenewExecution(ctx context.Context, execID, owner string, lease time.Duration) error {
+	if execID == "" {
+		return models.ErrExecutionsMissingID
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, e := range m.Executions {
+		if e.ID == execID && e.Status == models.ExecutionStatusRunning && e.Owner == owner {
+			e.LeaseUntil = common.UnixMilli(time.Now().Add(lease))
+			return nil
+		}
+	}
+	return models.ErrExecutionsLeaseLost
+}
+
+func (m *mockExecutions) GetExecutionByID(ctx context.Context, execID string) (*models.Execution, error) {
+	if execID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, e := range m.Executions {
+		if e.ID == execID {
+			return e.Clone(), nil
+		}
+	}
+	return nil, models.ErrExecutionsNotFound
+}
+
+type sortE []*models.Execution
+
+func (s sortE) Len() int           { return len(s) }
+func (s sortE) Less(i, j int) bool { return strings.Compare(s[i].ID, s[j].ID) < 0 }
+func (s sortE) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (m *mockExecutions) GetExecutions(ctx context.Context, filter *models.ExecutionFilter) (*models.ExecutionList, error) {
+	if filter == nil {
+		filter = new(models.ExecutionFilter)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	sort.Sort(sortE(m.Executions))
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	res := []*models.Execution{}
+	for _, e := range m.Executions {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if strings.Compare(cursor, e.ID) < 0 &&
+			(filter.Status == "" || filter.Status == e.Status) {
+			res = append(res, e.Clone())
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.ExecutionList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mockExecutions) InsertExecutionState(ctx context.Context, state *models.ExecutionState) (*models.ExecutionState, error) {
+	err := state.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	found := false
+	for _, e := range m.Executions {
+		if e.ID == state.ExecutionID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, models.ErrExecutionsNotFound
+	}
+
+	return m.appendState(state), nil
+}
+
+// appendState appends a copy of state to the history of its execution, with
+// the next sequence number, m.lock must be held
+func (m *mockExecutions) appendState(state *models.ExecutionState) *models.ExecutionState {
+	var seq int64
+	for _, s := range m.States {
+		if s.ExecutionID == state.ExecutionID && s.Seq > seq {
+			seq = s.Seq
+		}
+	}
+
+	cl := new(models.ExecutionState)
+	*cl = *state
+	cl.Seq = seq + 1
+	m.States = append(m.States, cl)
+
+	ret := *cl
+	return &ret
+}
+
+func (m *mockExecutions) GetExecutionStates(ctx context.Context, execID string) ([]*models.ExecutionState, error) {
+	if execID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	res := []*models.ExecutionState{}
+	for _, s := range m.States {
+		if s.ExecutionID == execID {
+			cl := *s
+			res = append(res, &cl)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Seq < res[j].Seq })
+	return res, nil
+}
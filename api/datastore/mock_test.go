@@ -13,3 +13,17 @@ func TestDatastore(t *testing.T) {
 	}
 	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
 }
+
+func TestExecutionStore(t *testing.T) {
+	f := func(t *testing.T) models.ExecutionStore {
+		return NewMockExecutionStore()
+	}
+	datastoretest.RunExecutionsTest(t, f)
+}
+
+func TestAsyncQueue(t *testing.T) {
+	f := func(t *testing.T) models.AsyncQueue {
+		return NewMockAsyncQueue()
+	}
+	datastoretest.RunAsyncQueueTest(t, f)
+}
@@ -0,0 +1,302 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	asyncCallSelector   = `SELECT id,app_id,fn_id,status,attempts,method,url,headers,body,result_status,result_headers,result,error,visible_at,reservation,created_at,updated_at FROM async_calls`
+	asyncCallIDSelector = asyncCallSelector + ` WHERE id=?`
+
+	// how many times Reserve looks for another call when the one it found is
+	// reserved by another worker first
+	asyncReserveRetries = 5
+)
+
+func (ds *SQLStore) Push(ctx context.Context, newCall *models.AsyncCall) (*models.AsyncCall, error) {
+	call := newCall.Clone()
+	call.Status = models.AsyncCallStatusQueued
+	call.Attempts = 0
+	call.VisibleAt = 0
+	call.Reservation = ""
+	call.CreatedAt = common.DateTime(time.Now())
+	call.UpdatedAt = call.CreatedAt
+
+	err := call.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	query := ds.db.Rebind(`INSERT INTO async_calls (
+		id,
+		app_id,
+		fn_id,
+		status,
+		attempts,
+		method,
+		url,
+		headers,
+		body,
+		result_status,
+		result_headers,
+		result,
+		error,
+		visible_at,
+		reservation,
+		created_at,
+		updated_at
+	)
+	VALUES (
+		:id,
+		:app_id,
+		:fn_id,
+		:status,
+		:attempts,
+		:method,
+		:url,
+		:headers,
+		:body,
+		:result_status,
+		:result_headers,
+		:result,
+		:error,
+		:visible_at,
+		:reservation,
+		:created_at,
+		:updated_at
+	);`)
+	_, err = ds.db.NamedExecContext(ctx, query, call)
+	if err != nil {
+		if ds.helper.IsDuplicateKeyError(err) {
+			return nil, models.ErrAsyncCallsAlreadyExists
+		}
+		return nil, err
+	}
+
+	return call, nil
+}
+
+func (ds *SQLStore) Reserve(ctx context.Context, visibility time.Duration) (*models.AsyncCall, error) {
+	for i := 0; i < asyncReserveRetries; i++ {
+		now := time.Now()
+
+		var callID string
+		var visibleAt int64
+		query := ds.db.Rebind(`SELECT id, visible_at FROM async_calls
+			WHERE (status=? OR status=?) AND visible_at<=?
+			ORDER BY id ASC LIMIT 1`)
+		row := ds.db.QueryRowContext(ctx, query, models.AsyncCallStatusQueued, models.AsyncCallStatusRunning, common.UnixMilli(now))
+		err := row.Scan(&callID, &visibleAt)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		// only one worker gets to move visible_at on from what it was, the
+		// others look again
+		query = ds.db.Rebind(`UPDATE async_calls SET
+			status=?,
+			attempts=attempts+1,
+			visible_at=?,
+			reservation=?,
+			updated_at=?
+			WHERE id=? AND visible_at=? AND (status=? OR status=?)`)
+		res, err := ds.db.ExecContext(ctx, query,
+			models.AsyncCallStatusRunning, common.UnixMilli(now.Add(visibility)), id.New().String(), common.DateTime(now),
+			callID, visibleAt, models.AsyncCallStatusQueued, models.AsyncCallStatusRunning)
+		if err != nil {
+			return nil, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if n == 1 {
+			return ds.GetAsyncCall(ctx, callID)
+		}
+	}
+	return nil, nil
+}
+
+func (ds *SQLStore) Release(ctx context.Context, callID, reservation string, delay time.Duration) error {
+	if callID == "" {
+		return models.ErrAsyncCallsMissingID
+	}
+
+	now := time.Now()
+	query := ds.db.Rebind(`UPDATE async_calls SET
+		status=?,
+		visible_at=?,
+		reservation=?,
+		updated_at=?
+		WHERE id=? AND reservation=? AND status=?`)
+	res, err := ds.db.ExecContext(ctx, query,
+		models.AsyncCallStatusQueued, common.UnixMilli(now.Add(delay)), "", common.DateTime(now),
+		callID, reservation, models.AsyncCallStatusRunning)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := ds.GetAsyncCall(ctx, callID); err != nil {
+			return err
+		}
+		return models.ErrAsyncCallsNotReserved
+	}
+	return nil
+}
+
+func (ds *SQLStore) Finish(ctx context.Context, call *models.AsyncCall) (*models.AsyncCall, error) {
+	if call.ID == "" {
+		return nil, models.ErrAsyncCallsMissingID
+	}
+	if !call.Done() {
+		return nil, models.ErrAsyncCallsInvalidStatus
+	}
+
+	var dst models.AsyncCall
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		query := tx.Rebind(asyncCallIDSelector)
+		row := tx.QueryRowxContext(ctx, query, call.ID)
+		err := row.StructScan(&dst)
+		if err == sql.ErrNoRows {
+			return models.ErrAsyncCallsNotFound
+		} else if err != nil {
+			return err
+		}
+		if call.Reservation == "" || dst.Reservation != call.Reservation || dst.Done() {
+			return models.ErrAsyncCallsNotReserved
+		}
+
+		dst.Status = call.Status
+		dst.ResultStatus = call.ResultStatus
+		dst.ResultHeaders = call.ResultHeaders
+		dst.Result = call.Result
+		dst.Error = call.Error
+		dst.UpdatedAt = common.DateTime(time.Now())
+
+		// the call may be reserved again between the select and the update
+		query = tx.Rebind(`UPDATE async_calls SET
+			status = :status,
+			result_status = :result_status,
+			result_headers = :result_headers,
+			result = :result,
+			error = :error,
+			updated_at = :updated_at
+			WHERE id = :id AND reservation = :reservation;`)
+		res, err := tx.NamedExecContext(ctx, query, &dst)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrAsyncCallsNotReserved
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return &dst, nil
+}
+
+func (ds *SQLStore) GetAsyncCall(ctx context.Context, callID string) (*models.AsyncCall, error) {
+	if callID == "" {
+		return nil, models.ErrAsyncCallsMissingID
+	}
+
+	var call models.AsyncCall
+	query := ds.db.Rebind(asyncCallIDSelector)
+	row := ds.db.QueryRowxContext(ctx, query, callID)
+
+	err := row.StructScan(&call)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrAsyncCallsNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+func buildFilterAsyncCallQuery(filter *models.AsyncCallFilter) (string, []interface{}, error) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return "", args, err
+		}
+		args = where(&b, args, "id>?", string(s))
+	}
+	if filter.FnID != "" {
+		args = where(&b, args, "fn_id=?", filter.FnID)
+	}
+	if filter.Status != "" {
+		args = where(&b, args, "status=?", filter.Status)
+	}
+
+	fmt.Fprintf(&b, ` ORDER BY id ASC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+	return b.String(), args, nil
+}
+
+func (ds *SQLStore) GetAsyncCalls(ctx context.Context, filter *models.AsyncCallFilter) (*models.AsyncCallList, error) {
+	res := &models.AsyncCallList{Items: []*models.AsyncCall{}}
+	if filter == nil {
+		filter = new(models.AsyncCallFilter)
+	}
+
+	filterQuery, args, err := buildFilterAsyncCallQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s %s", asyncCallSelector, filterQuery))
+	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var call models.AsyncCall
+		err := rows.StructScan(&call)
+		if err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &call)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
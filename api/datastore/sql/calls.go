@@ -0,0 +1,182 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	callSelector = `SELECT id,app_id,fn_id,trigger_id,status,error,stats,created_at,started_at,completed_at FROM calls`
+)
+
+func (ds *SQLStore) InsertCall(ctx context.Context, call *models.Call) error {
+	if call.ID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if call.FnID == "" {
+		return models.ErrDatastoreEmptyFnID
+	}
+
+	query := ds.db.Rebind(`INSERT INTO calls (
+		id,
+		app_id,
+		fn_id,
+		trigger_id,
+		status,
+		error,
+		stats,
+		created_at,
+		started_at,
+		completed_at
+	)
+	VALUES (
+		:id,
+		:app_id,
+		:fn_id,
+		:trigger_id,
+		:status,
+		:error,
+		:stats,
+		:created_at,
+		:started_at,
+		:completed_at
+	);`)
+	_, err := ds.db.NamedExecContext(ctx, query, call)
+	return err
+}
+
+func (ds *SQLStore) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	var call models.Call
+	query := ds.db.Rebind(fmt.Sprintf("%s WHERE id=? AND fn_id=?", callSelector))
+	row := ds.db.QueryRowxContext(ctx, query, callID, fnID)
+
+	err := row.StructScan(&call)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrCallNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+func buildFilterCallQuery(filter *models.CallFilter) (string, []interface{}, error) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	args = where(&b, args, "fn_id=?", filter.FnID)
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return "", args, err
+		}
+		args = where(&b, args, "id<?", string(s))
+	}
+	if !time.Time(filter.FromTime).IsZero() {
+		args = where(&b, args, "created_at>?", filter.FromTime.String())
+	}
+	if !time.Time(filter.ToTime).IsZero() {
+		args = where(&b, args, "created_at<?", filter.ToTime.String())
+	}
+
+	fmt.Fprintf(&b, ` ORDER BY id DESC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+	return b.String(), args, nil
+}
+
+func (ds *SQLStore) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	if filter == nil || filter.FnID == "" {
+		return nil, models.ErrDatastoreEmptyFnID
+	}
+	res := &models.CallList{Items: []*models.Call{}}
+
+	filterQuery, args, err := buildFilterCallQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s %s", callSelector, filterQuery))
+	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var call models.Call
+		err := rows.StructScan(&call)
+		if err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &call)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	if call.ID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if call.FnID == "" {
+		return models.ErrDatastoreEmptyFnID
+	}
+
+	// coerce this into a string for sql
+	var log string
+	if stringer, ok := callLog.(fmt.Stringer); ok {
+		log = stringer.String()
+	} else {
+		b, err := ioutil.ReadAll(callLog)
+		if err != nil {
+			return err
+		}
+		log = string(b)
+	}
+
+	return ds.Tx(func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`DELETE FROM logs WHERE id=?`)
+		if _, err := tx.ExecContext(ctx, query, call.ID); err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`INSERT INTO logs (id, app_id, fn_id, log) VALUES (?, ?, ?, ?);`)
+		_, err := tx.ExecContext(ctx, query, call.ID, call.AppID, call.FnID, log)
+		return err
+	})
+}
+
+func (ds *SQLStore) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	var log string
+	query := ds.db.Rebind(`SELECT log FROM logs WHERE id=? AND fn_id=?`)
+	row := ds.db.QueryRowContext(ctx, query, callID, fnID)
+
+	err := row.Scan(&log)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrCallLogNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(log), nil
+}
@@ -0,0 +1,415 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	executionSelector   = `SELECT id,state_machine,status,current_state,input,output,error,state_input,owner,lease_until,created_at,updated_at FROM executions`
+	executionIDSelector = executionSelector + ` WHERE id=?`
+
+	executionStateSelector = `SELECT execution_id,seq,name,type,status,input,output,error,started_at,completed_at FROM execution_states`
+)
+
+func (ds *SQLStore) InsertExecution(ctx context.Context, newExec *models.Execution) (*models.Execution, error) {
+	if newExec.ID != "" {
+		return nil, models.ErrExecutionIDProvided
+	}
+
+	exec := newExec.Clone()
+	exec.ID = id.New().String()
+	exec.CreatedAt = common.DateTime(time.Now())
+	exec.UpdatedAt = exec.CreatedAt
+
+	err := exec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	query := ds.db.Rebind(`INSERT INTO executions (
+		id,
+		state_machine,
+		status,
+		current_state,
+		input,
+		output,
+		error,
+		state_input,
+		owner,
+		lease_until,
+		created_at,
+		updated_at
+	)
+	VALUES (
+		:id,
+		:state_machine,
+		:status,
+		:current_state,
+		:input,
+		:output,
+		:error,
+		:state_input,
+		:owner,
+		:lease_until,
+		:created_at,
+		:updated_at
+	);`)
+	_, err = ds.db.NamedExecContext(ctx, query, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	return exec, nil
+}
+
+func (ds *SQLStore) UpdateExecution(ctx context.Context, exec *models.Execution) (*models.Execution, error) {
+	if exec.ID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+
+	var dst models.Execution
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		query := tx.Rebind(executionIDSelector)
+		row := tx.QueryRowxContext(ctx, query, exec.ID)
+		err := row.StructScan(&dst)
+		if err == sql.ErrNoRows {
+			return models.ErrExecutionsNotFound
+		} else if err != nil {
+			return err
+		}
+
+		dst.Status = exec.Status
+		dst.CurrentState = exec.CurrentState
+		dst.Output = exec.Output
+		dst.Error = exec.Error
+		dst.UpdatedAt = common.DateTime(time.Now())
+
+		err = dst.Validate()
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`UPDATE executions SET
+			status = :status,
+			current_state = :current_state,
+			output = :output,
+			error = :error,
+			updated_at = :updated_at
+			WHERE id = :id;`)
+		_, err = tx.NamedExecContext(ctx, query, &dst)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return &dst, nil
+}
+
+func (ds *SQLStore) AdvanceExecution(ctx context.Context, exec *models.Execution, newState *models.ExecutionState) (*models.Execution, error) {
+	if exec.ID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+	if newState != nil {
+		if err := newState.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var dst models.Execution
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		query := tx.Rebind(executionIDSelector)
+		row := tx.QueryRowxContext(ctx, query, exec.ID)
+		err := row.StructScan(&dst)
+		if err == sql.ErrNoRows {
+			return models.ErrExecutionsNotFound
+		} else if err != nil {
+			return err
+		}
+
+		dst.Status = exec.Status
+		dst.CurrentState = exec.CurrentState
+		dst.StateInput = exec.StateInput
+		dst.Output = exec.Output
+		dst.Error = exec.Error
+		dst.UpdatedAt = common.DateTime(time.Now())
+
+		err = dst.Validate()
+		if err != nil {
+			return err
+		}
+
+		// the owner is checked by the update itself, as another node may claim
+		// the execution between the select and the update
+		query = tx.Rebind(`UPDATE executions SET
+			status = ?,
+			current_state = ?,
+			state_input = ?,
+			output = ?,
+			error = ?,
+			updated_at = ?
+			WHERE id = ? AND owner = ?;`)
+		res, err := tx.ExecContext(ctx, query, dst.Status, dst.CurrentState, dst.StateInput, dst.Output, dst.Error, dst.UpdatedAt, dst.ID, exec.Owner)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrExecutionsLeaseLost
+		}
+
+		if newState != nil {
+			state := new(models.ExecutionState)
+			*state = *newState
+			state.ExecutionID = dst.ID
+			return insertExecutionState(ctx, tx, state)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return &dst, nil
+}
+
+func (ds *SQLStore) ClaimExecution(ctx context.Context, execID, owner string, lease time.Duration) (*models.Execution, error) {
+	if execID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+
+	now := time.Now()
+	query := ds.db.Rebind(`UPDATE executions SET
+		owner=?,
+		lease_until=?
+		WHERE id=? AND status=? AND (owner='' OR owner IS NULL OR lease_until<=?)`)
+	res, err := ds.db.ExecContext(ctx, query,
+		owner, common.UnixMilli(now.Add(lease)), execID, models.ExecutionStatusRunning, common.UnixMilli(now))
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	exec, err := ds.GetExecutionByID(ctx, execID)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return exec, nil
+}
+
+func (ds *SQLStore) RenewExecution(ctx context.Context, execID, owner string, lease time.Duration) error {
+	if execID == "" {
+		return models.ErrExecutionsMissingID
+	}
+
+	query := ds.db.Rebind(`UPDATE executions SET
+		lease_until=?
+		WHERE id=? AND status=? AND owner=?`)
+	res, err := ds.db.ExecContext(ctx, query,
+		common.UnixMilli(time.Now().Add(lease)), execID, models.ExecutionStatusRunning, owner)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrExecutionsLeaseLost
+	}
+	return nil
+}
+
+func (ds *SQLStore) GetExecutionByID(ctx context.Context, execID string) (*models.Execution, error) {
+	if execID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+
+	var exec models.Execution
+	query := ds.db.Rebind(executionIDSelector)
+	row := ds.db.QueryRowxContext(ctx, query, execID)
+
+	err := row.StructScan(&exec)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrExecutionsNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &exec, nil
+}
+
+func buildFilterExecutionQuery(filter *models.ExecutionFilter) (string, []interface{}, error) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return "", args, err
+		}
+		args = where(&b, args, "id>?", string(s))
+	}
+	if filter.Status != "" {
+		args = where(&b, args, "status=?", filter.Status)
+	}
+
+	fmt.Fprintf(&b, ` ORDER BY id ASC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+	return b.String(), args, nil
+}
+
+func (ds *SQLStore) GetExecutions(ctx context.Context, filter *models.ExecutionFilter) (*models.ExecutionList, error) {
+	res := &models.ExecutionList{Items: []*models.Execution{}}
+	if filter == nil {
+		filter = new(models.ExecutionFilter)
+	}
+
+	filterQuery, args, err := buildFilterExecutionQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s %s", executionSelector, filterQuery))
+	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var exec models.Execution
+		err := rows.StructScan(&exec)
+		if err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &exec)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) InsertExecutionState(ctx context.Context, newState *models.ExecutionState) (*models.ExecutionState, error) {
+	err := newState.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	state := new(models.ExecutionState)
+	*state = *newState
+
+	err = ds.Tx(func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`SELECT 1 FROM executions WHERE id=?`)
+		r := tx.QueryRowContext(ctx, query, state.ExecutionID)
+		if err := r.Scan(new(int)); err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrExecutionsNotFound
+			}
+			return err
+		}
+		return insertExecutionState(ctx, tx, state)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// insertExecutionState appends state to the history of its execution in tx,
+// assigning it the next sequence number
+func insertExecutionState(ctx context.Context, tx *sqlx.Tx, state *models.ExecutionState) error {
+	var seq sql.NullInt64
+	query := tx.Rebind(`SELECT MAX(seq) FROM execution_states WHERE execution_id=?`)
+	r := tx.QueryRowContext(ctx, query, state.ExecutionID)
+	if err := r.Scan(&seq); err != nil {
+		return err
+	}
+	state.Seq = seq.Int64 + 1
+
+	query = tx.Rebind(`INSERT INTO execution_states (
+		execution_id,
+		seq,
+		name,
+		type,
+		status,
+		input,
+		output,
+		error,
+		started_at,
+		completed_at
+	)
+	VALUES (
+		:execution_id,
+		:seq,
+		:name,
+		:type,
+		:status,
+		:input,
+		:output,
+		:error,
+		:started_at,
+		:completed_at
+	);`)
+	_, err := tx.NamedExecContext(ctx, query, state)
+	return err
+}
+
+func (ds *SQLStore) GetExecutionStates(ctx context.Context, execID string) ([]*models.ExecutionState, error) {
+	if execID == "" {
+		return nil, models.ErrExecutionsMissingID
+	}
+
+	res := []*models.ExecutionState{}
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s WHERE execution_id=? ORDER BY seq ASC", executionStateSelector))
+	rows, err := ds.db.QueryxContext(ctx, query, execID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var state models.ExecutionState
+		err := rows.StructScan(&state)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &state)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up25(ctx context.Context, tx *sqlx.Tx) error {
+	createQuery := `CREATE TABLE IF NOT EXISTS executions (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	state_machine text NOT NULL,
+	status varchar(256) NOT NULL,
+	current_state varchar(256),
+	input text,
+	output text,
+	error text,
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL
+);`
+	_, err := tx.ExecContext(ctx, createQuery)
+	return err
+}
+
+func down25(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE executions;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(25),
+		UpFunc:      up25,
+		DownFunc:    down25,
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up26(ctx context.Context, tx *sqlx.Tx) error {
+	createQuery := `CREATE TABLE IF NOT EXISTS execution_states (
+	execution_id varchar(256) NOT NULL,
+	seq int NOT NULL,
+	name varchar(256) NOT NULL,
+	type varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	input text,
+	output text,
+	error text,
+	started_at varchar(256) NOT NULL,
+	completed_at varchar(256) NOT NULL,
+	PRIMARY KEY (execution_id, seq)
+);`
+	_, err := tx.ExecContext(ctx, createQuery)
+	return err
+}
+
+func down26(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE execution_states;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(26),
+		UpFunc:      up26,
+		DownFunc:    down26,
+	})
+}
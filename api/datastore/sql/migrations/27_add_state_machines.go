@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up27(ctx context.Context, tx *sqlx.Tx) error {
+	createQuery := `CREATE TABLE IF NOT EXISTS state_machines (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	name varchar(256) NOT NULL,
+	version int NOT NULL,
+	definition text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT app_id_name_version_unique UNIQUE (app_id, name, version)
+);`
+	_, err := tx.ExecContext(ctx, createQuery)
+	return err
+}
+
+func down27(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE state_machines;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(27),
+		UpFunc:      up27,
+		DownFunc:    down27,
+	})
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up29(ctx context.Context, tx *sqlx.Tx) error {
+	createQuery := `CREATE TABLE IF NOT EXISTS async_calls (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	attempts int NOT NULL,
+	method varchar(256) NOT NULL,
+	url text NOT NULL,
+	headers text,
+	body text,
+	result_status int NOT NULL,
+	result_headers text,
+	result text,
+	error text,
+	visible_at bigint NOT NULL,
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL
+);`
+	_, err := tx.ExecContext(ctx, createQuery)
+	return err
+}
+
+func down29(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE async_calls;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(29),
+		UpFunc:      up29,
+		DownFunc:    down29,
+	})
+}
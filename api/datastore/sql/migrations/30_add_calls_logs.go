@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+// the calls and logs tables dropped in 23 and 24 come back, for the call
+// and log stores, with the columns they have now
+func up30(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS calls (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	trigger_id varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	error text,
+	stats text,
+	created_at varchar(256) NOT NULL,
+	started_at varchar(256) NOT NULL,
+	completed_at varchar(256) NOT NULL
+);`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS logs (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	log text NOT NULL
+);`)
+	return err
+}
+
+func down30(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE calls;")
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DROP TABLE logs;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(30),
+		UpFunc:      up30,
+		DownFunc:    down30,
+	})
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up31(ctx context.Context, tx *sqlx.Tx) error {
+	for _, q := range []string{
+		"ALTER TABLE executions ADD owner varchar(256);",
+		"ALTER TABLE executions ADD lease_until bigint;",
+		"ALTER TABLE executions ADD state_input text;",
+	} {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+
+	// the input of the current state of an execution is the output of the last
+	// state that succeeded, or the input of the execution if none has
+	_, err := tx.ExecContext(ctx, `UPDATE executions SET
+	owner = '',
+	lease_until = 0,
+	state_input = COALESCE((SELECT s.output FROM execution_states s
+		WHERE s.execution_id = executions.id AND s.seq = (SELECT MAX(m.seq) FROM execution_states m
+			WHERE m.execution_id = executions.id AND m.status = 'succeeded')), input, '');`)
+	return err
+}
+
+func down31(ctx context.Context, tx *sqlx.Tx) error {
+	for _, q := range []string{
+		"ALTER TABLE executions DROP COLUMN owner;",
+		"ALTER TABLE executions DROP COLUMN lease_until;",
+		"ALTER TABLE executions DROP COLUMN state_input;",
+	} {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(31),
+		UpFunc:      up31,
+		DownFunc:    down31,
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up32(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "ALTER TABLE async_calls ADD reservation varchar(256);")
+	if err != nil {
+		return err
+	}
+
+	// calls reserved before there were reservations are left to time out
+	_, err = tx.ExecContext(ctx, "UPDATE async_calls SET reservation='';")
+	return err
+}
+
+func down32(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "ALTER TABLE async_calls DROP COLUMN reservation;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(32),
+		UpFunc:      up32,
+		DownFunc:    down32,
+	})
+}
@@ -53,6 +53,7 @@ var tables = [...]string{
 	type varchar(256) NOT NULL,
 	source varchar(256) NOT NULL,
     annotations text NOT NULL,
+	state_machine varchar(256) DEFAULT '' NOT NULL,
     CONSTRAINT name_app_id_fn_id_unique UNIQUE (app_id, fn_id, name)
 );`,
 
@@ -70,6 +71,85 @@ var tables = [...]string{
 	updated_at varchar(256) NOT NULL,
     CONSTRAINT name_app_id_unique UNIQUE (app_id, name)
 );`,
+
+	`CREATE TABLE IF NOT EXISTS executions (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	state_machine text NOT NULL,
+	status varchar(256) NOT NULL,
+	current_state varchar(256),
+	input text,
+	output text,
+	error text,
+	state_input text,
+	owner varchar(256),
+	lease_until bigint,
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS execution_states (
+	execution_id varchar(256) NOT NULL,
+	seq int NOT NULL,
+	name varchar(256) NOT NULL,
+	type varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	input text,
+	output text,
+	error text,
+	started_at varchar(256) NOT NULL,
+	completed_at varchar(256) NOT NULL,
+	PRIMARY KEY (execution_id, seq)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS state_machines (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	name varchar(256) NOT NULL,
+	version int NOT NULL,
+	definition text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT app_id_name_version_unique UNIQUE (app_id, name, version)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS async_calls (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	attempts int NOT NULL,
+	method varchar(256) NOT NULL,
+	url text NOT NULL,
+	headers text,
+	body text,
+	result_status int NOT NULL,
+	result_headers text,
+	result text,
+	error text,
+	visible_at bigint NOT NULL,
+	reservation varchar(256),
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS calls (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	trigger_id varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	error text,
+	stats text,
+	created_at varchar(256) NOT NULL,
+	started_at varchar(256) NOT NULL,
+	completed_at varchar(256) NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS logs (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	log text NOT NULL
+);`,
 }
 
 const (
@@ -79,7 +159,7 @@ const (
 	fnSelector   = `SELECT id,name,app_id,image,memory,timeout,idle_timeout,config,annotations,created_at,updated_at FROM fns`
 	fnIDSelector = fnSelector + ` WHERE id=?`
 
-	triggerSelector   = `SELECT id,name,app_id,fn_id,type,source,annotations,created_at,updated_at FROM triggers`
+	triggerSelector   = `SELECT id,name,app_id,fn_id,state_machine,type,source,annotations,created_at,updated_at FROM triggers`
 	triggerIDSelector = triggerSelector + ` WHERE id=?`
 
 	triggerIDSourceSelector = triggerSelector + ` WHERE app_id=? AND type=? AND source=?`
@@ -88,10 +168,15 @@ const (
 )
 
 var ( // compiler will yell nice things about our upbringing as a child
-	_ models.Datastore = new(SQLStore)
+	_ models.Datastore      = new(SQLStore)
+	_ models.ExecutionStore = new(SQLStore)
+	_ models.AsyncQueue     = new(SQLStore)
+	_ models.CallStore      = new(SQLStore)
+	_ models.LogStore       = new(SQLStore)
 )
 
-// SQLStore implements models.Datastore
+// SQLStore implements models.Datastore, models.ExecutionStore, models.AsyncQueue,
+// models.CallStore and models.LogStore
 type SQLStore struct {
 	helper dbhelper.Helper
 	db     *sqlx.DB
@@ -283,6 +368,42 @@ func (ds *SQLStore) clear() error {
 
 		query = tx.Rebind(`DELETE FROM fns`)
 		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM executions`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM execution_states`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM state_machines`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM async_calls`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM calls`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM logs`)
+		_, err = tx.Exec(query)
 		return err
 	})
 }
@@ -409,6 +530,7 @@ func (ds *SQLStore) RemoveApp(ctx context.Context, appID string) error {
 		deletes := []string{
 			`DELETE FROM fns WHERE app_id=?`,
 			`DELETE FROM triggers WHERE app_id=?`,
+			`DELETE FROM state_machines WHERE app_id=?`,
 		}
 		for _, stmt := range deletes {
 			_, err := tx.ExecContext(ctx, tx.Rebind(stmt), appID)
@@ -781,18 +903,29 @@ func (ds *SQLStore) InsertTrigger(ctx context.Context, newTrigger *models.Trigge
 			}
 		}
 
-		query = tx.Rebind(`SELECT app_id FROM fns WHERE id=?`)
-		r = tx.QueryRowContext(ctx, query, trigger.FnID)
-		var app_id string
-		if err := r.Scan(&app_id); err != nil {
-			if err == sql.ErrNoRows {
-				return models.ErrFnsNotFound
-			} else if err != nil {
+		if trigger.Type == models.TriggerTypeStateMachine {
+			query = tx.Rebind(`SELECT 1 FROM state_machines WHERE app_id=? AND name=?`)
+			r = tx.QueryRowContext(ctx, query, trigger.AppID, trigger.StateMachine)
+			if err := r.Scan(new(int)); err != nil {
+				if err == sql.ErrNoRows {
+					return models.ErrStateMachinesNotFound
+				}
 				return err
 			}
-		}
-		if app_id != trigger.AppID {
-			return models.ErrTriggerFnIDNotSameApp
+		} else {
+			query = tx.Rebind(`SELECT app_id FROM fns WHERE id=?`)
+			r = tx.QueryRowContext(ctx, query, trigger.FnID)
+			var app_id string
+			if err := r.Scan(&app_id); err != nil {
+				if err == sql.ErrNoRows {
+					return models.ErrFnsNotFound
+				} else if err != nil {
+					return err
+				}
+			}
+			if app_id != trigger.AppID {
+				return models.ErrTriggerFnIDNotSameApp
+			}
 		}
 
 		query = tx.Rebind(`SELECT 1 FROM triggers WHERE app_id=? AND type=? and source=?`)
@@ -809,6 +942,7 @@ func (ds *SQLStore) InsertTrigger(ctx context.Context, newTrigger *models.Trigge
 			name,
 		  	app_id,
 			fn_id,
+			state_machine,
 			created_at,
 			updated_at,
 			type,
@@ -820,6 +954,7 @@ func (ds *SQLStore) InsertTrigger(ctx context.Context, newTrigger *models.Trigge
 			:name,
 			:app_id,
 			:fn_id,
+			:state_machine,
 			:created_at,
 			:updated_at,
 			:type,
@@ -860,11 +995,21 @@ func (ds *SQLStore) UpdateTrigger(ctx context.Context, trigger *models.Trigger)
 		if err != nil {
 			return err
 		}
+		if dst.Type == models.TriggerTypeStateMachine {
+			query = tx.Rebind(`SELECT 1 FROM state_machines WHERE app_id=? AND name=?`)
+			if err := tx.QueryRowContext(ctx, query, dst.AppID, dst.StateMachine).Scan(new(int)); err != nil {
+				if err == sql.ErrNoRows {
+					return models.ErrStateMachinesNotFound
+				}
+				return err
+			}
+		}
 		trigger = &dst // set for query & to return
 
 		query = tx.Rebind(`UPDATE triggers SET
 			name = :name,
 			fn_id = :fn_id,
+			state_machine = :state_machine,
 			updated_at = :updated_at,
 			source = :source,
 			annotations = :annotations
@@ -67,6 +67,15 @@ func TestDatastore(t *testing.T) {
 	t.Run(u.Scheme, func(t *testing.T) {
 		datastoretest.RunAllTests(t, f2, datastoretest.NewBasicResourceProvider())
 	})
+	t.Run(u.Scheme, func(t *testing.T) {
+		datastoretest.RunExecutionsTest(t, func(t *testing.T) models.ExecutionStore { return f(t) })
+	})
+	t.Run(u.Scheme, func(t *testing.T) {
+		datastoretest.RunAsyncQueueTest(t, func(t *testing.T) models.AsyncQueue { return f(t) })
+	})
+	t.Run(u.Scheme, func(t *testing.T) {
+		datastoretest.RunCallLogStoreTest(t, func(t *testing.T) datastoretest.CallLogStore { return f(t) })
+	})
 
 	// NOTE: sqlite3 does not like ALTER TABLE DROP COLUMN so do not run
 	// migration tests against it, only pg and mysql -- should prove UP migrations
@@ -95,6 +104,15 @@ func TestDatastore(t *testing.T) {
 
 		// test fresh w/o migrations
 		t.Run(u.Scheme, func(t *testing.T) { datastoretest.RunAllTests(t, f2, datastoretest.NewBasicResourceProvider()) })
+		t.Run(u.Scheme, func(t *testing.T) {
+			datastoretest.RunExecutionsTest(t, func(t *testing.T) models.ExecutionStore { return f(t) })
+		})
+		t.Run(u.Scheme, func(t *testing.T) {
+			datastoretest.RunAsyncQueueTest(t, func(t *testing.T) models.AsyncQueue { return f(t) })
+		})
+		t.Run(u.Scheme, func(t *testing.T) {
+			datastoretest.RunCallLogStoreTest(t, func(t *testing.T) datastoretest.CallLogStore { return f(t) })
+		})
 
 		f = func(t *testing.T) *SQLStore {
 			t.Log("with migrations now!")
@@ -115,6 +133,15 @@ func TestDatastore(t *testing.T) {
 
 		// test that migrations work & things work with them
 		t.Run(u.Scheme, func(t *testing.T) { datastoretest.RunAllTests(t, f2, datastoretest.NewBasicResourceProvider()) })
+		t.Run(u.Scheme, func(t *testing.T) {
+			datastoretest.RunExecutionsTest(t, func(t *testing.T) models.ExecutionStore { return f(t) })
+		})
+		t.Run(u.Scheme, func(t *testing.T) {
+			datastoretest.RunAsyncQueueTest(t, func(t *testing.T) models.AsyncQueue { return f(t) })
+		})
+		t.Run(u.Scheme, func(t *testing.T) {
+			datastoretest.RunCallLogStoreTest(t, func(t *testing.T) datastoretest.CallLogStore { return f(t) })
+		})
 	}
 
 	if pg := os.Getenv("POSTGRES_URL"); pg != "" {
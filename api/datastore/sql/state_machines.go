@@ -0,0 +1,206 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	stateMachineSelector = `SELECT id,app_id,name,version,definition,created_at FROM state_machines`
+
+	// latest version of each state machine, for listing
+	stateMachineLatest = `version = (SELECT MAX(version) FROM state_machines sm WHERE sm.app_id = state_machines.app_id AND sm.name = state_machines.name)`
+)
+
+func (ds *SQLStore) InsertStateMachine(ctx context.Context, newSM *models.StoredStateMachine) (*models.StoredStateMachine, error) {
+	sm := newSM.Clone()
+	sm.ID = id.New().String()
+	sm.CreatedAt = common.DateTime(time.Now())
+
+	err := sm.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ds.Tx(func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`SELECT 1 FROM apps WHERE id=?`)
+		r := tx.QueryRowContext(ctx, query, sm.AppID)
+		if err := r.Scan(new(int)); err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrAppsNotFound
+			}
+			return err
+		}
+
+		var latest sql.NullInt64
+		query = tx.Rebind(`SELECT MAX(version) FROM state_machines WHERE app_id=? AND name=?`)
+		r = tx.QueryRowContext(ctx, query, sm.AppID, sm.Name)
+		if err := r.Scan(&latest); err != nil {
+			return err
+		}
+		sm.Version = latest.Int64 + 1
+
+		query = tx.Rebind(`INSERT INTO state_machines (
+			id,
+			app_id,
+			name,
+			version,
+			definition,
+			created_at
+		)
+		VALUES (
+			:id,
+			:app_id,
+			:name,
+			:version,
+			:definition,
+			:created_at
+		);`)
+		_, err := tx.NamedExecContext(ctx, query, sm)
+		return err
+	})
+
+	if err != nil {
+		if ds.helper.IsDuplicateKeyError(err) {
+			return nil, models.ErrStateMachinesVersionConflict
+		}
+		return nil, err
+	}
+	return sm, nil
+}
+
+func (ds *SQLStore) GetStateMachine(ctx context.Context, appID, name string, version int64) (*models.StoredStateMachine, error) {
+	var row *sqlx.Row
+	if version == 0 {
+		/* #nosec */
+		query := ds.db.Rebind(fmt.Sprintf("%s WHERE app_id=? AND name=? ORDER BY version DESC LIMIT 1", stateMachineSelector))
+		row = ds.db.QueryRowxContext(ctx, query, appID, name)
+	} else {
+		/* #nosec */
+		query := ds.db.Rebind(fmt.Sprintf("%s WHERE app_id=? AND name=? AND version=?", stateMachineSelector))
+		row = ds.db.QueryRowxContext(ctx, query, appID, name, version)
+	}
+
+	var sm models.StoredStateMachine
+	err := row.StructScan(&sm)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrStateMachinesNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &sm, nil
+}
+
+func buildFilterStateMachineQuery(filter *models.StateMachineFilter) (string, []interface{}, error) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	args = where(&b, args, "app_id=?", filter.AppID)
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		cursor = string(s)
+	}
+
+	if filter.Name != "" {
+		// every version of one state machine, by version
+		args = where(&b, args, "name=?", filter.Name)
+		if cursor != "" {
+			v, err := strconv.ParseInt(cursor, 10, 64)
+			if err != nil {
+				return "", nil, err
+			}
+			args = where(&b, args, "version<?", v)
+		}
+		fmt.Fprintf(&b, ` ORDER BY version DESC`)
+	} else {
+		// the latest version of each state machine, by name
+		fmt.Fprintf(&b, ` AND %s`, stateMachineLatest)
+		args = where(&b, args, "name>?", cursor)
+		fmt.Fprintf(&b, ` ORDER BY name ASC`)
+	}
+
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+	return b.String(), args, nil
+}
+
+func (ds *SQLStore) GetStateMachines(ctx context.Context, filter *models.StateMachineFilter) (*models.StateMachineList, error) {
+	res := &models.StateMachineList{Items: []*models.StoredStateMachine{}}
+	if filter == nil {
+		filter = new(models.StateMachineFilter)
+	}
+
+	filterQuery, args, err := buildFilterStateMachineQuery(filter)
+	if err != nil {
+		return res, err
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s %s", stateMachineSelector, filterQuery))
+	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sm models.StoredStateMachine
+		err := rows.StructScan(&sm)
+		if err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &sm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := res.Items[len(res.Items)-1]
+		cursor := last.Name
+		if filter.Name != "" {
+			cursor = strconv.FormatInt(last.Version, 10)
+		}
+		res.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(cursor))
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) RemoveStateMachine(ctx context.Context, appID, name string) error {
+	return ds.Tx(func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`DELETE FROM state_machines WHERE app_id=? AND name=?`)
+		res, err := tx.ExecContext(ctx, query, appID, name)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrStateMachinesNotFound
+		}
+
+		query = tx.Rebind(`DELETE FROM triggers WHERE app_id=? AND type=? AND state_machine=?`)
+		_, err = tx.ExecContext(ctx, query, appID, models.TriggerTypeStateMachine, name)
+		return err
+	})
+}
@@ -0,0 +1,257 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureColumns are the columns of the Azure Functions invocation trace that
+// name a function, the per-minute counts follow them
+var azureColumns = []string{"HashOwner", "HashApp", "HashFunction", "Trigger"}
+
+// AzureFunction is a function of the Azure Functions invocation trace, with
+// the number of times it was invoked in each minute of the trace
+type AzureFunction struct {
+	HashOwner    string
+	HashApp      string
+	HashFunction string
+	Trigger      string
+	Counts       []int
+}
+
+// ReadAzureTrace reads a trace in the format of the invocations per function
+// per minute files of the Azure Functions public dataset: a header of
+// HashOwner, HashApp, HashFunction and Trigger followed by a column for each
+// minute, then a row for each function.
+func ReadAzureTrace(r io.Reader) ([]*AzureFunction, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("trace is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(header) <= len(azureColumns) {
+		return nil, errors.New("trace has no minutes")
+	}
+	for i, col := range azureColumns {
+		if strings.TrimSpace(header[i]) != col {
+			return nil, fmt.Errorf("trace column %d is %q, expected %q", i+1, header[i], col)
+		}
+	}
+
+	var fns []*AzureFunction
+	for row := 2; ; row++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		fn := &AzureFunction{
+			HashOwner:    rec[0],
+			HashApp:      rec[1],
+			HashFunction: rec[2],
+			Trigger:      rec[3],
+			Counts:       make([]int, len(rec)-len(azureColumns)),
+		}
+		for i, f := range rec[len(azureColumns):] {
+			n, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid count %q for minute %d on row %d", f, i+1, row)
+			}
+			fn.Counts[i] = n
+		}
+		fns = append(fns, fn)
+	}
+	return fns, nil
+}
+
+// ReplaySpec declares a replay of the Azure Functions invocation trace, in
+// which each traced function that is mapped to a function of ours is invoked
+// as often as it was in each minute of the trace
+type ReplaySpec struct {
+	// File names the trace, it is resolved by whoever runs the replay
+	File string `json:"file"`
+	// Functions maps the HashFunction of traced functions to the IDs of the
+	// functions to invoke in their place; unmapped functions are not replayed
+	Functions map[string]string `json:"functions"`
+
+	// From is the first minute of the trace to replay, counting from 1, and
+	// Minutes how many to replay. By default the whole trace is replayed.
+	From    int `json:"from,omitempty"`
+	Minutes int `json:"minutes,omitempty"`
+
+	// TimeScale compresses the trace, a scale of 240 plays a minute of it in
+	// a quarter of a second
+	TimeScale float64 `json:"time_scale,omitempty"`
+	// Burst sends the requests of each minute at once at its start, rather
+	// than spreading them evenly across it
+	Burst bool `json:"burst,omitempty"`
+	// MaxInFlight bounds the requests outstanding at once, across every
+	// function. 0 is unbounded.
+	MaxInFlight int `json:"max_in_flight,omitempty"`
+	// Interval is the length of each interval of the report, in the time of
+	// the replay rather than of the trace. The default is one second.
+	Interval Duration `json:"interval,omitempty"`
+	// Body is sent as the body of every request
+	Body string `json:"body,omitempty"`
+	// Record adds every request to the report
+	Record bool `json:"record,omitempty"`
+}
+
+// Validate checks that a replay spec can be run against a trace, which is
+// not read yet
+func (s *ReplaySpec) Validate() error {
+	if s.File == "" {
+		return errors.New("replay requires a trace file")
+	}
+	if len(s.Functions) == 0 {
+		return errors.New("replay requires functions")
+	}
+	for hash, fnID := range s.Functions {
+		if hash == "" || fnID == "" {
+			return errors.New("replay functions must map a function hash to a function ID")
+		}
+	}
+	if s.From < 0 || s.Minutes < 0 {
+		return errors.New("from and minutes must not be negative")
+	}
+	if s.TimeScale < 0 {
+		return errors.New("time_scale must not be negative")
+	}
+	if s.MaxInFlight < 0 {
+		return errors.New("max_in_flight must not be negative")
+	}
+	if s.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+	return nil
+}
+
+// RequestRecord is one request of a recorded replay
+type RequestRecord struct {
+	Function string   `json:"function"`
+	FnID     string   `json:"fn_id"`
+	Sent     Duration `json:"sent"`
+	Latency  float64  `json:"latency"` // milliseconds
+	Start    string   `json:"start,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// ReplayReport is the outcome of a replay, overall and for each traced function
+type ReplayReport struct {
+	Report
+	Functions map[string]*Report `json:"functions"`
+	Requests  []RequestRecord    `json:"requests,omitempty"`
+}
+
+// Replay sends the requests of the mapped functions of trace as they are
+// due, invoking each through the Invoker that invoke returns for its
+// function ID, and reports on them once every request has completed.
+func Replay(ctx context.Context, spec *ReplaySpec, trace []*AzureFunction, invoke func(fnID string) Invoker, classify Classify) (*ReplayReport, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string]*AzureFunction, len(trace))
+	for _, fn := range trace {
+		byHash[fn.HashFunction] = fn
+	}
+
+	hashes := make([]string, 0, len(spec.Functions))
+	for hash := range spec.Functions {
+		if byHash[hash] == nil {
+			return nil, fmt.Errorf("function %q is not in the trace", hash)
+		}
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	// every function is replayed as a trace workload of its own, which are
+	// merged into one schedule
+	r := &runner{maxInFlight: spec.MaxInFlight}
+	specs := make([]*Spec, len(hashes))
+	scheduled := make([]int, len(hashes))
+	for i, hash := range hashes {
+		counts := byHash[hash].Counts
+		from := spec.From
+		if from > 0 {
+			from--
+		}
+		if from >= len(counts) {
+			return nil, fmt.Errorf("trace has only %d minutes", len(counts))
+		}
+		counts = counts[from:]
+		if spec.Minutes > 0 && spec.Minutes < len(counts) {
+			counts = counts[:spec.Minutes]
+		}
+
+		specs[i] = &Spec{
+			Type:      TypeTrace,
+			Trace:     &Trace{Counts: counts, Interval: Duration(time.Minute), Burst: spec.Burst},
+			TimeScale: spec.TimeScale,
+		}
+		at, err := specs[i].Schedule()
+		if err != nil {
+			return nil, fmt.Errorf("function %q: %v", hash, err)
+		}
+		scheduled[i] = len(at)
+		for _, due := range at {
+			r.arrivals = append(r.arrivals, arrival{due: due, target: i})
+		}
+		r.invokers = append(r.invokers, invoke(spec.Functions[hash]))
+	}
+	if len(r.arrivals) > MaxRequests {
+		return nil, fmt.Errorf("replay would send %d requests, more than %d", len(r.arrivals), MaxRequests)
+	}
+	sort.SliceStable(r.arrivals, func(i, j int) bool { return r.arrivals[i].due < r.arrivals[j].due })
+
+	results, elapsed := r.run(ctx)
+	elapsed = workloadLength(ctx, elapsed, specs[0].length())
+	interval := reportInterval(spec.Interval)
+
+	report := &ReplayReport{
+		Report:    *newReport(results, len(r.arrivals), elapsed, interval, classify),
+		Functions: make(map[string]*Report, len(hashes)),
+	}
+	perFn := make([][]result, len(hashes))
+	for _, res := range results {
+		perFn[res.target] = append(perFn[res.target], res)
+	}
+	for i, hash := range hashes {
+		report.Functions[hash] = newReport(perFn[i], scheduled[i], elapsed, interval, classify)
+	}
+
+	if spec.Record {
+		if classify == nil {
+			classify = ClassifyByCode
+		}
+		report.Requests = make([]RequestRecord, len(results))
+		for i, res := range results {
+			rec := RequestRecord{
+				Function: hashes[res.target],
+				FnID:     spec.Functions[hashes[res.target]],
+				Sent:     Duration(res.sent),
+				Latency:  ms(res.done - res.sent),
+				Start:    res.start,
+			}
+			if res.err != nil {
+				rec.Error = classify(res.err)
+			}
+			report.Requests[i] = rec
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,107 @@
+package loadgen
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const azureTrace = `HashOwner,HashApp,HashFunction,Trigger,1,2,3
+o1,a1,f1,http,2,0,1
+o1,a1,f2,timer,1,1,1
+o2,a2,f3,queue,5,5,5
+`
+
+func TestReadAzureTrace(t *testing.T) {
+	fns, err := ReadAzureTrace(strings.NewReader(azureTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fns) != 3 {
+		t.Fatalf("expected 3 functions, got %d", len(fns))
+	}
+	if fns[1].HashApp != "a1" || fns[1].HashFunction != "f2" || fns[1].Trigger != "timer" {
+		t.Fatalf("unexpected function %+v", fns[1])
+	}
+	if len(fns[0].Counts) != 3 || fns[0].Counts[0] != 2 || fns[0].Counts[2] != 1 {
+		t.Fatalf("unexpected counts %v", fns[0].Counts)
+	}
+
+	for i, test := range []struct {
+		trace string
+		err   string
+	}{
+		{"", "trace is empty"},
+		{"HashOwner,HashApp,HashFunction,Trigger\n", "no minutes"},
+		{"Owner,HashApp,HashFunction,Trigger,1\n", `trace column 1 is "Owner"`},
+		{"HashOwner,HashApp,HashFunction,Trigger,1,2\no,a,f,http,1,x\n", "minute 2 on row 2"},
+		{"HashOwner,HashApp,HashFunction,Trigger,1,2\no,a,f,http,1\n", "wrong number of fields"},
+	} {
+		_, err := ReadAzureTrace(strings.NewReader(test.trace))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Test %d: expected error containing %q, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	fns, err := ReadAzureTrace(strings.NewReader(azureTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first call of each function is cold, the rest warm
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	invoke := func(fnID string) Invoker {
+		return func(ctx context.Context) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[fnID]++
+			if calls[fnID] == 1 {
+				return "cold", nil
+			}
+			return "warm", nil
+		}
+	}
+
+	spec := &ReplaySpec{
+		File:      "d01.csv",
+		Functions: map[string]string{"f1": "fn1", "f2": "fn2"},
+		From:      2,
+		TimeScale: 6000,
+		Record:    true,
+	}
+	report, err := Replay(context.Background(), spec, fns, invoke, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Scheduled != 3 || report.Succeeded != 3 {
+		t.Fatalf("expected minutes 2 and 3 of f1 and f2 to send 3 requests, got %d sent and %d succeeded", report.Scheduled, report.Succeeded)
+	}
+	if calls["fn1"] != 1 || calls["fn2"] != 2 {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+	if report.Starts["cold"] != 2 || report.Starts["warm"] != 1 {
+		t.Fatalf("unexpected start types %v", report.Starts)
+	}
+	if f2 := report.Functions["f2"]; f2 == nil || f2.Succeeded != 2 || f2.Starts["warm"] != 1 {
+		t.Fatalf("unexpected report for f2 %+v", f2)
+	}
+	if len(report.Requests) != 3 || report.Requests[0].Function != "f2" || report.Requests[0].FnID != "fn2" || report.Requests[0].Start != "cold" {
+		t.Fatalf("unexpected requests %+v", report.Requests)
+	}
+
+	spec.Functions = map[string]string{"f9": "fn9"}
+	if _, err := Replay(context.Background(), spec, fns, invoke, nil); err == nil || !strings.Contains(err.Error(), `"f9" is not in the trace`) {
+		t.Fatalf("expected an unknown function to fail, got %v", err)
+	}
+
+	spec.Functions = map[string]string{"f1": "fn1"}
+	spec.From = 4
+	if _, err := Replay(context.Background(), spec, fns, invoke, nil); err == nil || !strings.Contains(err.Error(), "only 3 minutes") {
+		t.Fatalf("expected a replay past the end of the trace to fail, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type codeErr int
+
+func (c codeErr) Error() string { return http.StatusText(int(c)) }
+func (c codeErr) Code() int     { return int(c) }
+
+func TestSpecUnmarshal(t *testing.T) {
+	var spec Spec
+	err := json.Unmarshal([]byte(`{"type":"step","steps":[{"rate":2,"duration":"1m"},{"rate":4,"duration":30}]}`), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Steps[0].Duration != Duration(time.Minute) || spec.Steps[1].Duration != Duration(30*time.Second) {
+		t.Fatalf("unexpected step durations %v", spec.Steps)
+	}
+
+	if err := json.Unmarshal([]byte(`{"duration":"soon"}`), &spec); err == nil {
+		t.Fatal("expected an invalid duration to fail")
+	}
+}
+
+func TestSpecValidate(t *testing.T) {
+	for i, test := range []struct {
+		spec Spec
+		err  string
+	}{
+		{Spec{}, "missing workload type"},
+		{Spec{Type: "ramp"}, "unknown workload type"},
+		{Spec{Type: TypeConstant, Duration: Duration(time.Second)}, "positive rate"},
+		{Spec{Type: TypePoisson, Rate: 1}, "positive duration"},
+		{Spec{Type: TypeStep}, "requires steps"},
+		{Spec{Type: TypeStep, Steps: []Step{{Rate: 1}}}, "step 0"},
+		{Spec{Type: TypeTrace}, "requires a trace"},
+		{Spec{Type: TypeTrace, Trace: &Trace{}}, "no counts"},
+		{Spec{Type: TypeTrace, Trace: &Trace{Counts: []int{1, -1}}}, "trace count 1"},
+		{Spec{Type: TypeConstant, Rate: 1, Duration: Duration(time.Second), TimeScale: -1}, "time_scale"},
+		{Spec{Type: TypeConstant, Rate: 1e6, Duration: Duration(time.Minute)}, "more than"},
+		{Spec{Type: TypeConstant, Rate: 1, Duration: Duration(time.Second)}, ""},
+		{Spec{Type: TypeTrace, Trace: &Trace{File: "day1.csv"}}, ""},
+	} {
+		err := test.spec.Validate()
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("Test %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Test %d: expected error containing %q, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	ms := time.Millisecond
+
+	constant := Spec{Type: TypeConstant, Rate: 4, Duration: Duration(time.Second), TimeScale: 2}
+	at, err := constant.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSchedule(t, "constant", at, []time.Duration{0, 125 * ms, 250 * ms, 375 * ms})
+
+	step := Spec{Type: TypeStep, Steps: []Step{
+		{Rate: 2, Duration: Duration(time.Second)},
+		{Rate: 0, Duration: Duration(time.Second)},
+		{Rate: 1, Duration: Duration(time.Second)},
+	}}
+	at, err = step.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSchedule(t, "step", at, []time.Duration{0, 500 * ms, 2 * time.Second})
+
+	trace := Spec{Type: TypeTrace, TimeScale: 240, Trace: &Trace{Counts: []int{2, 0, 1}}}
+	at, err = trace.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSchedule(t, "trace", at, []time.Duration{0, 125 * ms, 500 * ms})
+
+	trace.Trace.Burst = true
+	at, err = trace.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSchedule(t, "burst trace", at, []time.Duration{0, 0, 500 * ms})
+
+	trace.Trace = &Trace{File: "day1.csv"}
+	if _, err := trace.Schedule(); err == nil {
+		t.Fatal("expected a trace with no counts to fail to schedule")
+	}
+}
+
+func TestSchedulePoisson(t *testing.T) {
+	spec := Spec{Type: TypePoisson, Rate: 100, Duration: Duration(time.Minute), Seed: 7}
+	at, err := spec.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(at) < 5400 || len(at) > 6600 {
+		t.Fatalf("expected about 6000 requests, got %d", len(at))
+	}
+	for i := 1; i < len(at); i++ {
+		if at[i] < at[i-1] || at[i] >= time.Minute {
+			t.Fatalf("request %d at %v is out of order or past the end", i, at[i])
+		}
+	}
+
+	again, _ := spec.Schedule()
+	if len(again) != len(at) || again[100] != at[100] {
+		t.Fatal("expected the same seed to give the same schedule")
+	}
+}
+
+func expectSchedule(t *testing.T, name string, got, expected []time.Duration) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("%s: expected schedule %v, got %v", name, expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("%s: expected schedule %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestReadTrace(t *testing.T) {
+	counts, err := ReadTrace(strings.NewReader("1, 2,3\n\n4,,5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 5 || counts[0] != 1 || counts[4] != 5 {
+		t.Fatalf("unexpected counts %v", counts)
+	}
+
+	if _, err := ReadTrace(strings.NewReader("1,x")); err == nil {
+		t.Fatal("expected a trace with a non numeric count to fail")
+	}
+	if _, err := ReadTrace(strings.NewReader("")); err == nil {
+		t.Fatal("expected an empty trace to fail")
+	}
+}
+
+func TestRun(t *testing.T) {
+	spec := &Spec{
+		Type:     TypeConstant,
+		Rate:     100,
+		Duration: Duration(200 * time.Millisecond),
+		Interval: Duration(100 * time.Millisecond),
+	}
+
+	var calls int32
+	report, err := Run(context.Background(), spec, func(ctx context.Context) (string, error) {
+		n := atomic.AddInt32(&calls, 1)
+		switch {
+		case n%10 == 0:
+			return "", codeErr(http.StatusServiceUnavailable)
+		case n%5 == 0:
+			return "", errors.New("boom")
+		case n == 1:
+			time.Sleep(5 * time.Millisecond)
+			return "cold", nil
+		}
+		time.Sleep(time.Millisecond)
+		return "warm", nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Scheduled != 20 || report.Sent != 20 {
+		t.Fatalf("expected 20 requests scheduled and sent, got %d and %d", report.Scheduled, report.Sent)
+	}
+	if report.Succeeded != 16 || report.Failed != 4 {
+		t.Fatalf("expected 16 successes and 4 failures, got %d and %d", report.Succeeded, report.Failed)
+	}
+	if report.Errors["503"] != 2 || report.Errors["other"] != 2 {
+		t.Fatalf("unexpected error breakdown %v", report.Errors)
+	}
+	if report.Latency.Min < 1 || report.Latency.P50 < report.Latency.Min || report.Latency.Max < report.Latency.P99 {
+		t.Fatalf("unexpected latency %+v", report.Latency)
+	}
+	if report.Starts["cold"] != 1 || report.Starts["warm"] != 15 {
+		t.Fatalf("unexpected start types %v", report.Starts)
+	}
+	if report.StartLatency["cold"].Min < 5 {
+		t.Fatalf("unexpected cold start latency %+v", report.StartLatency["cold"])
+	}
+
+	var sent, done int
+	for _, i := range report.Intervals {
+		sent += i.Sent
+		done += i.Succeeded + i.Failed
+	}
+	if len(report.Intervals) < 2 || sent != 20 || done != 20 {
+		t.Fatalf("unexpected intervals %+v", report.Intervals)
+	}
+}
+
+func TestRunMaxInFlight(t *testing.T) {
+	spec := &Spec{Type: TypeTrace, Trace: &Trace{Counts: []int{20}, Burst: true}, MaxInFlight: 3}
+
+	var inFlight, most int32
+	report, err := Run(context.Background(), spec, func(ctx context.Context) (string, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return "", nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Succeeded != 20 {
+		t.Fatalf("expected 20 successes, got %d", report.Succeeded)
+	}
+	if most > 3 {
+		t.Fatalf("expected at most 3 requests in flight, got %d", most)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	spec := &Spec{Type: TypeConstant, Rate: 10, Duration: Duration(time.Minute)}
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	report, err := Run(ctx, spec, func(ctx context.Context) (string, error) { return "", nil }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scheduled != 600 || report.Sent == 0 || report.Sent > 3 {
+		t.Fatalf("expected a couple of the 600 requests sent before cancellation, got %d of %d", report.Sent, report.Scheduled)
+	}
+}
@@ -0,0 +1,157 @@
+package loadgen
+
+import (
+	"sort"
+	"time"
+)
+
+// Latency summarises the latency of successful requests, in milliseconds
+type Latency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// Interval counts the requests of one interval of a run. Requests are counted
+// as sent in the interval they started in, and as succeeded or failed in the
+// one they completed in.
+type Interval struct {
+	Start      Duration `json:"start"`
+	Sent       int      `json:"sent"`
+	Succeeded  int      `json:"succeeded"`
+	Failed     int      `json:"failed"`
+	Throughput float64  `json:"throughput"` // successful requests per second
+
+	// Starts counts the successful requests by how they were started
+	Starts map[string]int `json:"starts,omitempty"`
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Scheduled  int      `json:"scheduled"`
+	Sent       int      `json:"sent"`
+	Succeeded  int      `json:"succeeded"`
+	Failed     int      `json:"failed"`
+	Duration   Duration `json:"duration"`
+	Throughput float64  `json:"throughput"` // successful requests per second
+
+	Latency Latency        `json:"latency"`
+	Errors  map[string]int `json:"errors,omitempty"`
+
+	// Starts counts the successful requests by how they were started, where
+	// the invoker could tell, and StartLatency summarises the latency of each
+	Starts       map[string]int     `json:"starts,omitempty"`
+	StartLatency map[string]Latency `json:"start_latency,omitempty"`
+
+	Intervals []Interval `json:"intervals"`
+}
+
+func newReport(results []result, scheduled int, elapsed, interval time.Duration, classify Classify) *Report {
+	if classify == nil {
+		classify = ClassifyByCode
+	}
+	r := &Report{
+		Scheduled: scheduled,
+		Sent:      len(results),
+		Duration:  Duration(elapsed),
+		Errors:    make(map[string]int),
+		Starts:    make(map[string]int),
+	}
+
+	n := int(elapsed / interval)
+	if elapsed%interval != 0 || n == 0 {
+		n++
+	}
+	r.Intervals = make([]Interval, n)
+	for i := range r.Intervals {
+		r.Intervals[i].Start = Duration(time.Duration(i) * interval)
+	}
+	bucket := func(d time.Duration) *Interval {
+		i := int(d / interval)
+		if i >= n {
+			i = n - 1
+		}
+		return &r.Intervals[i]
+	}
+
+	var latencies []time.Duration
+	byStart := make(map[string][]time.Duration)
+	for _, res := range results {
+		bucket(res.sent).Sent++
+		if res.err != nil {
+			r.Failed++
+			r.Errors[classify(res.err)]++
+			bucket(res.done).Failed++
+			continue
+		}
+		r.Succeeded++
+		b := bucket(res.done)
+		b.Succeeded++
+		latencies = append(latencies, res.done-res.sent)
+		if res.start != "" {
+			r.Starts[res.start]++
+			if b.Starts == nil {
+				b.Starts = make(map[string]int)
+			}
+			b.Starts[res.start]++
+			byStart[res.start] = append(byStart[res.start], res.done-res.sent)
+		}
+	}
+
+	for i := range r.Intervals {
+		length := interval
+		if end := time.Duration(i+1) * interval; end > elapsed && elapsed > time.Duration(i)*interval {
+			length = elapsed - time.Duration(i)*interval
+		}
+		r.Intervals[i].Throughput = float64(r.Intervals[i].Succeeded) / length.Seconds()
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(r.Succeeded) / elapsed.Seconds()
+	}
+	r.Latency = summarise(latencies)
+	if len(byStart) > 0 {
+		r.StartLatency = make(map[string]Latency, len(byStart))
+		for st, l := range byStart {
+			r.StartLatency[st] = summarise(l)
+		}
+	}
+	return r
+}
+
+func summarise(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	return Latency{
+		Min:  ms(latencies[0]),
+		Mean: ms(sum / time.Duration(len(latencies))),
+		P50:  ms(percentile(latencies, 50)),
+		P90:  ms(percentile(latencies, 90)),
+		P95:  ms(percentile(latencies, 95)),
+		P99:  ms(percentile(latencies, 99)),
+		Max:  ms(latencies[len(latencies)-1]),
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,151 @@
+package loadgen
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Invoker sends one request of a workload, returning once it has completed.
+// It returns how the request was started, such as "cold" or "warm", or an
+// empty string if that is not known.
+type Invoker func(ctx context.Context) (start string, err error)
+
+// Classify names the kind of a failed request in a report. The default uses
+// the status code of errors that have one, as API errors do.
+type Classify func(err error) string
+
+// ClassifyByCode is the default Classify
+func ClassifyByCode(err error) string {
+	if e, ok := err.(interface{ Code() int }); ok {
+		return strconv.Itoa(e.Code())
+	}
+	if err == context.DeadlineExceeded {
+		return "timeout"
+	}
+	if err == context.Canceled {
+		return "canceled"
+	}
+	return "other"
+}
+
+// result is the outcome of one request, times are offsets from the start
+type result struct {
+	target int
+	sent   time.Duration
+	done   time.Duration
+	start  string
+	err    error
+}
+
+// arrival is a request due to be sent to a target
+type arrival struct {
+	due    time.Duration
+	target int
+}
+
+// runner sends requests to a set of targets as they are due
+type runner struct {
+	arrivals    []arrival
+	invokers    []Invoker
+	maxInFlight int
+}
+
+// run sends every arrival, in the order they are due, and returns the
+// outcome of each along with how long it took. Requests that are due after
+// ctx is done are not sent; those in flight are left to their invoker.
+func (r *runner) run(ctx context.Context) ([]result, time.Duration) {
+	var slots chan struct{}
+	if r.maxInFlight > 0 {
+		slots = make(chan struct{}, r.maxInFlight)
+	}
+
+	results := make([]result, 0, len(r.arrivals))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+Schedule:
+	for _, a := range r.arrivals {
+		if wait := a.due - time.Since(start); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				break Schedule
+			}
+		}
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				break Schedule
+			}
+		}
+
+		wg.Add(1)
+		go func(a arrival) {
+			defer wg.Done()
+			sent := time.Since(start)
+			st, err := r.invokers[a.target](ctx)
+			done := time.Since(start)
+			if slots != nil {
+				<-slots
+			}
+			mu.Lock()
+			results = append(results, result{target: a.target, sent: sent, done: done, start: st, err: err})
+			mu.Unlock()
+		}(a)
+	}
+	wg.Wait()
+
+	// the results are in the order requests completed, put them in the order
+	// they were sent for anyone recording them
+	sort.SliceStable(results, func(i, j int) bool { return results[i].sent < results[j].sent })
+	return results, time.Since(start)
+}
+
+// Run sends the workload of spec through invoke, as it is scheduled, and
+// reports on it once every request has completed. Requests that are due
+// after ctx is done are not sent; those in flight are left to invoke.
+func Run(ctx context.Context, spec *Spec, invoke Invoker, classify Classify) (*Report, error) {
+	at, err := spec.Schedule()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &runner{
+		arrivals:    make([]arrival, len(at)),
+		invokers:    []Invoker{invoke},
+		maxInFlight: spec.MaxInFlight,
+	}
+	for i, due := range at {
+		r.arrivals[i] = arrival{due: due}
+	}
+
+	results, elapsed := r.run(ctx)
+	elapsed = workloadLength(ctx, elapsed, spec.length())
+	return newReport(results, len(at), elapsed, reportInterval(spec.Interval), classify), nil
+}
+
+// workloadLength is how long a run lasted for its report: a workload may be
+// quiet at its end, which still counts towards its length unless it was cut
+// short.
+func workloadLength(ctx context.Context, elapsed, length time.Duration) time.Duration {
+	if length > elapsed && ctx.Err() == nil {
+		return length
+	}
+	return elapsed
+}
+
+func reportInterval(d Duration) time.Duration {
+	if d == 0 {
+		return time.Second
+	}
+	return time.Duration(d)
+}
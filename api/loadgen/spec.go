@@ -0,0 +1,309 @@
+// Package loadgen drives a function with a declared shape of traffic and
+// reports how it coped, so that experiments are described in a request rather
+// than written as new handlers.
+package loadgen
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Workload types
+const (
+	// TypeConstant sends Rate requests per second for Duration
+	TypeConstant = "constant"
+	// TypeStep sends each of Steps in turn, at its own rate for its own duration
+	TypeStep = "step"
+	// TypePoisson sends requests with exponentially distributed gaps averaging
+	// Rate per second for Duration
+	TypePoisson = "poisson"
+	// TypeTrace replays the number of requests recorded in each interval of a trace
+	TypeTrace = "trace"
+)
+
+// MaxRequests caps the number of requests a single workload may send
+const MaxRequests = 1000000
+
+// Duration is a time.Duration that is written in JSON as a string such as
+// "1m30s", a plain number is taken as seconds.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case string:
+		p, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(p)
+	default:
+		return fmt.Errorf("invalid duration %s", b)
+	}
+	return nil
+}
+
+// Step is one stage of a step workload
+type Step struct {
+	Rate     float64  `json:"rate"`
+	Duration Duration `json:"duration"`
+}
+
+// Trace is a recording of how many requests arrived in each interval
+type Trace struct {
+	// Counts is the number of requests in each interval, in order
+	Counts []int `json:"counts,omitempty"`
+	// File names a CSV file of counts to use instead of Counts, it is
+	// resolved by whoever runs the workload
+	File string `json:"file,omitempty"`
+	// Interval is the length of each interval in the trace, default one minute
+	Interval Duration `json:"interval,omitempty"`
+	// Burst sends the requests of each interval at once at its start, rather
+	// than spreading them evenly across it
+	Burst bool `json:"burst,omitempty"`
+}
+
+// Spec declares a workload to send to a function
+type Spec struct {
+	Type     string   `json:"type"`
+	Rate     float64  `json:"rate,omitempty"`
+	Duration Duration `json:"duration,omitempty"`
+	Steps    []Step   `json:"steps,omitempty"`
+	Trace    *Trace   `json:"trace,omitempty"`
+
+	// TimeScale speeds up the workload, a scale of 240 plays a minute of it
+	// in a quarter of a second. Rates are per second of the unscaled workload.
+	TimeScale float64 `json:"time_scale,omitempty"`
+	// Seed seeds the random gaps of a poisson workload, so runs can be repeated
+	Seed int64 `json:"seed,omitempty"`
+	// MaxInFlight bounds the requests outstanding at once, requests due while
+	// it is reached are sent late rather than dropped. 0 is unbounded.
+	MaxInFlight int `json:"max_in_flight,omitempty"`
+	// Interval is the length of each interval of the report, default one second
+	Interval Duration `json:"interval,omitempty"`
+	// Body is sent as the body of every request
+	Body string `json:"body,omitempty"`
+}
+
+var errNoTraceCounts = errors.New("trace has no counts")
+
+// Validate checks that a spec describes a workload that can be scheduled
+func (s *Spec) Validate() error {
+	if s.TimeScale < 0 {
+		return errors.New("time_scale must not be negative")
+	}
+	if s.MaxInFlight < 0 {
+		return errors.New("max_in_flight must not be negative")
+	}
+	if s.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+
+	switch s.Type {
+	case TypeConstant, TypePoisson:
+		if s.Rate <= 0 {
+			return fmt.Errorf("%s workload requires a positive rate", s.Type)
+		}
+		if s.Duration <= 0 {
+			return fmt.Errorf("%s workload requires a positive duration", s.Type)
+		}
+	case TypeStep:
+		if len(s.Steps) == 0 {
+			return errors.New("step workload requires steps")
+		}
+		for i, st := range s.Steps {
+			if st.Rate < 0 || st.Duration <= 0 {
+				return fmt.Errorf("step %d requires a rate of at least 0 and a positive duration", i)
+			}
+		}
+	case TypeTrace:
+		if s.Trace == nil {
+			return errors.New("trace workload requires a trace")
+		}
+		if s.Trace.Interval < 0 {
+			return errors.New("trace interval must not be negative")
+		}
+		if len(s.Trace.Counts) == 0 && s.Trace.File == "" {
+			return errNoTraceCounts
+		}
+		for i, n := range s.Trace.Counts {
+			if n < 0 {
+				return fmt.Errorf("trace count %d is negative", i)
+			}
+		}
+	case "":
+		return errors.New("missing workload type")
+	default:
+		return fmt.Errorf("unknown workload type %q", s.Type)
+	}
+
+	if n := s.expectedRequests(); n > MaxRequests {
+		return fmt.Errorf("workload would send about %.0f requests, more than %d", n, MaxRequests)
+	}
+	return nil
+}
+
+func (s *Spec) expectedRequests() float64 {
+	switch s.Type {
+	case TypeConstant, TypePoisson:
+		return s.Rate * time.Duration(s.Duration).Seconds()
+	case TypeStep:
+		var n float64
+		for _, st := range s.Steps {
+			n += st.Rate * time.Duration(st.Duration).Seconds()
+		}
+		return n
+	case TypeTrace:
+		var n float64
+		for _, c := range s.Trace.Counts {
+			n += float64(c)
+		}
+		return n
+	}
+	return 0
+}
+
+func (s *Spec) scale(d time.Duration) time.Duration {
+	if s.TimeScale == 0 {
+		return d
+	}
+	return time.Duration(float64(d) / s.TimeScale)
+}
+
+func (s *Spec) traceInterval() time.Duration {
+	if s.Trace.Interval == 0 {
+		return time.Minute
+	}
+	return time.Duration(s.Trace.Interval)
+}
+
+// Schedule returns when each request of the workload is due, as offsets from
+// its start in ascending order, with the time scale applied. A trace workload
+// must have its counts in the spec by now.
+func (s *Spec) Schedule() ([]time.Duration, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	var at []time.Duration
+	switch s.Type {
+	case TypeConstant:
+		at = evenly(nil, 0, time.Duration(s.Duration), s.Rate)
+	case TypeStep:
+		var start time.Duration
+		for _, st := range s.Steps {
+			at = evenly(at, start, time.Duration(st.Duration), st.Rate)
+			start += time.Duration(st.Duration)
+		}
+	case TypePoisson:
+		rng := rand.New(rand.NewSource(s.Seed))
+		end := float64(time.Duration(s.Duration))
+		for t := rng.ExpFloat64() / s.Rate * float64(time.Second); t < end; t += rng.ExpFloat64() / s.Rate * float64(time.Second) {
+			at = append(at, time.Duration(t))
+		}
+	case TypeTrace:
+		if len(s.Trace.Counts) == 0 {
+			return nil, errNoTraceCounts
+		}
+		interval := s.traceInterval()
+		for i, n := range s.Trace.Counts {
+			start := time.Duration(i) * interval
+			if s.Trace.Burst {
+				for j := 0; j < n; j++ {
+					at = append(at, start)
+				}
+				continue
+			}
+			for j := 0; j < n; j++ {
+				at = append(at, start+interval*time.Duration(j)/time.Duration(n))
+			}
+		}
+	}
+
+	for i := range at {
+		at[i] = s.scale(at[i])
+	}
+	return at, nil
+}
+
+// evenly appends the offsets of requests sent at rate per second, evenly
+// spaced, from start for d
+func evenly(at []time.Duration, start, d time.Duration, rate float64) []time.Duration {
+	if rate == 0 {
+		return at
+	}
+	gap := float64(time.Second) / rate
+	for t := float64(0); t < float64(d); t += gap {
+		at = append(at, start+time.Duration(t))
+	}
+	return at
+}
+
+// length is how long the workload lasts, with the time scale applied, which
+// may be later than its last request
+func (s *Spec) length() time.Duration {
+	var d time.Duration
+	switch s.Type {
+	case TypeConstant, TypePoisson:
+		d = time.Duration(s.Duration)
+	case TypeStep:
+		for _, st := range s.Steps {
+			d += time.Duration(st.Duration)
+		}
+	case TypeTrace:
+		d = time.Duration(len(s.Trace.Counts)) * s.traceInterval()
+	}
+	return s.scale(d)
+}
+
+// ReadTrace reads the counts of a trace from CSV, every field of every record
+// in order. Fields that are blank are skipped.
+func ReadTrace(r io.Reader) ([]int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var counts []int
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range rec {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			n, err := strconv.Atoi(f)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid trace count %q in record %d", f, line)
+			}
+			counts = append(counts, n)
+		}
+	}
+	if len(counts) == 0 {
+		return nil, errNoTraceCounts
+	}
+	return counts, nil
+}
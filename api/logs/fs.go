@@ -0,0 +1,192 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+const (
+	callFileExt = ".json"
+	logFileExt  = ".log"
+)
+
+// fsStore keeps each call in a directory per fn, as a JSON file of its
+// record and a text file of its log, both named after the call ID
+type fsStore struct {
+	dir string
+}
+
+var _ Store = &fsStore{}
+
+// NewFS creates a store of calls and logs in dir, which is created if it does
+// not exist.
+func NewFS(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	return &fsStore{dir: dir}, nil
+}
+
+// validName reports whether an ID may be used as a file name under the store
+// directory, without escaping it
+func validName(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+func (fs *fsStore) path(fnID, callID, ext string) string {
+	return filepath.Join(fs.dir, fnID, callID+ext)
+}
+
+// writeFile replaces the file at path with b, so that it is never read half
+// written
+func (fs *fsStore) writeFile(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (fs *fsStore) InsertCall(ctx context.Context, call *models.Call) error {
+	if !validName(call.ID) {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if !validName(call.FnID) {
+		return models.ErrDatastoreEmptyFnID
+	}
+
+	b, err := json.Marshal(callRecord(call))
+	if err != nil {
+		return err
+	}
+	return fs.writeFile(fs.path(call.FnID, call.ID, callFileExt), b)
+}
+
+func (fs *fsStore) readCall(fnID, callID string) (*models.Call, error) {
+	b, err := ioutil.ReadFile(fs.path(fnID, callID, callFileExt))
+	if os.IsNotExist(err) {
+		return nil, models.ErrCallNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	var call models.Call
+	if err := json.Unmarshal(b, &call); err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+func (fs *fsStore) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	if !validName(fnID) || !validName(callID) {
+		return nil, models.ErrCallNotFound
+	}
+	return fs.readCall(fnID, callID)
+}
+
+func (fs *fsStore) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	if filter == nil || !validName(filter.FnID) {
+		return nil, models.ErrDatastoreEmptyFnID
+	}
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	res := &models.CallList{Items: []*models.Call{}}
+
+	files, err := ioutil.ReadDir(filepath.Join(fs.dir, filter.FnID))
+	if os.IsNotExist(err) {
+		return res, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, f := range files {
+		name := f.Name()
+		if strings.HasSuffix(name, callFileExt) && !strings.HasPrefix(name, ".") {
+			ids = append(ids, strings.TrimSuffix(name, callFileExt))
+		}
+	}
+	// newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	for _, id := range ids {
+		if filter.PerPage > 0 && len(res.Items) == filter.PerPage {
+			break
+		}
+		if cursor != "" && strings.Compare(id, cursor) >= 0 {
+			continue
+		}
+		call, err := fs.readCall(filter.FnID, id)
+		if err == models.ErrCallNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if callInTimeRange(call, filter) {
+			res.Items = append(res.Items, call)
+		}
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return res, nil
+}
+
+func (fs *fsStore) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	if !validName(call.ID) {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if !validName(call.FnID) {
+		return models.ErrDatastoreEmptyFnID
+	}
+
+	b, err := ioutil.ReadAll(callLog)
+	if err != nil {
+		return err
+	}
+	return fs.writeFile(fs.path(call.FnID, call.ID, logFileExt), b)
+}
+
+func (fs *fsStore) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	if !validName(fnID) || !validName(callID) {
+		return nil, models.ErrCallLogNotFound
+	}
+	b, err := ioutil.ReadFile(fs.path(fnID, callID, logFileExt))
+	if os.IsNotExist(err) {
+		return nil, models.ErrCallLogNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
@@ -0,0 +1,67 @@
+// Package logs holds the stores that the records and logs of calls are kept
+// in once the calls have run.
+package logs
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Store keeps both the records and the logs of calls
+type Store interface {
+	models.CallStore
+	models.LogStore
+}
+
+// New creates a Store from the specified URL. A file URL keeps calls in a
+// directory of the local filesystem, a memory URL keeps them in memory, and
+// any other URL is a datastore URL, eg. of a sql database, that calls are
+// kept in.
+func New(ctx context.Context, storeURL string) (Store, error) {
+	log := common.Logger(ctx)
+	u, err := url.Parse(storeURL)
+	if err != nil {
+		return nil, fmt.Errorf("bad log store URL %s: %v", storeURL, err)
+	}
+	log.WithFields(logrus.Fields{"store": u.Scheme}).Debug("creating new log store")
+
+	switch u.Scheme {
+	case "file":
+		return NewFS(u.Path)
+	case "memory":
+		return NewMock(), nil
+	}
+
+	ds, err := datastore.New(ctx, storeURL)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := ds.(Store)
+	if !ok {
+		return nil, fmt.Errorf("data store %s cannot store calls and logs", u.Scheme)
+	}
+	return store, nil
+}
+
+// callRecord returns the part of call that is stored, its status and timings,
+// leaving out its config and request, which may hold secrets
+func callRecord(call *models.Call) *models.Call {
+	return &models.Call{
+		ID:          call.ID,
+		AppID:       call.AppID,
+		FnID:        call.FnID,
+		TriggerID:   call.TriggerID,
+		Status:      call.Status,
+		Error:       call.Error,
+		Stats:       call.Stats,
+		CreatedAt:   call.CreatedAt,
+		StartedAt:   call.StartedAt,
+		CompletedAt: call.CompletedAt,
+	}
+}
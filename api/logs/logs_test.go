@@ -0,0 +1,51 @@
+package logs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	_ "github.com/fnproject/fn/api/datastore/sql"
+	_ "github.com/fnproject/fn/api/datastore/sql/sqlite"
+)
+
+func TestMock(t *testing.T) {
+	f := func(t *testing.T) datastoretest.CallLogStore {
+		return NewMock()
+	}
+	datastoretest.RunCallLogStoreTest(t, f)
+}
+
+func TestFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := func(t *testing.T) datastoretest.CallLogStore {
+		s, err := New(context.Background(), "file://"+dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	datastoretest.RunCallLogStoreTest(t, f)
+}
+
+func TestNewFromDatastoreURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-logs-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := New(context.Background(), "sqlite3://"+dir+"/fn.db"); err != nil {
+		t.Fatalf("expected a sql store of calls and logs, got: %v", err)
+	}
+	if _, err := New(context.Background(), "nope://"); err == nil {
+		t.Fatal("expected an unknown store URL to be refused")
+	}
+}
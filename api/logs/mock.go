@@ -0,0 +1,140 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+type mock struct {
+	lock  sync.Mutex
+	Calls []*models.Call
+	Logs  map[string][]byte
+}
+
+var _ Store = &mock{}
+
+// NewMock creates a new in-memory store of calls and logs. It is safe for
+// concurrent use, since calls are inserted from the goroutines that run them.
+func NewMock() Store {
+	return &mock{Logs: make(map[string][]byte)}
+}
+
+func (m *mock) InsertCall(ctx context.Context, call *models.Call) error {
+	if call.ID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if call.FnID == "" {
+		return models.ErrDatastoreEmptyFnID
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.Calls = append(m.Calls, callRecord(call))
+	// newest first
+	sort.Slice(m.Calls, func(i, j int) bool { return m.Calls[i].ID > m.Calls[j].ID })
+	return nil
+}
+
+func (m *mock) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, c := range m.Calls {
+		if c.ID == callID && c.FnID == fnID {
+			cl := *c
+			return &cl, nil
+		}
+	}
+	return nil, models.ErrCallNotFound
+}
+
+func (m *mock) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	if filter == nil || filter.FnID == "" {
+		return nil, models.ErrDatastoreEmptyFnID
+	}
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	res := []*models.Call{}
+	for _, c := range m.Calls {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if c.FnID == filter.FnID &&
+			(cursor == "" || strings.Compare(c.ID, cursor) < 0) &&
+			callInTimeRange(c, filter) {
+			cl := *c
+			res = append(res, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.CallList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+// callInTimeRange reports whether call was created between the from and to
+// times of filter, where they are set
+func callInTimeRange(call *models.Call, filter *models.CallFilter) bool {
+	created := time.Time(call.CreatedAt)
+	from, to := time.Time(filter.FromTime), time.Time(filter.ToTime)
+	return (from.IsZero() || created.After(from)) && (to.IsZero() || created.Before(to))
+}
+
+func (m *mock) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	if call.ID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if call.FnID == "" {
+		return models.ErrDatastoreEmptyFnID
+	}
+
+	b, err := ioutil.ReadAll(callLog)
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.Logs[call.FnID+"/"+call.ID] = b
+	return nil
+}
+
+func (m *mock) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	b, ok := m.Logs[fnID+"/"+callID]
+	if !ok {
+		return nil, models.ErrCallLogNotFound
+	}
+	return bytes.NewReader(b), nil
+}
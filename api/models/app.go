@@ -54,6 +54,8 @@ var (
 		code:  http.StatusNotFound,
 		error: errors.New("App not found"),
 	}
+
+	errSyslogScheme = errors.New("invalid scheme, only [tcp, udp, tcp+tls] are supported")
 )
 
 type App struct {
@@ -76,14 +78,23 @@ func (a *App) Validate() error {
 		return err
 	}
 
+	if _, err := AppMaxConcurrency(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := AppMaxPriority(a.Annotations); err != nil {
+		return err
+	}
+
 	if a.SyslogURL != nil && *a.SyslogURL != "" {
 		url, err := url.Parse(strings.TrimSpace(*a.SyslogURL))
 		if err == nil {
-			// See: https://docs.docker.com/config/containers/logging/syslog/#options
 			switch url.Scheme {
-			case "udp", "tcp", "unix", "unixgram", "tcp+tls":
+			case "udp", "tcp", "tcp+tls":
+			case "unix", "unixgram":
+				// deprecated, see ValidateSyslogScheme
 			default:
-				err = fmt.Errorf("invalid scheme, only [tcp, udp, unix, unixgram, tcp+tls] are supported")
+				err = errSyslogScheme
 			}
 		}
 		if err != nil { // not else if for a reason...
@@ -93,6 +104,21 @@ func (a *App) Validate() error {
 	return nil
 }
 
+// ValidateSyslogScheme returns an error if the syslog url of a names a socket
+// of the host, with the unix or unixgram scheme. Apps could once be stored with
+// those, which the agent does not dial, so Validate still accepts them for the
+// apps to be updated, but no app may set one anew.
+func (a *App) ValidateSyslogScheme() error {
+	if a.SyslogURL == nil {
+		return nil
+	}
+	url, err := url.Parse(strings.TrimSpace(*a.SyslogURL))
+	if err == nil && (url.Scheme == "unix" || url.Scheme == "unixgram") {
+		return ErrInvalidSyslog(fmt.Sprintf(`invalid syslog url: "%v" %v`, *a.SyslogURL, errSyslogScheme))
+	}
+	return nil
+}
+
 func (a *App) ValidateName() error {
 	if a.Name == "" {
 		return ErrMissingName
@@ -179,13 +179,20 @@ func TestValidateAppName(t *testing.T) {
 func TestValidateApp(t *testing.T) {
 	valid_name := "valid_name"
 	valid_syslog := "tcp://localhost:13371"
+	unix_syslog := "unix:///dev/log"
+	valid_concurrency, _ := EmptyAnnotations().With(AppMaxConcurrencyAnnotation, 10)
+	invalid_concurrency, _ := EmptyAnnotations().With(AppMaxConcurrencyAnnotation, 0)
 
 	testCases := []struct {
 		App  App
 		Want error
 	}{
 		{App{Name: valid_name, SyslogURL: &valid_syslog}, nil},
+		// stored before unix urls were deprecated, see TestValidateSyslogScheme
+		{App{Name: valid_name, SyslogURL: &unix_syslog}, nil},
 		{App{Name: ""}, ErrMissingName},
+		{App{Name: valid_name, Annotations: valid_concurrency}, nil},
+		{App{Name: valid_name, Annotations: invalid_concurrency}, ErrAppsInvalidMaxConcurrency},
 	}
 
 	for _, testCase := range testCases {
@@ -197,3 +204,25 @@ func TestValidateApp(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateSyslogScheme(t *testing.T) {
+	valid_syslog := "tcp://localhost:13371"
+	unix_syslog := "unix:///dev/log"
+	unixgram_syslog := "unixgram:///dev/log"
+
+	testCases := []struct {
+		App  App
+		Want error
+	}{
+		{App{}, nil},
+		{App{SyslogURL: &valid_syslog}, nil},
+		{App{SyslogURL: &unix_syslog}, ErrInvalidSyslog(`invalid syslog url: "unix:///dev/log" invalid scheme, only [tcp, udp, tcp+tls] are supported`)},
+		{App{SyslogURL: &unixgram_syslog}, ErrInvalidSyslog(`invalid syslog url: "unixgram:///dev/log" invalid scheme, only [tcp, udp, tcp+tls] are supported`)},
+	}
+
+	for _, testCase := range testCases {
+		if got := testCase.App.ValidateSyslogScheme(); got != testCase.Want {
+			t.Errorf("App.ValidateSyslogScheme() failed for %+v - wanted: %v but got: %v", testCase.App, testCase.Want, got)
+		}
+	}
+}
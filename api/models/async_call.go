@@ -0,0 +1,184 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Async call statuses
+const (
+	AsyncCallStatusQueued    = "queued"
+	AsyncCallStatusRunning   = "running"
+	AsyncCallStatusSucceeded = "succeeded"
+	AsyncCallStatusFailed    = "failed"
+	// AsyncCallStatusDead is the status of a call that could not be run in as
+	// many attempts as a worker makes, the dead-letter list is the calls with it
+	AsyncCallStatusDead = "dead"
+)
+
+var asyncCallStatuses = []string{
+	AsyncCallStatusQueued,
+	AsyncCallStatusRunning,
+	AsyncCallStatusSucceeded,
+	AsyncCallStatusFailed,
+	AsyncCallStatusDead,
+}
+
+var (
+	//ErrAsyncCallsNotFound - async call not found
+	ErrAsyncCallsNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Async call not found"),
+	}
+	//ErrAsyncCallsMissingID - no ID specified for an async call
+	ErrAsyncCallsMissingID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing async call ID"),
+	}
+	//ErrAsyncCallsMissingFnID - an async call was queued without the fn to run
+	ErrAsyncCallsMissingFnID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing fn ID on async call"),
+	}
+	//ErrAsyncCallsAlreadyExists - an async call was queued with the ID of another
+	ErrAsyncCallsAlreadyExists = err{
+		code:  http.StatusConflict,
+		error: errors.New("Async call with specified ID already exists"),
+	}
+	//ErrAsyncCallsNotReserved - an async call was released or finished by a worker that no longer has it reserved
+	ErrAsyncCallsNotReserved = err{
+		code:  http.StatusConflict,
+		error: errors.New("Async call is not reserved by this worker"),
+	}
+	//ErrAsyncCallsInvalidStatus - unknown async call status
+	ErrAsyncCallsInvalidStatus = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid status for async call"),
+	}
+)
+
+// AsyncCall is an invocation that is queued to run later. The request is kept
+// with it so that a worker can make the call, as many times as it takes for
+// it to finish, and the response is kept once it has so that the caller can
+// get it by the ID of the call.
+type AsyncCall struct {
+	ID     string `json:"id" db:"id"`
+	AppID  string `json:"app_id" db:"app_id"`
+	FnID   string `json:"fn_id" db:"fn_id"`
+	Status string `json:"status" db:"status"`
+	// Attempts is how many times a worker has reserved the call to run it
+	Attempts int `json:"attempts" db:"attempts"`
+
+	// the request to make
+	Method  string  `json:"-" db:"method"`
+	URL     string  `json:"-" db:"url"`
+	Headers Headers `json:"-" db:"headers"`
+	Body    string  `json:"-" db:"body"`
+
+	// the response of the fn, once the call has finished
+	ResultStatus  int     `json:"result_status,omitempty" db:"result_status"`
+	ResultHeaders Headers `json:"result_headers,omitempty" db:"result_headers"`
+	Result        string  `json:"result,omitempty" db:"result"`
+	Error         string  `json:"error,omitempty" db:"error"`
+
+	// VisibleAt is when, in unix milliseconds, the call may next be reserved
+	VisibleAt int64 `json:"-" db:"visible_at"`
+	// Reservation identifies the reservation of the worker that has the call,
+	// only that worker may release or finish it
+	Reservation string `json:"-" db:"reservation"`
+
+	CreatedAt common.DateTime `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt common.DateTime `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// Validate checks that an async call has valid data for inserting into a store
+func (c *AsyncCall) Validate() error {
+	if c.ID == "" {
+		return ErrAsyncCallsMissingID
+	}
+	if c.FnID == "" {
+		return ErrAsyncCallsMissingFnID
+	}
+	if !ValidAsyncCallStatus(c.Status) {
+		return ErrAsyncCallsInvalidStatus
+	}
+	return nil
+}
+
+// Clone creates a deep copy of an async call
+func (c *AsyncCall) Clone() *AsyncCall {
+	clone := new(AsyncCall)
+	*clone = *c
+	if c.Headers != nil {
+		clone.Headers = Headers(http.Header(c.Headers).Clone())
+	}
+	if c.ResultHeaders != nil {
+		clone.ResultHeaders = Headers(http.Header(c.ResultHeaders).Clone())
+	}
+	return clone
+}
+
+// Done returns whether the async call has reached a terminal status
+func (c *AsyncCall) Done() bool {
+	return c.Status == AsyncCallStatusSucceeded || c.Status == AsyncCallStatusFailed || c.Status == AsyncCallStatusDead
+}
+
+// ValidAsyncCallStatus checks that a given async call status is known
+func ValidAsyncCallStatus(s string) bool {
+	for _, v := range asyncCallStatuses {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// AsyncCallFilter is a search criteria on async calls
+type AsyncCallFilter struct {
+	FnID   string // exact match
+	Status string // exact match
+
+	Cursor  string
+	PerPage int
+}
+
+// AsyncCallList is a container of async calls returned by search, optionally indicating the next page cursor
+type AsyncCallList struct {
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Items      []*AsyncCall `json:"items"`
+}
+
+// AsyncQueue persists async calls until they have run. Delivery is at least
+// once: a call reserved by a worker that goes away becomes visible again once
+// its visibility timeout is over, and is reserved by another.
+type AsyncQueue interface {
+	// Push queues a new call, with the ID it was given. Returns ErrAsyncCallsAlreadyExists
+	// if a call exists with the same ID.
+	Push(ctx context.Context, call *AsyncCall) (*AsyncCall, error)
+
+	// Reserve takes the oldest call that is visible, marks it running and hides it from other
+	// workers for visibility. The call has a new Reservation, that Release and Finish take.
+	// Returns nil, and no error, if no call is visible.
+	Reserve(ctx context.Context, visibility time.Duration) (*AsyncCall, error)
+
+	// Release makes a call reserved with reservation visible again after delay, for another
+	// attempt. Returns ErrAsyncCallsNotFound if no call exists with the ID, and
+	// ErrAsyncCallsNotReserved if the call is no longer under reservation.
+	Release(ctx context.Context, callID, reservation string, delay time.Duration) error
+
+	// Finish records the status and result of a call that will not be run again, if the call
+	// is still under call.Reservation. Returns ErrAsyncCallsNotFound if no call exists with the
+	// same ID, and ErrAsyncCallsNotReserved if the call is no longer under the reservation.
+	Finish(ctx context.Context, call *AsyncCall) (*AsyncCall, error)
+
+	// GetAsyncCall returns a call by ID. Returns ErrAsyncCallsMissingID if callID is empty.
+	// Returns ErrAsyncCallsNotFound if a call is not found.
+	GetAsyncCall(ctx context.Context, callID string) (*AsyncCall, error)
+
+	// GetAsyncCalls returns a list of calls, and a cursor, applying any additional filters provided.
+	GetAsyncCalls(ctx context.Context, filter *AsyncCallFilter) (*AsyncCallList, error)
+}
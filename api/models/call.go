@@ -15,8 +15,29 @@ const (
 	TypeSync = "sync"
 	// TypeDetached is used for calls which return an ack to the caller as soon as the call starts
 	TypeDetached = "detached"
+	// TypeAsync is used for calls which are queued and acknowledged before they start, to be run
+	// by a worker and have their result fetched later
+	TypeAsync = "async"
 )
 
+// Start types of a call, which tell how the container it ran in came to be
+// available to it
+const (
+	// StartTypeWarm is a call that ran in a container that was already running
+	StartTypeWarm = "warm"
+	// StartTypeCold is a call that ran in a container started for it
+	StartTypeCold = "cold"
+	// StartTypePreforkPool is a call that ran in a container started for it,
+	// in the namespaces of a container from the prefork pool
+	StartTypePreforkPool = "prefork-pool"
+	// StartTypePausedResume is a call that ran in a container that was paused
+	// while idle and resumed for it
+	StartTypePausedResume = "paused-resume"
+)
+
+// StartTypeHeader is the response header that tells the start type of a call
+const StartTypeHeader = "Fn-Start-Type"
+
 var possibleStatuses = [...]string{"delayed", "queued", "running", "success", "error", "cancelled"}
 
 // Call is a representation of a specific invocation of a fn.
@@ -111,6 +132,19 @@ type Call struct {
 	// SyslogURL is a syslog URL to send all logs to.
 	SyslogURL string `json:"syslog_url,omitempty" db:"-"`
 
+	// Priority of the call, one of PriorityLow, PriorityNormal and PriorityHigh.
+	Priority int `json:"priority,omitempty" db:"-"`
+
+	// FnMaxConcurrency and AppMaxConcurrency are the most calls of the fn of
+	// the call, and of the fns of its app, that an agent runs at once, as the
+	// fn and the app each have it annotated. 0 is no limit.
+	FnMaxConcurrency  int `json:"fn_max_concurrency,omitempty" db:"-"`
+	AppMaxConcurrency int `json:"app_max_concurrency,omitempty" db:"-"`
+
+	// Stream is whether the response of the call is sent to the client as the
+	// function writes it, rather than once the call ends.
+	Stream bool `json:"stream,omitempty" db:"-"`
+
 	// Time when call completed, whether it was successful or failed. Always in UTC.
 	CompletedAt common.DateTime `json:"completed_at,omitempty" db:"completed_at"`
 
@@ -123,6 +157,10 @@ type Call struct {
 	// Duration that user code was running for, in nanoseconds.
 	ExecutionDuration time.Duration `json:"execution_duration,omitempty" db:"execution_duration"`
 
+	// StartType tells how the container the call ran in came to be
+	// available, empty if the call has not run in a container.
+	StartType string `json:"start_type,omitempty" db:"-"`
+
 	// Stats is a list of metrics from this call's execution, possibly empty.
 	Stats stats.Stats `json:"stats,omitempty" db:"stats"`
 
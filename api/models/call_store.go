@@ -0,0 +1,32 @@
+package models
+
+import (
+	"context"
+	"io"
+)
+
+// CallStore persists the records of calls once they have run, with their
+// status and timings, so that they can be looked up after the fact.
+type CallStore interface {
+	// InsertCall inserts the record of a call. Returns ErrDatastoreEmptyCallID if the
+	// call has no ID, and ErrDatastoreEmptyFnID if it has no fn ID.
+	InsertCall(ctx context.Context, call *Call) error
+
+	// GetCall returns the record of a call of a fn. Returns ErrCallNotFound if
+	// there is none.
+	GetCall(ctx context.Context, fnID, callID string) (*Call, error)
+
+	// GetCalls returns the records of the calls of the fn of filter, newest first, and a
+	// cursor. Returns ErrDatastoreEmptyFnID if filter has no fn ID.
+	GetCalls(ctx context.Context, filter *CallFilter) (*CallList, error)
+}
+
+// LogStore persists the stderr captured from calls.
+type LogStore interface {
+	// InsertLog inserts the log of a call, overwriting any it had before.
+	InsertLog(ctx context.Context, call *Call, callLog io.Reader) error
+
+	// GetLog returns the log of a call of a fn. Returns ErrCallLogNotFound if
+	// there is none.
+	GetLog(ctx context.Context, fnID, callID string) (io.Reader, error)
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChoiceRule is a single rule of a Choice state. A top level rule has a Next
+// state, and is either a comparison of the value at Variable or a boolean
+// combination of nested rules. The first comparison operator set is used.
+type ChoiceRule struct {
+	Variable string
+	Next     string
+
+	And []*ChoiceRule
+	Or  []*ChoiceRule
+	Not *ChoiceRule
+
+	StringEquals            *string
+	StringLessThan          *string
+	StringGreaterThan       *string
+	StringLessThanEquals    *string
+	StringGreaterThanEquals *string
+	StringMatches           *string
+
+	NumericEquals            *float64
+	NumericLessThan          *float64
+	NumericGreaterThan       *float64
+	NumericLessThanEquals    *float64
+	NumericGreaterThanEquals *float64
+
+	BooleanEquals *bool
+
+	TimestampEquals            *string
+	TimestampLessThan          *string
+	TimestampGreaterThan       *string
+	TimestampLessThanEquals    *string
+	TimestampGreaterThanEquals *string
+
+	IsPresent   *bool
+	IsNull      *bool
+	IsString    *bool
+	IsNumeric   *bool
+	IsBoolean   *bool
+	IsTimestamp *bool
+}
+
+// Choose returns the name of the state a Choice state transitions to for
+// the given input, which is the Next of the first matching rule, or the
+// Default if no rule matches.
+func (s *State) Choose(input interface{}) (string, error) {
+	for _, rule := range s.Choices {
+		ok, err := rule.Matches(input)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return rule.Next, nil
+		}
+	}
+	if s.Default != "" {
+		return s.Default, nil
+	}
+	return "", NewStateError(StatesErrorNoChoiceMatched, "no choice rule matched and there is no Default")
+}
+
+// Matches evaluates the rule against the given input
+func (r *ChoiceRule) Matches(input interface{}) (bool, error) {
+	switch {
+	case r.And != nil:
+		for _, sub := range r.And {
+			ok, err := sub.Matches(input)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case r.Or != nil:
+		for _, sub := range r.Or {
+			ok, err := sub.Matches(input)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case r.Not != nil:
+		ok, err := r.Not.Matches(input)
+		return !ok, err
+	}
+
+	if r.Variable == "" {
+		return false, NewStateError(StatesErrorRuntime, "choice rule has no Variable")
+	}
+	v, err := JSONPathGet(input, r.Variable)
+	if r.IsPresent != nil {
+		return (err == nil) == *r.IsPresent, nil
+	}
+	if err != nil {
+		return false, NewStateError(StatesErrorRuntime, err.Error())
+	}
+
+	switch {
+	case r.IsNull != nil:
+		return (v == nil) == *r.IsNull, nil
+	case r.IsString != nil:
+		_, ok := v.(string)
+		return ok == *r.IsString, nil
+	case r.IsNumeric != nil:
+		_, ok := v.(float64)
+		return ok == *r.IsNumeric, nil
+	case r.IsBoolean != nil:
+		_, ok := v.(bool)
+		return ok == *r.IsBoolean, nil
+	case r.IsTimestamp != nil:
+		_, ok := timestampValue(v)
+		return ok == *r.IsTimestamp, nil
+	}
+
+	if c, ok := r.stringComparison(); ok {
+		s, isString := v.(string)
+		return isString && c(s), nil
+	}
+	if c, ok := r.numericComparison(); ok {
+		n, isNumber := v.(float64)
+		return isNumber && c(n), nil
+	}
+	if r.BooleanEquals != nil {
+		b, isBool := v.(bool)
+		return isBool && b == *r.BooleanEquals, nil
+	}
+	if c, ok, err := r.timestampComparison(); ok {
+		if err != nil {
+			return false, err
+		}
+		t, isTime := timestampValue(v)
+		return isTime && c(t), nil
+	}
+
+	return false, NewStateError(StatesErrorRuntime, fmt.Sprintf("choice rule on %s has no comparison", r.Variable))
+}
+
+func (r *ChoiceRule) stringComparison() (func(string) bool, bool) {
+	switch {
+	case r.StringEquals != nil:
+		return func(s string) bool { return s == *r.StringEquals }, true
+	case r.StringLessThan != nil:
+		return func(s string) bool { return s < *r.StringLessThan }, true
+	case r.StringGreaterThan != nil:
+		return func(s string) bool { return s > *r.StringGreaterThan }, true
+	case r.StringLessThanEquals != nil:
+		return func(s string) bool { return s <= *r.StringLessThanEquals }, true
+	case r.StringGreaterThanEquals != nil:
+		return func(s string) bool { return s >= *r.StringGreaterThanEquals }, true
+	case r.StringMatches != nil:
+		return func(s string) bool { return wildcardMatch(*r.StringMatches, s) }, true
+	}
+	return nil, false
+}
+
+func (r *ChoiceRule) numericComparison() (func(float64) bool, bool) {
+	switch {
+	case r.NumericEquals != nil:
+		return func(n float64) bool { return n == *r.NumericEquals }, true
+	case r.NumericLessThan != nil:
+		return func(n float64) bool { return n < *r.NumericLessThan }, true
+	case r.NumericGreaterThan != nil:
+		return func(n float64) bool { return n > *r.NumericGreaterThan }, true
+	case r.NumericLessThanEquals != nil:
+		return func(n float64) bool { return n <= *r.NumericLessThanEquals }, true
+	case r.NumericGreaterThanEquals != nil:
+		return func(n float64) bool { return n >= *r.NumericGreaterThanEquals }, true
+	}
+	return nil, false
+}
+
+func (r *ChoiceRule) timestampComparison() (func(time.Time) bool, bool, error) {
+	var op string
+	var ref *string
+	switch {
+	case r.TimestampEquals != nil:
+		op, ref = "==", r.TimestampEquals
+	case r.TimestampLessThan != nil:
+		op, ref = "<", r.TimestampLessThan
+	case r.TimestampGreaterThan != nil:
+		op, ref = ">", r.TimestampGreaterThan
+	case r.TimestampLessThanEquals != nil:
+		op, ref = "<=", r.TimestampLessThanEquals
+	case r.TimestampGreaterThanEquals != nil:
+		op, ref = ">=", r.TimestampGreaterThanEquals
+	default:
+		return nil, false, nil
+	}
+
+	rt, err := time.Parse(time.RFC3339, *ref)
+	if err != nil {
+		return nil, true, NewStateError(StatesErrorRuntime, fmt.Sprintf("invalid timestamp %q in choice rule", *ref))
+	}
+	return func(t time.Time) bool {
+		switch op {
+		case "==":
+			return t.Equal(rt)
+		case "<":
+			return t.Before(rt)
+		case ">":
+			return t.After(rt)
+		case "<=":
+			return !t.After(rt)
+		default:
+			return !t.Before(rt)
+		}
+	}, true, nil
+}
+
+func timestampValue(v interface{}) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+// wildcardMatch matches s against pattern, where * matches any run of
+// characters and \* a literal *
+func wildcardMatch(pattern, s string) bool {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			cur.WriteByte(pattern[i])
+		case pattern[i] == '*':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(pattern[i])
+		}
+	}
+	parts = append(parts, cur.String())
+
+	if len(parts) == 1 {
+		return s == parts[0]
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, p)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
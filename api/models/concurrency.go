@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// FnMaxConcurrencyAnnotation is the annotation for the most calls of a fn that an agent runs at once, further
+	// calls wait in a bounded queue
+	FnMaxConcurrencyAnnotation = "fnproject.io/fn/max_concurrency"
+	// AppMaxConcurrencyAnnotation is the annotation for the most calls of the fns of an app that an agent runs at
+	// once, further calls wait in a bounded queue
+	AppMaxConcurrencyAnnotation = "fnproject.io/app/max_concurrency"
+)
+
+var (
+	ErrFnsInvalidMaxConcurrency = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("%s annotation must be a positive integer", FnMaxConcurrencyAnnotation),
+	}
+	ErrAppsInvalidMaxConcurrency = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("%s annotation must be a positive integer", AppMaxConcurrencyAnnotation),
+	}
+	ErrCallConcurrencyLimit = err{
+		code:  http.StatusTooManyRequests,
+		error: errors.New("Too many concurrent calls to the function or its app, and too many waiting for them to finish"),
+	}
+)
+
+// FnMaxConcurrency returns the most concurrent calls of a fn that the
+// annotations of the fn ask for, 0 if they don't.
+func FnMaxConcurrency(annotations Annotations) (int, error) {
+	var max int
+	if v, ok := annotations.Get(FnMaxConcurrencyAnnotation); ok {
+		if json.Unmarshal(v, &max) != nil || max < 1 {
+			return 0, ErrFnsInvalidMaxConcurrency
+		}
+	}
+	return max, nil
+}
+
+// AppMaxConcurrency returns the most concurrent calls of the fns of an app
+// that the annotations of the app ask for, 0 if they don't.
+func AppMaxConcurrency(annotations Annotations) (int, error) {
+	var max int
+	if v, ok := annotations.Get(AppMaxConcurrencyAnnotation); ok {
+		if json.Unmarshal(v, &max) != nil || max < 1 {
+			return 0, ErrAppsInvalidMaxConcurrency
+		}
+	}
+	return max, nil
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The data flow of a state follows the states language:
+//
+//	raw input -> InputPath -> Parameters -> (state runs) -> ResultSelector
+//	  -> ResultPath (merged into the raw input) -> OutputPath -> output
+//
+// Choice, Wait and Succeed states only support InputPath and OutputPath,
+// their output being their effective input. Map and Parallel states apply
+// Parameters to each of their items rather than to their input as a whole.
+
+// HasDataFlow returns whether any of the data flow fields are set on the
+// state. States without any are passed their input as is and their result
+// is their output, which need not be JSON.
+func (s *State) HasDataFlow() bool {
+	return s.InputPath != "" || len(s.Parameters) > 0 || len(s.ResultSelector) > 0 ||
+		s.ResultPath != "" || s.OutputPath != ""
+}
+
+// iterates returns whether the state runs a state machine per item of its input
+func (s *State) iterates() bool {
+	return s.Type == StateTypeMap || s.Type == StateTypeParallel
+}
+
+// passesInput returns whether the output of the state is its effective input
+func (s *State) passesInput() bool {
+	return s.Type == StateTypeChoice || s.Type == StateTypeWait || s.Type == StateTypeSucceed
+}
+
+func pathOrRoot(p string) string {
+	if p == "" {
+		return "$"
+	}
+	return p
+}
+
+// EffectiveInput applies InputPath, then Parameters where they apply to the
+// whole input, to the raw input of the state.
+func (s *State) EffectiveInput(raw interface{}) (interface{}, error) {
+	input, err := JSONPathGet(raw, pathOrRoot(s.InputPath))
+	if err != nil {
+		return nil, NewStateError(StatesErrorRuntime, fmt.Sprintf("InputPath: %v", err))
+	}
+	if len(s.Parameters) == 0 || s.iterates() || s.passesInput() {
+		return input, nil
+	}
+	return ApplyTemplate(s.Parameters, input, nil)
+}
+
+// ItemInput returns the input for the iteration of a Map or Parallel state
+// over item, which is at index in its items. Without Parameters it is the
+// item itself; with them, the template is applied to the effective input of
+// the state, with the item available as `$$.Map.Item.Value`.
+func (s *State) ItemInput(input interface{}, item interface{}, index int) (interface{}, error) {
+	if len(s.Parameters) == 0 {
+		return item, nil
+	}
+	ctx := map[string]interface{}{
+		"Map": map[string]interface{}{
+			"Item": map[string]interface{}{
+				"Index": float64(index),
+				"Value": item,
+			},
+		},
+	}
+	return ApplyTemplate(s.Parameters, input, ctx)
+}
+
+// Output applies ResultSelector, ResultPath and OutputPath to the result of
+// the state to give its output. raw is the raw input to the state and
+// effective its effective input.
+func (s *State) Output(raw, effective, result interface{}) (interface{}, error) {
+	if s.passesInput() {
+		return s.outputPath(effective)
+	}
+
+	if len(s.ResultSelector) > 0 && s.Type != StateTypePass {
+		var err error
+		result, err = ApplyTemplate(s.ResultSelector, result, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	merged, err := JSONPathSet(raw, pathOrRoot(s.ResultPath), result)
+	if err != nil {
+		return nil, NewStateError(StatesErrorResultPathMatchFailure, err.Error())
+	}
+	return s.outputPath(merged)
+}
+
+func (s *State) outputPath(v interface{}) (interface{}, error) {
+	out, err := JSONPathGet(v, pathOrRoot(s.OutputPath))
+	if err != nil {
+		return nil, NewStateError(StatesErrorRuntime, fmt.Sprintf("OutputPath: %v", err))
+	}
+	return out, nil
+}
+
+// ApplyTemplate builds a payload from a Parameters or ResultSelector
+// template. Fields whose name ends in `.$` are replaced, without the suffix,
+// by the value at the path they hold, looked up in data or, for paths that
+// start with `$$`, in the context object ctx.
+func ApplyTemplate(tmpl json.RawMessage, data interface{}, ctx interface{}) (interface{}, error) {
+	var t interface{}
+	if err := json.Unmarshal(tmpl, &t); err != nil {
+		return nil, NewStateError(StatesErrorRuntime, fmt.Sprintf("invalid template: %v", err))
+	}
+	return applyTemplate(t, data, ctx)
+}
+
+func applyTemplate(t interface{}, data interface{}, ctx interface{}) (interface{}, error) {
+	switch x := t.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(x))
+		for k, v := range x {
+			if !strings.HasSuffix(k, ".$") {
+				nv, err := applyTemplate(v, data, ctx)
+				if err != nil {
+					return nil, err
+				}
+				res[k] = nv
+				continue
+			}
+
+			p, ok := v.(string)
+			if !ok {
+				return nil, NewStateError(StatesErrorParameterPathFailure, fmt.Sprintf("value of %s must be a path", k))
+			}
+			src := data
+			if strings.HasPrefix(p, "$$") {
+				src, p = ctx, p[1:]
+			}
+			nv, err := JSONPathGet(src, p)
+			if err != nil {
+				return nil, NewStateError(StatesErrorParameterPathFailure, err.Error())
+			}
+			res[strings.TrimSuffix(k, ".$")] = nv
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(x))
+		for i, v := range x {
+			nv, err := applyTemplate(v, data, ctx)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = nv
+		}
+		return res, nil
+	default:
+		return t, nil
+	}
+}
@@ -73,6 +73,22 @@ type Datastore interface {
 	// GetTriggerBySource loads a trigger by type and source ID - this is only needed when the data store is also used for agent read access
 	GetTriggerBySource(ctx context.Context, appId string, triggerType, source string) (*Trigger, error)
 
+	// InsertStateMachine stores a state machine as the next version of the state machines of the same name in its app.
+	// Returns ErrStateMachinesIDProvided or ErrStateMachinesVersionProvided if either is set, and ErrAppsNotFound if the app does not exist.
+	InsertStateMachine(ctx context.Context, sm *StoredStateMachine) (*StoredStateMachine, error)
+
+	// GetStateMachine gets a version of a state machine by its app and name, version 0 being the latest.
+	// Returns ErrStateMachinesNotFound if no matching state machine is found.
+	GetStateMachine(ctx context.Context, appID, name string, version int64) (*StoredStateMachine, error)
+
+	// GetStateMachines gets the latest version of each state machine in an app or, when a name is set
+	// in the filter, every version of that state machine. Returns ErrStateMachinesMissingAppID if no AppID is set.
+	GetStateMachines(ctx context.Context, filter *StateMachineFilter) (*StateMachineList, error)
+
+	// RemoveStateMachine removes every version of a state machine, and the statemachine triggers that refer to it.
+	// Returns ErrStateMachinesNotFound if no matching state machine is found.
+	RemoveStateMachine(ctx context.Context, appID, name string) error
+
 	// implements io.Closer to shutdown
 	io.Closer
 }
@@ -148,6 +148,10 @@ var (
 		code:  http.StatusBadRequest,
 		error: errors.New("Async functions are not supported on this server"),
 	}
+	ErrCallsUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Calls are not stored on this server"),
+	}
 
 	ErrDetachUnsupported = err{
 		code:  http.StatusNotImplemented,
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Execution statuses
+const (
+	ExecutionStatusRunning   = "running"
+	ExecutionStatusSucceeded = "succeeded"
+	ExecutionStatusFailed    = "failed"
+)
+
+var executionStatuses = []string{ExecutionStatusRunning, ExecutionStatusSucceeded, ExecutionStatusFailed}
+
+var (
+	//ErrExecutionsNotFound - execution not found
+	ErrExecutionsNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Execution not found"),
+	}
+	//ErrExecutionsMissingID - no ID specified for an execution lookup or update
+	ErrExecutionsMissingID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing execution ID"),
+	}
+	//ErrExecutionIDProvided - an ID was specified on execution creation
+	ErrExecutionIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("ID cannot be provided for Execution creation"),
+	}
+	//ErrExecutionsMissingStateMachine - no state machine on an execution
+	ErrExecutionsMissingStateMachine = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing state machine on Execution"),
+	}
+	//ErrExecutionsInvalidStatus - unknown execution status
+	ErrExecutionsInvalidStatus = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid status for Execution"),
+	}
+	//ErrExecutionsMissingStateName - a state record was written without the name of the state
+	ErrExecutionsMissingStateName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing state name on Execution state"),
+	}
+	//ErrExecutionsLeaseLost - an execution was written to by a node that no longer owns it
+	ErrExecutionsLeaseLost = err{
+		code:  http.StatusConflict,
+		error: errors.New("Execution is owned by another node"),
+	}
+	//ErrExecutionsNotSupported - the server has nowhere to keep executions
+	ErrExecutionsNotSupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Executions are not supported by this server"),
+	}
+)
+
+// Execution is a single run of a StateMachine. Executions are persisted as
+// they progress, so that a run can be resumed from the last completed state
+// after the node that started it goes away.
+type Execution struct {
+	ID           string        `json:"id" db:"id"`
+	StateMachine *StateMachine `json:"state_machine" db:"state_machine"`
+	Status       string        `json:"status" db:"status"`
+	CurrentState string        `json:"current_state,omitempty" db:"current_state"`
+	Input        string        `json:"input" db:"input"`
+	Output       string        `json:"output,omitempty" db:"output"`
+	Error        string        `json:"error,omitempty" db:"error"`
+
+	// StateInput is the input of CurrentState, the output of the state before it
+	StateInput string `json:"-" db:"state_input"`
+
+	// Owner is the node running the execution, which holds it until LeaseUntil,
+	// in unix milliseconds, unless it renews its lease
+	Owner      string `json:"-" db:"owner"`
+	LeaseUntil int64  `json:"-" db:"lease_until"`
+
+	CreatedAt common.DateTime `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt common.DateTime `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// Validate checks that an execution has valid data for inserting into a store
+func (e *Execution) Validate() error {
+	if e.StateMachine == nil {
+		return ErrExecutionsMissingStateMachine
+	}
+	if !ValidExecutionStatus(e.Status) {
+		return ErrExecutionsInvalidStatus
+	}
+	return nil
+}
+
+// Clone creates a copy of an execution, the state machine is shared as it is
+// never modified once an execution has started.
+func (e *Execution) Clone() *Execution {
+	clone := new(Execution)
+	*clone = *e
+	return clone
+}
+
+// Done returns whether the execution has reached a terminal status
+func (e *Execution) Done() bool {
+	return e.Status == ExecutionStatusSucceeded || e.Status == ExecutionStatusFailed
+}
+
+// ValidExecutionStatus checks that a given execution status is known
+func ValidExecutionStatus(s string) bool {
+	for _, v := range executionStatuses {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecutionState is the record of a single state of an execution having run,
+// with the input it was given and the output it produced.
+type ExecutionState struct {
+	ExecutionID string          `json:"execution_id" db:"execution_id"`
+	Seq         int64           `json:"seq" db:"seq"`
+	Name        string          `json:"name" db:"name"`
+	Type        string          `json:"type" db:"type"`
+	Status      string          `json:"status" db:"status"`
+	Input       string          `json:"input" db:"input"`
+	Output      string          `json:"output,omitempty" db:"output"`
+	Error       string          `json:"error,omitempty" db:"error"`
+	StartedAt   common.DateTime `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt common.DateTime `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// Validate checks that an execution state has valid data for inserting into a store
+func (s *ExecutionState) Validate() error {
+	if s.ExecutionID == "" {
+		return ErrExecutionsMissingID
+	}
+	if s.Name == "" {
+		return ErrExecutionsMissingStateName
+	}
+	if !ValidExecutionStatus(s.Status) {
+		return ErrExecutionsInvalidStatus
+	}
+	return nil
+}
+
+// ExecutionFilter is a search criteria on executions
+type ExecutionFilter struct {
+	Status string // exact match
+
+	Cursor  string
+	PerPage int
+}
+
+// ExecutionList is a container of executions returned by search, optionally indicating the next page cursor
+type ExecutionList struct {
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Items      []*Execution `json:"items"`
+}
+
+// ExecutionStateList is the history of an execution, one record per state run
+type ExecutionStateList struct {
+	Items []*ExecutionState `json:"items"`
+}
+
+// ExecutionStore persists state machine executions and the states they went through.
+type ExecutionStore interface {
+	// InsertExecution inserts a new execution, assigning it an ID.
+	// Returns ErrExecutionIDProvided if the execution already has an ID.
+	InsertExecution(ctx context.Context, exec *Execution) (*Execution, error)
+
+	// UpdateExecution updates the status, current state, output and error of an execution.
+	// Returns ErrExecutionsNotFound if no execution exists with the same ID.
+	UpdateExecution(ctx context.Context, exec *Execution) (*Execution, error)
+
+	// GetExecutionByID returns an execution by ID. Returns ErrExecutionsMissingID if execID is empty.
+	// Returns ErrExecutionsNotFound if an execution is not found.
+	GetExecutionByID(ctx context.Context, execID string) (*Execution, error)
+
+	// GetExecutions returns a list of executions, and a cursor, applying any additional filters provided.
+	GetExecutions(ctx context.Context, filter *ExecutionFilter) (*ExecutionList, error)
+
+	// AdvanceExecution records that a state of exec has run, appending state to its
+	// history and updating the status, current state, state input, output and error
+	// of exec, in one transaction. state may be nil if no state ran. Returns
+	// ErrExecutionsNotFound if no execution exists with the same ID, and
+	// ErrExecutionsLeaseLost if it is owned by a node other than exec.Owner.
+	AdvanceExecution(ctx context.Context, exec *Execution, state *ExecutionState) (*Execution, error)
+
+	// ClaimExecution makes owner the owner of a running execution, for lease, if the
+	// execution has no owner or the lease of its owner is over. Returns nil, and no
+	// error, if another node holds the execution. Returns ErrExecutionsNotFound if
+	// no execution exists with the ID.
+	ClaimExecution(ctx context.Context, execID, owner string, lease time.Duration) (*Execution, error)
+
+	// RenewExecution extends the lease of owner on a running execution it owns.
+	// Returns ErrExecutionsLeaseLost if the execution is no longer running or owned by owner.
+	RenewExecution(ctx context.Context, execID, owner string, lease time.Duration) error
+
+	// InsertExecutionState appends a state record to an execution, assigning it the next sequence number.
+	// Returns ErrExecutionsNotFound if the execution does not exist.
+	InsertExecutionState(ctx context.Context, state *ExecutionState) (*ExecutionState, error)
+
+	// GetExecutionStates returns the state records of an execution, in the order they were inserted.
+	GetExecutionStates(ctx context.Context, execID string) ([]*ExecutionState, error)
+}
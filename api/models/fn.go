@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -66,6 +67,18 @@ var (
 		code:  http.StatusBadRequest,
 		error: fmt.Errorf("idle_timeout value is out of range, must be between 0 and %d", MaxIdleTimeout),
 	}
+	ErrFnsInvalidMinHot = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("%s annotation must be a non-negative integer", FnMinHotAnnotation),
+	}
+	ErrFnsInvalidMaxHot = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("%s annotation must be a positive integer, no less than %s", FnMaxHotAnnotation, FnMinHotAnnotation),
+	}
+	ErrFnsInvalidEvictionPriority = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("%s annotation must be an integer", FnEvictionPriorityAnnotation),
+	}
 	ErrFnsNotFound = err{
 		code:  http.StatusNotFound,
 		error: errors.New("Fn not found"),
@@ -79,6 +92,43 @@ var (
 // FnInvokeEndpointAnnotation is the annotation that exposes the fn invoke endpoint For want of a better place to put this it's here
 const FnInvokeEndpointAnnotation = "fnproject.io/fn/invokeEndpoint"
 
+const (
+	// FnMinHotAnnotation is the annotation for the number of hot containers the agent keeps for a fn, once it is called
+	FnMinHotAnnotation = "fnproject.io/fn/min_hot"
+	// FnMaxHotAnnotation is the annotation for the most hot containers the agent starts for a fn, calls queue beyond it
+	FnMaxHotAnnotation = "fnproject.io/fn/max_hot"
+	// FnEvictionPriorityAnnotation is the annotation for the priority of the hot containers of a fn under the
+	// priority eviction policy, those with lower priorities are evicted first
+	FnEvictionPriorityAnnotation = "fnproject.io/fn/eviction_priority"
+)
+
+// HotContainers returns the minimum and maximum numbers of hot containers that
+// annotations ask for, 0 for either that is not set
+func HotContainers(annotations Annotations) (min, max int, err error) {
+	if v, ok := annotations.Get(FnMinHotAnnotation); ok {
+		if json.Unmarshal(v, &min) != nil || min < 0 {
+			return 0, 0, ErrFnsInvalidMinHot
+		}
+	}
+	if v, ok := annotations.Get(FnMaxHotAnnotation); ok {
+		if json.Unmarshal(v, &max) != nil || max < 1 || max < min {
+			return 0, 0, ErrFnsInvalidMaxHot
+		}
+	}
+	return min, max, nil
+}
+
+// EvictionPriority returns the eviction priority that annotations ask for, 0
+// if it is not set
+func EvictionPriority(annotations Annotations) (priority int, err error) {
+	if v, ok := annotations.Get(FnEvictionPriorityAnnotation); ok {
+		if json.Unmarshal(v, &priority) != nil {
+			return 0, ErrFnsInvalidEvictionPriority
+		}
+	}
+	return priority, nil
+}
+
 // Fn contains information about a function configuration.
 type Fn struct {
 	// ID is the generated resource id.
@@ -170,6 +220,22 @@ func (f *Fn) Validate() error {
 		return ErrInvalidMemory
 	}
 
+	if _, _, err := HotContainers(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := EvictionPriority(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := FnMaxConcurrency(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := FnStream(f.Annotations); err != nil {
+		return err
+	}
+
 	return f.Annotations.Validate()
 }
 
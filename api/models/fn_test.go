@@ -173,6 +173,52 @@ func TestValidateFn(t *testing.T) {
 	testFn.Memory = 0
 	testCases = append(testCases, test{testFn, ErrInvalidMemory})
 
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnMinHotAnnotation, 2)
+	testFn.Annotations, _ = testFn.Annotations.With(FnMaxHotAnnotation, 4)
+	testCases = append(testCases, test{testFn, nil})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnMinHotAnnotation, -1)
+	testCases = append(testCases, test{testFn, ErrFnsInvalidMinHot})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnMinHotAnnotation, "two")
+	testCases = append(testCases, test{testFn, ErrFnsInvalidMinHot})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnMaxHotAnnotation, 0)
+	testCases = append(testCases, test{testFn, ErrFnsInvalidMaxHot})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnMinHotAnnotation, 3)
+	testFn.Annotations, _ = testFn.Annotations.With(FnMaxHotAnnotation, 2)
+	testCases = append(testCases, test{testFn, ErrFnsInvalidMaxHot})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnEvictionPriorityAnnotation, -5)
+	testCases = append(testCases, test{testFn, nil})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnEvictionPriorityAnnotation, "high")
+	testCases = append(testCases, test{testFn, ErrFnsInvalidEvictionPriority})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnMaxConcurrencyAnnotation, 4)
+	testCases = append(testCases, test{testFn, nil})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnMaxConcurrencyAnnotation, 0)
+	testCases = append(testCases, test{testFn, ErrFnsInvalidMaxConcurrency})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnStreamAnnotation, true)
+	testCases = append(testCases, test{testFn, nil})
+
+	testFn = generateValidFn()
+	testFn.Annotations, _ = testFn.Annotations.With(FnStreamAnnotation, "yes")
+	testCases = append(testCases, test{testFn, ErrFnsInvalidStream})
+
 	for _, testCase := range testCases {
 		got := testCase.Fn.Validate()
 
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is a single step of a path, either a field name or an array index
+type jsonPathStep struct {
+	field string
+	index int
+	isIdx bool
+}
+
+// parseJSONPath parses the subset of JSONPath used by the states language,
+// that is a reference path such as `$`, `$.a.b`, `$.a[0].b` or `$['a b']`.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid path %q: must start with $", path)
+	}
+
+	var steps []jsonPathStep
+	rest := path[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid path %q: empty field name", path)
+			}
+			steps = append(steps, jsonPathStep{field: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated [", path)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				steps = append(steps, jsonPathStep{field: inner[1 : len(inner)-1]})
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid path %q: bad index %q", path, inner)
+			}
+			steps = append(steps, jsonPathStep{index: i, isIdx: true})
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// JSONPathGet returns the value at path in data, which is a document as
+// decoded by encoding/json into an interface{}. It returns an error if the
+// path is invalid or does not exist in data.
+func JSONPathGet(data interface{}, path string) (interface{}, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cur := data
+	for _, st := range steps {
+		if st.isIdx {
+			arr, ok := cur.([]interface{})
+			if !ok || st.index >= len(arr) {
+				return nil, fmt.Errorf("path %q not found in input", path)
+			}
+			cur = arr[st.index]
+			continue
+		}
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("path %q not found in input", path)
+		}
+		cur, ok = obj[st.field]
+		if !ok {
+			return nil, fmt.Errorf("path %q not found in input", path)
+		}
+	}
+	return cur, nil
+}
+
+// JSONPathSet sets the value at path in data, creating any objects along
+// the way that don't exist, and returns the updated document. Only field
+// paths are supported, a path of `$` replaces data entirely.
+func JSONPathSet(data interface{}, path string, value interface{}) (interface{}, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return value, nil
+	}
+
+	root, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot set %q, input is not an object", path)
+	}
+	cur := root
+	for i, st := range steps {
+		if st.isIdx {
+			return nil, fmt.Errorf("cannot set %q, array indexes are not supported", path)
+		}
+		if i == len(steps)-1 {
+			cur[st.field] = value
+			break
+		}
+		next, ok := cur[st.field]
+		if !ok || next == nil {
+			next = make(map[string]interface{})
+			cur[st.field] = next
+		}
+		obj, ok := next.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot set %q, %s is not an object", path, st.field)
+		}
+		cur = obj
+	}
+	return root, nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+var (
+	ErrLogsFollowRequired = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Logs can only be followed, with follow=true"),
+	}
+	ErrLogsFollowUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Following logs is not supported by this server"),
+	}
+)
+
+// LogLine is a line of the stderr of a call, as it is followed while the call
+// runs
+type LogLine struct {
+	// AppID is the ID of the app of the call
+	AppID string `json:"app_id"`
+	// FnID is the ID of the fn of the call
+	FnID string `json:"fn_id"`
+	// CallID is the ID of the call that logged the line
+	CallID string `json:"call_id"`
+	// Time is when the line was logged
+	Time common.DateTime `json:"time"`
+	// Line is the line, without its newline
+	Line string `json:"line"`
+}
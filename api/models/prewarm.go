@@ -0,0 +1,47 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+)
+
+var (
+	// MaxPreWarmContainers is the most containers one pre-warm may ask for
+	MaxPreWarmContainers = 100
+
+	ErrPreWarmInvalidContainers = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("containers value is out of range, must be between 1 and %d", MaxPreWarmContainers),
+	}
+	ErrPreWarmInvalidKeepWarm = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("keep_warm value is out of range, must be between 0 and %d", MaxIdleTimeout),
+	}
+)
+
+// PreWarm asks for hot containers of a function to be started ahead of its
+// calls, so that they take the cold starts instead of the first calls.
+type PreWarm struct {
+	// Containers is how many containers to start.
+	Containers int `json:"containers"`
+
+	// KeepWarm is how long in seconds each container stays idle waiting for
+	// its first call. The idle_timeout of the function is used if it is 0.
+	KeepWarm int32 `json:"keep_warm,omitempty"`
+
+	// Started is how many containers were started, as many as there were
+	// resources for. It is set in the response.
+	Started int `json:"started"`
+}
+
+// Validate checks that a pre-warm asks for a sensible number of containers
+// and time to keep them.
+func (p *PreWarm) Validate() error {
+	if p.Containers < 1 || p.Containers > MaxPreWarmContainers {
+		return ErrPreWarmInvalidContainers
+	}
+	if p.KeepWarm < 0 || p.KeepWarm > MaxIdleTimeout {
+		return ErrPreWarmInvalidKeepWarm
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Call priorities. Calls of a higher priority take idle containers before
+// calls of a lower priority waiting for the same function, and may evict the
+// idle containers of lower priorities to start their own.
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+const (
+	// PriorityHeader is the request header with which an invocation asks for a priority, one of
+	// "low", "normal" and "high", up to the max_priority annotation of its app
+	PriorityHeader = "Fn-Priority"
+	// AppMaxPriorityAnnotation is the annotation for the highest priority the invocations of an app may ask for,
+	// "normal" if it is not set
+	AppMaxPriorityAnnotation = "fnproject.io/app/max_priority"
+	// TriggerPriorityAnnotation is the annotation for the priority of the invocations through a trigger, which
+	// takes the place of the priority they ask for
+	TriggerPriorityAnnotation = "fnproject.io/trigger/priority"
+)
+
+var priorityNames = map[string]int{
+	"low":    PriorityLow,
+	"normal": PriorityNormal,
+	"high":   PriorityHigh,
+}
+
+var (
+	ErrCallInvalidPriority = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid %s header, must be one of low, normal and high", PriorityHeader),
+	}
+	ErrAppsInvalidMaxPriority = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("%s annotation must be one of low, normal and high", AppMaxPriorityAnnotation),
+	}
+	ErrTriggerInvalidPriority = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("%s annotation must be one of low, normal and high", TriggerPriorityAnnotation),
+	}
+)
+
+// ParsePriority returns the priority with name, one of "low", "normal" and "high"
+func ParsePriority(name string) (int, error) {
+	p, ok := priorityNames[name]
+	if !ok {
+		return PriorityNormal, errors.New("unknown priority")
+	}
+	return p, nil
+}
+
+// priorityAnnotation returns the priority that key of annotations holds, def
+// if it is not set
+func priorityAnnotation(annotations Annotations, key string, def int, invalid error) (int, error) {
+	v, ok := annotations.Get(key)
+	if !ok {
+		return def, nil
+	}
+	var name string
+	if json.Unmarshal(v, &name) != nil {
+		return def, invalid
+	}
+	p, err := ParsePriority(name)
+	if err != nil {
+		return def, invalid
+	}
+	return p, nil
+}
+
+// AppMaxPriority returns the highest priority that the invocations of an app
+// with annotations may ask for
+func AppMaxPriority(annotations Annotations) (int, error) {
+	return priorityAnnotation(annotations, AppMaxPriorityAnnotation, PriorityNormal, ErrAppsInvalidMaxPriority)
+}
+
+// TriggerPriority returns the priority of the invocations through a trigger
+// with annotations, and false if it does not set one
+func TriggerPriority(annotations Annotations) (int, bool, error) {
+	if _, ok := annotations.Get(TriggerPriorityAnnotation); !ok {
+		return PriorityNormal, false, nil
+	}
+	p, err := priorityAnnotation(annotations, TriggerPriorityAnnotation, PriorityNormal, ErrTriggerInvalidPriority)
+	return p, err == nil, err
+}
+
+// RequestPriority returns the priority that an invocation asks for with
+// header, no higher than the max_priority annotation of its app. It is
+// PriorityNormal, or the maximum if it is lower, if header is empty.
+func RequestPriority(header string, app *App) (int, error) {
+	max, _ := AppMaxPriority(app.Annotations)
+	p := PriorityNormal
+	if header != "" {
+		var err error
+		if p, err = ParsePriority(header); err != nil {
+			return PriorityNormal, ErrCallInvalidPriority
+		}
+	}
+	if p > max {
+		p = max
+	}
+	return p, nil
+}
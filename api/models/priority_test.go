@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestRequestPriority(t *testing.T) {
+	high, _ := EmptyAnnotations().With(AppMaxPriorityAnnotation, "high")
+
+	for i, test := range []struct {
+		header      string
+		annotations Annotations
+		priority    int
+		err         error
+	}{
+		{"", nil, PriorityNormal, nil},
+		{"low", nil, PriorityLow, nil},
+		{"high", nil, PriorityNormal, nil},
+		{"high", high, PriorityHigh, nil},
+		{"urgent", high, PriorityNormal, ErrCallInvalidPriority},
+	} {
+		p, err := RequestPriority(test.header, &App{Annotations: test.annotations})
+		if err != test.err {
+			t.Fatalf("Test %d: expected error %v, got %v", i, test.err, err)
+		}
+		if p != test.priority {
+			t.Fatalf("Test %d: expected priority %d, got %d", i, test.priority, p)
+		}
+	}
+}
+
+func TestTriggerPriority(t *testing.T) {
+	low, _ := EmptyAnnotations().With(TriggerPriorityAnnotation, "low")
+	invalid, _ := EmptyAnnotations().With(TriggerPriorityAnnotation, 2)
+
+	if p, ok, err := TriggerPriority(low); p != PriorityLow || !ok || err != nil {
+		t.Fatalf("expected low priority, got %d %v %v", p, ok, err)
+	}
+	if _, ok, err := TriggerPriority(nil); ok || err != nil {
+		t.Fatalf("expected no priority, got %v %v", ok, err)
+	}
+	if _, ok, err := TriggerPriority(invalid); ok || err != ErrTriggerInvalidPriority {
+		t.Fatalf("expected an invalid priority, got %v %v", ok, err)
+	}
+}
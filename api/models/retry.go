@@ -0,0 +1,147 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Error names that match more than one error in ErrorEquals
+const (
+	StatesErrorAll        = "States.ALL"
+	StatesErrorTimeout    = "States.Timeout"
+	StatesErrorTaskFailed = "States.TaskFailed"
+)
+
+// Retry defaults, as per the states language
+const (
+	DefaultRetryIntervalSeconds = 1
+	DefaultRetryMaxAttempts     = 3
+	DefaultRetryBackoffRate     = 2.0
+)
+
+// Retrier is a Retry policy of a state, applied to the errors it matches
+type Retrier struct {
+	ErrorEquals     []string
+	IntervalSeconds *int64
+	MaxAttempts     *int64
+	BackoffRate     *float64
+}
+
+// Catcher sends a state machine to the Next state when a state fails with an
+// error it matches, with the error placed in the state's input at ResultPath.
+type Catcher struct {
+	ErrorEquals []string
+	Next        string
+	ResultPath  string
+}
+
+// StateErrorName returns the name an error is known by to Retry and Catch.
+// Errors raised by the state machine keep their name, function timeouts are
+// States.Timeout and other function errors States.TaskFailed. Errors from fn
+// itself are named after their HTTP status, eg. Fn.ServiceUnavailable for a
+// server that is too busy.
+func StateErrorName(e error) string {
+	if se, ok := e.(*StateError); ok {
+		return se.Name
+	}
+	if e == ErrCallTimeout {
+		return StatesErrorTimeout
+	}
+	if IsFuncError(e) {
+		return StatesErrorTaskFailed
+	}
+	if code := GetAPIErrorCode(e); code != 0 {
+		if text := http.StatusText(code); text != "" {
+			return "Fn." + strings.NewReplacer(" ", "", "-", "", "'", "").Replace(text)
+		}
+	}
+	return StatesErrorTaskFailed
+}
+
+// matchesError returns whether the list of error names matches the named
+// error. States.ALL matches any error but States.Runtime, and
+// States.TaskFailed any error that is not itself a States error.
+func matchesError(errorEquals []string, name string) bool {
+	for _, e := range errorEquals {
+		switch {
+		case e == name:
+			return true
+		case e == StatesErrorAll && name != StatesErrorRuntime:
+			return true
+		case e == StatesErrorTaskFailed && !strings.HasPrefix(name, "States."):
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns whether the retrier applies to the named error
+func (r *Retrier) Matches(name string) bool {
+	return matchesError(r.ErrorEquals, name)
+}
+
+// BackOff returns the delays between the attempts of this retrier
+func (r *Retrier) BackOff() common.BackOff {
+	interval := int64(DefaultRetryIntervalSeconds)
+	if r.IntervalSeconds != nil {
+		interval = *r.IntervalSeconds
+	}
+	attempts := int64(DefaultRetryMaxAttempts)
+	if r.MaxAttempts != nil {
+		attempts = *r.MaxAttempts
+	}
+	if attempts < 0 {
+		attempts = 0
+	}
+	rate := DefaultRetryBackoffRate
+	if r.BackoffRate != nil {
+		rate = *r.BackoffRate
+	}
+	return common.NewRateBackOff(time.Duration(interval)*time.Second, rate, uint64(attempts))
+}
+
+// Matches returns whether the catcher applies to the named error
+func (c *Catcher) Matches(name string) bool {
+	return matchesError(c.ErrorEquals, name)
+}
+
+// ErrorOutput builds the output of a state whose error was caught by c, that
+// is the raw input of the state with the error at c's ResultPath.
+func (c *Catcher) ErrorOutput(raw interface{}, e error) (interface{}, error) {
+	cause := e.Error()
+	if se, ok := e.(*StateError); ok {
+		cause = se.Cause
+	}
+	errDoc := map[string]interface{}{
+		"Error": StateErrorName(e),
+		"Cause": cause,
+	}
+	out, err := JSONPathSet(raw, pathOrRoot(c.ResultPath), errDoc)
+	if err != nil {
+		return nil, NewStateError(StatesErrorResultPathMatchFailure, err.Error())
+	}
+	return out, nil
+}
+
+// Retrier returns the first Retry policy of the state that matches the named error
+func (s *State) Retrier(name string) (int, *Retrier) {
+	for i, r := range s.Retry {
+		if r.Matches(name) {
+			return i, r
+		}
+	}
+	return -1, nil
+}
+
+// Catcher returns the first Catch of the state that matches the named error
+func (s *State) Catcher(name string) *Catcher {
+	for _, c := range s.Catch {
+		if c.Matches(name) {
+			return c
+		}
+	}
+	return nil
+}
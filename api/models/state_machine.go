@@ -1,5 +1,14 @@
 package models
 
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
 // StateMachine Reference: https://states-language.net
 type StateMachine struct {
 	Comment string
@@ -7,6 +16,42 @@ type StateMachine struct {
 	States  map[string]*State
 }
 
+// implements sql.Valuer, returning a string
+func (sm StateMachine) Value() (driver.Value, error) {
+	var b bytes.Buffer
+	err := json.NewEncoder(&b).Encode(sm)
+	// return a string type
+	return driver.Value(b.String()), err
+}
+
+// implements sql.Scanner
+func (sm *StateMachine) Scan(value interface{}) error {
+	if value == nil {
+		*sm = StateMachine{}
+		return nil
+	}
+	bv, err := driver.String.ConvertValue(value)
+	if err == nil {
+		var b []byte
+		switch x := bv.(type) {
+		case []byte:
+			b = x
+		case string:
+			b = []byte(x)
+		}
+
+		if len(b) > 0 {
+			return json.Unmarshal(b, sm)
+		}
+
+		*sm = StateMachine{}
+		return nil
+	}
+
+	// otherwise, return an error
+	return fmt.Errorf("state machine invalid db format: %T %T value, err: %v", value, bv, err)
+}
+
 // State Single state in the state machine
 type State struct {
 	Type              string
@@ -16,6 +61,39 @@ type State struct {
 	Comment           string
 	End               bool
 	ParallelExecution *ParallelExecution
+
+	// Choice
+	Choices []*ChoiceRule
+	Default string
+
+	// Wait, one of these
+	Seconds       int64
+	Timestamp     string
+	SecondsPath   string
+	TimestampPath string
+
+	// Pass, the raw JSON to output in place of the input
+	Result json.RawMessage
+
+	// Fail
+	Error string
+	Cause string
+
+	// Map
+	ItemsPath      string
+	Iterator       *StateMachine
+	MaxConcurrency int
+
+	// Data flow, see dataflow.go. The paths default to `$`.
+	InputPath      string
+	Parameters     json.RawMessage
+	ResultSelector json.RawMessage
+	ResultPath     string
+	OutputPath     string
+
+	// Error handling, see retry.go
+	Retry []*Retrier
+	Catch []*Catcher
 }
 
 // ParallelExecution records the metadata for parallel execution
@@ -23,14 +101,121 @@ type ParallelExecution struct {
 	IterableItemsKey string
 	IterableItemName string
 	StateMachine     StateMachine
+
+	// MaxConcurrency is the most branches to run at once, 0 for no limit
+	MaxConcurrency int
 }
 
 // Constants
 const (
 	StateTypeTask     = "Task"
 	StateTypeParallel = "Parallel"
+	StateTypeChoice   = "Choice"
+	StateTypeWait     = "Wait"
+	StateTypePass     = "Pass"
+	StateTypeSucceed  = "Succeed"
+	StateTypeFail     = "Fail"
+	StateTypeMap      = "Map"
+)
+
+// Predefined error names, as raised by the states language runtime
+const (
+	StatesErrorRuntime         = "States.Runtime"
+	StatesErrorNoChoiceMatched = "States.NoChoiceMatched"
+
+	StatesErrorParameterPathFailure   = "States.ParameterPathFailure"
+	StatesErrorResultPathMatchFailure = "States.ResultPathMatchFailure"
 )
 
+// StateError is an error raised while running a state machine, either by a
+// Fail state or by the runtime. It carries a name so that it can be matched
+// on by whoever handles it.
+type StateError struct {
+	Name  string `json:"error"`
+	Cause string `json:"cause,omitempty"`
+}
+
+// NewStateError creates a StateError with the given name and cause
+func NewStateError(name string, cause string) *StateError {
+	return &StateError{Name: name, Cause: cause}
+}
+
+func (e *StateError) Error() string {
+	switch {
+	case e.Name == "" && e.Cause == "":
+		return "state machine failed"
+	case e.Name == "":
+		return e.Cause
+	case e.Cause == "":
+		return e.Name
+	}
+	return e.Name + ": " + e.Cause
+}
+
+// Code implements APIError, a failed state machine is reported like a failed function
+func (e *StateError) Code() int { return http.StatusBadGateway }
+
+// Terminal returns whether the state ends the state machine it is part of
+func (s *State) Terminal() bool {
+	return s.End || s.Type == StateTypeSucceed || s.Type == StateTypeFail
+}
+
+// WaitUntil returns the time a Wait state should wait until, given the
+// state input and the time at which the state was entered.
+func (s *State) WaitUntil(input interface{}, now time.Time) (time.Time, error) {
+	switch {
+	case s.Timestamp != "":
+		t, err := time.Parse(time.RFC3339, s.Timestamp)
+		if err != nil {
+			return now, NewStateError(StatesErrorRuntime, err.Error())
+		}
+		return t, nil
+	case s.SecondsPath != "":
+		v, err := JSONPathGet(input, s.SecondsPath)
+		if err != nil {
+			return now, NewStateError(StatesErrorRuntime, err.Error())
+		}
+		secs, ok := v.(float64)
+		if !ok || secs < 0 {
+			return now, NewStateError(StatesErrorRuntime, fmt.Sprintf("SecondsPath %q is not a positive number", s.SecondsPath))
+		}
+		return now.Add(time.Duration(secs * float64(time.Second))), nil
+	case s.TimestampPath != "":
+		v, err := JSONPathGet(input, s.TimestampPath)
+		if err != nil {
+			return now, NewStateError(StatesErrorRuntime, err.Error())
+		}
+		ts, ok := v.(string)
+		if !ok {
+			return now, NewStateError(StatesErrorRuntime, fmt.Sprintf("TimestampPath %q is not a timestamp", s.TimestampPath))
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return now, NewStateError(StatesErrorRuntime, err.Error())
+		}
+		return t, nil
+	default:
+		return now.Add(time.Duration(s.Seconds) * time.Second), nil
+	}
+}
+
+// MapItems returns the items a Map state iterates over, at ItemsPath in the input
+func (s *State) MapItems(input interface{}) ([]interface{}, error) {
+	path := s.ItemsPath
+	if path == "" {
+		path = "$"
+	}
+	v, err := JSONPathGet(input, path)
+	if err != nil {
+		return nil, NewStateError(StatesErrorRuntime, err.Error())
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, NewStateError(StatesErrorRuntime, fmt.Sprintf("ItemsPath %q is not an array", path))
+	}
+	return items, nil
+}
+
 // BenchmarkRequest - benchmark request
 type BenchmarkRequest struct {
 	AppName  string
@@ -47,6 +232,15 @@ type BenchmarkResult struct {
 	Checkpoints           []Checkpoint
 	AverageThroughput     float64
 	TotalError            int64
+	// LatencyByStartType splits the completed requests by their start type
+	LatencyByStartType map[string]StartTypeLatency
+}
+
+// StartTypeLatency - latency of the completed requests of one start type
+type StartTypeLatency struct {
+	Count          int64
+	TotalLatency   int64
+	AverageLatency float64
 }
 
 // Checkpoint - checkpoint
@@ -56,4 +250,5 @@ type Checkpoint struct {
 	Checkpoints      []int64
 	ErrorCount       int64
 	CompletedRequest int64
+	StartTypes       map[string]StartTypeLatency
 }
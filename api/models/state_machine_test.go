@@ -0,0 +1,434 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func decodeDoc(t *testing.T, s string) interface{} {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatalf("bad test document %s: %v", s, err)
+	}
+	return doc
+}
+
+func TestJSONPathGet(t *testing.T) {
+	doc := decodeDoc(t, `{"a": {"b": [10, {"c": "x"}]}, "d e": true}`)
+
+	for i, test := range []struct {
+		path     string
+		expected interface{}
+		err      bool
+	}{
+		{"$", doc, false},
+		{"$.a.b[0]", float64(10), false},
+		{"$.a.b[1].c", "x", false},
+		{"$['d e']", true, false},
+		{"$.a.missing", nil, true},
+		{"$.a.b[2]", nil, true},
+		{"$.a.b.c", nil, true},
+		{"a.b", nil, true},
+		{"$..a", nil, true},
+	} {
+		v, err := JSONPathGet(doc, test.path)
+		if (err != nil) != test.err {
+			t.Errorf("Test %d: %s: expected error %v, got %v", i, test.path, test.err, err)
+			continue
+		}
+		if !test.err && test.path != "$" && v != test.expected {
+			t.Errorf("Test %d: %s: expected %v, got %v", i, test.path, test.expected, v)
+		}
+	}
+}
+
+func TestChoiceRules(t *testing.T) {
+	doc := decodeDoc(t, `{"type": "Private", "value": 42, "ok": true, "when": "2020-01-02T00:00:00Z", "name": "log-2020.txt", "nothing": null}`)
+
+	for i, test := range []struct {
+		rule     string
+		expected bool
+		err      bool
+	}{
+		{`{"Variable": "$.type", "StringEquals": "Private"}`, true, false},
+		{`{"Variable": "$.type", "StringEquals": "Public"}`, false, false},
+		{`{"Variable": "$.value", "StringEquals": "42"}`, false, false},
+		{`{"Variable": "$.type", "StringGreaterThan": "Pa"}`, true, false},
+		{`{"Variable": "$.name", "StringMatches": "log-*.txt"}`, true, false},
+		{`{"Variable": "$.name", "StringMatches": "log-*.csv"}`, false, false},
+		{`{"Variable": "$.value", "NumericEquals": 42}`, true, false},
+		{`{"Variable": "$.value", "NumericLessThan": 42}`, false, false},
+		{`{"Variable": "$.value", "NumericGreaterThanEquals": 42}`, true, false},
+		{`{"Variable": "$.ok", "BooleanEquals": true}`, true, false},
+		{`{"Variable": "$.when", "TimestampLessThan": "2021-01-01T00:00:00Z"}`, true, false},
+		{`{"Variable": "$.when", "TimestampEquals": "bogus"}`, false, true},
+		{`{"Variable": "$.nothing", "IsNull": true}`, true, false},
+		{`{"Variable": "$.value", "IsNumeric": true}`, true, false},
+		{`{"Variable": "$.when", "IsTimestamp": true}`, true, false},
+		{`{"Variable": "$.missing", "IsPresent": false}`, true, false},
+		{`{"Variable": "$.missing", "StringEquals": "x"}`, false, true},
+		{`{"Variable": "$.type"}`, false, true},
+		{`{"And": [{"Variable": "$.value", "NumericGreaterThan": 40}, {"Variable": "$.ok", "BooleanEquals": true}]}`, true, false},
+		{`{"And": [{"Variable": "$.value", "NumericGreaterThan": 40}, {"Variable": "$.ok", "BooleanEquals": false}]}`, false, false},
+		{`{"Or": [{"Variable": "$.value", "NumericLessThan": 0}, {"Variable": "$.type", "StringEquals": "Private"}]}`, true, false},
+		{`{"Not": {"Variable": "$.type", "StringEquals": "Private"}}`, false, false},
+	} {
+		var rule ChoiceRule
+		if err := json.Unmarshal([]byte(test.rule), &rule); err != nil {
+			t.Fatalf("Test %d: bad rule: %v", i, err)
+		}
+		ok, err := rule.Matches(doc)
+		if (err != nil) != test.err {
+			t.Errorf("Test %d: %s: expected error %v, got %v", i, test.rule, test.err, err)
+			continue
+		}
+		if ok != test.expected {
+			t.Errorf("Test %d: %s: expected %v, got %v", i, test.rule, test.expected, ok)
+		}
+	}
+}
+
+func TestChoose(t *testing.T) {
+	var state State
+	err := json.Unmarshal([]byte(`{
+		"Type": "Choice",
+		"Choices": [
+			{"Variable": "$.n", "NumericLessThan": 0, "Next": "negative"},
+			{"Variable": "$.n", "NumericEquals": 0, "Next": "zero"}
+		]
+	}`), &state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := state.Choose(decodeDoc(t, `{"n": 0}`))
+	if err != nil || next != "zero" {
+		t.Fatalf("expected zero, got %q (%v)", next, err)
+	}
+
+	_, err = state.Choose(decodeDoc(t, `{"n": 1}`))
+	if serr, ok := err.(*StateError); !ok || serr.Name != StatesErrorNoChoiceMatched {
+		t.Fatalf("expected %s, got %v", StatesErrorNoChoiceMatched, err)
+	}
+
+	state.Default = "positive"
+	next, err = state.Choose(decodeDoc(t, `{"n": 1}`))
+	if err != nil || next != "positive" {
+		t.Fatalf("expected default, got %q (%v)", next, err)
+	}
+}
+
+func TestWaitUntil(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	doc := decodeDoc(t, `{"secs": 1.5, "at": "2020-01-01T01:00:00Z", "bad": "soon"}`)
+
+	for i, test := range []struct {
+		state    State
+		expected time.Time
+		err      bool
+	}{
+		{State{Seconds: 10}, now.Add(10 * time.Second), false},
+		{State{Timestamp: "2020-01-01T00:05:00Z"}, now.Add(5 * time.Minute), false},
+		{State{SecondsPath: "$.secs"}, now.Add(1500 * time.Millisecond), false},
+		{State{TimestampPath: "$.at"}, now.Add(time.Hour), false},
+		{State{SecondsPath: "$.bad"}, now, true},
+		{State{TimestampPath: "$.bad"}, now, true},
+		{State{SecondsPath: "$.missing"}, now, true},
+		{State{Timestamp: "soon"}, now, true},
+	} {
+		until, err := test.state.WaitUntil(doc, now)
+		if (err != nil) != test.err {
+			t.Errorf("Test %d: expected error %v, got %v", i, test.err, err)
+			continue
+		}
+		// errors are state errors, for a Catch to match
+		if test.err && StateErrorName(err) != StatesErrorRuntime {
+			t.Errorf("Test %d: expected a %s error, got %v", i, StatesErrorRuntime, err)
+		}
+		if !test.err && !until.Equal(test.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, until)
+		}
+	}
+}
+
+func TestMapItems(t *testing.T) {
+	items, err := (&State{}).MapItems(decodeDoc(t, `[1, 2, 3]`))
+	if err != nil || len(items) != 3 {
+		t.Fatalf("expected the whole input to be iterated, got %v (%v)", items, err)
+	}
+
+	items, err = (&State{ItemsPath: "$.xs"}).MapItems(decodeDoc(t, `{"xs": ["a"]}`))
+	if err != nil || len(items) != 1 || items[0] != "a" {
+		t.Fatalf("expected items at ItemsPath, got %v (%v)", items, err)
+	}
+
+	_, err = (&State{ItemsPath: "$.xs"}).MapItems(decodeDoc(t, `{"xs": "a"}`))
+	if err == nil {
+		t.Fatal("expected an error iterating over a non-array")
+	}
+}
+
+func TestJSONPathSet(t *testing.T) {
+	doc := decodeDoc(t, `{"a": {"b": 1}, "s": "x"}`)
+
+	out, err := JSONPathSet(doc, "$.a.c.d", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(out)
+	if string(b) != `{"a":{"b":1,"c":{"d":"new"}},"s":"x"}` {
+		t.Fatalf("unexpected document after set: %s", b)
+	}
+
+	out, err = JSONPathSet(doc, "$", "replaced")
+	if err != nil || out != "replaced" {
+		t.Fatalf("expected $ to replace the document, got %v (%v)", out, err)
+	}
+
+	for _, p := range []string{"$.s.x", "$.a[0]"} {
+		if _, err := JSONPathSet(doc, p, 1); err == nil {
+			t.Errorf("%s: expected an error", p)
+		}
+	}
+	if _, err := JSONPathSet("scalar", "$.a", 1); err == nil {
+		t.Error("expected an error setting a field on a scalar")
+	}
+}
+
+func TestStateDataFlow(t *testing.T) {
+	for i, test := range []struct {
+		state    string
+		input    string
+		result   string
+		expected string
+	}{
+		// no data flow fields, result replaces the input
+		{`{"Type": "Task"}`, `{"a": 1}`, `{"r": 2}`, `{"r":2}`},
+		// result merged into the input
+		{`{"Type": "Task", "ResultPath": "$.res"}`, `{"a": 1}`, `{"r": 2}`, `{"a":1,"res":{"r":2}}`},
+		// selecting parts of the result, then of the output
+		{`{"Type": "Task", "ResultSelector": {"val.$": "$.r", "fixed": true}, "ResultPath": "$.res", "OutputPath": "$.res"}`, `{"a": 1}`, `{"r": 2}`, `{"fixed":true,"val":2}`},
+		// Choice, Wait and Succeed output their effective input
+		{`{"Type": "Succeed", "InputPath": "$.a", "OutputPath": "$.b"}`, `{"a": {"b": 3}}`, ``, `3`},
+		{`{"Type": "Wait", "InputPath": "$.a"}`, `{"a": {"b": 3}}`, ``, `{"b":3}`},
+	} {
+		var state State
+		if err := json.Unmarshal([]byte(test.state), &state); err != nil {
+			t.Fatalf("Test %d: bad state: %v", i, err)
+		}
+		raw := decodeDoc(t, test.input)
+		effective, err := state.EffectiveInput(raw)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error: %v", i, err)
+		}
+		var result interface{}
+		if test.result != "" {
+			result = decodeDoc(t, test.result)
+		}
+		out, err := state.Output(raw, effective, result)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error: %v", i, err)
+		}
+		b, _ := json.Marshal(out)
+		if string(b) != test.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, test.expected, b)
+		}
+	}
+}
+
+func TestStateEffectiveInput(t *testing.T) {
+	state := State{
+		Type:       StateTypeTask,
+		InputPath:  "$.order",
+		Parameters: json.RawMessage(`{"id.$": "$.id", "items": {"first.$": "$.items[0]"}, "source": "shop"}`),
+	}
+	raw := decodeDoc(t, `{"order": {"id": "o1", "items": ["x", "y"]}, "other": 1}`)
+	effective, err := state.EffectiveInput(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(effective)
+	if string(b) != `{"id":"o1","items":{"first":"x"},"source":"shop"}` {
+		t.Fatalf("unexpected effective input %s", b)
+	}
+
+	state.Parameters = json.RawMessage(`{"id.$": "$.missing"}`)
+	_, err = state.EffectiveInput(raw)
+	if serr, ok := err.(*StateError); !ok || serr.Name != StatesErrorParameterPathFailure {
+		t.Fatalf("expected %s, got %v", StatesErrorParameterPathFailure, err)
+	}
+
+	// Map states apply Parameters per item, with the item in the context object
+	mapState := State{Type: StateTypeMap, Parameters: json.RawMessage(`{"item.$": "$$.Map.Item.Value", "index.$": "$$.Map.Item.Index", "all.$": "$.n"}`)}
+	effective, err = mapState.EffectiveInput(decodeDoc(t, `{"n": 7}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemInput, err := mapState.ItemInput(effective, "a", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ = json.Marshal(itemInput)
+	if string(b) != `{"all":7,"index":1,"item":"a"}` {
+		t.Fatalf("unexpected item input %s", b)
+	}
+}
+
+func TestStateErrorName(t *testing.T) {
+	for i, test := range []struct {
+		err      error
+		expected string
+	}{
+		{NewStateError("Custom.Error", "because"), "Custom.Error"},
+		{ErrCallTimeout, StatesErrorTimeout},
+		{ErrFunctionFailed, StatesErrorTaskFailed},
+		{ErrCallTimeoutServerBusy, "Fn.ServiceUnavailable"},
+		{ErrFnsNotFound, "Fn.NotFound"},
+		{errors.New("boom"), StatesErrorTaskFailed},
+	} {
+		if name := StateErrorName(test.err); name != test.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, test.expected, name)
+		}
+	}
+}
+
+func TestRetryAndCatchMatching(t *testing.T) {
+	var state State
+	err := json.Unmarshal([]byte(`{
+		"Type": "Task",
+		"Retry": [
+			{"ErrorEquals": ["States.Timeout"], "MaxAttempts": 1},
+			{"ErrorEquals": ["States.TaskFailed"]}
+		],
+		"Catch": [
+			{"ErrorEquals": ["Custom.Error"], "Next": "custom", "ResultPath": "$.error"},
+			{"ErrorEquals": ["States.ALL"], "Next": "all"}
+		]
+	}`), &state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		name          string
+		expectedRetry int
+		expectedCatch string
+	}{
+		{StatesErrorTimeout, 0, "all"},
+		{"Fn.ServiceUnavailable", 1, "all"},
+		{StatesErrorTaskFailed, 1, "all"},
+		{"Custom.Error", 1, "custom"},
+		{StatesErrorNoChoiceMatched, -1, "all"},
+		{StatesErrorRuntime, -1, ""},
+	} {
+		idx, _ := state.Retrier(test.name)
+		if idx != test.expectedRetry {
+			t.Errorf("Test %d: %s: expected retrier %d, got %d", i, test.name, test.expectedRetry, idx)
+		}
+		next := ""
+		if c := state.Catcher(test.name); c != nil {
+			next = c.Next
+		}
+		if next != test.expectedCatch {
+			t.Errorf("Test %d: %s: expected catcher %q, got %q", i, test.name, test.expectedCatch, next)
+		}
+	}
+
+	// defaults are 3 attempts, starting at a second and doubling
+	b := state.Retry[1].BackOff()
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		d, ok := b.NextBackOff()
+		if !ok || d != expected {
+			t.Fatalf("expected a retry after %v, got %v (%v)", expected, d, ok)
+		}
+	}
+	if _, ok := b.NextBackOff(); ok {
+		t.Fatal("expected retries to run out")
+	}
+
+	out, err := state.Catch[0].ErrorOutput(decodeDoc(t, `{"a": 1}`), NewStateError("Custom.Error", "because"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := json.Marshal(out)
+	if string(bs) != `{"a":1,"error":{"Cause":"because","Error":"Custom.Error"}}` {
+		t.Fatalf("unexpected error output %s", bs)
+	}
+}
+
+func TestStateMachineValidate(t *testing.T) {
+	for i, test := range []struct {
+		sm  string
+		err string
+	}{
+		{`{"StartAt": "a", "States": {"a": {"Type": "Task", "AppName": "app", "FuncName": "/fn", "End": true}}}`, ""},
+		{`{"StartAt": "c", "States": {
+			"c": {"Type": "Choice", "Choices": [{"Variable": "$.x", "BooleanEquals": true, "Next": "a"}], "Default": "b"},
+			"a": {"Type": "Pass", "Next": "c"},
+			"b": {"Type": "Succeed"}}}`, ""},
+		{`{"StartAt": "m", "States": {"m": {"Type": "Map", "End": true, "Iterator": {"StartAt": "i", "States": {"i": {"Type": "Pass", "End": true}}}}}}`, ""},
+		{`{"StartAt": "a", "States": {"a": {"Type": "Task", "AppName": "app", "FuncName": "/fn", "Next": "b",
+			"Catch": [{"ErrorEquals": ["States.ALL"], "Next": "f"}]}, "b": {"Type": "Succeed"}, "f": {"Type": "Fail"}}}`, ""},
+
+		{`{"States": {"a": {"Type": "Pass", "End": true}}}`, "Invalid state machine: missing StartAt"},
+		{`{"StartAt": "x", "States": {"a": {"Type": "Pass", "End": true}}}`, `Invalid state machine: StartAt state "x" does not exist`},
+		{`{"StartAt": "a", "States": {"a": {"Type": "Nope", "End": true}}}`, `Invalid state machine: state "a" has unknown type "Nope"`},
+		{`{"StartAt": "a", "States": {"a": {"Type": "Task", "End": true}}}`, `Invalid state machine: Task state "a" requires AppName and FuncName`},
+		{`{"StartAt": "a", "States": {"a": {"Type": "Pass"}}}`, `Invalid state machine: state "a" has neither Next nor End`},
+		{`{"StartAt": "a", "States": {"a": {"Type": "Pass", "Next": "b"}}}`, `Invalid state machine: state "a" refers to missing state "b"`},
+		{`{"StartAt": "a", "States": {"a": {"Type": "Pass", "End": true}, "b": {"Type": "Pass", "End": true}}}`, `Invalid state machine: unreachable states b`},
+		{`{"StartAt": "a", "States": {"a": {"Type": "Pass", "Next": "b"}, "b": {"Type": "Pass", "Next": "a"}}}`, `Invalid state machine: states a, b form a cycle with no End`},
+		{`{"StartAt": "c", "States": {
+			"c": {"Type": "Choice", "Choices": [{"Variable": "$.x", "BooleanEquals": true, "Next": "a"}], "Default": "b"},
+			"a": {"Type": "Pass", "Next": "a"},
+			"b": {"Type": "Succeed"}}}`, `Invalid state machine: states a form a cycle with no End`},
+		{`{"StartAt": "c", "States": {"c": {"Type": "Choice", "Choices": [{"Variable": "$.x", "BooleanEquals": true}]}}}`, `Invalid state machine: Choice state "c" has a rule with no Next`},
+		{`{"StartAt": "m", "States": {"m": {"Type": "Map", "End": true, "Iterator": {"StartAt": "i", "States": {"i": {"Type": "Pass"}}}}}}`,
+			`Invalid state machine: m: state "i" has neither Next nor End`},
+		{`{"StartAt": "p", "States": {"p": {"Type": "Parallel", "End": true, "ParallelExecution": {"MaxConcurrency": -1,
+			"StateMachine": {"StartAt": "i", "States": {"i": {"Type": "Pass", "End": true}}}}}}}`,
+			`Invalid state machine: Parallel state "p" has a negative MaxConcurrency`},
+		{`{"StartAt": "f", "States": {"f": {"Type": "Fail", "Retry": [{"ErrorEquals": ["States.ALL"]}]}}}`,
+			`Invalid state machine: Fail state "f" cannot Retry or Catch`},
+		{`{"StartAt": "f", "States": {"f": {"Type": "Fail", "Catch": [{"ErrorEquals": ["States.ALL"], "Next": "s"}]}, "s": {"Type": "Succeed"}}}`,
+			`Invalid state machine: Fail state "f" cannot Retry or Catch`},
+	} {
+		var sm StateMachine
+		if err := json.Unmarshal([]byte(test.sm), &sm); err != nil {
+			t.Fatalf("Test %d: bad test state machine: %v", i, err)
+		}
+		err := sm.Validate()
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("Test %d: expected state machine to be valid, got %v", i, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Test %d: expected error `%s`, got `%v`", i, test.err, err)
+			continue
+		}
+		if GetAPIErrorCode(err) != 400 {
+			t.Errorf("Test %d: expected a bad request, got %d", i, GetAPIErrorCode(err))
+		}
+	}
+}
+
+func TestStateMachineFuncRefs(t *testing.T) {
+	var sm StateMachine
+	err := json.Unmarshal([]byte(`{"StartAt": "a", "States": {
+		"a": {"Type": "Task", "AppName": "app", "FuncName": "/one", "Next": "p"},
+		"p": {"Type": "Parallel", "Next": "b", "ParallelExecution": {"StateMachine": {"StartAt": "x", "States": {
+			"x": {"Type": "Task", "AppName": "app", "FuncName": "/two", "End": true}}}}},
+		"b": {"Type": "Task", "AppName": "app", "FuncName": "/one", "End": true}}}`), &sm)
+	if err != nil {
+		t.Fatalf("bad test state machine: %v", err)
+	}
+
+	refs := sm.FuncRefs()
+	if len(refs) != 2 || refs[0] != (FuncRef{"app", "/one"}) || refs[1] != (FuncRef{"app", "/two"}) {
+		t.Fatalf("expected each function once, got %+v", refs)
+	}
+}
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var stateTypes = []string{
+	StateTypeTask, StateTypeParallel, StateTypeChoice, StateTypeWait,
+	StateTypePass, StateTypeSucceed, StateTypeFail, StateTypeMap,
+}
+
+// ValidStateType checks that a given state type is known
+func ValidStateType(t string) bool {
+	for _, v := range stateTypes {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+func invalidStateMachine(format string, args ...interface{}) error {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid state machine: "+format, args...))
+}
+
+// Validate checks the structure of a state machine, and of those nested in
+// its Parallel and Map states: that every state it refers to exists, that
+// every state can be reached from StartAt and that every state can reach an
+// end, so that there are no cycles that can't be left.
+func (sm *StateMachine) Validate() error {
+	return sm.validate("")
+}
+
+func (sm *StateMachine) validate(prefix string) error {
+	if sm.StartAt == "" {
+		return invalidStateMachine("%smissing StartAt", prefix)
+	}
+	if len(sm.States) == 0 {
+		return invalidStateMachine("%sno States", prefix)
+	}
+	if _, ok := sm.States[sm.StartAt]; !ok {
+		return invalidStateMachine("%sStartAt state %q does not exist", prefix, sm.StartAt)
+	}
+
+	names := make([]string, 0, len(sm.States))
+	for name := range sm.States {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := sm.validateState(prefix, name, sm.States[name]); err != nil {
+			return err
+		}
+	}
+
+	// everything must be reachable from the start
+	reached := map[string]bool{sm.StartAt: true}
+	queue := []string{sm.StartAt}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range sm.States[cur].transitions() {
+			if !reached[next] {
+				reached[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	var unreachable []string
+	for _, name := range names {
+		if !reached[name] {
+			unreachable = append(unreachable, name)
+		}
+	}
+	if len(unreachable) > 0 {
+		return invalidStateMachine("%sunreachable states %s", prefix, strings.Join(unreachable, ", "))
+	}
+
+	// and everything must be able to get to an end, working back from the ends
+	ends := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, name := range names {
+			if ends[name] {
+				continue
+			}
+			state := sm.States[name]
+			ok := state.Terminal()
+			for _, next := range state.transitions() {
+				ok = ok || ends[next]
+			}
+			if ok {
+				ends[name] = true
+				changed = true
+			}
+		}
+	}
+	var stuck []string
+	for _, name := range names {
+		if !ends[name] {
+			stuck = append(stuck, name)
+		}
+	}
+	if len(stuck) > 0 {
+		return invalidStateMachine("%sstates %s form a cycle with no End", prefix, strings.Join(stuck, ", "))
+	}
+	return nil
+}
+
+func (sm *StateMachine) validateState(prefix, name string, state *State) error {
+	if state == nil {
+		return invalidStateMachine("%sstate %q is empty", prefix, name)
+	}
+	if !ValidStateType(state.Type) {
+		return invalidStateMachine("%sstate %q has unknown type %q", prefix, name, state.Type)
+	}
+
+	switch state.Type {
+	case StateTypeTask:
+		if state.AppName == "" || state.FuncName == "" {
+			return invalidStateMachine("%sTask state %q requires AppName and FuncName", prefix, name)
+		}
+	case StateTypeParallel:
+		if state.ParallelExecution == nil {
+			return invalidStateMachine("%sParallel state %q requires ParallelExecution", prefix, name)
+		}
+		if state.ParallelExecution.MaxConcurrency < 0 {
+			return invalidStateMachine("%sParallel state %q has a negative MaxConcurrency", prefix, name)
+		}
+		if err := state.ParallelExecution.StateMachine.validate(prefix + name + ": "); err != nil {
+			return err
+		}
+	case StateTypeMap:
+		if state.Iterator == nil {
+			return invalidStateMachine("%sMap state %q requires Iterator", prefix, name)
+		}
+		if state.MaxConcurrency < 0 {
+			return invalidStateMachine("%sMap state %q has a negative MaxConcurrency", prefix, name)
+		}
+		if err := state.Iterator.validate(prefix + name + ": "); err != nil {
+			return err
+		}
+	case StateTypeChoice:
+		if len(state.Choices) == 0 {
+			return invalidStateMachine("%sChoice state %q has no Choices", prefix, name)
+		}
+		for _, rule := range state.Choices {
+			if rule.Next == "" {
+				return invalidStateMachine("%sChoice state %q has a rule with no Next", prefix, name)
+			}
+		}
+		if state.End {
+			return invalidStateMachine("%sChoice state %q cannot End", prefix, name)
+		}
+	}
+
+	switch state.Type {
+	case StateTypeChoice, StateTypeSucceed, StateTypeFail:
+	default:
+		if !state.End && state.Next == "" {
+			return invalidStateMachine("%sstate %q has neither Next nor End", prefix, name)
+		}
+	}
+
+	// a Fail state ends the state machine with its error, which is neither
+	// retried nor caught
+	if state.Type == StateTypeFail && (len(state.Retry) > 0 || len(state.Catch) > 0) {
+		return invalidStateMachine("%sFail state %q cannot Retry or Catch", prefix, name)
+	}
+
+	for _, c := range state.Catch {
+		if c.Next == "" {
+			return invalidStateMachine("%sstate %q has a Catch with no Next", prefix, name)
+		}
+	}
+
+	for _, next := range state.transitions() {
+		if _, ok := sm.States[next]; !ok {
+			return invalidStateMachine("%sstate %q refers to missing state %q", prefix, name, next)
+		}
+	}
+	return nil
+}
+
+// transitions returns the states that a state can go to next
+func (s *State) transitions() []string {
+	var next []string
+	switch s.Type {
+	case StateTypeChoice:
+		for _, rule := range s.Choices {
+			next = append(next, rule.Next)
+		}
+		if s.Default != "" {
+			next = append(next, s.Default)
+		}
+	case StateTypeSucceed, StateTypeFail:
+	default:
+		if !s.End && s.Next != "" {
+			next = append(next, s.Next)
+		}
+	}
+	for _, c := range s.Catch {
+		next = append(next, c.Next)
+	}
+	return next
+}
+
+// FuncRef is a reference from a Task state to the function it invokes, by
+// the name of its app and the source of its HTTP trigger
+type FuncRef struct {
+	AppName  string
+	FuncName string
+}
+
+// FuncRefs returns the functions invoked by the state machine, including by
+// any state machines nested in it, each only once
+func (sm *StateMachine) FuncRefs() []FuncRef {
+	seen := make(map[FuncRef]bool)
+	var refs []FuncRef
+	var walk func(*StateMachine)
+	walk = func(m *StateMachine) {
+		names := make([]string, 0, len(m.States))
+		for name := range m.States {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			state := m.States[name]
+			if state == nil {
+				continue
+			}
+			switch {
+			case state.Type == StateTypeTask:
+				ref := FuncRef{state.AppName, state.FuncName}
+				if !seen[ref] {
+					seen[ref] = true
+					refs = append(refs, ref)
+				}
+			case state.ParallelExecution != nil:
+				walk(&state.ParallelExecution.StateMachine)
+			case state.Iterator != nil:
+				walk(state.Iterator)
+			}
+		}
+	}
+	walk(sm)
+	return refs
+}
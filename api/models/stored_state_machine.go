@@ -0,0 +1,140 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// MaxLengthStateMachineName is the maximum length of the name of a stored state machine
+const MaxLengthStateMachineName = 255
+
+var (
+	//ErrStateMachinesNotFound - no stored state machine with the given name or version
+	ErrStateMachinesNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("State machine not found"),
+	}
+	//ErrStateMachinesNotSupported - the server has no datastore to load state machines from
+	ErrStateMachinesNotSupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("Stored state machines are not supported by this server"),
+	}
+	//ErrStateMachinesVersionConflict - another version of the state machine was stored at the same time
+	ErrStateMachinesVersionConflict = err{
+		code:  http.StatusConflict,
+		error: errors.New("A new version of this State machine was stored concurrently, try again"),
+	}
+	//ErrStateMachinesIDProvided - an ID was specified on state machine creation
+	ErrStateMachinesIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("ID cannot be provided for State machine creation"),
+	}
+	//ErrStateMachinesVersionProvided - a version was specified on state machine creation
+	ErrStateMachinesVersionProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Version cannot be provided for State machine creation"),
+	}
+	//ErrStateMachinesInvalidVersion - a version that is not a positive number was asked for
+	ErrStateMachinesInvalidVersion = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid version for State machine"),
+	}
+	//ErrStateMachinesMissingAppID - no app ID on a stored state machine
+	ErrStateMachinesMissingAppID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing App ID on State machine"),
+	}
+	//ErrStateMachinesMissingName - no name on a stored state machine
+	ErrStateMachinesMissingName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing name on State machine"),
+	}
+	//ErrStateMachinesTooLongName - name exceeds MaxLengthStateMachineName
+	ErrStateMachinesTooLongName = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("State machine name must be %v characters or less", MaxLengthStateMachineName),
+	}
+	//ErrStateMachinesInvalidName - name does not comply with naming spec
+	ErrStateMachinesInvalidName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid name for State machine"),
+	}
+	//ErrStateMachinesMissingDefinition - no definition on a stored state machine
+	ErrStateMachinesMissingDefinition = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing definition on State machine"),
+	}
+)
+
+// StoredStateMachine is a StateMachine kept under a name in an app, so that
+// it can be started by name or by a trigger. Storing a state machine under a
+// name that is already taken adds a new version rather than replacing it, so
+// executions can always tell which definition they ran.
+type StoredStateMachine struct {
+	ID         string          `json:"id" db:"id"`
+	AppID      string          `json:"app_id" db:"app_id"`
+	Name       string          `json:"name" db:"name"`
+	Version    int64           `json:"version" db:"version"`
+	Definition *StateMachine   `json:"definition" db:"definition"`
+	CreatedAt  common.DateTime `json:"created_at,omitempty" db:"created_at"`
+}
+
+// Validate checks that a stored state machine has valid data for inserting
+// into a store, including that its definition is well formed. It does not
+// check that the functions the definition refers to exist.
+func (s *StoredStateMachine) Validate() error {
+	if s.AppID == "" {
+		return ErrStateMachinesMissingAppID
+	}
+	if err := s.ValidateName(); err != nil {
+		return err
+	}
+	if s.Definition == nil {
+		return ErrStateMachinesMissingDefinition
+	}
+	return s.Definition.Validate()
+}
+
+// ValidateName checks the name of a stored state machine, which follows the
+// same rules as the name of a trigger
+func (s *StoredStateMachine) ValidateName() error {
+	if s.Name == "" {
+		return ErrStateMachinesMissingName
+	}
+	if len(s.Name) > MaxLengthStateMachineName {
+		return ErrStateMachinesTooLongName
+	}
+	for _, c := range s.Name {
+		if !(unicode.IsLetter(c) || unicode.IsNumber(c) || c == '_' || c == '-') {
+			return ErrStateMachinesInvalidName
+		}
+	}
+	return nil
+}
+
+// Clone creates a copy of a stored state machine, the definition is shared
+// as stored versions are never modified.
+func (s *StoredStateMachine) Clone() *StoredStateMachine {
+	clone := new(StoredStateMachine)
+	*clone = *s
+	return clone
+}
+
+// StateMachineFilter is a search criteria on stored state machines
+type StateMachineFilter struct {
+	AppID string // this is exact match mandatory
+	Name  string // exact match, lists every version of the state machine when set
+
+	Cursor  string
+	PerPage int
+}
+
+// StateMachineList is a container of stored state machines returned by search, optionally indicating the next page cursor
+type StateMachineList struct {
+	NextCursor string                `json:"next_cursor,omitempty"`
+	Items      []*StoredStateMachine `json:"items"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// StreamHeader is the request header with which an invocation asks for its response to be streamed to it as
+	// the fn writes it, "true" or "false", in place of the stream annotation of its fn
+	StreamHeader = "Fn-Stream"
+	// FnStreamAnnotation is the annotation that streams the responses of the invocations of a fn, unless they ask
+	// not to with StreamHeader
+	FnStreamAnnotation = "fnproject.io/fn/stream"
+
+	// CallStatusTrailer is the trailer of a streamed response that tells the final status of its call, as the
+	// status code is sent before the call ends
+	CallStatusTrailer = "Fn-Call-Status"
+	// CallErrorTrailer is the trailer of a streamed response that tells why its call failed, if it did
+	CallErrorTrailer = "Fn-Call-Error"
+)
+
+var (
+	ErrCallInvalidStream = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid %s header, must be true or false", StreamHeader),
+	}
+	ErrFnsInvalidStream = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("%s annotation must be a boolean", FnStreamAnnotation),
+	}
+)
+
+// FnStream returns whether annotations ask for the responses of a fn to be
+// streamed, false if they do not say
+func FnStream(annotations Annotations) (stream bool, err error) {
+	if v, ok := annotations.Get(FnStreamAnnotation); ok {
+		if json.Unmarshal(v, &stream) != nil {
+			return false, ErrFnsInvalidStream
+		}
+	}
+	return stream, nil
+}
+
+// RequestStream returns whether an invocation of fn with header is streamed.
+// The header decides if it is set, the stream annotation of fn otherwise.
+func RequestStream(header string, fn *Fn) (bool, error) {
+	if header != "" {
+		stream, err := strconv.ParseBool(header)
+		if err != nil {
+			return false, ErrCallInvalidStream
+		}
+		return stream, nil
+	}
+	stream, _ := FnStream(fn.Annotations)
+	return stream, nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestRequestStream(t *testing.T) {
+	buffered := &Fn{}
+	streamed := &Fn{}
+	streamed.Annotations, _ = EmptyAnnotations().With(FnStreamAnnotation, true)
+
+	for i, test := range []struct {
+		header   string
+		fn       *Fn
+		expected bool
+		err      error
+	}{
+		{"", buffered, false, nil},
+		{"", streamed, true, nil},
+		{"true", buffered, true, nil},
+		{"false", streamed, false, nil},
+		{"maybe", streamed, false, ErrCallInvalidStream},
+	} {
+		stream, err := RequestStream(test.header, test.fn)
+		if stream != test.expected || err != test.err {
+			t.Fatalf("Test %d: expected %v, %v but got %v, %v", i, test.expected, test.err, stream, err)
+		}
+	}
+}
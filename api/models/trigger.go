@@ -16,15 +16,16 @@ const TriggerHTTPEndpointAnnotation = "fnproject.io/trigger/httpEndpoint"
 
 // Trigger represents a binding between a Function and an external event source
 type Trigger struct {
-	ID          string          `json:"id" db:"id"`
-	Name        string          `json:"name" db:"name"`
-	AppID       string          `json:"app_id" db:"app_id"`
-	FnID        string          `json:"fn_id" db:"fn_id"`
-	CreatedAt   common.DateTime `json:"created_at,omitempty" db:"created_at"`
-	UpdatedAt   common.DateTime `json:"updated_at,omitempty" db:"updated_at"`
-	Type        string          `json:"type" db:"type"`
-	Source      string          `json:"source" db:"source"`
-	Annotations Annotations     `json:"annotations,omitempty" db:"annotations"`
+	ID           string          `json:"id" db:"id"`
+	Name         string          `json:"name" db:"name"`
+	AppID        string          `json:"app_id" db:"app_id"`
+	FnID         string          `json:"fn_id" db:"fn_id"`
+	StateMachine string          `json:"state_machine,omitempty" db:"state_machine"`
+	CreatedAt    common.DateTime `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt    common.DateTime `json:"updated_at,omitempty" db:"updated_at"`
+	Type         string          `json:"type" db:"type"`
+	Source       string          `json:"source" db:"source"`
+	Annotations  Annotations     `json:"annotations,omitempty" db:"annotations"`
 }
 
 // Equals compares two triggers for semantic equality  it ignores timestamp fields but includes annotations
@@ -34,6 +35,7 @@ func (t *Trigger) Equals(t2 *Trigger) bool {
 	eq = eq && t.Name == t2.Name
 	eq = eq && t.AppID == t2.AppID
 	eq = eq && t.FnID == t2.FnID
+	eq = eq && t.StateMachine == t2.StateMachine
 
 	eq = eq && t.Type == t2.Type
 	eq = eq && t.Source == t2.Source
@@ -49,6 +51,7 @@ func (t *Trigger) EqualsWithAnnotationSubset(t2 *Trigger) bool {
 	eq = eq && t.Name == t2.Name
 	eq = eq && t.AppID == t2.AppID
 	eq = eq && t.FnID == t2.FnID
+	eq = eq && t.StateMachine == t2.StateMachine
 
 	eq = eq && t.Type == t2.Type
 	eq = eq && t.Source == t2.Source
@@ -60,7 +63,11 @@ func (t *Trigger) EqualsWithAnnotationSubset(t2 *Trigger) bool {
 //TriggerTypeHTTP represents an HTTP trigger
 const TriggerTypeHTTP = "http"
 
-var triggerTypes = []string{TriggerTypeHTTP}
+//TriggerTypeStateMachine represents an HTTP trigger that starts the latest version of a stored state machine
+//with the request body as its input, rather than calling a function
+const TriggerTypeStateMachine = "statemachine"
+
+var triggerTypes = []string{TriggerTypeHTTP, TriggerTypeStateMachine}
 
 //ValidTriggerTypes lists the supported trigger types in this service
 func ValidTriggerTypes() []string {
@@ -108,6 +115,14 @@ var (
 	ErrTriggerMissingFnID = err{
 		code:  http.StatusBadRequest,
 		error: errors.New("Missing Fn ID on Trigger")}
+	//ErrTriggerMissingStateMachine - no state machine specified on a statemachine trigger
+	ErrTriggerMissingStateMachine = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing State machine on Trigger")}
+	//ErrTriggerStateMachineWithFn - a statemachine trigger was also given a function
+	ErrTriggerStateMachineWithFn = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Fn ID cannot be provided for a statemachine Trigger")}
 	//ErrTriggerFnIDNotSameApp - specified Fn does not belong to the same app as the provided AppID
 	ErrTriggerFnIDNotSameApp = err{
 		code:  http.StatusBadRequest,
@@ -148,14 +163,21 @@ func (t *Trigger) Validate() error {
 		return err
 	}
 
-	if t.FnID == "" {
-		return ErrTriggerMissingFnID
-	}
-
 	if !ValidTriggerType(t.Type) {
 		return ErrTriggerTypeUnknown
 	}
 
+	if t.Type == TriggerTypeStateMachine {
+		if t.StateMachine == "" {
+			return ErrTriggerMissingStateMachine
+		}
+		if t.FnID != "" {
+			return ErrTriggerStateMachineWithFn
+		}
+	} else if t.FnID == "" {
+		return ErrTriggerMissingFnID
+	}
+
 	if t.Source == "" {
 		return ErrTriggerMissingSource
 	}
@@ -169,6 +191,10 @@ func (t *Trigger) Validate() error {
 		return err
 	}
 
+	if _, _, err := TriggerPriority(t.Annotations); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -210,6 +236,10 @@ func (t *Trigger) Update(patch *Trigger) {
 		t.FnID = patch.FnID
 	}
 
+	if patch.StateMachine != "" {
+		t.StateMachine = patch.StateMachine
+	}
+
 	if patch.Name != "" {
 		t.Name = patch.Name
 	}
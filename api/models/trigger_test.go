@@ -79,6 +79,7 @@ func triggerFieldGenerators(t *testing.T) map[string]gopter.Gen {
 	fieldGens["Name"] = gen.AlphaString()
 	fieldGens["AppID"] = gen.AlphaString()
 	fieldGens["FnID"] = gen.AlphaString()
+	fieldGens["StateMachine"] = gen.AlphaString()
 	fieldGens["CreatedAt"] = datetimeGenerator()
 	fieldGens["UpdatedAt"] = datetimeGenerator()
 	fieldGens["Type"] = gen.AlphaString()
@@ -2,6 +2,7 @@ package runnerpool
 
 import (
 	"context"
+	"time"
 
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
@@ -16,6 +17,7 @@ type placerTracker struct {
 	cancel     context.CancelFunc
 	tracker    *attemptTracker
 	isPlaced   bool
+	priority   int
 }
 
 func NewPlacerTracker(requestCtx context.Context, cfg *PlacerConfig, call RunnerCall) *placerTracker {
@@ -32,6 +34,7 @@ func NewPlacerTracker(requestCtx context.Context, cfg *PlacerConfig, call Runner
 		placerCtx:  ctx,
 		cancel:     cancel,
 		tracker:    newAttemptTracker(requestCtx),
+		priority:   call.Model().Priority,
 	}
 }
 
@@ -136,7 +139,7 @@ func (tr *placerTracker) RetryAllBackoff(numOfRunners int, err error) bool {
 		}
 	}
 
-	t := common.NewTimer(tr.cfg.RetryAllDelay)
+	t := common.NewTimer(retryAllDelay(tr.cfg.RetryAllDelay, tr.priority))
 	defer t.Stop()
 
 	select {
@@ -149,3 +152,16 @@ func (tr *placerTracker) RetryAllBackoff(numOfRunners int, err error) bool {
 
 	return true
 }
+
+// retryAllDelay returns how long a call of priority waits before trying the
+// runner list again. Low priority calls wait longer, so that the runners that
+// free up go to the other calls first, and high priority calls wait less.
+func retryAllDelay(delay time.Duration, priority int) time.Duration {
+	switch {
+	case priority < models.PriorityNormal:
+		return delay * 4
+	case priority > models.PriorityNormal:
+		return delay / 2
+	}
+	return delay
+}
@@ -24,6 +24,22 @@ type RunnerPool interface {
 	Shutdown(ctx context.Context) error
 }
 
+// PreWarmer is implemented by runners that can start hot containers for a
+// call ahead of its calls
+type PreWarmer interface {
+	// PreWarm starts up to n idle hot containers for call, each of which
+	// waits keepWarm for its first call, and returns how many were started
+	PreWarm(ctx context.Context, call RunnerCall, n int, keepWarm time.Duration) (int, error)
+}
+
+// LogFollower is implemented by runners that can follow the logs of the
+// calls of a fn as they run
+type LogFollower interface {
+	// FollowLogs sends the lines logged by the calls of fnID of appID to
+	// lines, until ctx is done or the runner ends the stream
+	FollowLogs(ctx context.Context, appID, fnID string, lines chan<- *models.LogLine) error
+}
+
 // RunnerStatus is general information on Runner health as returned by Runner::Status() call
 type RunnerStatus struct {
 	ActiveRequestCount    int32           // Number of active running requests on Runner
@@ -69,3 +85,11 @@ type RunnerCall interface {
 	AddUserExecutionTime(dur time.Duration)
 	GetUserExecutionTime() *time.Duration
 }
+
+// BodyStreamer is a RunnerCall that may stream its request body, rather than
+// have it buffered. A streamed body can only be read once, so it is only sent
+// to a runner once the runner acknowledges the call, and the call is not
+// placed on another runner after that.
+type BodyStreamer interface {
+	StreamsBody() bool
+}
@@ -22,6 +22,11 @@ func (s *Server) handleAppCreate(c *gin.Context) {
 		return
 	}
 
+	if err := app.ValidateSyslogScheme(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
 	app, err = s.datastore.InsertApp(ctx, app)
 	if err != nil {
 		handleErrorResponse(c, err)
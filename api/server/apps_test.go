@@ -58,7 +58,8 @@ func TestAppCreate(t *testing.T) {
 		{datastore.NewMock(), "/v2/apps", `{ "name": "app", "annotations" : { "":"val" }}`, http.StatusBadRequest, models.ErrInvalidAnnotationKey},
 		{datastore.NewMock(), "/v2/apps", `{"name": "app", "annotations" : { "key":"" }}`, http.StatusBadRequest, models.ErrInvalidAnnotationValue},
 		{datastore.NewMock(), "/v2/apps", `{ "name": "app", "syslog_url":"yo"}`, http.StatusBadRequest, errors.New(`invalid syslog url: "yo"`)},
-		{datastore.NewMock(), "/v2/apps", `{"name": "app", "syslog_url":"yo://sup.com:1"}`, http.StatusBadRequest, errors.New(`invalid syslog url: "yo://sup.com:1" invalid scheme, only [tcp, udp, unix, unixgram, tcp+tls] are supported`)},
+		{datastore.NewMock(), "/v2/apps", `{"name": "app", "syslog_url":"yo://sup.com:1"}`, http.StatusBadRequest, errors.New(`invalid syslog url: "yo://sup.com:1" invalid scheme, only [tcp, udp, tcp+tls] are supported`)},
+		{datastore.NewMock(), "/v2/apps", `{"name": "app", "syslog_url":"unix:///dev/log"}`, http.StatusBadRequest, errors.New(`invalid syslog url: "unix:///dev/log" invalid scheme, only [tcp, udp, tcp+tls] are supported`)},
 		// success
 		{datastore.NewMock(), "/v2/apps", `{ "name": "teste"  }`, http.StatusOK, nil},
 		{datastore.NewMock(), "/v2/apps", `{  "name": "teste" , "annotations": {"k1":"v1", "k2":[]}}`, http.StatusOK, nil},
@@ -280,7 +281,13 @@ func TestAppUpdate(t *testing.T) {
 		Name: "myapp",
 		ID:   "appId",
 	}
-	ds := datastore.NewMockInit([]*models.App{app})
+	unixSyslog := "unix:///dev/log"
+	unixApp := &models.App{
+		Name:      "unixapp",
+		ID:        "unixAppId",
+		SyslogURL: &unixSyslog,
+	}
+	ds := datastore.NewMockInit([]*models.App{app, unixApp})
 
 	for i, test := range []struct {
 		mock          models.Datastore
@@ -308,6 +315,13 @@ func TestAppUpdate(t *testing.T) {
 
 		// success
 		{ds, "/v2/apps/appId", `{ "syslog_url":"tcp://example.com:443" }`, http.StatusOK, nil},
+
+		// a deprecated syslog url cannot be set anew
+		{ds, "/v2/apps/appId", `{ "syslog_url":"unix:///dev/log" }`, http.StatusBadRequest, errors.New(`invalid syslog url: "unix:///dev/log" invalid scheme, only [tcp, udp, tcp+tls] are supported`)},
+
+		// success: an app stored with a deprecated syslog url can still be updated
+		{ds, "/v2/apps/unixAppId", `{ "annotations":{"foo":"bar"}}`, http.StatusOK, nil},
+		{ds, "/v2/apps/unixAppId", `{ "syslog_url":"unix:///dev/log", "config": { "test": "1" } }`, http.StatusOK, nil},
 	} {
 		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
 			rnr, cancel := testRunner(t)
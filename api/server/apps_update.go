@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
 )
@@ -32,11 +33,28 @@ func (s *Server) handleAppUpdate(c *gin.Context) {
 		handleErrorResponse(c, models.ErrAppsIDMismatch)
 		return
 	}
+
+	// a deprecated syslog url may be kept, but not set anew
+	if err := app.ValidateSyslogScheme(); err != nil {
+		old, getErr := s.datastore.GetAppByID(ctx, app.ID)
+		if getErr != nil {
+			handleErrorResponse(c, getErr)
+			return
+		}
+		if old.SyslogURL == nil || *old.SyslogURL != *app.SyslogURL {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
 	app, err = s.datastore.UpdateApp(ctx, app)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
+	if err := app.ValidateSyslogScheme(); err != nil {
+		common.Logger(ctx).WithError(err).WithField("app_id", app.ID).Warn("App keeps a deprecated syslog url, which its logs are not sent to")
+	}
 
 	c.JSON(http.StatusOK, app)
 }
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleAsyncCallGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if s.asyncQueue == nil {
+		handleErrorResponse(c, models.ErrAsyncUnsupported)
+		return
+	}
+
+	call, err := s.asyncQueue.GetAsyncCall(ctx, c.Param(api.CallID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, call)
+}
+
+// handleAsyncCallList lists async calls, the dead-letter list is the calls
+// with status=dead
+func (s *Server) handleAsyncCallList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if s.asyncQueue == nil {
+		handleErrorResponse(c, models.ErrAsyncUnsupported)
+		return
+	}
+
+	filter := &models.AsyncCallFilter{}
+	filter.Cursor, filter.PerPage = pageParams(c)
+	filter.FnID = c.Query("fn_id")
+	filter.Status = c.Query("status")
+	if filter.Status != "" && !models.ValidAsyncCallStatus(filter.Status) {
+		handleErrorResponse(c, models.ErrAsyncCallsInvalidStatus)
+		return
+	}
+
+	calls, err := s.asyncQueue.GetAsyncCalls(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calls)
+}
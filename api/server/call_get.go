@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleCallGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if s.callStore == nil {
+		handleErrorResponse(c, models.ErrCallsUnsupported)
+		return
+	}
+
+	call, err := s.callStore.GetCall(ctx, c.Param(api.FnID), c.Param(api.CallID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, call)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleCallList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if s.callStore == nil {
+		handleErrorResponse(c, models.ErrCallsUnsupported)
+		return
+	}
+
+	filter := &models.CallFilter{FnID: c.Param(api.FnID)}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	var err error
+	filter.FromTime, filter.ToTime, err = timeParams(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	calls, err := s.callStore.GetCalls(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calls)
+}
+
+// timeParams returns the from_time and to_time query parameters, zero if not given
+func timeParams(c *gin.Context) (fromTime, toTime common.DateTime, err error) {
+	if from := c.Query("from_time"); from != "" {
+		fromTime, err = common.ParseDateTime(from)
+		if err != nil {
+			return fromTime, toTime, models.ErrInvalidFromTime
+		}
+	}
+	if to := c.Query("to_time"); to != "" {
+		toTime, err = common.ParseDateTime(to)
+		if err != nil {
+			return fromTime, toTime, models.ErrInvalidToTime
+		}
+	}
+	return fromTime, toTime, nil
+}
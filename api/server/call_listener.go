@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"io"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+)
+
+// callStoreListener keeps the record and the log of each call once it has
+// run. A call is never failed for not being kept, errors are only logged.
+type callStoreListener struct {
+	calls models.CallStore
+	logs  models.LogStore
+}
+
+var _ fnext.CallListener = &callStoreListener{}
+
+func (l *callStoreListener) BeforeCall(ctx context.Context, call *models.Call) error {
+	return nil
+}
+
+func (l *callStoreListener) AfterCall(ctx context.Context, call *models.Call) error {
+	log := common.Logger(ctx).WithField("call_id", call.ID)
+
+	if l.calls != nil {
+		if err := l.calls.InsertCall(ctx, call); err != nil {
+			log.WithError(err).Error("error inserting call")
+		}
+	}
+
+	if l.logs != nil {
+		if callLog, ok := ctx.Value(fnext.CallLogKey).(io.Reader); ok {
+			if err := l.logs.InsertLog(ctx, call, callLog); err != nil {
+				log.WithError(err).Error("error inserting call log")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleCallLogGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if s.logStore == nil {
+		handleErrorResponse(c, models.ErrCallsUnsupported)
+		return
+	}
+
+	callLog, err := s.logStore.GetLog(ctx, c.Param(api.FnID), c.Param(api.CallID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, callLog)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCallsUnsupported(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(stateMachineDatastore(), mockAgentSubmitting(nil), ServerTypeFull)
+
+	for _, path := range []string{"/v2/fns/fn_id/calls", "/v2/fns/fn_id/calls/call_id", "/v2/fns/fn_id/calls/call_id/log"} {
+		_, rec := routerRequest(t, srv.Router, "GET", path, nil)
+		if rec.Code != models.ErrCallsUnsupported.Code() {
+			t.Fatalf("Expected status code to be %d for %s but was %d", models.ErrCallsUnsupported.Code(), path, rec.Code)
+		}
+	}
+}
+
+func TestCallStoreListener(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	store := logs.NewMock()
+	rnr := mockAgentSubmitting(nil)
+	rnr.On("AddCallListener", mock.Anything).Return()
+	srv := testServer(stateMachineDatastore(), rnr, ServerTypeFull, WithCallStore(store), WithLogStore(store))
+
+	rnr.AssertCalled(t, "AddCallListener", mock.Anything)
+	listener := rnr.Calls[len(rnr.Calls)-1].Arguments.Get(0).(fnext.CallListener)
+
+	now := time.Now()
+	var calls []*models.Call
+	for i := 0; i < 3; i++ {
+		call := &models.Call{
+			ID:          id.New().String(),
+			AppID:       "app_id",
+			FnID:        "fn_id",
+			Status:      "success",
+			CreatedAt:   common.DateTime(now.Add(time.Duration(i) * time.Second)),
+			StartedAt:   common.DateTime(now.Add(time.Duration(i) * time.Second)),
+			CompletedAt: common.DateTime(now.Add(time.Duration(i)*time.Second + time.Millisecond)),
+		}
+		ctx := context.WithValue(context.Background(), fnext.CallLogKey, strings.NewReader("log of call "+call.ID))
+		if err := listener.AfterCall(ctx, call); err != nil {
+			t.Fatalf("unexpected error from the listener: %v", err)
+		}
+		calls = append(calls, call)
+	}
+
+	_, rec := routerRequest(t, srv.Router, "GET", "/v2/fns/fn_id/calls/"+calls[0].ID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var call models.Call
+	if err := json.NewDecoder(rec.Body).Decode(&call); err != nil {
+		t.Fatalf("unexpected error decoding call: %v", err)
+	}
+	if call.ID != calls[0].ID || call.Status != "success" {
+		t.Fatalf("Expected call %s to succeed, got %+v", calls[0].ID, call)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", "/v2/fns/fn_id/calls/"+calls[1].ID+"/log", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec.Body.String() != "log of call "+calls[1].ID {
+		t.Fatalf("Expected the log of call %s, got %q", calls[1].ID, rec.Body.String())
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", "/v2/fns/fn_id/calls?per_page=2", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var list models.CallList
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("unexpected error decoding calls: %v", err)
+	}
+	if len(list.Items) != 2 || list.Items[0].ID != calls[2].ID || list.NextCursor == "" {
+		t.Fatalf("Expected the newest 2 calls and a cursor, got %+v", list)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", "/v2/fns/fn_id/calls?per_page=2&cursor="+list.NextCursor, nil)
+	list = models.CallList{}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("unexpected error decoding calls: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].ID != calls[0].ID {
+		t.Fatalf("Expected the oldest call on the second page, got %+v", list.Items)
+	}
+
+	for path, code := range map[string]int{
+		"/v2/fns/fn_id/calls/nope":            http.StatusNotFound,
+		"/v2/fns/fn_id/calls/nope/log":        http.StatusNotFound,
+		"/v2/fns/other/calls/" + calls[0].ID:  http.StatusNotFound,
+		"/v2/fns/fn_id/calls?from_time=never": http.StatusBadRequest,
+	} {
+		_, rec := routerRequest(t, srv.Router, "GET", path, nil)
+		if rec.Code != code {
+			t.Fatalf("Expected status code to be %d for %s but was %d", code, path, rec.Code)
+		}
+	}
+}
+
+func TestCallStoreListenerLB(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	// the datastore of an lb is local to it, calls are not recorded there
+	rnr := mockAgentSubmitting(nil)
+	rnr.On("AddCallListener", mock.Anything).Return()
+	ds := struct {
+		models.Datastore
+		logs.Store
+	}{stateMachineDatastore(), logs.NewMock()}
+	testServer(ds, rnr, ServerTypeLB)
+	rnr.AssertNotCalled(t, "AddCallListener", mock.Anything)
+
+	// a shared store records the calls, but not their logs, which the lb never has
+	store := logs.NewMock()
+	rnr = mockAgentSubmitting(nil)
+	rnr.On("AddCallListener", mock.Anything).Return()
+	testServer(stateMachineDatastore(), rnr, ServerTypeLB, WithCallStore(store), WithLogStore(store))
+	rnr.AssertCalled(t, "AddCallListener", mock.Anything)
+	listener := rnr.Calls[len(rnr.Calls)-1].Arguments.Get(0).(*callStoreListener)
+	if listener.calls != store || listener.logs != nil {
+		t.Fatalf("Expected the lb to record calls only in the shared store, got %+v", listener)
+	}
+}
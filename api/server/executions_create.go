@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// executionRequest is the body of POST /v2/executions. The input may be any
+// JSON value, a JSON string is passed to the first state unquoted.
+type executionRequest struct {
+	StateMachine *models.StateMachine `json:"state_machine"`
+	Input        json.RawMessage      `json:"input,omitempty"`
+}
+
+func (r *executionRequest) input() string {
+	if len(r.Input) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(r.Input, &s); err == nil {
+		return s
+	}
+	return string(r.Input)
+}
+
+// handleExecutionCreate starts a state machine and returns straight away
+// with the execution, which can then be polled for its status.
+func (s *Server) handleExecutionCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if s.executionStore == nil {
+		handleErrorResponse(c, models.ErrExecutionsNotSupported)
+		return
+	}
+
+	var req executionRequest
+	err := c.BindJSON(&req)
+	if err != nil {
+		if !models.IsAPIError(err) {
+			err = models.ErrInvalidJSON
+		}
+		handleErrorResponse(c, err)
+		return
+	}
+	if req.StateMachine == nil {
+		handleErrorResponse(c, models.ErrExecutionsMissingStateMachine)
+		return
+	}
+
+	exec, _, err := s.startExecution(ctx, req.StateMachine, req.input())
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Location", "/v2/executions/"+exec.ID)
+	c.JSON(http.StatusAccepted, exec)
+}
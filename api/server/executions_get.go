@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleExecutionGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if s.executionStore == nil {
+		handleErrorResponse(c, models.ErrExecutionsNotSupported)
+		return
+	}
+
+	exec, err := s.executionStore.GetExecutionByID(ctx, c.Param(api.ExecutionID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, exec)
+}
+
+func (s *Server) handleExecutionHistory(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if s.executionStore == nil {
+		handleErrorResponse(c, models.ErrExecutionsNotSupported)
+		return
+	}
+
+	execID := c.Param(api.ExecutionID)
+	// 404 for executions that don't exist, rather than an empty history
+	_, err := s.executionStore.GetExecutionByID(ctx, execID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	states, err := s.executionStore.GetExecutionStates(ctx, execID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.ExecutionStateList{Items: states})
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestExecutionCreate(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	sm, _ := json.Marshal(twoTaskStateMachine())
+
+	for i, test := range []struct {
+		es            models.ExecutionStore
+		body          string
+		expectedCode  int
+		expectedError error
+		expectedInput string
+	}{
+		{nil, fmt.Sprintf(`{"state_machine": %s}`, sm), http.StatusNotImplemented, models.ErrExecutionsNotSupported, ""},
+		{datastore.NewMockExecutionStore(), ``, http.StatusBadRequest, models.ErrInvalidJSON, ""},
+		{datastore.NewMockExecutionStore(), `{"input": "x"}`, http.StatusBadRequest, models.ErrExecutionsMissingStateMachine, ""},
+		{datastore.NewMockExecutionStore(), fmt.Sprintf(`{"state_machine": %s, "input": "plain"}`, sm), http.StatusAccepted, nil, "plain"},
+		{datastore.NewMockExecutionStore(), fmt.Sprintf(`{"state_machine": %s, "input": {"a": 1}}`, sm), http.StatusAccepted, nil, `{"a": 1}`},
+	} {
+		var opts []Option
+		if test.es != nil {
+			opts = append(opts, WithExecutionStore(test.es))
+		}
+		srv := testServer(stateMachineDatastore(), mockAgentSubmitting(nil), ServerTypeFull, opts...)
+
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/v2/executions", bytes.NewBufferString(test.body))
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d. body: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+
+		if test.expectedError != nil {
+			resp := getErrorResponse(t, rec)
+			if resp.Message != test.expectedError.Error() {
+				t.Errorf("Test %d: Expected error message to have `%s`, but it was `%s`", i, test.expectedError, resp.Message)
+			}
+			continue
+		}
+
+		var exec models.Execution
+		if err := json.NewDecoder(rec.Body).Decode(&exec); err != nil {
+			t.Fatalf("Test %d: error decoding execution: %v", i, err)
+		}
+		if exec.ID == "" || exec.Status != models.ExecutionStatusRunning || exec.Input != test.expectedInput {
+			t.Errorf("Test %d: unexpected execution returned: %+v", i, exec)
+		}
+		if loc := rec.Header().Get("Location"); loc != "/v2/executions/"+exec.ID {
+			t.Errorf("Test %d: Expected location of the execution, got %q", i, loc)
+		}
+	}
+}
+
+func TestExecutionGetAndHistory(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	es := datastore.NewMockExecutionStore()
+	srv := testServer(stateMachineDatastore(), mockAgentSubmitting(nil), ServerTypeFull, WithExecutionStore(es))
+
+	body, _ := json.Marshal(&executionRequest{StateMachine: twoTaskStateMachine()})
+	_, rec := routerRequest(t, srv.Router, http.MethodPost, "/v2/executions", bytes.NewReader(body))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	var exec models.Execution
+	if err := json.NewDecoder(rec.Body).Decode(&exec); err != nil {
+		t.Fatalf("error decoding execution: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, rec = routerRequest(t, srv.Router, http.MethodGet, "/v2/executions/"+exec.ID, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&exec); err != nil {
+			t.Fatalf("error decoding execution: %v", err)
+		}
+		if exec.Done() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("execution did not finish, still in state %s", exec.CurrentState)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if exec.Status != models.ExecutionStatusSucceeded {
+		t.Fatalf("expected execution to succeed, got %s (%s)", exec.Status, exec.Error)
+	}
+
+	_, rec = routerRequest(t, srv.Router, http.MethodGet, "/v2/executions/"+exec.ID+"/history", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var history models.ExecutionStateList
+	if err := json.NewDecoder(rec.Body).Decode(&history); err != nil {
+		t.Fatalf("error decoding history: %v", err)
+	}
+	if len(history.Items) != 2 || history.Items[0].Name != "first" || history.Items[1].Name != "second" {
+		t.Fatalf("expected history of both states in order, got %+v", history.Items)
+	}
+
+	for _, path := range []string{"/v2/executions/notreal", "/v2/executions/notreal/history"} {
+		_, rec = routerRequest(t, srv.Router, http.MethodGet, path, nil)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: Expected status code to be %d but was %d", path, http.StatusNotFound, rec.Code)
+		}
+	}
+
+	// executions started over the API are the same as any other, and resumable
+	list, err := es.GetExecutions(context.Background(), nil)
+	if err != nil || len(list.Items) != 1 {
+		t.Fatalf("expected a single stored execution, got %v (%v)", list, err)
+	}
+}
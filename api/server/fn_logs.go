@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleFnLogs follows the logs of the calls of a fn as they run, until the
+// client goes away. Lines are sent as server-sent events of JSON if the client
+// accepts them, and as chunked plain text otherwise.
+func (s *Server) handleFnLogs(c *gin.Context) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	if c.Query("follow") != "true" {
+		handleErrorResponse(c, models.ErrLogsFollowRequired)
+		return
+	}
+
+	lf, ok := s.agent.(agent.LogFollower)
+	if !ok {
+		handleErrorResponse(c, models.ErrLogsFollowUnsupported)
+		return
+	}
+
+	fn, err := s.lbReadAccess.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	sse := strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	if sse {
+		c.Header("Content-Type", "text/event-stream")
+	} else {
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	lines := make(chan *models.LogLine)
+	done := make(chan error, 1)
+	go func() {
+		done <- lf.FollowLogs(ctx, fn.AppID, fn.ID, lines)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-done:
+			if err != nil {
+				common.Logger(ctx).WithError(err).Info("Failed to follow logs")
+			}
+			return
+		case line := <-lines:
+			if sse {
+				b, err := json.Marshal(line)
+				if err != nil {
+					continue
+				}
+				_, err = fmt.Fprintf(c.Writer, "data: %s\n\n", b)
+			} else {
+				_, err = fmt.Fprintln(c.Writer, line.Line)
+			}
+			if err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
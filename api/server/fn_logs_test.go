@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFnLogsFollow(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	lines := []*models.LogLine{
+		{AppID: "app_id", FnID: "fn_id", CallID: "one", Line: "hello"},
+		{AppID: "app_id", FnID: "fn_id", CallID: "two", Line: "world"},
+	}
+
+	for i, test := range []struct {
+		path         string
+		accept       string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"/v2/fns/fn_id/logs?follow=true", "", http.StatusOK, ""},
+		{"/v2/fns/fn_id/logs?follow=true", "text/event-stream", http.StatusOK, ""},
+		{"/v2/fns/fn_id/logs", "", http.StatusBadRequest, models.ErrLogsFollowRequired.Error()},
+		{"/v2/fns/nofn/logs?follow=true", "", http.StatusNotFound, models.ErrFnsNotFound.Error()},
+	} {
+		rnr := new(agent.MockAgent)
+		rnr.On("FollowLogs", "app_id", "fn_id").Return(lines, nil)
+
+		srv := testServer(storedStateMachineDatastore(), rnr, ServerTypeFull)
+		req := createRequest(t, "GET", test.path, nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: expected status %d, got %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		// following sets up no call, whose logger and buffers would leak
+		rnr.AssertNotCalled(t, "GetCall", mock.Anything)
+		if test.expectedErr != "" {
+			resp := getErrorResponse(t, rec)
+			if !strings.Contains(resp.Message, test.expectedErr) {
+				t.Fatalf("Test %d: expected error containing %q, got %q", i, test.expectedErr, resp.Message)
+			}
+			continue
+		}
+
+		if test.accept == "" {
+			if body := rec.Body.String(); body != "hello\nworld\n" {
+				t.Fatalf("Test %d: expected the lines as text, got %q", i, body)
+			}
+			continue
+		}
+
+		if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Test %d: expected server-sent events, got %q", i, ct)
+		}
+		events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+		if len(events) != len(lines) {
+			t.Fatalf("Test %d: expected %d events, got %q", i, len(lines), rec.Body.String())
+		}
+		for j, event := range events {
+			var line models.LogLine
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(event, "data: ")), &line); err != nil {
+				t.Fatalf("Test %d: could not decode event %q: %v", i, event, err)
+			}
+			if line.CallID != lines[j].CallID || line.Line != lines[j].Line {
+				t.Fatalf("Test %d: expected line %+v, got %+v", i, lines[j], line)
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// asyncPollInterval is how long an async worker waits to look at the
+	// queue again once it finds it empty
+	asyncPollInterval = time.Second
+
+	// the delay before a call that failed for the platform is attempted
+	// again doubles with each attempt, from asyncMinRetryDelay up to
+	// asyncMaxRetryDelay
+	asyncMinRetryDelay = time.Second
+	asyncMaxRetryDelay = 5 * time.Minute
+
+	// errAsyncCallExpired is the error of a call that ran out of attempts
+	// without any of them finishing
+	errAsyncCallExpired = errors.New("async call ran out of attempts without finishing any of them")
+)
+
+// enqueueAsync queues req to be run later by an async worker, and acks it
+// with the ID of the call that its result can be fetched by.
+func (s *Server) enqueueAsync(resp http.ResponseWriter, req *http.Request, app *models.App, fn *models.Fn) error {
+	if s.asyncQueue == nil {
+		return models.ErrAsyncUnsupported
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	call, err := s.asyncQueue.Push(req.Context(), &models.AsyncCall{
+		ID:      id.New().String(),
+		AppID:   app.ID,
+		FnID:    fn.ID,
+		Method:  req.Method,
+		URL:     reqURL(req),
+		Headers: models.Headers(req.Header.Clone()),
+		Body:    string(body),
+	})
+	if err != nil {
+		return err
+	}
+
+	resp.Header().Set("Fn-Call-Id", call.ID)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusAccepted)
+	return json.NewEncoder(resp).Encode(call)
+}
+
+// startAsyncWorkers starts the workers that drain the async queue, until ctx
+// is done
+func (s *Server) startAsyncWorkers(ctx context.Context) {
+	for i := 0; i < s.asyncWorkers; i++ {
+		go s.runAsyncWorker(ctx)
+	}
+}
+
+func (s *Server) runAsyncWorker(ctx context.Context) {
+	log := common.Logger(ctx)
+	for {
+		call, err := s.asyncQueue.Reserve(ctx, s.asyncVisibility)
+		if err != nil {
+			log.WithError(err).Error("failed to reserve async call")
+		}
+		if call != nil {
+			s.runAsyncCall(ctx, call)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(asyncPollInterval):
+		}
+	}
+}
+
+// runAsyncCall makes a call reserved from the async queue. A call which
+// finishes, or fails for the function or for its request, is finished with
+// its result. A call which fails for the platform is released to be attempted
+// again, unless it has run out of attempts, in which case it is dead.
+func (s *Server) runAsyncCall(ctx context.Context, call *models.AsyncCall) {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"call_id": call.ID, "fn_id": call.FnID, "attempt": call.Attempts})
+
+	// a call whose worker crashed or hung is neither finished nor released,
+	// and is reserved again once its reservation expires, without a check of
+	// its attempts after it runs
+	if call.Attempts > s.asyncMaxAttempts {
+		log.Error("async call ran out of attempts, its reservations expiring")
+		call.Status = models.AsyncCallStatusDead
+		call.Error = errAsyncCallExpired.Error()
+		if _, err := s.asyncQueue.Finish(ctx, call); err != nil {
+			log.WithError(err).Error("failed to finish async call")
+		}
+		return
+	}
+
+	writer := &syncResponseWriter{
+		headers: make(http.Header),
+		status:  http.StatusOK,
+		Buffer:  new(bytes.Buffer),
+	}
+	err := s.invokeAsyncCall(ctx, call, writer)
+
+	switch {
+	case err == nil:
+		call.Status = models.AsyncCallStatusSucceeded
+		call.ResultStatus = writer.Status()
+		call.ResultHeaders = models.Headers(writer.Header())
+		call.Result = writer.String()
+	case !isRetryableAsyncError(err):
+		call.Status = models.AsyncCallStatusFailed
+		call.ResultStatus = models.GetAPIErrorCode(err)
+		call.Error = err.Error()
+	case call.Attempts >= s.asyncMaxAttempts:
+		log.WithError(err).Error("async call ran out of attempts")
+		call.Status = models.AsyncCallStatusDead
+		call.Error = err.Error()
+	default:
+		log.WithError(err).Info("async call failed, will retry")
+		if err := s.asyncQueue.Release(ctx, call.ID, call.Reservation, asyncRetryDelay(call.Attempts)); err != nil {
+			log.WithError(err).Error("failed to release async call")
+		}
+		return
+	}
+
+	if _, err := s.asyncQueue.Finish(ctx, call); err != nil {
+		log.WithError(err).Error("failed to finish async call")
+	}
+}
+
+// invokeAsyncCall runs the request of call through the agent, under the ID
+// it was queued with, writing the response of the fn to writer
+func (s *Server) invokeAsyncCall(ctx context.Context, call *models.AsyncCall, writer http.ResponseWriter) error {
+	fn, err := s.lbReadAccess.GetFnByID(ctx, call.FnID)
+	if err != nil {
+		return err
+	}
+	app, err := s.lbReadAccess.GetAppByID(ctx, fn.AppID)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(call.Method, call.URL, strings.NewReader(call.Body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header = http.Header(call.Headers).Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	opts := getCallOptions(req, app, fn, nil, writer)
+	opts = append(opts, agent.WithCallID(call.ID))
+
+	agentCall, err := s.agent.GetCall(opts...)
+	if err != nil {
+		return err
+	}
+	return s.agent.Submit(agentCall)
+}
+
+// isRetryableAsyncError reports whether an async call that failed with err
+// may succeed if it is attempted again, which is when the platform, and not
+// the function or the request, is at fault
+func isRetryableAsyncError(err error) bool {
+	if models.IsFuncError(err) {
+		return false
+	}
+	code := models.GetAPIErrorCode(err)
+	return code == 0 || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// asyncRetryDelay returns how long to wait before the next attempt of a call
+// that failed on its attempts-th attempt
+func asyncRetryDelay(attempts int) time.Duration {
+	delay := asyncMinRetryDelay
+	for i := 1; i < attempts && delay < asyncMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return common.MinDuration(delay, asyncMaxRetryDelay)
+}
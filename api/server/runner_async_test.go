@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func asyncRequest(t *testing.T, srv *Server, body string) (string, int) {
+	req := createRequest(t, "POST", "/invoke/fn_id", strings.NewReader(body))
+	req.Header.Set("Fn-Invoke-Type", models.TypeAsync)
+	_, rec := routerRequest2(t, srv.Router, req)
+	return rec.Header().Get("Fn-Call-Id"), rec.Code
+}
+
+func getAsyncCall(t *testing.T, srv *Server, callID string) *models.AsyncCall {
+	_, rec := routerRequest(t, srv.Router, "GET", "/v2/async/"+callID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var call models.AsyncCall
+	if err := json.NewDecoder(rec.Body).Decode(&call); err != nil {
+		t.Fatalf("unexpected error decoding async call: %v", err)
+	}
+	return &call
+}
+
+func TestAsyncInvokeUnsupported(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(stateMachineDatastore(), mockAgentSubmitting(nil), ServerTypeFull)
+
+	_, code := asyncRequest(t, srv, "hello")
+	if code != models.ErrAsyncUnsupported.Code() {
+		t.Fatalf("Expected status code to be %d but was %d", models.ErrAsyncUnsupported.Code(), code)
+	}
+}
+
+func TestAsyncInvoke(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	for i, test := range []struct {
+		submitErr        error
+		maxAttempts      int
+		expectedStatus   string
+		expectedAttempts int
+	}{
+		{nil, 3, models.AsyncCallStatusSucceeded, 1},
+		{models.NewFuncError(models.ErrCallTimeout), 3, models.AsyncCallStatusFailed, 1},
+		{models.ErrCallTimeoutServerBusy, 3, models.AsyncCallStatusQueued, 1},
+		{models.ErrCallTimeoutServerBusy, 1, models.AsyncCallStatusDead, 1},
+	} {
+		aq := datastore.NewMockAsyncQueue()
+
+		submitted := make(chan string, 1)
+		rnr := new(agent.MockAgent)
+		rnr.On("GetCall", mock.Anything).Return()
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			submitted <- args.Get(0).(agent.Call).Model().ID
+		}).Return(test.submitErr)
+
+		srv := testServer(stateMachineDatastore(), rnr, ServerTypeFull,
+			WithAsyncQueue(aq), WithAsyncWorkers(1, time.Minute, test.maxAttempts))
+
+		callID, code := asyncRequest(t, srv, "hello")
+		if code != http.StatusAccepted {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d", i, http.StatusAccepted, code)
+		}
+		if callID == "" {
+			t.Fatalf("Test %d: Expected a call ID to be returned", i)
+		}
+		if call := getAsyncCall(t, srv, callID); call.Status != models.AsyncCallStatusQueued {
+			t.Fatalf("Test %d: Expected the call to be queued but was %s", i, call.Status)
+		}
+
+		// nothing ran while the call was queued
+		select {
+		case id := <-submitted:
+			t.Fatalf("Test %d: Expected the call to not run yet, but %s was submitted", i, id)
+		default:
+		}
+
+		call, err := aq.Reserve(context.Background(), time.Minute)
+		if err != nil || call == nil {
+			t.Fatalf("Test %d: Expected to reserve the call, got %+v: %v", i, call, err)
+		}
+		srv.runAsyncCall(context.Background(), call)
+
+		if id := <-submitted; id != callID {
+			t.Fatalf("Test %d: Expected the call to run with ID %s but was %s", i, callID, id)
+		}
+
+		call = getAsyncCall(t, srv, callID)
+		if call.Status != test.expectedStatus || call.Attempts != test.expectedAttempts {
+			t.Fatalf("Test %d: Expected call status %s after %d attempts but was %s after %d",
+				i, test.expectedStatus, test.expectedAttempts, call.Status, call.Attempts)
+		}
+		if test.submitErr != nil && call.Status != models.AsyncCallStatusQueued && call.Error != test.submitErr.Error() {
+			t.Fatalf("Test %d: Expected call error %q but was %q", i, test.submitErr.Error(), call.Error)
+		}
+	}
+}
+
+func TestAsyncDeadLetters(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	aq := datastore.NewMockAsyncQueue()
+	srv := testServer(stateMachineDatastore(), mockAgentSubmitting(models.ErrCallTimeoutServerBusy), ServerTypeFull,
+		WithAsyncQueue(aq), WithAsyncWorkers(1, time.Minute, 1))
+
+	deadID, _ := asyncRequest(t, srv, "first")
+	call, err := aq.Reserve(context.Background(), time.Minute)
+	if err != nil || call == nil {
+		t.Fatalf("Expected to reserve the call, got %+v: %v", call, err)
+	}
+	srv.runAsyncCall(context.Background(), call)
+
+	asyncRequest(t, srv, "second")
+
+	_, rec := routerRequest(t, srv.Router, "GET", "/v2/async?status=dead", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var list models.AsyncCallList
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("unexpected error decoding async calls: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].ID != deadID {
+		t.Fatalf("Expected only call %s in the dead-letter list, got %+v", deadID, list.Items)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", "/v2/async?status=nope", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code to be %d but was %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestAsyncRetryDelay(t *testing.T) {
+	for attempts, expected := range map[int]time.Duration{
+		1:  asyncMinRetryDelay,
+		2:  2 * asyncMinRetryDelay,
+		3:  4 * asyncMinRetryDelay,
+		20: asyncMaxRetryDelay,
+	} {
+		if d := asyncRetryDelay(attempts); d != expected {
+			t.Fatalf("Expected a delay of %s after %d attempts but was %s", expected, attempts, d)
+		}
+	}
+}
+
+func TestAsyncExpiredReservations(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	aq := datastore.NewMockAsyncQueue()
+	rnr := mockAgentSubmitting(nil)
+	srv := testServer(stateMachineDatastore(), rnr, ServerTypeFull,
+		WithAsyncQueue(aq), WithAsyncWorkers(1, time.Minute, 2))
+
+	callID, _ := asyncRequest(t, srv, "crashes its worker")
+
+	// each worker crashes with the call reserved, so that its reservation
+	// expires without the call being finished or released
+	var call *models.AsyncCall
+	for i := 1; i <= 3; i++ {
+		var err error
+		call, err = aq.Reserve(context.Background(), 0)
+		if err != nil || call == nil {
+			t.Fatalf("Expected to reserve the call for attempt %d, got %+v: %v", i, call, err)
+		}
+	}
+	srv.runAsyncCall(context.Background(), call)
+
+	rnr.AssertNotCalled(t, "Submit", mock.Anything)
+	call = getAsyncCall(t, srv, callID)
+	if call.Status != models.AsyncCallStatusDead || call.Attempts != 3 {
+		t.Fatalf("Expected the call to be dead after 3 attempts, got %+v", call)
+	}
+	if next, err := aq.Reserve(context.Background(), 0); err != nil || next != nil {
+		t.Fatalf("Expected no call to reserve, got %+v: %v", next, err)
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/datastore"
 	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestBadRequests(t *testing.T) {
@@ -441,3 +443,77 @@ func TestInvokeRunnerMinimalConcurrentHotSync(t *testing.T) {
 		}
 	}
 }
+
+func TestFnInvokeStream(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp", Config: models.Config{}}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	streamedFn := &models.Fn{ID: "streamed_fn_id", Name: "mystreamedfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	streamedFn.Annotations, _ = models.EmptyAnnotations().With(models.FnStreamAnnotation, true)
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn, streamedFn})
+
+	for i, test := range []struct {
+		path           string
+		streamHeader   string
+		writes         []string
+		submitErr      error
+		expectedCode   int
+		expectedBody   string
+		expectedStatus string
+	}{
+		{"/invoke/fn_id", "true", []string{"hello ", "world"}, nil, http.StatusOK, "hello world", "success"},
+		{"/invoke/streamed_fn_id", "", []string{"hello"}, nil, http.StatusOK, "hello", "success"},
+		{"/invoke/streamed_fn_id", "", nil, nil, http.StatusOK, "", "success"},
+		{"/invoke/streamed_fn_id", "", []string{"partial"}, models.ErrFunctionFailed, http.StatusOK, "partial", "error"},
+		{"/invoke/streamed_fn_id", "", nil, models.ErrFunctionFailed, models.ErrFunctionFailed.Code(), "", ""},
+		{"/invoke/fn_id", "maybe", nil, nil, models.ErrCallInvalidStream.Code(), "", ""},
+	} {
+		rnr := new(agent.MockAgent)
+		rnr.On("GetCall", mock.Anything).Return()
+		rnr.On("Submit", mock.Anything).Run(func(args mock.Arguments) {
+			call := args.Get(0).(interface{ ResponseWriter() http.ResponseWriter })
+			rw := call.ResponseWriter()
+			rw.Header().Set("Content-Type", "text/event-stream")
+			rw.WriteHeader(http.StatusOK)
+			for _, w := range test.writes {
+				rw.Write([]byte(w))
+			}
+		}).Return(test.submitErr)
+		srv := testServer(ds, rnr, ServerTypeFull)
+
+		req := createRequest(t, "POST", test.path, strings.NewReader("body"))
+		if test.streamHeader != "" {
+			req.Header.Set(models.StreamHeader, test.streamHeader)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d. body: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedStatus == "" {
+			continue
+		}
+		if rec.Body.String() != test.expectedBody {
+			t.Fatalf("Test %d: Expected body %q but was %q", i, test.expectedBody, rec.Body.String())
+		}
+		if !rec.Flushed {
+			t.Fatalf("Test %d: Expected the response to be flushed as it was written", i)
+		}
+		resp := rec.Result()
+		if resp.Header.Get("Content-Type") != "text/event-stream" || resp.Header.Get("Content-Length") != "" {
+			t.Fatalf("Test %d: Expected the fn's headers and no length, got %v", i, resp.Header)
+		}
+		if status := resp.Trailer.Get(models.CallStatusTrailer); status != test.expectedStatus {
+			t.Fatalf("Test %d: Expected the %s trailer to be %q but was %q", i, models.CallStatusTrailer, test.expectedStatus, status)
+		}
+		if test.submitErr != nil && resp.Trailer.Get(models.CallErrorTrailer) != test.submitErr.Error() {
+			t.Fatalf("Test %d: Expected the %s trailer to be %q but was %q", i, models.CallErrorTrailer, test.submitErr.Error(), resp.Trailer.Get(models.CallErrorTrailer))
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -26,32 +27,6 @@ func (s *Server) handleHTTPTriggerCall(c *gin.Context) {
 	}
 }
 
-func (s *Server) handleHTTPSchedulerCall(c *gin.Context) {
-	var stateMachine models.StateMachine
-	var inputString string
-	body, err := ioutil.ReadAll(c.Request.Body)
-	if err != nil {
-		handleErrorResponse(c, err)
-		return
-	}
-	err = json.Unmarshal(body, &stateMachine)
-	if err != nil {
-		handleErrorResponse(c, err)
-		return
-	}
-	if input, ok := c.Request.Header["Input-String"]; ok {
-		inputString = input[0]
-	} else {
-		inputString = ""
-	}
-	result, err := s.handleStateMachine(c, &stateMachine, &inputString)
-	if err != nil {
-		handleErrorResponse(c, err)
-		return
-	}
-	c.Writer.WriteString(*result)
-}
-
 func (s *Server) benchmark(c *gin.Context) {
 	var benchmarkRequest models.BenchmarkRequest
 	var inputString string
@@ -95,13 +70,23 @@ func (s *Server) benchmark(c *gin.Context) {
 			var err error
 			completedRequest := 0
 			errorCount := int64(0)
+			startTypes := make(map[string]models.StartTypeLatency)
 		SendLoop:
 			for {
-				_, checkpoints, err = s.syncFunctionInvokeBenchmark(c, getHTTPRequest(inputString), benchmarkRequest.AppName, benchmarkRequest.FuncName)
+				var startType string
+				requestStart := time.Now().UnixNano()
+				_, checkpoints, startType, err = s.syncFunctionInvokeBenchmark(c, getHTTPRequest(inputString), benchmarkRequest.AppName, benchmarkRequest.FuncName)
 				if err != nil {
 					errorCount++
 				} else {
 					completedRequest++
+					if startType == "" {
+						startType = "unknown"
+					}
+					st := startTypes[startType]
+					st.Count++
+					st.TotalLatency += time.Now().UnixNano() - requestStart
+					startTypes[startType] = st
 				}
 				select {
 				case <-timer:
@@ -114,7 +99,7 @@ func (s *Server) benchmark(c *gin.Context) {
 			/*for i := range checkpoints {
 				elapsedTime = append(elapsedTime, checkpoints[i]-beforeInvoke)
 			}*/
-			*finish <- models.Checkpoint{Start: beforeInvoke, End: end, Checkpoints: checkpoints, CompletedRequest: int64(completedRequest), ErrorCount: errorCount}
+			*finish <- models.Checkpoint{Start: beforeInvoke, End: end, Checkpoints: checkpoints, CompletedRequest: int64(completedRequest), ErrorCount: errorCount, StartTypes: startTypes}
 		}(&channel, start)
 	}
 
@@ -138,7 +123,7 @@ end:
 	close(errorChannel)
 
 	if success {
-		benchmarkResult := models.BenchmarkResult{Checkpoints: results}
+		benchmarkResult := models.BenchmarkResult{Checkpoints: results, LatencyByStartType: make(map[string]models.StartTypeLatency)}
 		minStart, maxEnd := results[0].Start, results[0].End
 		sumLatency := int64(0)
 		totalCompletedRequest := int64(0)
@@ -153,6 +138,16 @@ end:
 			}
 			totalCompletedRequest += results[i].CompletedRequest
 			totalError += results[i].ErrorCount
+			for startType, l := range results[i].StartTypes {
+				total := benchmarkResult.LatencyByStartType[startType]
+				total.Count += l.Count
+				total.TotalLatency += l.TotalLatency
+				benchmarkResult.LatencyByStartType[startType] = total
+			}
+		}
+		for startType, l := range benchmarkResult.LatencyByStartType {
+			l.AverageLatency = float64(l.TotalLatency) / float64(l.Count)
+			benchmarkResult.LatencyByStartType[startType] = l
 		}
 		benchmarkResult.ElapsedTime = maxEnd - minStart
 		// maybe here
@@ -198,109 +193,6 @@ func mockInput() string {
 	return "Hello"
 }
 
-func (s *Server) handleStateMachine(c *gin.Context, stateMachine *models.StateMachine, input *string) (*string, error) {
-	current := stateMachine.StartAt
-	for {
-		if state, ok := stateMachine.States[current]; ok {
-			var result *string
-			var err error
-			var intermediateMap map[string]interface{}
-			switch state.Type {
-			case models.StateTypeTask:
-				result, err = s.handleTask(c, state, input)
-				if err != nil {
-					return result, err
-				}
-			case models.StateTypeParallel:
-				{
-					intermediateMap = make(map[string]interface{})
-					err = json.Unmarshal([]byte(*input), &intermediateMap)
-					if err != nil {
-						return nil, err
-					}
-					parallelExecution := state.ParallelExecution
-					if parallelExecution == nil {
-						return nil, fmt.Errorf("a parallel task requires ParallelExecution field")
-					}
-
-					iterableItems, ok := intermediateMap[parallelExecution.IterableItemsKey]
-					if !ok {
-						return nil, fmt.Errorf("required key %s not found", parallelExecution.IterableItemsKey)
-					}
-
-					iterableItemsArray, ok := iterableItems.([]interface{})
-					if !ok {
-						return nil, fmt.Errorf("required []interface{} type, get %T", iterableItems)
-					}
-					results, err := s.handleParallel(c, &parallelExecution.StateMachine, iterableItemsArray, parallelExecution.IterableItemName)
-					if err != nil {
-						return nil, err
-					}
-					jsonBytes, err := json.Marshal(results)
-					if err != nil {
-						return nil, err
-					}
-					jsonString := string(jsonBytes)
-					result = &jsonString
-				}
-			default:
-				return nil, fmt.Errorf("Unknown state type: %s", state.Type)
-			}
-			if state.End {
-				return result, nil
-			}
-			input = result
-			current = state.Next
-		} else {
-			return nil, fmt.Errorf("Unknown state name: %s", current)
-		}
-	}
-}
-
-func (s *Server) handleTask(c *gin.Context, state *models.State, input *string) (*string, error) {
-	req := getHTTPRequest(*input)
-	result, err := s.syncFunctionInvoke(c, req, state.AppName, state.FuncName)
-	return result, err
-}
-
-func (s *Server) handleParallel(c *gin.Context, stateMachine *models.StateMachine, iterableItems []interface{}, iterableItemName string) ([]*string, error) {
-	parallelCount := len(iterableItems)
-	resultChannels := make([]chan *string, parallelCount)
-	errChannels := make([]chan error, parallelCount)
-
-	for i := range iterableItems {
-		resultChannel := make(chan *string)
-		errChannel := make(chan error)
-		resultChannels[i] = resultChannel
-		errChannels[i] = errChannel
-		input := make(map[string]interface{})
-		input[iterableItemName] = iterableItems[i]
-		payload, err := json.Marshal(input)
-		if err != nil {
-			return nil, err
-		}
-		inputString := string(payload)
-		go func(resultChannel chan *string, errChannel chan error) {
-			result, err := s.handleStateMachine(c, stateMachine, &inputString)
-			resultChannel <- result
-			errChannel <- err
-		}(resultChannel, errChannel)
-	}
-
-	results := make([]*string, parallelCount)
-	errors := make([]error, parallelCount)
-	var err error
-	for i := 0; i < parallelCount; i++ {
-		results[i] = <-resultChannels[i]
-		errors[i] = <-errChannels[i]
-		if errors[i] != nil {
-			err = errors[i]
-		}
-	}
-
-	return results, err
-}
-
 func getHTTPRequest(payload string) *http.Request {
 	req := &http.Request{}
 	req.Method = "POST"
@@ -326,8 +218,7 @@ func getHTTPRequest(payload string) *http.Request {
 	return req
 }
 
-func (s *Server) syncFunctionInvoke(c *gin.Context, req *http.Request, appName string, funcName string) (*string, error) {
-	ctx := c.Request.Context()
+func (s *Server) syncFunctionInvoke(ctx context.Context, req *http.Request, appName string, funcName string) (*string, error) {
 	appID, err := s.lbReadAccess.GetAppID(ctx, appName)
 	if err != nil {
 		return nil, err
@@ -348,6 +239,7 @@ func (s *Server) syncFunctionInvoke(c *gin.Context, req *http.Request, appName s
 		return nil, err
 	}
 
+	req = req.WithContext(ctx)
 	requestURL := reqURL(req)
 	headers := make(http.Header, 3)
 	headers.Set("Fn-Http-Method", req.Method)
@@ -362,7 +254,9 @@ func (s *Server) syncFunctionInvoke(c *gin.Context, req *http.Request, appName s
 	return result, nil
 }
 
-func (s *Server) syncFunctionInvokeBenchmark(c *gin.Context, req *http.Request, appName string, funcName string) (*string, []int64, error) {
+// syncFunctionInvokeBenchmark invokes a function for a benchmark, returning
+// its result, when each step of looking it up finished and its start type
+func (s *Server) syncFunctionInvokeBenchmark(c *gin.Context, req *http.Request, appName string, funcName string) (*string, []int64, string, error) {
 	var checkpoints []int64
 	ctx := c.Request.Context()
 
@@ -370,28 +264,28 @@ func (s *Server) syncFunctionInvokeBenchmark(c *gin.Context, req *http.Request,
 
 	appID, err := s.lbReadAccess.GetAppID(ctx, appName)
 	if err != nil {
-		return nil, checkpoints, err
+		return nil, checkpoints, "", err
 	}
 
 	checkpoints = append(checkpoints, time.Now().UnixNano())
 
 	app, err := s.lbReadAccess.GetAppByID(ctx, appID)
 	if err != nil {
-		return nil, checkpoints, err
+		return nil, checkpoints, "", err
 	}
 
 	checkpoints = append(checkpoints, time.Now().UnixNano())
 
 	trigger, err := s.lbReadAccess.GetTriggerBySource(ctx, appID, "http", funcName)
 	if err != nil {
-		return nil, checkpoints, err
+		return nil, checkpoints, "", err
 	}
 
 	checkpoints = append(checkpoints, time.Now().UnixNano())
 
 	fn, err := s.lbReadAccess.GetFnByID(ctx, trigger.FnID)
 	if err != nil {
-		return nil, checkpoints, err
+		return nil, checkpoints, "", err
 	}
 
 	checkpoints = append(checkpoints, time.Now().UnixNano())
@@ -405,9 +299,9 @@ func (s *Server) syncFunctionInvokeBenchmark(c *gin.Context, req *http.Request,
 
 	result, err := s.fnInvokeFunctionWithResult(headers, req, app, fn, trigger)
 	if err != nil {
-		return nil, checkpoints, err
+		return nil, checkpoints, headers.Get(models.StartTypeHeader), err
 	}
-	return result, checkpoints, nil
+	return result, checkpoints, headers.Get(models.StartTypeHeader), nil
 }
 
 // handleTriggerHTTPFunctionCall2 executes the function and returns an error
@@ -434,6 +328,13 @@ func (s *Server) handleTriggerHTTPFunctionCall2(c *gin.Context) error {
 	routePath := p
 
 	trigger, err := s.lbReadAccess.GetTriggerBySource(ctx, appID, "http", routePath)
+	if err == models.ErrTriggerNotFound {
+		// the source may instead start a stored state machine
+		smTrigger, smErr := s.lbReadAccess.GetTriggerBySource(ctx, appID, models.TriggerTypeStateMachine, routePath)
+		if smErr == nil {
+			return s.serveStateMachineTrigger(c, smTrigger)
+		}
+	}
 
 	if err != nil {
 		return err
@@ -500,7 +401,7 @@ func (trw *triggerResponseWriter) WriteHeader(serviceStatus int) {
 					userStatus = statusInt
 				}
 			}
-		case k == "Content-Type", k == "Fn-Call-Id":
+		case k == "Content-Type", k == "Fn-Call-Id", k == "Trailer":
 			gwHeaders[k] = vs
 		}
 	}
@@ -524,6 +425,13 @@ func (trw *triggerResponseWriter) WriteHeader(serviceStatus int) {
 	trw.inner.WriteHeader(finalStatus)
 }
 
+// Flush implements http.Flusher, so that streamed responses go out as they are written
+func (trw *triggerResponseWriter) Flush() {
+	if f, ok := trw.inner.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func reqURL(req *http.Request) string {
 	if req.URL.Scheme == "" {
 		if req.TLS == nil {
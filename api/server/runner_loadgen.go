@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/loadgen"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func invalidWorkload(err error) error {
+	return models.NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid workload: %v", err))
+}
+
+// handleLoadGen runs the workload in the request body against a function,
+// invoking it through the agent as /invoke would, and responds with a report
+// once the workload is done.
+func (s *Server) handleLoadGen(c *gin.Context) {
+	ctx := c.Request.Context()
+	spec := &loadgen.Spec{}
+
+	err := c.BindJSON(spec)
+	if err != nil {
+		if !models.IsAPIError(err) {
+			err = models.ErrInvalidJSON
+		}
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := spec.Validate(); err != nil {
+		handleErrorResponse(c, invalidWorkload(err))
+		return
+	}
+	if spec.Type == loadgen.TypeTrace && spec.Trace.File != "" {
+		if err := s.loadTrace(spec.Trace); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	fn, err := s.lbReadAccess.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	app, err := s.lbReadAccess.GetAppByID(ctx, fn.AppID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	report, err := loadgen.Run(ctx, spec, s.loadGenInvoker(app, fn, spec.Body), nil)
+	if err != nil {
+		handleErrorResponse(c, invalidWorkload(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleReplay replays the Azure Functions trace named in the request body
+// against the functions it is mapped to, and responds with a report once the
+// replay is done.
+func (s *Server) handleReplay(c *gin.Context) {
+	ctx := c.Request.Context()
+	spec := &loadgen.ReplaySpec{}
+
+	err := c.BindJSON(spec)
+	if err != nil {
+		if !models.IsAPIError(err) {
+			err = models.ErrInvalidJSON
+		}
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := spec.Validate(); err != nil {
+		handleErrorResponse(c, invalidWorkload(err))
+		return
+	}
+
+	f, err := s.openTrace(spec.File)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	trace, err := loadgen.ReadAzureTrace(f)
+	f.Close()
+	if err != nil {
+		handleErrorResponse(c, invalidWorkload(fmt.Errorf("trace file %q: %v", spec.File, err)))
+		return
+	}
+
+	// look every function up before starting, rather than failing its requests
+	invokers := make(map[string]loadgen.Invoker, len(spec.Functions))
+	for _, fnID := range spec.Functions {
+		if invokers[fnID] != nil {
+			continue
+		}
+		fn, err := s.lbReadAccess.GetFnByID(ctx, fnID)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		app, err := s.lbReadAccess.GetAppByID(ctx, fn.AppID)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		invokers[fnID] = s.loadGenInvoker(app, fn, spec.Body)
+	}
+
+	report, err := loadgen.Replay(ctx, spec, trace, func(fnID string) loadgen.Invoker { return invokers[fnID] }, nil)
+	if err != nil {
+		handleErrorResponse(c, invalidWorkload(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// openTrace opens the named file in the trace directory, which the name may
+// not leave
+func (s *Server) openTrace(name string) (*os.File, error) {
+	if s.loadGenTraceDir == "" {
+		return nil, invalidWorkload(errors.New("trace files are not enabled on this server"))
+	}
+
+	f, err := os.Open(filepath.Join(s.loadGenTraceDir, filepath.Clean("/"+name)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, invalidWorkload(fmt.Errorf("trace file %q does not exist", name))
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// loadTrace reads the counts of a trace from its file in the trace directory
+func (s *Server) loadTrace(trace *loadgen.Trace) error {
+	f, err := s.openTrace(trace.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	counts, err := loadgen.ReadTrace(f)
+	if err != nil {
+		return invalidWorkload(fmt.Errorf("trace file %q: %v", trace.File, err))
+	}
+	trace.Counts = counts
+	return nil
+}
+
+// loadGenInvoker returns a loadgen.Invoker that calls fn with body, and tells
+// the start type of the call when the agent knows it. Responses
+// from the function are discarded, those with an error status fail the request.
+func (s *Server) loadGenInvoker(app *models.App, fn *models.Fn, body string) loadgen.Invoker {
+	return func(ctx context.Context) (string, error) {
+		req, err := http.NewRequest(http.MethodPost, "/invoke/"+fn.ID, strings.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		req = req.WithContext(ctx)
+
+		writer := &syncResponseWriter{
+			headers: make(http.Header),
+			status:  200,
+			Buffer:  new(bytes.Buffer),
+		}
+
+		call, err := s.agent.GetCall(getCallOptions(req, app, fn, nil, writer)...)
+		if err != nil {
+			return "", err
+		}
+		err = s.agent.Submit(call)
+
+		start := call.Model().StartType
+		if err != nil {
+			return start, err
+		}
+		if writer.Status() >= http.StatusBadRequest {
+			return start, models.NewAPIError(writer.Status(), fmt.Errorf("function responded with status %d", writer.Status()))
+		}
+		return start, nil
+	}
+}
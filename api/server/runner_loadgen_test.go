@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/loadgen"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLoadGen(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	dir, err := ioutil.TempDir("", "loadgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "day1.csv"), []byte("2,0,1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := new(agent.MockAgent)
+	failing.On("GetCall", mock.Anything).Return()
+	failing.On("Submit", mock.Anything).Return(nil).Once()
+	failing.On("Submit", mock.Anything).Return(models.ErrCallTimeoutServerBusy)
+
+	for i, test := range []struct {
+		agent        *agent.MockAgent
+		path         string
+		body         string
+		expectedCode int
+		expectedErr  string
+		succeeded    int
+		errors       map[string]int
+	}{
+		{mockAgentSubmitting(nil), "/loadgen/fn_id", `{"type":"constant","rate":100,"duration":"50ms"}`, http.StatusOK, "", 5, nil},
+		{mockAgentSubmitting(nil), "/loadgen/fn_id", `{"type":"trace","time_scale":6000,"trace":{"file":"../day1.csv"}}`, http.StatusOK, "", 3, nil},
+		{failing, "/loadgen/fn_id", `{"type":"trace","trace":{"counts":[3],"burst":true}}`, http.StatusOK, "", 1, map[string]int{"503": 2}},
+		{mockAgentSubmitting(nil), "/loadgen/fn_id", `{"type":"ramp"}`, http.StatusBadRequest, "Invalid workload: unknown workload type", 0, nil},
+		{mockAgentSubmitting(nil), "/loadgen/fn_id", `{"type":"trace","trace":{"file":"day2.csv"}}`, http.StatusBadRequest, `trace file "day2.csv" does not exist`, 0, nil},
+		{mockAgentSubmitting(nil), "/loadgen/fn_id", `{"type":`, http.StatusBadRequest, models.ErrInvalidJSON.Error(), 0, nil},
+		{mockAgentSubmitting(nil), "/loadgen/nofn", `{"type":"constant","rate":1,"duration":1}`, http.StatusNotFound, models.ErrFnsNotFound.Error(), 0, nil},
+	} {
+		srv := testServer(storedStateMachineDatastore(), test.agent, ServerTypeFull, WithLoadGenTraceDir(dir))
+		_, rec := routerRequest(t, srv.Router, "POST", test.path, bytes.NewBufferString(test.body))
+
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: expected status %d, got %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedErr != "" {
+			resp := getErrorResponse(t, rec)
+			if !strings.Contains(resp.Message, test.expectedErr) {
+				t.Fatalf("Test %d: expected error containing %q, got %q", i, test.expectedErr, resp.Message)
+			}
+			continue
+		}
+
+		var report loadgen.Report
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("Test %d: could not decode report: %v", i, err)
+		}
+		if report.Succeeded != test.succeeded {
+			t.Fatalf("Test %d: expected %d successful requests, got %d", i, test.succeeded, report.Succeeded)
+		}
+		for k, v := range test.errors {
+			if report.Errors[k] != v {
+				t.Fatalf("Test %d: expected %d %s errors, got %v", i, v, k, report.Errors)
+			}
+		}
+	}
+}
+
+func TestLoadGenReplay(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	dir, err := ioutil.TempDir("", "loadgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	trace := "HashOwner,HashApp,HashFunction,Trigger,1,2\no1,a1,f1,http,2,1\no1,a1,f2,http,0,3\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "d01.csv"), []byte(trace), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		body         string
+		expectedCode int
+		expectedErr  string
+		succeeded    int
+	}{
+		{`{"file":"d01.csv","functions":{"f1":"fn_id","f2":"fn_id"},"time_scale":6000,"record":true}`, http.StatusOK, "", 6},
+		{`{"file":"d01.csv","functions":{"f2":"fn_id"},"minutes":1,"time_scale":6000}`, http.StatusOK, "", 0},
+		{`{"file":"d01.csv"}`, http.StatusBadRequest, "Invalid workload: replay requires functions", 0},
+		{`{"file":"d01.csv","functions":{"f3":"fn_id"}}`, http.StatusBadRequest, `function "f3" is not in the trace`, 0},
+		{`{"file":"d02.csv","functions":{"f1":"fn_id"}}`, http.StatusBadRequest, `trace file "d02.csv" does not exist`, 0},
+		{`{"file":"d01.csv","functions":{"f1":"nofn"}}`, http.StatusNotFound, models.ErrFnsNotFound.Error(), 0},
+	} {
+		srv := testServer(storedStateMachineDatastore(), mockAgentSubmitting(nil), ServerTypeFull, WithLoadGenTraceDir(dir))
+		_, rec := routerRequest(t, srv.Router, "POST", "/replay", bytes.NewBufferString(test.body))
+
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: expected status %d, got %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedErr != "" {
+			resp := getErrorResponse(t, rec)
+			if !strings.Contains(resp.Message, test.expectedErr) {
+				t.Fatalf("Test %d: expected error containing %q, got %q", i, test.expectedErr, resp.Message)
+			}
+			continue
+		}
+
+		var report loadgen.ReplayReport
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("Test %d: could not decode report: %v", i, err)
+		}
+		if report.Succeeded != test.succeeded {
+			t.Fatalf("Test %d: expected %d successful requests, got %d", i, test.succeeded, report.Succeeded)
+		}
+		if i == 0 && (len(report.Requests) != 6 || report.Functions["f2"].Succeeded != 3) {
+			t.Fatalf("Test %d: unexpected report %+v", i, report)
+		}
+	}
+}
+
+func TestLoadGenStartTypes(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	// the first call starts a container, the rest find it running
+	var calls int32
+	rnr := new(agent.MockAgent)
+	rnr.On("GetCall", mock.Anything).Return()
+	rnr.On("Submit", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		startType := models.StartTypeWarm
+		if atomic.AddInt32(&calls, 1) == 1 {
+			startType = models.StartTypeCold
+		}
+		args.Get(0).(agent.Call).Model().StartType = startType
+	})
+
+	srv := testServer(storedStateMachineDatastore(), rnr, ServerTypeFull)
+	body := `{"type":"trace","trace":{"counts":[4],"interval":"10ms"},"max_in_flight":1}`
+	_, rec := routerRequest(t, srv.Router, "POST", "/loadgen/fn_id", bytes.NewBufferString(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var report loadgen.Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("could not decode report: %v", err)
+	}
+	if report.Starts[models.StartTypeCold] != 1 || report.Starts[models.StartTypeWarm] != 3 {
+		t.Fatalf("unexpected start types %v", report.Starts)
+	}
+	if _, ok := report.StartLatency[models.StartTypeCold]; !ok {
+		t.Fatalf("expected latency of cold starts, got %v", report.StartLatency)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handlePreWarm starts hot containers for a function ahead of its calls, as
+// many as the request asks for and the agent has resources for, and responds
+// with how many were started.
+func (s *Server) handlePreWarm(c *gin.Context) {
+	ctx := c.Request.Context()
+	pw := &models.PreWarm{}
+
+	err := c.BindJSON(pw)
+	if err != nil {
+		if !models.IsAPIError(err) {
+			err = models.ErrInvalidJSON
+		}
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := pw.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	fn, err := s.lbReadAccess.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	app, err := s.lbReadAccess.GetAppByID(ctx, fn.AppID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	// the containers are started as they would be for an invocation of fn
+	req, err := http.NewRequest(http.MethodPost, "/invoke/"+fn.ID, http.NoBody)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	req = req.WithContext(ctx)
+
+	writer := &syncResponseWriter{
+		headers: make(http.Header),
+		status:  200,
+		Buffer:  new(bytes.Buffer),
+	}
+
+	call, err := s.agent.GetCall(getCallOptions(req, app, fn, nil, writer)...)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	pw.Started, err = s.agent.PreWarm(ctx, call, pw.Containers, time.Duration(pw.KeepWarm)*time.Second)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pw)
+}
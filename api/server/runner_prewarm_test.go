@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPreWarm(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	for i, test := range []struct {
+		path         string
+		body         string
+		expectedCode int
+		expectedErr  string
+		started      int
+	}{
+		{"/prewarm/fn_id", `{"containers":3,"keep_warm":600}`, http.StatusOK, "", 2},
+		{"/prewarm/fn_id", `{"containers":1}`, http.StatusOK, "", 1},
+		{"/prewarm/fn_id", `{"containers":0}`, http.StatusBadRequest, models.ErrPreWarmInvalidContainers.Error(), 0},
+		{"/prewarm/fn_id", `{"containers":1,"keep_warm":-1}`, http.StatusBadRequest, models.ErrPreWarmInvalidKeepWarm.Error(), 0},
+		{"/prewarm/fn_id", `{"containers":`, http.StatusBadRequest, models.ErrInvalidJSON.Error(), 0},
+		{"/prewarm/nofn", `{"containers":1}`, http.StatusNotFound, models.ErrFnsNotFound.Error(), 0},
+	} {
+		// the agent has resources for two containers
+		rnr := new(agent.MockAgent)
+		rnr.On("GetCall", mock.Anything).Return()
+		rnr.On("PreWarm", mock.Anything, 3, 10*time.Minute).Return(2, nil)
+		rnr.On("PreWarm", mock.Anything, 1, time.Duration(0)).Return(1, nil)
+
+		srv := testServer(storedStateMachineDatastore(), rnr, ServerTypeFull)
+		_, rec := routerRequest(t, srv.Router, "POST", test.path, bytes.NewBufferString(test.body))
+
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: expected status %d, got %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedErr != "" {
+			resp := getErrorResponse(t, rec)
+			if !strings.Contains(resp.Message, test.expectedErr) {
+				t.Fatalf("Test %d: expected error containing %q, got %q", i, test.expectedErr, resp.Message)
+			}
+			continue
+		}
+
+		var pw models.PreWarm
+		if err := json.NewDecoder(rec.Body).Decode(&pw); err != nil {
+			t.Fatalf("Test %d: could not decode response: %v", i, err)
+		}
+		if pw.Started != test.started {
+			t.Fatalf("Test %d: expected %d containers started, got %d", i, test.started, pw.Started)
+		}
+	}
+}
@@ -0,0 +1,771 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// executionResult is what an execution run hands back to whoever is waiting on it
+type executionResult struct {
+	exec *models.Execution
+	err  error
+}
+
+// handleHTTPSchedulerCall runs the state machine in the request body and
+// writes its final output. The execution itself is not tied to the request,
+// if the client goes away it carries on (and is persisted, if the server has
+// an execution store).
+func (s *Server) handleHTTPSchedulerCall(c *gin.Context) {
+	var stateMachine models.StateMachine
+	var inputString string
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	err = json.Unmarshal(body, &stateMachine)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if input, ok := c.Request.Header["Input-String"]; ok {
+		inputString = input[0]
+	} else {
+		inputString = ""
+	}
+
+	ctx := c.Request.Context()
+	_, done, err := s.startExecution(ctx, &stateMachine, inputString)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	exec, err := waitExecution(ctx, done)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	c.Writer.WriteString(exec.Output)
+}
+
+// serveStateMachineTrigger runs the latest version of the state machine a
+// statemachine trigger refers to, with the request body as its input, and
+// writes its final output.
+func (s *Server) serveStateMachineTrigger(c *gin.Context, trigger *models.Trigger) error {
+	ctx := c.Request.Context()
+
+	sm, err := s.lbReadAccess.GetStateMachine(ctx, trigger.AppID, trigger.StateMachine, 0)
+	if err != nil {
+		return err
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	exec, done, err := s.startExecution(ctx, sm.Definition, string(body))
+	if err != nil {
+		return err
+	}
+	if exec.ID != "" {
+		c.Header("Fn-Execution-Id", exec.ID)
+	}
+
+	exec, err = waitExecution(ctx, done)
+	if err != nil {
+		return err
+	}
+	c.Status(http.StatusOK)
+	c.Writer.WriteString(exec.Output)
+	return nil
+}
+
+// waitExecution waits for a started execution to finish, or for ctx to be done
+func waitExecution(ctx context.Context, done <-chan executionResult) (*models.Execution, error) {
+	select {
+	case res := <-done:
+		return res.exec, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startExecution records a new execution of stateMachine and runs it in the
+// background, owned by this node. The returned channel receives the finished
+// execution.
+func (s *Server) startExecution(ctx context.Context, stateMachine *models.StateMachine, input string) (*models.Execution, <-chan executionResult, error) {
+	if err := stateMachine.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	exec := &models.Execution{
+		StateMachine: stateMachine,
+		Status:       models.ExecutionStatusRunning,
+		CurrentState: stateMachine.StartAt,
+		Input:        input,
+		StateInput:   input,
+	}
+
+	if s.executionStore != nil {
+		exec.Owner = s.executionOwner
+		exec.LeaseUntil = common.UnixMilli(time.Now().Add(s.executionLease))
+
+		var err error
+		exec, err = s.executionStore.InsertExecution(ctx, exec)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	started := exec.Clone()
+	done := make(chan executionResult, 1)
+	go func() {
+		exec, err := s.runExecution(common.BackgroundContext(ctx), exec)
+		done <- executionResult{exec, err}
+	}()
+	return started, done, nil
+}
+
+// watchExecutions resumes the executions that are left running without an
+// owner, eg. by a node that went away, now and then every executionLease
+// until ctx is done.
+func (s *Server) watchExecutions(ctx context.Context) {
+	ticker := time.NewTicker(s.executionLease)
+	defer ticker.Stop()
+	for {
+		s.resumeExecutions(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resumeExecutions claims every running execution whose owner's lease is
+// over, and continues each one it gets from the state it was about to run.
+// Executions that another node claims first are left to it.
+func (s *Server) resumeExecutions(ctx context.Context) {
+	log := common.Logger(ctx)
+	filter := &models.ExecutionFilter{Status: models.ExecutionStatusRunning, PerPage: 100}
+	for {
+		list, err := s.executionStore.GetExecutions(ctx, filter)
+		if err != nil {
+			log.WithError(err).Error("failed to list running executions")
+			return
+		}
+
+		now := common.UnixMilli(time.Now())
+		for _, exec := range list.Items {
+			if exec.Owner == s.executionOwner && exec.LeaseUntil > now {
+				// still running here
+				continue
+			}
+			claimed, err := s.executionStore.ClaimExecution(ctx, exec.ID, s.executionOwner, s.executionLease)
+			if err != nil {
+				log.WithError(err).WithFields(logrus.Fields{"execution_id": exec.ID}).Error("failed to claim execution")
+				continue
+			}
+			if claimed == nil {
+				continue
+			}
+			log.WithFields(logrus.Fields{"execution_id": claimed.ID, "state": claimed.CurrentState}).Info("resuming execution")
+			go s.runExecution(ctx, claimed)
+		}
+
+		if list.NextCursor == "" {
+			return
+		}
+		filter.Cursor = list.NextCursor
+	}
+}
+
+// keepExecutionLease renews the lease of this node on an execution until ctx
+// is done. If the lease is lost, or can't be renewed before it is over, the
+// run of the execution is cancelled, as another node may take it over.
+func (s *Server) keepExecutionLease(ctx context.Context, cancel context.CancelFunc, execID string) {
+	ticker := time.NewTicker(s.executionLease / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		err := s.executionStore.RenewExecution(ctx, execID, s.executionOwner, s.executionLease)
+		if err == nil {
+			renewed = time.Now()
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log := common.Logger(ctx).WithError(err)
+		if err == models.ErrExecutionsLeaseLost || time.Since(renewed) >= s.executionLease*2/3 {
+			log.Error("execution lease lost, stopping execution")
+			cancel()
+			return
+		}
+		log.Warn("failed to renew execution lease")
+	}
+}
+
+// runExecution runs exec from its current state until it ends or fails,
+// persisting each state as it completes together with the state after it. If
+// ctx is cancelled part way, or this node loses the execution to another, the
+// execution is left running in the store so that it can be resumed later.
+func (s *Server) runExecution(ctx context.Context, exec *models.Execution) (*models.Execution, error) {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"execution_id": exec.ID})
+
+	if s.executionStore != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go s.keepExecutionLease(ctx, cancel, exec.ID)
+	}
+
+	current := exec.CurrentState
+	input := exec.StateInput
+	for {
+		state, ok := exec.StateMachine.States[current]
+		if !ok {
+			return s.finishExecution(ctx, exec, nil, "", fmt.Errorf("Unknown state name: %s", current))
+		}
+
+		startedAt := time.Now()
+		output, next, err := s.runState(ctx, state, input)
+		if err != nil && ctx.Err() != nil {
+			log.WithError(err).Info("execution interrupted")
+			return exec, err
+		}
+		record := executionStateRecord(exec, current, state, input, output, err, startedAt)
+		if err != nil {
+			return s.finishExecution(ctx, exec, record, "", err)
+		}
+		if next == "" {
+			return s.finishExecution(ctx, exec, record, output, nil)
+		}
+
+		current = next
+		input = output
+		exec.CurrentState = current
+		exec.StateInput = input
+		if err := s.advanceExecution(ctx, exec, record); err == models.ErrExecutionsLeaseLost {
+			log.WithError(err).Info("execution taken over by another node")
+			return exec, err
+		} else if err != nil {
+			log.WithError(err).Error("failed to persist execution progress")
+		}
+	}
+}
+
+// finishExecution ends exec with the output or error of its last state,
+// which record is the record of
+func (s *Server) finishExecution(ctx context.Context, exec *models.Execution, record *models.ExecutionState, output string, err error) (*models.Execution, error) {
+	exec.CurrentState = ""
+	exec.StateInput = ""
+	if err != nil {
+		exec.Status = models.ExecutionStatusFailed
+		exec.Error = err.Error()
+	} else {
+		exec.Status = models.ExecutionStatusSucceeded
+		exec.Output = output
+	}
+
+	if perr := s.advanceExecution(ctx, exec, record); perr != nil {
+		common.Logger(ctx).WithError(perr).Error("failed to persist execution result")
+	}
+	return exec, err
+}
+
+// advanceExecution persists exec and the record of the state that took it
+// there in one go, so that a state is never both recorded and run again
+func (s *Server) advanceExecution(ctx context.Context, exec *models.Execution, record *models.ExecutionState) error {
+	if s.executionStore == nil {
+		return nil
+	}
+	_, err := s.executionStore.AdvanceExecution(ctx, exec, record)
+	return err
+}
+
+// executionStateRecord is the record of state, named name, having run with
+// input, and produced output or err
+func executionStateRecord(exec *models.Execution, name string, state *models.State, input, output string, err error, startedAt time.Time) *models.ExecutionState {
+	record := &models.ExecutionState{
+		ExecutionID: exec.ID,
+		Name:        name,
+		Type:        state.Type,
+		Status:      models.ExecutionStatusSucceeded,
+		Input:       input,
+		Output:      output,
+		StartedAt:   common.DateTime(startedAt),
+		CompletedAt: common.DateTime(time.Now()),
+	}
+	if err != nil {
+		record.Status = models.ExecutionStatusFailed
+		record.Error = err.Error()
+	}
+	return record
+}
+
+// handleStateMachine runs a state machine in memory, this is used for the
+// branches of parallel and map states, which are not persisted on their own.
+func (s *Server) handleStateMachine(ctx context.Context, stateMachine *models.StateMachine, input string) (string, error) {
+	current := stateMachine.StartAt
+	for {
+		state, ok := stateMachine.States[current]
+		if !ok {
+			return "", fmt.Errorf("Unknown state name: %s", current)
+		}
+		output, next, err := s.runState(ctx, state, input)
+		if err != nil {
+			return output, err
+		}
+		if next == "" {
+			return output, nil
+		}
+		input = output
+		current = next
+	}
+}
+
+// runState runs a single state with the given input, returning its output and
+// the name of the state to run next, which is empty if the state machine ends.
+// Errors are retried and caught as per the Retry and Catch of the state.
+func (s *Server) runState(ctx context.Context, state *models.State, input string) (string, string, error) {
+	var backoffs map[int]common.BackOff
+	for {
+		output, next, err := s.runStateOnce(ctx, state, input)
+		// a Fail state is not retried or caught, whichever it has
+		if err == nil || ctx.Err() != nil || state.Type == models.StateTypeFail {
+			return output, next, err
+		}
+
+		name := models.StateErrorName(err)
+		log := common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"error_name": name})
+		if i, retrier := state.Retrier(name); retrier != nil {
+			if backoffs == nil {
+				backoffs = make(map[int]common.BackOff)
+			}
+			b, ok := backoffs[i]
+			if !ok {
+				b = retrier.BackOff()
+				backoffs[i] = b
+			}
+			if delay, ok := b.NextBackOff(); ok {
+				log.WithFields(logrus.Fields{"delay": delay}).Info("retrying state")
+				if err := sleepContext(ctx, delay); err != nil {
+					return "", "", err
+				}
+				continue
+			}
+		}
+
+		if catcher := state.Catcher(name); catcher != nil {
+			log.WithFields(logrus.Fields{"next": catcher.Next}).Info("state error caught")
+			return catchStateError(catcher, input, err)
+		}
+		return output, next, err
+	}
+}
+
+// catchStateError builds the output of a state whose error was caught, going
+// on to the catcher's Next state
+func catchStateError(catcher *models.Catcher, input string, stateErr error) (string, string, error) {
+	var raw interface{} = input
+	if doc, err := decodeStateInput(input); err == nil {
+		raw = doc
+	}
+	out, err := catcher.ErrorOutput(raw, stateErr)
+	if err != nil {
+		return "", "", err
+	}
+	output, err := json.Marshal(out)
+	if err != nil {
+		return "", "", err
+	}
+	return string(output), catcher.Next, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runStateOnce runs a single attempt of a state, applying its data flow
+func (s *Server) runStateOnce(ctx context.Context, state *models.State, input string) (string, string, error) {
+	if !state.HasDataFlow() {
+		return s.runStateBody(ctx, state, input)
+	}
+
+	raw, err := decodeStateInput(input)
+	if err != nil {
+		return "", "", err
+	}
+	effective, err := state.EffectiveInput(raw)
+	if err != nil {
+		return "", "", err
+	}
+	effectiveInput, err := json.Marshal(effective)
+	if err != nil {
+		return "", "", err
+	}
+
+	result, next, err := s.runStateBody(ctx, state, string(effectiveInput))
+	if err != nil {
+		return "", "", err
+	}
+
+	out, err := state.Output(raw, effective, decodeStateResult(result))
+	if err != nil {
+		return "", "", err
+	}
+	output, err := json.Marshal(out)
+	if err != nil {
+		return "", "", err
+	}
+	return string(output), next, nil
+}
+
+// runStateBody runs a state on its effective input, returning its result and
+// the name of the state to run next.
+func (s *Server) runStateBody(ctx context.Context, state *models.State, input string) (string, string, error) {
+	var output string
+	var err error
+
+	switch state.Type {
+	case models.StateTypeTask:
+		output, err = s.handleTask(ctx, state, input)
+	case models.StateTypeParallel:
+		output, err = s.handleParallelState(ctx, state, input)
+	case models.StateTypeMap:
+		output, err = s.handleMapState(ctx, state, input)
+	case models.StateTypePass:
+		output = input
+		if len(state.Result) > 0 {
+			output = string(state.Result)
+		}
+	case models.StateTypeWait:
+		output, err = handleWaitState(ctx, state, input)
+	case models.StateTypeChoice:
+		next, err := handleChoiceState(state, input)
+		return input, next, err
+	case models.StateTypeSucceed:
+		return input, "", nil
+	case models.StateTypeFail:
+		return "", "", models.NewStateError(state.Error, state.Cause)
+	default:
+		return "", "", fmt.Errorf("Unknown state type: %s", state.Type)
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+	if state.End {
+		return output, "", nil
+	}
+	if state.Next == "" {
+		return "", "", fmt.Errorf("%s state has neither Next nor End", state.Type)
+	}
+	return output, state.Next, nil
+}
+
+// decodeStateInput decodes the input of a state that needs to look inside it,
+// an empty input being an empty object.
+func decodeStateInput(input string) (interface{}, error) {
+	if strings.TrimSpace(input) == "" {
+		return map[string]interface{}{}, nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal([]byte(input), &doc); err != nil {
+		return nil, models.NewStateError(models.StatesErrorRuntime, fmt.Sprintf("state input is not valid JSON: %v", err))
+	}
+	return doc, nil
+}
+
+// decodeStateResult decodes the result of a state, a result that is not JSON
+// is taken to be a string.
+func decodeStateResult(result string) interface{} {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(result), &doc); err != nil {
+		return result
+	}
+	return doc
+}
+
+func handleChoiceState(state *models.State, input string) (string, error) {
+	doc, err := decodeStateInput(input)
+	if err != nil {
+		return "", err
+	}
+	return state.Choose(doc)
+}
+
+func handleWaitState(ctx context.Context, state *models.State, input string) (string, error) {
+	var doc interface{}
+	if state.SecondsPath != "" || state.TimestampPath != "" {
+		var err error
+		doc, err = decodeStateInput(input)
+		if err != nil {
+			return "", err
+		}
+	}
+	until, err := state.WaitUntil(doc, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	if err := sleepContext(ctx, time.Until(until)); err != nil {
+		return "", err
+	}
+	return input, nil
+}
+
+func (s *Server) handleMapState(ctx context.Context, state *models.State, input string) (string, error) {
+	if state.Iterator == nil {
+		return "", fmt.Errorf("a map state requires Iterator field")
+	}
+	doc, err := decodeStateInput(input)
+	if err != nil {
+		return "", err
+	}
+	items, err := state.MapItems(doc)
+	if err != nil {
+		return "", err
+	}
+
+	inputs := make([]string, len(items))
+	for i, item := range items {
+		itemInput, err := state.ItemInput(doc, item, i)
+		if err != nil {
+			return "", err
+		}
+		payload, err := json.Marshal(itemInput)
+		if err != nil {
+			return "", err
+		}
+		inputs[i] = string(payload)
+	}
+
+	results, err := s.runBranches(ctx, state.Iterator, inputs, state.MaxConcurrency)
+	if err != nil {
+		return "", err
+	}
+	return jsonArray(results)
+}
+
+// runBranches runs stateMachine once for each of inputs, with at most
+// maxConcurrency running at a time (no limit if it is 0), and at most as many
+// across the server as it has branch slots. The outputs are returned in the
+// same order as the inputs. The first branch to fail cancels the others and
+// fails the lot, there are no partial results.
+func (s *Server) runBranches(ctx context.Context, stateMachine *models.StateMachine, inputs []string, maxConcurrency int) ([]string, error) {
+	if maxConcurrency <= 0 || maxConcurrency > len(inputs) {
+		maxConcurrency = len(inputs)
+	}
+	sem := make(chan struct{}, maxConcurrency)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	results := make([]string, len(inputs))
+	var wg sync.WaitGroup
+launch:
+	for i := range inputs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break launch
+		}
+
+		run := func(i int) {
+			defer func() { <-sem }()
+			out, err := s.handleStateMachine(withinBranch(ctx), stateMachine, inputs[i])
+			if err != nil {
+				fail(err)
+				return
+			}
+			results[i] = out
+		}
+
+		inline, err := s.acquireBranchSlot(ctx)
+		if err != nil {
+			<-sem
+			break launch
+		}
+		if inline {
+			// no slot to spare, and this is a branch already holding one,
+			// so run in place rather than wait on branches that wait on us
+			run(i)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				s.releaseBranchSlot()
+				wg.Done()
+			}()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+type branchKey struct{}
+
+// withinBranch marks ctx as belonging to a branch of a Parallel or Map state
+func withinBranch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, branchKey{}, true)
+}
+
+func isBranch(ctx context.Context) bool {
+	b, _ := ctx.Value(branchKey{}).(bool)
+	return b
+}
+
+// acquireBranchSlot takes one of the server-wide branch slots, waiting for
+// one to free up if need be. Branches that start branches of their own do not
+// wait, as the slots may all be held by their siblings doing the same; they
+// are told to run the branch inline instead, within their own slot.
+func (s *Server) acquireBranchSlot(ctx context.Context) (inline bool, err error) {
+	if s.branchSlots == nil {
+		return false, nil
+	}
+	if isBranch(ctx) {
+		select {
+		case s.branchSlots <- struct{}{}:
+			return false, nil
+		default:
+			return true, nil
+		}
+	}
+	select {
+	case s.branchSlots <- struct{}{}:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (s *Server) releaseBranchSlot() {
+	if s.branchSlots != nil {
+		<-s.branchSlots
+	}
+}
+
+// jsonArray collects the outputs of branches into a JSON array, outputs that
+// are JSON are included as is, anything else as a string.
+func jsonArray(outputs []string) (string, error) {
+	arr := make([]json.RawMessage, len(outputs))
+	for i, out := range outputs {
+		if json.Valid([]byte(out)) {
+			arr[i] = json.RawMessage(out)
+			continue
+		}
+		b, err := json.Marshal(out)
+		if err != nil {
+			return "", err
+		}
+		arr[i] = b
+	}
+	b, err := json.Marshal(arr)
+	return string(b), err
+}
+
+func (s *Server) handleTask(ctx context.Context, state *models.State, input string) (string, error) {
+	req := getHTTPRequest(input)
+	result, err := s.syncFunctionInvoke(ctx, req, state.AppName, state.FuncName)
+	if err != nil {
+		return "", err
+	}
+	return *result, nil
+}
+
+func (s *Server) handleParallelState(ctx context.Context, state *models.State, input string) (string, error) {
+	intermediateMap := make(map[string]interface{})
+	err := json.Unmarshal([]byte(input), &intermediateMap)
+	if err != nil {
+		return "", err
+	}
+	parallelExecution := state.ParallelExecution
+	if parallelExecution == nil {
+		return "", fmt.Errorf("a parallel task requires ParallelExecution field")
+	}
+
+	iterableItems, ok := intermediateMap[parallelExecution.IterableItemsKey]
+	if !ok {
+		return "", fmt.Errorf("required key %s not found", parallelExecution.IterableItemsKey)
+	}
+
+	iterableItemsArray, ok := iterableItems.([]interface{})
+	if !ok {
+		return "", fmt.Errorf("required []interface{} type, get %T", iterableItems)
+	}
+
+	// each branch gets {IterableItemName: item}, unless Parameters say otherwise
+	inputs := make([]string, len(iterableItemsArray))
+	for i, item := range iterableItemsArray {
+		var branchInput interface{} = map[string]interface{}{parallelExecution.IterableItemName: item}
+		if len(state.Parameters) > 0 {
+			branchInput, err = state.ItemInput(intermediateMap, item, i)
+			if err != nil {
+				return "", err
+			}
+		}
+		payload, err := json.Marshal(branchInput)
+		if err != nil {
+			return "", err
+		}
+		inputs[i] = string(payload)
+	}
+
+	results, err := s.runBranches(ctx, &parallelExecution.StateMachine, inputs, parallelExecution.MaxConcurrency)
+	if err != nil {
+		return "", err
+	}
+	jsonBytes, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
@@ -0,0 +1,543 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func stateMachineDatastore() models.Datastore {
+	app := &models.App{ID: "app_id", Name: "myapp", Config: models.Config{}}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	return datastore.NewMockInit(
+		[]*models.App{app},
+		[]*models.Fn{fn},
+		[]*models.Trigger{
+			{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: "http", Source: "/myfn"},
+		},
+	)
+}
+
+func twoTaskStateMachine() *models.StateMachine {
+	return &models.StateMachine{
+		StartAt: "first",
+		States: map[string]*models.State{
+			"first":  {Type: models.StateTypeTask, AppName: "myapp", FuncName: "/myfn", Next: "second"},
+			"second": {Type: models.StateTypeTask, AppName: "myapp", FuncName: "/myfn", End: true},
+		},
+	}
+}
+
+func mockAgentSubmitting(err error) *agent.MockAgent {
+	rnr := new(agent.MockAgent)
+	rnr.On("GetCall", mock.Anything).Return()
+	rnr.On("Submit", mock.Anything).Return(err)
+	return rnr
+}
+
+func onlyExecution(t *testing.T, es models.ExecutionStore) *models.Execution {
+	list, err := es.GetExecutions(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error listing executions: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Fatalf("expected 1 execution, got %d", len(list.Items))
+	}
+	return list.Items[0]
+}
+
+func TestStateMachineExecutionPersisted(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	for i, test := range []struct {
+		submitErr      error
+		expectedCode   int
+		expectedStatus string
+		expectedStates int
+	}{
+		{nil, http.StatusOK, models.ExecutionStatusSucceeded, 2},
+		{models.ErrCallTimeoutServerBusy, http.StatusServiceUnavailable, models.ExecutionStatusFailed, 1},
+	} {
+		es := datastore.NewMockExecutionStore()
+		srv := testServer(stateMachineDatastore(), mockAgentSubmitting(test.submitErr), ServerTypeFull, WithExecutionStore(es))
+
+		body, _ := json.Marshal(twoTaskStateMachine())
+		_, rec := routerRequest(t, srv.Router, "POST", "/schedule", bytes.NewReader(body))
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d. body: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+
+		exec := onlyExecution(t, es)
+		if exec.Status != test.expectedStatus {
+			t.Fatalf("Test %d: Expected execution status %s but was %s", i, test.expectedStatus, exec.Status)
+		}
+		if test.submitErr != nil && exec.Error != test.submitErr.Error() {
+			t.Fatalf("Test %d: Expected execution error %q but was %q", i, test.submitErr.Error(), exec.Error)
+		}
+
+		states, err := es.GetExecutionStates(context.Background(), exec.ID)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error getting states: %v", i, err)
+		}
+		if len(states) != test.expectedStates {
+			t.Fatalf("Test %d: Expected %d recorded states but got %d", i, test.expectedStates, len(states))
+		}
+	}
+}
+
+func TestStateMachineExecutionResume(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	es := datastore.NewMockExecutionStore()
+	rnr := mockAgentSubmitting(nil)
+	srv := testServer(stateMachineDatastore(), rnr, ServerTypeFull, WithExecutionStore(es))
+
+	// an execution that got as far as its second state before the server went away
+	exec, err := es.InsertExecution(ctx, &models.Execution{
+		StateMachine: twoTaskStateMachine(),
+		Status:       models.ExecutionStatusRunning,
+		CurrentState: "second",
+		Input:        "{}",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error inserting execution: %v", err)
+	}
+	_, err = es.InsertExecutionState(ctx, &models.ExecutionState{
+		ExecutionID: exec.ID,
+		Name:        "first",
+		Type:        models.StateTypeTask,
+		Status:      models.ExecutionStatusSucceeded,
+		Input:       "{}",
+		StartedAt:   common.DateTime(time.Now()),
+		CompletedAt: common.DateTime(time.Now()),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error inserting state: %v", err)
+	}
+
+	srv.resumeExecutions(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		exec, err = es.GetExecutionByID(ctx, exec.ID)
+		if err != nil {
+			t.Fatalf("unexpected error getting execution: %v", err)
+		}
+		if exec.Done() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("execution was not resumed, still in state %s", exec.CurrentState)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if exec.Status != models.ExecutionStatusSucceeded {
+		t.Fatalf("expected resumed execution to succeed, got %s (%s)", exec.Status, exec.Error)
+	}
+	rnr.AssertNumberOfCalls(t, "Submit", 1)
+
+	states, err := es.GetExecutionStates(ctx, exec.ID)
+	if err != nil {
+		t.Fatalf("unexpected error getting states: %v", err)
+	}
+	if len(states) != 2 || states[1].Name != "second" {
+		t.Fatalf("expected only the second state to be run on resume, got %+v", states)
+	}
+}
+
+func TestStateMachineExecutionResumeOnce(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	es := datastore.NewMockExecutionStore()
+	rnr1, rnr2 := mockAgentSubmitting(nil), mockAgentSubmitting(nil)
+	srv1 := testServer(stateMachineDatastore(), rnr1, ServerTypeFull, WithExecutionStore(es))
+	srv2 := testServer(stateMachineDatastore(), rnr2, ServerTypeLB, WithExecutionStore(es))
+
+	// an execution another node is running, with its lease not yet over
+	exec, err := es.InsertExecution(ctx, &models.Execution{
+		StateMachine: twoTaskStateMachine(),
+		Status:       models.ExecutionStatusRunning,
+		CurrentState: "second",
+		Input:        "{}",
+		StateInput:   "{}",
+		Owner:        "gone",
+		LeaseUntil:   common.UnixMilli(time.Now().Add(time.Minute)),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error inserting execution: %v", err)
+	}
+
+	srv1.resumeExecutions(ctx)
+	srv2.resumeExecutions(ctx)
+	time.Sleep(50 * time.Millisecond)
+	rnr1.AssertNumberOfCalls(t, "Submit", 0)
+	rnr2.AssertNumberOfCalls(t, "Submit", 0)
+
+	// the owner goes away, both nodes look for executions to resume
+	if err := es.RenewExecution(ctx, exec.ID, "gone", -time.Second); err != nil {
+		t.Fatalf("unexpected error expiring lease: %v", err)
+	}
+	srv1.resumeExecutions(ctx)
+	srv2.resumeExecutions(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		exec, err = es.GetExecutionByID(ctx, exec.ID)
+		if err != nil {
+			t.Fatalf("unexpected error getting execution: %v", err)
+		}
+		if exec.Done() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("execution was not resumed, still in state %s", exec.CurrentState)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if exec.Status != models.ExecutionStatusSucceeded || exec.Owner != srv1.executionOwner {
+		t.Fatalf("expected execution to be resumed by the first node to claim it, got %+v", exec)
+	}
+	rnr1.AssertNumberOfCalls(t, "Submit", 1)
+	rnr2.AssertNumberOfCalls(t, "Submit", 0)
+
+	states, err := es.GetExecutionStates(ctx, exec.ID)
+	if err != nil {
+		t.Fatalf("unexpected error getting states: %v", err)
+	}
+	if len(states) != 1 || states[0].Name != "second" {
+		t.Fatalf("expected the second state to be run once, got %+v", states)
+	}
+}
+
+func TestStateMachineStateTypes(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(stateMachineDatastore(), mockAgentSubmitting(nil), ServerTypeFull)
+
+	const classify = `{
+		"StartAt": "check",
+		"States": {
+			"check": {
+				"Type": "Choice",
+				"Choices": [
+					{"Variable": "$.n", "NumericGreaterThan": 10, "Next": "big"},
+					{"Variable": "$.n", "NumericLessThan": 0, "Next": "negative"}
+				],
+				"Default": "wait"
+			},
+			"wait": {"Type": "Wait", "Seconds": 0, "Next": "small"},
+			"small": {"Type": "Pass", "Result": {"size": "small"}, "End": true},
+			"big": {"Type": "Pass", "Result": {"size": "big"}, "Next": "done"},
+			"done": {"Type": "Succeed"},
+			"negative": {"Type": "Fail", "Error": "NegativeNumber", "Cause": "n must not be negative"}
+		}
+	}`
+
+	const mapped = `{
+		"StartAt": "each",
+		"States": {
+			"each": {
+				"Type": "Map",
+				"ItemsPath": "$.items",
+				"MaxConcurrency": 2,
+				"Iterator": {
+					"StartAt": "check",
+					"States": {
+						"check": {
+							"Type": "Choice",
+							"Choices": [{"Variable": "$.n", "NumericGreaterThan": 10, "Next": "big"}],
+							"Default": "small"
+						},
+						"small": {"Type": "Pass", "Result": "small", "End": true},
+						"big": {"Type": "Pass", "Result": "big", "End": true}
+					}
+				},
+				"End": true
+			}
+		}
+	}`
+
+	// a Timestamp that does not parse is a States.Runtime error, that Catch
+	// matches
+	const badWait = `{
+		"StartAt": "wait",
+		"States": {
+			"wait": {
+				"Type": "Wait",
+				"Timestamp": "soon",
+				"Catch": [{"ErrorEquals": ["States.Runtime"], "Next": "caught"}],
+				"Next": "done"
+			},
+			"caught": {"Type": "Pass", "Result": {"caught": true}, "End": true},
+			"done": {"Type": "Succeed"}
+		}
+	}`
+
+	for i, test := range []struct {
+		stateMachine   string
+		input          string
+		expectedCode   int
+		expectedOutput string
+	}{
+		{classify, `{"n": 5}`, http.StatusOK, `{"size": "small"}`},
+		{classify, `{"n": 50}`, http.StatusOK, `{"size": "big"}`},
+		{classify, `{"n": -1}`, http.StatusBadGateway, `{"message":"NegativeNumber: n must not be negative"}`},
+		{classify, `{}`, http.StatusBadGateway, `{"message":"States.Runtime: path \"$.n\" not found in input"}`},
+		{mapped, `{"items": [{"n": 1}, {"n": 20}, {"n": 3}]}`, http.StatusOK, `["small","big","small"]`},
+		{mapped, `{"items": 1}`, http.StatusBadGateway, `{"message":"States.Runtime: ItemsPath \"$.items\" is not an array"}`},
+		{badWait, `{}`, http.StatusOK, `{"caught": true}`},
+	} {
+		req := createRequest(t, http.MethodPost, "/schedule", bytes.NewBufferString(test.stateMachine))
+		req.Header.Set("Input-String", test.input)
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d. body: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if body := bytes.TrimSpace(rec.Body.Bytes()); string(body) != test.expectedOutput {
+			t.Fatalf("Test %d: Expected output %s but got %s", i, test.expectedOutput, body)
+		}
+	}
+}
+
+func TestStateMachineDataFlow(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(stateMachineDatastore(), mockAgentSubmitting(nil), ServerTypeFull)
+
+	const pipeline = `{
+		"StartAt": "defaults",
+		"States": {
+			"defaults": {"Type": "Pass", "Result": {"currency": "EUR"}, "ResultPath": "$.settings", "Next": "each"},
+			"each": {
+				"Type": "Map",
+				"ItemsPath": "$.orders",
+				"Parameters": {"order.$": "$$.Map.Item.Value", "currency.$": "$.settings.currency"},
+				"Iterator": {
+					"StartAt": "shape",
+					"States": {
+						"shape": {"Type": "Pass", "Parameters": {"id.$": "$.order.id", "currency.$": "$.currency"}, "End": true}
+					}
+				},
+				"ResultPath": "$.shaped",
+				"Next": "check"
+			},
+			"check": {
+				"Type": "Choice",
+				"InputPath": "$.shaped",
+				"Choices": [{"Variable": "$[0].currency", "StringEquals": "EUR", "Next": "done"}],
+				"OutputPath": "$[1]"
+			},
+			"done": {"Type": "Succeed"}
+		}
+	}`
+
+	req := createRequest(t, http.MethodPost, "/schedule", bytes.NewBufferString(pipeline))
+	req.Header.Set("Input-String", `{"orders": [{"id": "a", "qty": 1}, {"id": "b", "qty": 2}]}`)
+	_, rec := routerRequest2(t, srv.Router, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if body := rec.Body.String(); body != `{"currency":"EUR","id":"b"}` {
+		t.Fatalf("Expected the data to flow through the states, got %s", body)
+	}
+}
+
+func TestStateMachineRetryAndCatch(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	const stateMachine = `{
+		"StartAt": "call",
+		"States": {
+			"call": {
+				"Type": "Task",
+				"AppName": "myapp",
+				"FuncName": "/myfn",
+				"Retry": [{"ErrorEquals": ["Fn.ServiceUnavailable"], "IntervalSeconds": 0, "MaxAttempts": 2}],
+				"Catch": [{"ErrorEquals": ["States.ALL"], "Next": "fallback", "ResultPath": "$.error"}],
+				"End": true
+			},
+			"fallback": {"Type": "Pass", "End": true}
+		}
+	}`
+
+	busy := models.ErrCallTimeoutServerBusy
+	for i, test := range []struct {
+		failures       int
+		expectedSubmit int
+		expectedOutput string
+	}{
+		// busy twice, then the retry goes through
+		{2, 3, ``},
+		// busy every time, retries run out and the error is caught
+		{3, 3, `{"error":{"Cause":"Timed out - server too busy","Error":"Fn.ServiceUnavailable"},"in":1}`},
+	} {
+		rnr := new(agent.MockAgent)
+		rnr.On("GetCall", mock.Anything).Return()
+		rnr.On("Submit", mock.Anything).Return(busy).Times(test.failures)
+		rnr.On("Submit", mock.Anything).Return(nil)
+		srv := testServer(stateMachineDatastore(), rnr, ServerTypeFull)
+
+		req := createRequest(t, http.MethodPost, "/schedule", bytes.NewBufferString(stateMachine))
+		req.Header.Set("Input-String", `{"in": 1}`)
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d. body: %s", i, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if body := rec.Body.String(); body != test.expectedOutput {
+			t.Fatalf("Test %d: Expected output %s but got %s", i, test.expectedOutput, body)
+		}
+		rnr.AssertNumberOfCalls(t, "Submit", test.expectedSubmit)
+	}
+}
+
+// inFlightAgent is a mock agent whose calls take a little while, recording
+// the most calls it had in flight at once
+func inFlightAgent(maxInFlight *int32) *agent.MockAgent {
+	var inFlight int32
+	rnr := new(agent.MockAgent)
+	rnr.On("GetCall", mock.Anything).Return()
+	rnr.On("Submit", mock.Anything).Return(nil).Run(func(mock.Arguments) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+	})
+	return rnr
+}
+
+func TestStateMachineBranchConcurrency(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	// each branch calls the function, keeping its input as its output
+	const branch = `{"StartAt": "call", "States": {"call": {"Type": "Task", "AppName": "myapp", "FuncName": "/myfn",
+		"ResultPath": "$.r", "OutputPath": "$.%s", "End": true}}}`
+	parallel := func(maxConcurrency int) string {
+		return fmt.Sprintf(`{"StartAt": "fan", "States": {"fan": {"Type": "Parallel", "End": true,
+			"ParallelExecution": {"IterableItemsKey": "items", "IterableItemName": "item", "MaxConcurrency": %d, "StateMachine": %s}}}}`,
+			maxConcurrency, fmt.Sprintf(branch, "item"))
+	}
+	mapped := fmt.Sprintf(`{"StartAt": "outer", "States": {"outer": {"Type": "Map", "End": true, "Iterator":
+		{"StartAt": "inner", "States": {"inner": {"Type": "Map", "ItemsPath": "$.items", "End": true, "Iterator": %s}}}}}}`,
+		fmt.Sprintf(branch, "n"))
+
+	for i, test := range []struct {
+		stateMachine   string
+		input          string
+		serverMax      int
+		expectedMax    int32
+		expectedOutput string
+	}{
+		{parallel(2), `{"items": [1, 2, 3, 4, 5, 6]}`, 0, 2, `["1","2","3","4","5","6"]`},
+		{parallel(0), `{"items": [1, 2, 3, 4]}`, 1, 1, `["1","2","3","4"]`},
+		// the outer branches hold the only slots, so the inner ones run in place
+		{mapped, `[{"items": [{"n": 1}, {"n": 2}]}, {"items": [{"n": 3}]}]`, 2, 2, `[[1,2],[3]]`},
+	} {
+		var maxInFlight int32
+		var opts []Option
+		if test.serverMax > 0 {
+			opts = append(opts, WithMaxWorkflowBranches(test.serverMax))
+		}
+		srv := testServer(stateMachineDatastore(), inFlightAgent(&maxInFlight), ServerTypeFull, opts...)
+
+		req := createRequest(t, http.MethodPost, "/schedule", bytes.NewBufferString(test.stateMachine))
+		req.Header.Set("Input-String", test.input)
+		_, rec := routerRequest2(t, srv.Router, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d. body: %s", i, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if body := rec.Body.String(); body != test.expectedOutput {
+			t.Fatalf("Test %d: Expected output %s but got %s", i, test.expectedOutput, body)
+		}
+		if maxInFlight > test.expectedMax {
+			t.Errorf("Test %d: Expected at most %d branches at once, got %d", i, test.expectedMax, maxInFlight)
+		}
+	}
+}
+
+func TestStateMachineBranchFailureCancels(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	const stateMachine = `{"StartAt": "fan", "States": {"fan": {"Type": "Parallel", "End": true,
+		"ParallelExecution": {"IterableItemsKey": "items", "IterableItemName": "item", "MaxConcurrency": 1, "StateMachine":
+			{"StartAt": "call", "States": {"call": {"Type": "Task", "AppName": "myapp", "FuncName": "/myfn", "End": true}}}}}}}`
+
+	rnr := new(agent.MockAgent)
+	rnr.On("GetCall", mock.Anything).Return()
+	rnr.On("Submit", mock.Anything).Return(models.ErrCallTimeoutServerBusy).Once()
+	rnr.On("Submit", mock.Anything).Return(nil)
+	srv := testServer(stateMachineDatastore(), rnr, ServerTypeFull)
+
+	req := createRequest(t, http.MethodPost, "/schedule", bytes.NewBufferString(stateMachine))
+	req.Header.Set("Input-String", `{"items": [1, 2, 3, 4, 5]}`)
+	_, rec := routerRequest2(t, srv.Router, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusServiceUnavailable, rec.Code, rec.Body.String())
+	}
+	// the first branch failed, so none of the others should have been started
+	rnr.AssertNumberOfCalls(t, "Submit", 1)
+}
@@ -15,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 	"unicode"
 
 	"contrib.go.opencensus.io/exporter/jaeger"
@@ -32,6 +33,8 @@ import (
 	"github.com/fnproject/fn/api/agent/hybrid"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
 	"github.com/fnproject/fn/api/models"
 	pool "github.com/fnproject/fn/api/runnerpool"
 	"github.com/fnproject/fn/api/version"
@@ -64,6 +67,12 @@ const (
 	// possible schemes: { postgres, sqlite3, mysql }
 	EnvDBURL = "FN_DB_URL"
 
+	// EnvLogDBURL is a url to a store of the records and logs of calls:
+	// possible schemes: { file, memory } or any of EnvDBURL's
+	// defaults to the db of EnvDBURL. An lb node only records calls if this is
+	// set, to a store that the api nodes share.
+	EnvLogDBURL = "FN_LOGSTORE_URL"
+
 	// EnvRunnerURL is a url pointing to an Fn API service.
 	EnvRunnerURL = "FN_RUNNER_API_URL"
 
@@ -107,6 +116,24 @@ const (
 	// EnvMaxRequestSize sets the limit in bytes for any API request body's length.
 	EnvMaxRequestSize = "FN_MAX_REQUEST_SIZE"
 
+	// EnvMaxWorkflowBranches limits the number of Parallel and Map branches of
+	// state machines running at once on this server, across all executions.
+	EnvMaxWorkflowBranches = "FN_MAX_WORKFLOW_BRANCHES"
+
+	// EnvLoadGenTraceDir is a directory of trace files that load generator
+	// workloads may replay by name.
+	EnvLoadGenTraceDir = "FN_LOADGEN_TRACE_DIR"
+
+	// EnvAsyncWorkers is how many calls of the async queue run at once on this server.
+	EnvAsyncWorkers = "FN_ASYNC_WORKERS"
+
+	// EnvAsyncVisibilityTimeout is how long an async call is hidden from other workers once
+	// one reserves it, after which it is run again. It should be longer than any fn timeout.
+	EnvAsyncVisibilityTimeout = "FN_ASYNC_VISIBILITY_TIMEOUT"
+
+	// EnvAsyncMaxAttempts is how many times an async call is attempted before it goes to the dead-letter list.
+	EnvAsyncMaxAttempts = "FN_ASYNC_MAX_ATTEMPTS"
+
 	// EnvMaxHeaderSize sets the limit in bytes for any API request body's length.
 	EnvMaxHeaderSize = "FN_MAX_REQUEST_HEADER_SIZE"
 
@@ -141,6 +168,18 @@ const (
 
 	// DefaultGRPCPort is 9190
 	DefaultGRPCPort = 9190
+
+	// DefaultAsyncWorkers is 10
+	DefaultAsyncWorkers = 10
+
+	// DefaultAsyncVisibilityTimeout is 10 minutes
+	DefaultAsyncVisibilityTimeout = 10 * time.Minute
+
+	// DefaultAsyncMaxAttempts is 5
+	DefaultAsyncMaxAttempts = 5
+
+	// DefaultExecutionLease is 30 seconds
+	DefaultExecutionLease = 30 * time.Second
 )
 
 // NodeType is the mode to run fn in.
@@ -193,9 +232,34 @@ type Server struct {
 	Router      *gin.Engine
 	AdminRouter *gin.Engine
 
-	agent     agent.Agent
-	datastore models.Datastore
-	nodeType  NodeType
+	agent          agent.Agent
+	datastore      models.Datastore
+	executionStore models.ExecutionStore
+	asyncQueue     models.AsyncQueue
+	callStore      models.CallStore
+	logStore       models.LogStore
+	nodeType       NodeType
+
+	// callStoreShared is set when the call and log stores are configured on
+	// their own, rather than taken from the datastore of this node
+	callStoreShared bool
+
+	// async calls are drained by asyncWorkers workers, which hide a call
+	// from the others for asyncVisibility and make asyncMaxAttempts attempts
+	asyncWorkers     int
+	asyncVisibility  time.Duration
+	asyncMaxAttempts int
+
+	// executions are run by the node that owns them, executionOwner being this
+	// node, which holds them for executionLease at a time
+	executionOwner string
+	executionLease time.Duration
+
+	// branchSlots bounds the state machine branches in flight, nil for no bound
+	branchSlots chan struct{}
+
+	// loadGenTraceDir holds the trace files load generator workloads may name
+	loadGenTraceDir string
 
 	// Service Settings for Admin/Web/gRPC. Note that for gRPC only
 	// TLSConfig and Addr are transferrable from http.Server to GRPC service.
@@ -251,10 +315,17 @@ func NewFromEnv(ctx context.Context, opts ...Option) *Server {
 	opts = append(opts, WithZipkin(getEnv(EnvZipkinURL, "")))
 	opts = append(opts, WithJaeger(getEnv(EnvJaegerURL, "")))
 	opts = append(opts, WithPrometheus()) // TODO option to turn this off?
+	opts = append(opts, WithLogURL(getEnv(EnvLogDBURL, "")))
 	opts = append(opts, WithDBURL(getEnv(EnvDBURL, defaultDB)))
 	opts = append(opts, WithType(nodeType))
 
 	opts = append(opts, LimitRequestBody(int64(getEnvInt(EnvMaxRequestSize, 0))))
+	opts = append(opts, WithMaxWorkflowBranches(getEnvInt(EnvMaxWorkflowBranches, 0)))
+	opts = append(opts, WithLoadGenTraceDir(getEnv(EnvLoadGenTraceDir, "")))
+	opts = append(opts, WithAsyncWorkers(
+		getEnvInt(EnvAsyncWorkers, DefaultAsyncWorkers),
+		getEnvDuration(EnvAsyncVisibilityTimeout, DefaultAsyncVisibilityTimeout),
+		getEnvInt(EnvAsyncMaxAttempts, DefaultAsyncMaxAttempts)))
 
 	publicLBURL := getEnv(EnvPublicLoadBalancerURL, "")
 	if publicLBURL != "" {
@@ -344,6 +415,22 @@ func WithDBURL(dbURL string) Option {
 	}
 }
 
+// WithLogURL maps EnvLogDBURL
+func WithLogURL(logstoreURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		if logstoreURL != "" {
+			ls, err := logs.New(ctx, logstoreURL)
+			if err != nil {
+				return err
+			}
+			s.callStore = ls
+			s.logStore = ls
+			s.callStoreShared = true
+		}
+		return nil
+	}
+}
+
 // WithType maps EnvNodeType
 func WithType(t NodeType) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -371,6 +458,18 @@ func WithReadDataAccess(ds agent.ReadDataAccess) Option {
 // WithDatastore allows directly setting a datastore
 func WithDatastore(ds models.Datastore) Option {
 	return func(ctx context.Context, s *Server) error {
+		if es, ok := ds.(models.ExecutionStore); ok && s.executionStore == nil {
+			s.executionStore = es
+		}
+		if aq, ok := ds.(models.AsyncQueue); ok && s.asyncQueue == nil {
+			s.asyncQueue = aq
+		}
+		if cs, ok := ds.(models.CallStore); ok && s.callStore == nil {
+			s.callStore = cs
+		}
+		if ls, ok := ds.(models.LogStore); ok && s.logStore == nil {
+			s.logStore = ls
+		}
 		s.datastore = ds
 		s.datastore = datastore.Wrap(s.datastore)
 		s.datastore = fnext.NewDatastore(s.datastore, s.appListeners, s.fnListeners, s.triggerListeners)
@@ -381,6 +480,86 @@ func WithDatastore(ds models.Datastore) Option {
 	}
 }
 
+// WithExecutionStore allows directly setting the store that state machine
+// executions are persisted to. If none is set, and the datastore does not
+// implement models.ExecutionStore, executions only live in memory.
+func WithExecutionStore(es models.ExecutionStore) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.executionStore = es
+		return nil
+	}
+}
+
+// WithAsyncQueue allows directly setting the queue that async calls are
+// persisted to. If none is set, and the datastore does not implement
+// models.AsyncQueue, async calls are not supported.
+func WithAsyncQueue(aq models.AsyncQueue) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.asyncQueue = aq
+		return nil
+	}
+}
+
+// WithCallStore allows directly setting the store that the records of calls
+// are kept in. If none is set, and the datastore does not implement
+// models.CallStore, calls are not recorded.
+func WithCallStore(cs models.CallStore) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.callStore = cs
+		s.callStoreShared = true
+		return nil
+	}
+}
+
+// WithLogStore allows directly setting the store that the logs of calls are
+// kept in. If none is set, and the datastore does not implement
+// models.LogStore, the logs of calls are not kept.
+func WithLogStore(ls models.LogStore) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.logStore = ls
+		s.callStoreShared = true
+		return nil
+	}
+}
+
+// WithAsyncWorkers sets how many workers drain the async queue, how long a
+// call is hidden from the other workers once one reserves it, and how many
+// attempts are made to run a call before it goes to the dead-letter list.
+func WithAsyncWorkers(workers int, visibility time.Duration, maxAttempts int) Option {
+	return func(ctx context.Context, s *Server) error {
+		if workers < 0 || visibility <= 0 || maxAttempts <= 0 {
+			return fmt.Errorf("invalid async workers %d, visibility timeout %s and max attempts %d", workers, visibility, maxAttempts)
+		}
+		s.asyncWorkers = workers
+		s.asyncVisibility = visibility
+		s.asyncMaxAttempts = maxAttempts
+		return nil
+	}
+}
+
+// WithMaxWorkflowBranches limits the number of Parallel and Map branches
+// that run at once across every state machine execution on this server.
+// There is no limit if max is 0.
+func WithMaxWorkflowBranches(max int) Option {
+	return func(ctx context.Context, s *Server) error {
+		if max < 0 {
+			return fmt.Errorf("invalid max workflow branches %d", max)
+		}
+		if max > 0 {
+			s.branchSlots = make(chan struct{}, max)
+		}
+		return nil
+	}
+}
+
+// WithLoadGenTraceDir maps EnvLoadGenTraceDir
+func WithLoadGenTraceDir(dir string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.loadGenTraceDir = dir
+		return nil
+	}
+}
+
 // WithAgent allows directly setting an agent
 func WithAgent(agent agent.Agent) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -531,6 +710,13 @@ func New(ctx context.Context, opts ...Option) *Server {
 		fnListeners:      new(fnListeners),
 		triggerListeners: new(triggerListeners),
 
+		asyncWorkers:     DefaultAsyncWorkers,
+		asyncVisibility:  DefaultAsyncVisibilityTimeout,
+		asyncMaxAttempts: DefaultAsyncMaxAttempts,
+
+		executionOwner: id.New().String(),
+		executionLease: DefaultExecutionLease,
+
 		// Almost everything else is configured through opts (see NewFromEnv for ex.) or below
 	}
 
@@ -591,6 +777,18 @@ func New(ctx context.Context, opts ...Option) *Server {
 
 	}
 
+	// record the calls that run on this node, if there is anywhere to keep them
+	switch {
+	case s.nodeType == ServerTypeFull && (s.callStore != nil || s.logStore != nil):
+		s.AddCallListener(&callStoreListener{calls: s.callStore, logs: s.logStore})
+	case s.nodeType == ServerTypeLB && s.callStoreShared && s.callStore != nil:
+		// the logs of a call stay on the runner that ran it, so an lb only
+		// keeps the records, and only in a store that the api nodes share
+		s.AddCallListener(&callStoreListener{calls: s.callStore})
+	case s.nodeType == ServerTypeLB:
+		logrus.Warnf("calls are not recorded on this lb node, set %s to a call store shared with the api nodes to record them", EnvLogDBURL)
+	}
+
 	s.Router.Use(loggerWrap, traceWrap) // TODO should be opts
 	optionalCorsWrap(s.Router)          // TODO should be an opt
 	apiMetricsWrap(s)
@@ -916,6 +1114,14 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 		}()
 	}
 
+	if s.executionStore != nil && (s.nodeType == ServerTypeFull || s.nodeType == ServerTypeLB) {
+		go s.watchExecutions(ctx)
+	}
+
+	if s.asyncQueue != nil && (s.nodeType == ServerTypeFull || s.nodeType == ServerTypeLB) {
+		s.startAsyncWorkers(ctx)
+	}
+
 	// listening for signals or listener errors or cancellations on all registered contexts.
 	s.extraCtxs = append(s.extraCtxs, ctx)
 	cases := make([]reflect.SelectCase, len(s.extraCtxs))
@@ -949,10 +1155,6 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 	}
 }
 
-func (s *Server) goneResponse(c *gin.Context) {
-	c.Status(http.StatusGone)
-}
-
 func (s *Server) bindHandlers(ctx context.Context) {
 	engine := s.Router
 	admin := s.AdminRouter
@@ -996,12 +1198,16 @@ func (s *Server) bindHandlers(ctx context.Context) {
 			v2.GET("/triggers/:trigger_id", s.handleTriggerGet)
 			v2.PUT("/triggers/:trigger_id", s.handleTriggerUpdate)
 			v2.DELETE("/triggers/:trigger_id", s.handleTriggerDelete)
+
+			v2.GET("/statemachines", s.handleStateMachineList)
+			v2.POST("/statemachines", s.handleStateMachineCreate)
+			v2.GET("/statemachines/:statemachine_name", s.handleStateMachineGet)
+			v2.DELETE("/statemachines/:statemachine_name", s.handleStateMachineDelete)
 		}
 
-		// TODO remove these in 30 days or something
-		v2.GET("/fns/:fn_id/calls", s.goneResponse)
-		v2.GET("/fns/:fn_id/calls/:call_id", s.goneResponse)
-		v2.GET("/fns/:fn_id/calls/:call_id/log", s.goneResponse)
+		v2.GET("/fns/:fn_id/calls", s.handleCallList)
+		v2.GET("/fns/:fn_id/calls/:call_id", s.handleCallGet)
+		v2.GET("/fns/:fn_id/calls/:call_id/log", s.handleCallLogGet)
 
 		// TODO figure out how to deprecate
 		runner := cleanv2.Group("/runner")
@@ -1022,27 +1228,38 @@ func (s *Server) bindHandlers(ctx context.Context) {
 			lbFnInvokeGroup.POST("/:fn_id", s.handleFnInvokeCall)
 		}
 
-		lbSpikeGroup := engine.Group("/spike")
-		lbSpikeGroup.Any("/:fn_id/*spike_cnt", s.handleSpikeCall)
+		lbLoadGenGroup := engine.Group("/loadgen")
+		lbLoadGenGroup.POST("/:fn_id", s.handleLoadGen)
 
-		// API for random function invoke, useless, abondon for now
-		lbRandomGroup := engine.Group("/random")
-		lbRandomGroup.Any("/:fn_id/*spike_cnt", s.randomInvoke)
-
-		lbRealSpikeGroup := engine.Group("/realspike")
-		lbRealSpikeGroup.Any("/:fn_id/*spike_cnt", s.handleRealSpike)
-
-		lbRealSpikeGroup2 := engine.Group("/realspike2")
-		lbRealSpikeGroup2.Any("/:fn_id/*spike_cnt", s.handleRealSpike2)
-
-		lbCacheGroup := engine.Group("/cache")
-		lbCacheGroup.Any("/:fn_id/*spike_cnt", s.handleCache)
+		lbReplayGroup := engine.Group("/replay")
+		lbReplayGroup.POST("", s.handleReplay)
 
 		lbSchedulerGroup := engine.Group("/schedule")
 		lbSchedulerGroup.Any("", s.handleHTTPSchedulerCall)
 
+		// executions are run by the node that accepts them, so these live
+		// alongside the invoke endpoints rather than with the rest of /v2
+		executions := engine.Group("/v2/executions")
+		executions.Use(s.apiMiddlewareWrapper())
+		executions.POST("", s.handleExecutionCreate)
+		executions.GET("/:execution_id", s.handleExecutionGet)
+		executions.GET("/:execution_id/history", s.handleExecutionHistory)
+
+		// as are the async calls, which the workers of this node drain
+		async := engine.Group("/v2/async")
+		async.Use(s.apiMiddlewareWrapper())
+		async.GET("", s.handleAsyncCallList)
+		async.GET("/:call_id", s.handleAsyncCallGet)
+
+		// logs are followed on the nodes that run calls, or route them to runners
+		fnLogs := engine.Group("/v2/fns")
+		fnLogs.Use(s.apiMiddlewareWrapper())
+		fnLogs.GET("/:fn_id/logs", s.handleFnLogs)
+
 		benchmarkGroup := engine.Group("/benchmark")
 		benchmarkGroup.Any("", s.benchmark)
+
+		admin.POST("/prewarm/:fn_id", s.handlePreWarm)
 	}
 
 	engine.NoRoute(func(c *gin.Context) {
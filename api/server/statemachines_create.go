@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleStateMachineCreate stores a state machine, as a new version if one of
+// the same name already exists in the app.
+func (s *Server) handleStateMachineCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+	sm := &models.StoredStateMachine{}
+
+	err := c.BindJSON(sm)
+	if err != nil {
+		if !models.IsAPIError(err) {
+			err = models.ErrInvalidJSON
+		}
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if sm.Definition != nil {
+		if err := sm.Definition.Validate(); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		if err := s.checkStateMachineFuncs(ctx, sm.Definition); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	smCreated, err := s.datastore.InsertStateMachine(ctx, sm)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, smCreated)
+}
+
+// checkStateMachineFuncs checks that every function the state machine invokes
+// exists, by looking up the app and HTTP trigger each Task state refers to.
+func (s *Server) checkStateMachineFuncs(ctx context.Context, sm *models.StateMachine) error {
+	for _, ref := range sm.FuncRefs() {
+		appID, err := s.datastore.GetAppID(ctx, ref.AppName)
+		if err == models.ErrAppsNotFound {
+			return models.NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid state machine: app %q does not exist", ref.AppName))
+		} else if err != nil {
+			return err
+		}
+
+		_, err = s.datastore.GetTriggerBySource(ctx, appID, models.TriggerTypeHTTP, ref.FuncName)
+		if err == models.ErrTriggerNotFound {
+			return models.NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid state machine: function %q does not exist in app %q", ref.FuncName, ref.AppName))
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
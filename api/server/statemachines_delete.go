@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+// handleStateMachineDelete removes every version of a stored state machine
+func (s *Server) handleStateMachineDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.datastore.RemoveStateMachine(ctx, c.Query("app_id"), c.Param(api.StateMachineName))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
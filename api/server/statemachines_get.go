@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleStateMachineGet returns the latest version of a stored state machine,
+// or the version given in the query.
+func (s *Server) handleStateMachineGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var version int64
+	if v := c.Query("version"); v != "" {
+		var err error
+		version, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || version <= 0 {
+			handleErrorResponse(c, models.ErrStateMachinesInvalidVersion)
+			return
+		}
+	}
+
+	sm, err := s.datastore.GetStateMachine(ctx, c.Query("app_id"), c.Param(api.StateMachineName), version)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sm)
+}
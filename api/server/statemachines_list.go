@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleStateMachineList lists the latest version of each state machine in
+// an app, or every version of one state machine if a name is given.
+func (s *Server) handleStateMachineList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var filter models.StateMachineFilter
+	filter.Cursor, filter.PerPage = pageParams(c)
+	filter.AppID = c.Query("app_id")
+	filter.Name = c.Query("name")
+
+	sms, err := s.datastore.GetStateMachines(ctx, &filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sms)
+}
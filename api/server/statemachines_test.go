@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func storedStateMachineDatastore() models.Datastore {
+	app := &models.App{ID: "app_id", Name: "myapp", Config: models.Config{}}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	return datastore.NewMockInit(
+		[]*models.App{app},
+		[]*models.Fn{fn},
+		[]*models.Trigger{
+			{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: models.TriggerTypeHTTP, Source: "/myfn"},
+			{ID: "sm_trigger_id", Name: "smtrigger", AppID: app.ID, Type: models.TriggerTypeStateMachine, Source: "/workflow", StateMachine: "mysm"},
+		},
+		[]*models.StoredStateMachine{
+			{ID: "sm_id", AppID: app.ID, Name: "mysm", Version: 1, Definition: twoTaskStateMachine()},
+		},
+	)
+}
+
+func TestStateMachineCreate(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	sm, _ := json.Marshal(twoTaskStateMachine())
+	missingNext, _ := json.Marshal(&models.StateMachine{
+		StartAt: "first",
+		States: map[string]*models.State{
+			"first": {Type: models.StateTypeTask, AppName: "myapp", FuncName: "/myfn"},
+		},
+	})
+	missingFn, _ := json.Marshal(&models.StateMachine{
+		StartAt: "first",
+		States: map[string]*models.State{
+			"first": {Type: models.StateTypeTask, AppName: "myapp", FuncName: "/nofn", End: true},
+		},
+	})
+	missingApp, _ := json.Marshal(&models.StateMachine{
+		StartAt: "first",
+		States: map[string]*models.State{
+			"first": {Type: models.StateTypeTask, AppName: "noapp", FuncName: "/myfn", End: true},
+		},
+	})
+
+	for i, test := range []struct {
+		body            string
+		expectedCode    int
+		expectedError   error
+		expectedVersion int64
+	}{
+		{``, http.StatusBadRequest, models.ErrInvalidJSON, 0},
+		{fmt.Sprintf(`{"name": "mysm", "definition": %s}`, sm), http.StatusBadRequest, models.ErrStateMachinesMissingAppID, 0},
+		{fmt.Sprintf(`{"app_id": "nope", "name": "mysm", "definition": %s}`, sm), http.StatusNotFound, models.ErrAppsNotFound, 0},
+		{`{"app_id": "app_id", "name": "mysm"}`, http.StatusBadRequest, models.ErrStateMachinesMissingDefinition, 0},
+		{fmt.Sprintf(`{"app_id": "app_id", "name": "mysm", "version": 4, "definition": %s}`, sm), http.StatusBadRequest, models.ErrStateMachinesVersionProvided, 0},
+		{fmt.Sprintf(`{"app_id": "app_id", "name": "mysm", "definition": %s}`, missingNext), http.StatusBadRequest,
+			fmt.Errorf(`Invalid state machine: state "first" has neither Next nor End`), 0},
+		{fmt.Sprintf(`{"app_id": "app_id", "name": "mysm", "definition": %s}`, missingFn), http.StatusBadRequest,
+			fmt.Errorf(`Invalid state machine: function "/nofn" does not exist in app "myapp"`), 0},
+		{fmt.Sprintf(`{"app_id": "app_id", "name": "mysm", "definition": %s}`, missingApp), http.StatusBadRequest,
+			fmt.Errorf(`Invalid state machine: app "noapp" does not exist`), 0},
+		{fmt.Sprintf(`{"app_id": "app_id", "name": "newsm", "definition": %s}`, sm), http.StatusOK, nil, 1},
+		{fmt.Sprintf(`{"app_id": "app_id", "name": "mysm", "definition": %s}`, sm), http.StatusOK, nil, 2},
+	} {
+		srv := testServer(storedStateMachineDatastore(), mockAgentSubmitting(nil), ServerTypeFull)
+
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/v2/statemachines", bytes.NewBufferString(test.body))
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d. body: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+
+		if test.expectedError != nil {
+			resp := getErrorResponse(t, rec)
+			if resp.Message != test.expectedError.Error() {
+				t.Errorf("Test %d: Expected error message to have `%s`, but it was `%s`", i, test.expectedError, resp.Message)
+			}
+			continue
+		}
+
+		var created models.StoredStateMachine
+		if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+			t.Fatalf("Test %d: error decoding state machine: %v", i, err)
+		}
+		if created.ID == "" || created.Version != test.expectedVersion || created.Definition == nil {
+			t.Errorf("Test %d: unexpected state machine returned: %+v", i, created)
+		}
+	}
+}
+
+func TestStateMachineGetListDelete(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := storedStateMachineDatastore()
+	srv := testServer(ds, mockAgentSubmitting(nil), ServerTypeFull)
+
+	sm, _ := json.Marshal(twoTaskStateMachine())
+	_, rec := routerRequest(t, srv.Router, http.MethodPost, "/v2/statemachines", bytes.NewBufferString(fmt.Sprintf(`{"app_id": "app_id", "name": "mysm", "definition": %s}`, sm)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected a second version to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for i, test := range []struct {
+		path            string
+		expectedCode    int
+		expectedError   error
+		expectedVersion int64
+	}{
+		{"/v2/statemachines/mysm", http.StatusBadRequest, models.ErrStateMachinesMissingAppID, 0},
+		{"/v2/statemachines/nosm?app_id=app_id", http.StatusNotFound, models.ErrStateMachinesNotFound, 0},
+		{"/v2/statemachines/mysm?app_id=app_id&version=x", http.StatusBadRequest, models.ErrStateMachinesInvalidVersion, 0},
+		{"/v2/statemachines/mysm?app_id=app_id&version=3", http.StatusNotFound, models.ErrStateMachinesNotFound, 0},
+		{"/v2/statemachines/mysm?app_id=app_id", http.StatusOK, nil, 2},
+		{"/v2/statemachines/mysm?app_id=app_id&version=1", http.StatusOK, nil, 1},
+	} {
+		_, rec := routerRequest(t, srv.Router, http.MethodGet, test.path, nil)
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d. body: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedError != nil {
+			resp := getErrorResponse(t, rec)
+			if resp.Message != test.expectedError.Error() {
+				t.Errorf("Test %d: Expected error message to have `%s`, but it was `%s`", i, test.expectedError, resp.Message)
+			}
+			continue
+		}
+		var got models.StoredStateMachine
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("Test %d: error decoding state machine: %v", i, err)
+		}
+		if got.Name != "mysm" || got.Version != test.expectedVersion {
+			t.Errorf("Test %d: expected version %d, got %+v", i, test.expectedVersion, got)
+		}
+	}
+
+	_, rec = routerRequest(t, srv.Router, http.MethodGet, "/v2/statemachines?app_id=app_id&name=mysm", nil)
+	var list models.StateMachineList
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("error decoding state machine list: %v", err)
+	}
+	if len(list.Items) != 2 || list.Items[0].Version != 2 || list.Items[1].Version != 1 {
+		t.Fatalf("expected both versions latest first, got %+v", list.Items)
+	}
+
+	_, rec = routerRequest(t, srv.Router, http.MethodDelete, "/v2/statemachines/mysm?app_id=app_id", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected state machine to be deleted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	_, rec = routerRequest(t, srv.Router, http.MethodGet, "/v2/statemachines/mysm?app_id=app_id&version=1", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected every version to be gone, got %d", rec.Code)
+	}
+	_, err := ds.GetTriggerBySource(context.Background(), "app_id", models.TriggerTypeStateMachine, "/workflow")
+	if err != models.ErrTriggerNotFound {
+		t.Fatalf("Expected the trigger of the state machine to be gone, got %v", err)
+	}
+}
+
+func TestStateMachineTrigger(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	for i, test := range []struct {
+		submitErr    error
+		expectedCode int
+	}{
+		{nil, http.StatusOK},
+		{models.ErrCallTimeoutServerBusy, http.StatusServiceUnavailable},
+	} {
+		es := datastore.NewMockExecutionStore()
+		srv := testServer(storedStateMachineDatastore(), mockAgentSubmitting(test.submitErr), ServerTypeFull, WithExecutionStore(es))
+
+		_, rec := routerRequest(t, srv.Router, http.MethodPost, "/t/myapp/workflow", bytes.NewBufferString(`{"order": 1}`))
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: Expected status code to be %d but was %d. body: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+
+		exec := onlyExecution(t, es)
+		if exec.Input != `{"order": 1}` {
+			t.Errorf("Test %d: expected the request body as the execution input, got %q", i, exec.Input)
+		}
+		if id := rec.Header().Get("Fn-Execution-Id"); id != exec.ID {
+			t.Errorf("Test %d: expected execution ID %q in the response, got %q", i, exec.ID, id)
+		}
+	}
+}
+
+func TestStateMachineTriggerLB(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	// the state machine is only in the datastore read through, not in the
+	// local datastore of the LB node
+	srv := testServer(datastore.NewMock(), mockAgentSubmitting(nil), ServerTypeLB,
+		WithReadDataAccess(storedStateMachineDatastore()))
+
+	_, rec := routerRequest(t, srv.Router, http.MethodPost, "/t/myapp/workflow", bytes.NewBufferString(`{"order": 1}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code to be %d but was %d. body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+}
@@ -16,7 +16,7 @@ type TriggerAnnotator interface {
 type requestBasedTriggerAnnotator struct{}
 
 func annotateTriggerWithBaseURL(baseURL string, app *models.App, t *models.Trigger) (*models.Trigger, error) {
-	if t.Type != models.TriggerTypeHTTP {
+	if t.Type != models.TriggerTypeHTTP && t.Type != models.TriggerTypeStateMachine {
 		return t, nil
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/agent/drivers/containerd"
 	"github.com/fnproject/fn/api/agent/drivers/docker"
 	"github.com/fnproject/fn/api/server"
 
@@ -52,5 +53,8 @@ func registerViews() {
 	// Register docker client views
 	docker.RegisterViews(keys, latencyDist)
 
+	// Register containerd client views
+	containerd.RegisterViews(keys)
+
 	server.RegisterAPIViews(keys, latencyDist)
 }
@@ -8,4 +8,8 @@ var (
 	// MiddlewareControllerKey is a context key. It can be used in handlers with context.WithValue to
 	// access the MiddlewareContext.
 	MiddlewareControllerKey = contextKey("middleware_controller")
+
+	// CallLogKey is a context key. In CallListener.AfterCall, it holds an io.Reader of the
+	// stderr captured from the call, if any was. It may only be read until AfterCall returns.
+	CallLogKey = contextKey("call_log")
 )